@@ -25,6 +25,7 @@ import (
 	"github.com/go-co-op/gocron/v2"
 	"github.com/labstack/echo-contrib/echoprometheus"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
@@ -39,16 +40,37 @@ func main() {
 
 	config := config.CreateNewConfig()
 
-	midtransClient := paymentgateway.CreateMidtransClient(config)
-
-	kafkaProducer := kafka.CreateKafkaProducer(config)
-	kafkaReader := kafka.CreateKafkaReader(config)
-
 	db, err := postgres.GetDBInstance(config.PostgreSQLConfig.DBUsername, config.PostgreSQLConfig.DBPassword, config.PostgreSQLConfig.DBHost, config.PostgreSQLConfig.DBPort, config.PostgreSQLConfig.DBName)
 	if err != nil {
 		panic(err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = runMigrateCommand(db, os.Args[2:])
+		if err != nil {
+			log.Fatal().Err(err).Str("component", "migrate").Msg("")
+		}
+		return
+	}
+
+	if config.AutoMigrate {
+		err = autoMigrate(db)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	paymentGateways := paymentgateway.CreateRegistry(
+		config.PaymentGatewayConfig.DefaultGateway,
+		paymentgateway.CreateMidtransGateway(config),
+		paymentgateway.CreateFakeGateway(),
+		paymentgateway.CreateCashGateway(),
+	)
+
+	kafkaProducer := kafka.CreateKafkaProducer(config)
+	orderEventWriter := kafka.CreateKafkaWriter(config, config.KafkaConfig.OrderEventTopic)
+	defer orderEventWriter.Close()
+
 	traceProvider, err := tracing.InitTracing(config.TracingConfig.CollectorHost)
 	if err != nil {
 		fmt.Println(err)
@@ -113,10 +135,22 @@ func main() {
 
 	cb := circuitbreaker.CreateCircuitBreaker("order-service")
 
+	isLoggedIn := middleware.JWTWithConfig(middleware.JWTConfig{
+		SigningKey: []byte(config.JWTSecret),
+		ErrorHandlerWithContext: func(err error, c echo.Context) error {
+			errorResponse := map[string]interface{}{
+				"status":  "error",
+				"message": "Invalid or expired JWT",
+				"errors":  nil,
+			}
+			return c.JSON(http.StatusUnauthorized, errorResponse)
+		},
+	})
+
 	orderRepo := repository.CreateOrderRepository(db)
-	orderSvc := service.CreateOrderService(orderRepo, midtransClient, kafkaReader, kafkaProducer, config, cb, productCommandGrpcClient, productQueryGrpcClient)
-	controller.CreateOrderController(g, orderSvc)
-	s, err := gocron.NewScheduler()
+	orderSvc := service.CreateOrderService(orderRepo, paymentGateways, kafkaProducer, orderEventWriter, config, cb, productCommandGrpcClient, productQueryGrpcClient)
+	controller.CreateOrderController(g, orderSvc, isLoggedIn)
+	s, err := gocron.NewScheduler(gocron.WithLocation(time.FixedZone("WIB", 7*60*60)))
 	if err != nil {
 		panic(err)
 	}
@@ -129,6 +163,74 @@ func main() {
 		gocron.NewTask(
 			orderSvc.RestoreExpiredPaymentItemStocks,
 		),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = s.NewJob(
+		gocron.DurationJob(
+			2*time.Second,
+		),
+		gocron.NewTask(
+			orderSvc.RelayOutboxMessages,
+		),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = s.NewJob(
+		gocron.DurationJob(
+			30*time.Second,
+		),
+		gocron.NewTask(
+			orderSvc.ResumeOrderSagas,
+		),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = s.NewJob(
+		gocron.DurationJob(
+			time.Hour,
+		),
+		gocron.NewTask(
+			orderSvc.PurgeExpiredIdempotencyKeys,
+		),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = s.NewJob(
+		gocron.DurationJob(
+			time.Minute,
+		),
+		gocron.NewTask(
+			orderSvc.ReconcilePayments,
+		),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	// The discrepancies of a day are reported once the day is over in WIB.
+	_, err = s.NewJob(
+		gocron.DailyJob(
+			1,
+			gocron.NewAtTimes(gocron.NewAtTime(1, 0, 0)),
+		),
+		gocron.NewTask(
+			orderSvc.ReportPaymentDiscrepancies,
+		),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
 	)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/alimikegami/point-of-sales/order-service/database/migration"
+	"github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/database/postgres"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/jmoiron/sqlx"
+)
+
+var errMigrateUsage = errors.New("usage: migrate up | down [steps] | status")
+
+// runMigrateCommand runs `migrate up`, `migrate down [steps]`, one step
+// unless told otherwise, or `migrate status` with the embedded migrations.
+func runMigrateCommand(db *sqlx.DB, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	steps := 1
+	if args[0] == "down" && len(args) > 1 {
+		var err error
+		steps, err = strconv.Atoi(args[1])
+		if err != nil || steps <= 0 {
+			return errMigrateUsage
+		}
+	}
+
+	return postgres.WithMigrate(context.Background(), db, migration.FS, func(m *migrate.Migrate) error {
+		var err error
+		switch args[0] {
+		case "up":
+			err = m.Up()
+		case "down":
+			err = m.Steps(-steps)
+		case "status":
+		default:
+			return errMigrateUsage
+		}
+
+		if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return err
+		}
+
+		version, dirty, err := m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			fmt.Println("no migration applied")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("version %d, dirty %t\n", version, dirty)
+		return nil
+	})
+}
+
+// autoMigrate brings the database up to date before the service starts.
+func autoMigrate(db *sqlx.DB) error {
+	return postgres.WithMigrate(context.Background(), db, migration.FS, func(m *migrate.Migrate) error {
+		err := m.Up()
+		if errors.Is(err, migrate.ErrNoChange) {
+			return nil
+		}
+
+		return err
+	})
+}
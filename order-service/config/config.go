@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,10 +14,15 @@ type Config struct {
 	PostgreSQLConfig          PostgreSQLConfig
 	JWTSecret                 string
 	MidtransConfig            MidtransConfig
+	PaymentGatewayConfig      PaymentGatewayConfig
+	IdempotencyConfig         IdempotencyConfig
+	TaxConfig                 TaxConfig
 	KafkaConfig               KafkaConfig
 	ProductQueryServiceHost   string
 	ProductCommandServiceHost string
 	TracingConfig             TracingConfig
+	// AutoMigrate applies the pending migrations when the service starts.
+	AutoMigrate bool
 }
 
 func CreateNewConfig() *Config {
@@ -32,12 +39,20 @@ func CreateNewConfig() *Config {
 			DBPassword: os.Getenv("DB_PASSWORD"),
 		},
 		KafkaConfig: KafkaConfig{
-			BrokerAddress: os.Getenv("BROKER_ADDRESS"),
-			BrokerTopic:   os.Getenv("BROKER_TOPIC"),
+			BrokerAddress:   os.Getenv("BROKER_ADDRESS"),
+			BrokerTopic:     os.Getenv("BROKER_TOPIC"),
+			OrderEventTopic: os.Getenv("ORDER_EVENT_TOPIC"),
 		},
 		JWTSecret: os.Getenv("JWT_SECRET"),
 		MidtransConfig: MidtransConfig{
-			ServerKey: os.Getenv("MIDTRANS_SERVER_KEY"),
+			ServerKey:   os.Getenv("MIDTRANS_SERVER_KEY"),
+			Environment: os.Getenv("MIDTRANS_ENVIRONMENT"),
+		},
+		PaymentGatewayConfig: PaymentGatewayConfig{
+			DefaultGateway: os.Getenv("PAYMENT_GATEWAY"),
+		},
+		TaxConfig: TaxConfig{
+			DefaultStoreCode: os.Getenv("DEFAULT_STORE_CODE"),
 		},
 		ProductQueryServiceHost:   os.Getenv("PRODUCT_QUERY_SERVICE_HOST"),
 		ProductCommandServiceHost: os.Getenv("PRODUCT_COMMAND_SERVICE_HOST"),
@@ -46,5 +61,27 @@ func CreateNewConfig() *Config {
 		},
 	}
 
+	keyTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_KEY_TTL"))
+	if err != nil || keyTTL <= 0 {
+		keyTTL = 24 * time.Hour
+	}
+	conf.IdempotencyConfig.KeyTTL = keyTTL
+
+	inProgressTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_IN_PROGRESS_TTL"))
+	if err != nil || inProgressTTL <= 0 {
+		inProgressTTL = 5 * time.Minute
+	}
+	conf.IdempotencyConfig.InProgressTTL = inProgressTTL
+
+	conf.AutoMigrate, _ = strconv.ParseBool(os.Getenv("AUTO_MIGRATE"))
+
+	if conf.PaymentGatewayConfig.DefaultGateway == "" {
+		conf.PaymentGatewayConfig.DefaultGateway = "midtrans"
+	}
+
+	if conf.TaxConfig.DefaultStoreCode == "" {
+		conf.TaxConfig.DefaultStoreCode = "default"
+	}
+
 	return &conf
 }
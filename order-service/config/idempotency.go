@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+type IdempotencyConfig struct {
+	// KeyTTL is how long the response of a request made with an
+	// Idempotency-Key is replayed for.
+	KeyTTL time.Duration
+	// InProgressTTL is how long a request holds its Idempotency-Key before
+	// a retry may take the key over, for a request cut off by a crash. It
+	// has to outlast the longest an order takes to be placed.
+	InProgressTTL time.Duration
+}
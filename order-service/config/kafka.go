@@ -1,7 +1,10 @@
 package config
 
 type KafkaConfig struct {
-	BrokerAddress   string
-	BrokerTopic     string
+	BrokerAddress string
+	BrokerTopic   string
+	// OrderEventTopic is the topic the order lifecycle events are published
+	// on, for other services to build on.
+	OrderEventTopic string
 	BrokerPartition int
 }
@@ -1,5 +1,6 @@
 package config
 
 type MidtransConfig struct {
-	ServerKey string
+	ServerKey   string
+	Environment string
 }
@@ -0,0 +1,6 @@
+package config
+
+type PaymentGatewayConfig struct {
+	// DefaultGateway is used for payment methods that do not name a gateway.
+	DefaultGateway string
+}
@@ -0,0 +1,6 @@
+package config
+
+type TaxConfig struct {
+	// DefaultStoreCode is used for orders that do not name a store.
+	DefaultStoreCode string
+}
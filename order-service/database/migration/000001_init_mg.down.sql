@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS order_details;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS payment_methods;
\ No newline at end of file
@@ -0,0 +1,39 @@
+CREATE TABLE payment_methods (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    channel VARCHAR(255),
+    mdr NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    mdr_type VARCHAR(50) NOT NULL,
+    img_url TEXT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    deleted_at BIGINT
+);
+
+CREATE TABLE orders (
+    id BIGSERIAL PRIMARY KEY,
+    payment_method_id BIGINT NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL,
+    mdr_fee NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    paid_at BIGINT,
+    transaction_number VARCHAR(255) NOT NULL,
+    payment_status VARCHAR(50) NOT NULL,
+    expired_at BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    deleted_at BIGINT,
+    FOREIGN KEY (payment_method_id) REFERENCES payment_methods(id)
+);
+
+CREATE TABLE order_details (
+    id BIGSERIAL PRIMARY KEY,
+    product_id VARCHAR(255) NOT NULL,
+    order_id BIGINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL,
+    product_name VARCHAR(255) NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    deleted_at BIGINT,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
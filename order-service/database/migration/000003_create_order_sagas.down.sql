@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_saga_steps;
+DROP TABLE IF EXISTS order_sagas;
\ No newline at end of file
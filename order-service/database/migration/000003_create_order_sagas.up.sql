@@ -0,0 +1,27 @@
+CREATE TABLE order_sagas (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_number VARCHAR(255) NOT NULL UNIQUE,
+    order_id BIGINT,
+    status VARCHAR(50) NOT NULL,
+    current_step VARCHAR(100) NOT NULL,
+    state JSONB NOT NULL,
+    last_error TEXT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_order_sagas_unfinished ON order_sagas (updated_at) WHERE status IN ('running', 'compensating');
+
+CREATE TABLE order_saga_steps (
+    id BIGSERIAL PRIMARY KEY,
+    saga_id BIGINT NOT NULL,
+    step VARCHAR(100) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    error TEXT,
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (saga_id) REFERENCES order_sagas(id)
+);
+
+CREATE INDEX idx_order_saga_steps_saga_id ON order_saga_steps (saga_id);
@@ -0,0 +1,5 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS payment_gateway;
+
+ALTER TABLE payment_methods
+    DROP COLUMN IF EXISTS payment_type,
+    DROP COLUMN IF EXISTS gateway;
@@ -0,0 +1,7 @@
+ALTER TABLE payment_methods
+    ADD COLUMN gateway VARCHAR(50),
+    ADD COLUMN payment_type VARCHAR(50) NOT NULL DEFAULT '';
+
+UPDATE payment_methods SET payment_type = 'qris' WHERE LOWER(name) = 'qris';
+
+ALTER TABLE orders ADD COLUMN payment_gateway VARCHAR(50) NOT NULL DEFAULT 'midtrans';
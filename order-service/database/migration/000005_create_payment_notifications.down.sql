@@ -0,0 +1 @@
+DROP TABLE IF EXISTS payment_notifications;
\ No newline at end of file
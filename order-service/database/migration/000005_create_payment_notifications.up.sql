@@ -0,0 +1,13 @@
+CREATE TABLE payment_notifications (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    transaction_id VARCHAR(255) NOT NULL,
+    transaction_status VARCHAR(50) NOT NULL,
+    fraud_status VARCHAR(50),
+    status_code VARCHAR(10) NOT NULL,
+    gross_amount VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at BIGINT NOT NULL,
+    UNIQUE (transaction_id, transaction_status),
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
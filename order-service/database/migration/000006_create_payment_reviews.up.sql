@@ -0,0 +1,14 @@
+CREATE TABLE payment_reviews (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    reason VARCHAR(100) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    decision VARCHAR(50),
+    note TEXT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    resolved_at BIGINT,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_payment_reviews_open ON payment_reviews (created_at) WHERE status = 'open';
@@ -0,0 +1,4 @@
+ALTER TABLE orders
+    ADD COLUMN cancelled_by BIGINT,
+    ADD COLUMN cancel_reason TEXT,
+    ADD COLUMN cancelled_at BIGINT;
@@ -0,0 +1,27 @@
+CREATE TABLE refunds (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    refund_key VARCHAR(255) NOT NULL UNIQUE,
+    amount NUMERIC(15, 2) NOT NULL,
+    reason TEXT NOT NULL,
+    restock BOOLEAN NOT NULL DEFAULT FALSE,
+    refunded_by BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_refunds_order_id ON refunds (order_id);
+
+CREATE TABLE refund_items (
+    id BIGSERIAL PRIMARY KEY,
+    refund_id BIGINT NOT NULL,
+    order_detail_id BIGINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL,
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (refund_id) REFERENCES refunds(id),
+    FOREIGN KEY (order_detail_id) REFERENCES order_details(id)
+);
+
+CREATE INDEX idx_refund_items_refund_id ON refund_items (refund_id);
+CREATE INDEX idx_refund_items_order_detail_id ON refund_items (order_detail_id);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS order_discounts;
+
+ALTER TABLE order_details
+    DROP COLUMN IF EXISTS discount_amount;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS discount_amount,
+    DROP COLUMN IF EXISTS subtotal,
+    DROP COLUMN IF EXISTS customer_id;
+
+DROP TABLE IF EXISTS promotions;
@@ -0,0 +1,51 @@
+CREATE TABLE promotions (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(100),
+    type VARCHAR(50) NOT NULL,
+    value NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    product_id VARCHAR(255),
+    buy_quantity BIGINT,
+    get_quantity BIGINT,
+    min_spend NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    max_discount NUMERIC(15, 2),
+    stackable BOOLEAN NOT NULL DEFAULT FALSE,
+    priority INT NOT NULL DEFAULT 0,
+    usage_limit BIGINT,
+    usage_limit_per_customer BIGINT,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    starts_at BIGINT NOT NULL,
+    ends_at BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    deleted_at BIGINT
+);
+
+CREATE UNIQUE INDEX idx_promotions_code ON promotions (code) WHERE deleted_at IS NULL;
+CREATE INDEX idx_promotions_window ON promotions (starts_at, ends_at) WHERE deleted_at IS NULL AND is_active;
+
+ALTER TABLE orders
+    ADD COLUMN customer_id BIGINT,
+    ADD COLUMN subtotal NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN discount_amount NUMERIC(15, 2) NOT NULL DEFAULT 0;
+
+UPDATE orders SET subtotal = amount;
+
+ALTER TABLE order_details
+    ADD COLUMN discount_amount NUMERIC(15, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE order_discounts (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    order_detail_id BIGINT,
+    promotion_id BIGINT NOT NULL,
+    promotion_name VARCHAR(255) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL,
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id),
+    FOREIGN KEY (order_detail_id) REFERENCES order_details(id),
+    FOREIGN KEY (promotion_id) REFERENCES promotions(id)
+);
+
+CREATE INDEX idx_order_discounts_order_id ON order_discounts (order_id);
+CREATE INDEX idx_order_discounts_promotion_id ON order_discounts (promotion_id);
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS order_charges;
+
+ALTER TABLE order_details
+    DROP COLUMN IF EXISTS total_amount,
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS service_charge_amount;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS service_charge_amount,
+    DROP COLUMN IF EXISTS prices_include_tax,
+    DROP COLUMN IF EXISTS store_code;
+
+DROP TABLE IF EXISTS product_tax_categories;
+DROP TABLE IF EXISTS tax_rates;
+DROP TABLE IF EXISTS store_tax_settings;
@@ -0,0 +1,61 @@
+CREATE TABLE store_tax_settings (
+    id BIGSERIAL PRIMARY KEY,
+    store_code VARCHAR(100) NOT NULL,
+    prices_include_tax BOOLEAN NOT NULL DEFAULT FALSE,
+    service_charge_rate NUMERIC(5, 2) NOT NULL DEFAULT 0,
+    service_charge_taxable BOOLEAN NOT NULL DEFAULT FALSE,
+    rounding_mode VARCHAR(20) NOT NULL DEFAULT 'half_up',
+    rounding_increment NUMERIC(15, 2) NOT NULL DEFAULT 1,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    deleted_at BIGINT
+);
+
+CREATE UNIQUE INDEX idx_store_tax_settings_store_code ON store_tax_settings (store_code) WHERE deleted_at IS NULL;
+
+CREATE TABLE tax_rates (
+    id BIGSERIAL PRIMARY KEY,
+    store_tax_setting_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(100),
+    rate NUMERIC(5, 2) NOT NULL,
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (store_tax_setting_id) REFERENCES store_tax_settings(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_tax_rates_store_tax_setting_id ON tax_rates (store_tax_setting_id);
+
+CREATE TABLE product_tax_categories (
+    product_id VARCHAR(255) PRIMARY KEY,
+    category VARCHAR(100) NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+ALTER TABLE orders
+    ADD COLUMN store_code VARCHAR(100),
+    ADD COLUMN prices_include_tax BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN service_charge_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN tax_amount NUMERIC(15, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE order_details
+    ADD COLUMN service_charge_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN tax_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN total_amount NUMERIC(15, 2) NOT NULL DEFAULT 0;
+
+UPDATE order_details SET total_amount = amount * quantity - discount_amount;
+
+CREATE TABLE order_charges (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    rate NUMERIC(5, 2) NOT NULL,
+    base_amount NUMERIC(15, 2) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL,
+    included_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX idx_order_charges_order_id ON order_charges (order_id);
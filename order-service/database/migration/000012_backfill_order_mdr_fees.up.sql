@@ -0,0 +1,10 @@
+UPDATE orders o
+SET mdr_fee = CASE pm.mdr_type
+        WHEN 'percentage' THEN ROUND(o.amount * pm.mdr / 100)
+        WHEN 'flat' THEN ROUND(pm.mdr)
+        ELSE 0
+    END
+FROM payment_methods pm
+WHERE pm.id = o.payment_method_id AND o.mdr_fee = 0;
+
+CREATE INDEX idx_orders_paid_at ON orders (paid_at) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE payment_methods
+    DROP COLUMN IF EXISTS is_active;
@@ -0,0 +1,2 @@
+ALTER TABLE payment_methods
+    ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT TRUE;
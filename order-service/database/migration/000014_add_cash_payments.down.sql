@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS cash_transactions;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS change_amount,
+    DROP COLUMN IF EXISTS tendered_amount,
+    DROP COLUMN IF EXISTS rounding_amount;
+
+ALTER TABLE store_tax_settings
+    DROP COLUMN IF EXISTS cash_rounding_increment;
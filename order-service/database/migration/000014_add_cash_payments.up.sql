@@ -0,0 +1,23 @@
+ALTER TABLE store_tax_settings
+    ADD COLUMN cash_rounding_increment NUMERIC(15, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE orders
+    ADD COLUMN rounding_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN tendered_amount NUMERIC(15, 2),
+    ADD COLUMN change_amount NUMERIC(15, 2);
+
+CREATE TABLE cash_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    refund_id BIGINT,
+    cashier_id BIGINT NOT NULL,
+    store_code VARCHAR(100),
+    type VARCHAR(50) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL,
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id),
+    FOREIGN KEY (refund_id) REFERENCES refunds(id)
+);
+
+CREATE INDEX idx_cash_transactions_cashier_id_created_at ON cash_transactions (cashier_id, created_at);
+CREATE INDEX idx_cash_transactions_created_at ON cash_transactions (created_at);
@@ -0,0 +1,5 @@
+ALTER TABLE cash_transactions DROP COLUMN IF EXISTS order_payment_id;
+
+ALTER TABLE payment_notifications DROP COLUMN IF EXISTS order_payment_id;
+
+DROP TABLE IF EXISTS order_payments;
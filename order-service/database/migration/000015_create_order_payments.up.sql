@@ -0,0 +1,41 @@
+CREATE TABLE order_payments (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    payment_method_id BIGINT NOT NULL,
+    payment_gateway VARCHAR(50) NOT NULL,
+    transaction_number VARCHAR(255) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL,
+    refunded_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    mdr_fee NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    tendered_amount NUMERIC(15, 2),
+    change_amount NUMERIC(15, 2),
+    cashier_id BIGINT,
+    qr_code TEXT,
+    va_number VARCHAR(255),
+    status VARCHAR(50) NOT NULL,
+    expired_at BIGINT NOT NULL,
+    paid_at BIGINT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id),
+    FOREIGN KEY (payment_method_id) REFERENCES payment_methods(id)
+);
+
+CREATE UNIQUE INDEX idx_order_payments_transaction_number ON order_payments (transaction_number);
+CREATE INDEX idx_order_payments_order_id ON order_payments (order_id);
+CREATE INDEX idx_order_payments_paid_at ON order_payments (paid_at);
+
+-- Every order placed so far was paid with a single tender under the order's
+-- own transaction number.
+INSERT INTO order_payments (order_id, payment_method_id, payment_gateway, transaction_number, amount, refunded_amount, mdr_fee, tendered_amount, change_amount, status, expired_at, paid_at, created_at, updated_at)
+SELECT o.id, o.payment_method_id, o.payment_gateway, o.transaction_number, o.amount, COALESCE(r.amount, 0), o.mdr_fee, o.tendered_amount, o.change_amount, o.payment_status, o.expired_at, o.paid_at, o.created_at, o.updated_at
+FROM orders o
+LEFT JOIN (SELECT order_id, SUM(amount) AS amount FROM refunds GROUP BY order_id) r ON r.order_id = o.id;
+
+ALTER TABLE payment_notifications ADD COLUMN order_payment_id BIGINT REFERENCES order_payments(id);
+
+UPDATE payment_notifications pn SET order_payment_id = op.id FROM order_payments op WHERE op.order_id = pn.order_id;
+
+ALTER TABLE cash_transactions ADD COLUMN order_payment_id BIGINT REFERENCES order_payments(id);
+
+UPDATE cash_transactions ct SET order_payment_id = op.id FROM order_payments op WHERE op.order_id = ct.order_id;
@@ -0,0 +1,9 @@
+CREATE TABLE receipt_templates (
+    store_code VARCHAR(100) PRIMARY KEY,
+    header TEXT NOT NULL DEFAULT '',
+    footer TEXT NOT NULL DEFAULT '',
+    logo BYTEA,
+    paper_width INT NOT NULL DEFAULT 80,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
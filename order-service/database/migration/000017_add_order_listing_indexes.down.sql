@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_order_payments_cashier_id;
+DROP INDEX IF EXISTS idx_order_payments_payment_method_id;
+DROP INDEX IF EXISTS idx_orders_paid_at_id;
+DROP INDEX IF EXISTS idx_orders_amount_id;
+DROP INDEX IF EXISTS idx_orders_created_at_id;
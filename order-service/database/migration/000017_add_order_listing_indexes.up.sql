@@ -0,0 +1,5 @@
+CREATE INDEX idx_orders_created_at_id ON orders (created_at, id);
+CREATE INDEX idx_orders_amount_id ON orders (amount, id);
+CREATE INDEX idx_orders_paid_at_id ON orders ((COALESCE(paid_at, 0)), id);
+CREATE INDEX idx_order_payments_payment_method_id ON order_payments (payment_method_id, order_id);
+CREATE INDEX idx_order_payments_cashier_id ON order_payments (cashier_id, order_id);
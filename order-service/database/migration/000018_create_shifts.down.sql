@@ -0,0 +1,11 @@
+DELETE FROM cash_transactions WHERE order_id IS NULL;
+
+ALTER TABLE cash_transactions
+    DROP COLUMN IF EXISTS note,
+    DROP COLUMN IF EXISTS shift_id,
+    ALTER COLUMN order_id SET NOT NULL;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS shift_id;
+
+DROP TABLE IF EXISTS shifts;
@@ -0,0 +1,31 @@
+CREATE TABLE shifts (
+    id BIGSERIAL PRIMARY KEY,
+    cashier_id BIGINT NOT NULL,
+    store_code VARCHAR(100),
+    terminal_code VARCHAR(100),
+    opening_float NUMERIC(15, 2) NOT NULL,
+    expected_cash NUMERIC(15, 2),
+    counted_cash NUMERIC(15, 2),
+    variance NUMERIC(15, 2),
+    status VARCHAR(20) NOT NULL,
+    close_note TEXT,
+    opened_at BIGINT NOT NULL,
+    closed_at BIGINT,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_shifts_open_cashier_id ON shifts (cashier_id) WHERE status = 'open';
+CREATE UNIQUE INDEX idx_shifts_open_terminal_code ON shifts (terminal_code) WHERE status = 'open' AND terminal_code IS NOT NULL;
+CREATE INDEX idx_shifts_opened_at ON shifts (opened_at);
+
+ALTER TABLE orders
+    ADD COLUMN shift_id BIGINT REFERENCES shifts(id);
+
+CREATE INDEX idx_orders_shift_id ON orders (shift_id);
+
+ALTER TABLE cash_transactions
+    ALTER COLUMN order_id DROP NOT NULL,
+    ADD COLUMN shift_id BIGINT REFERENCES shifts(id),
+    ADD COLUMN note TEXT;
+
+CREATE INDEX idx_cash_transactions_shift_id ON cash_transactions (shift_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_orders_cashier_id;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS cashier_id;
+
+DROP TABLE IF EXISTS customers;
@@ -0,0 +1,20 @@
+CREATE TABLE customers (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    phone VARCHAR(50),
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+ALTER TABLE orders
+    ADD COLUMN cashier_id BIGINT;
+
+-- Orders placed before the cashier was stored are attributed to the shift
+-- they were placed in, or to whoever took their cash.
+UPDATE orders SET cashier_id = COALESCE(
+    (SELECT shifts.cashier_id FROM shifts WHERE shifts.id = orders.shift_id),
+    (SELECT MIN(order_payments.cashier_id) FROM order_payments WHERE order_payments.order_id = orders.id)
+);
+
+CREATE INDEX idx_orders_cashier_id ON orders (cashier_id, id);
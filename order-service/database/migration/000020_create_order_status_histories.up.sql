@@ -0,0 +1,17 @@
+CREATE TABLE order_status_histories (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id),
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    actor_id BIGINT,
+    reason TEXT,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX idx_order_status_histories_order_id ON order_status_histories (order_id, id);
+
+-- Orders placed before the history was kept start it with the status they
+-- are in.
+INSERT INTO order_status_histories (order_id, to_status, source, actor_id, created_at)
+SELECT id, payment_status, 'migration', NULL, updated_at FROM orders;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_order_payments_created_at;
+DROP TABLE IF EXISTS payment_discrepancies;
@@ -0,0 +1,17 @@
+CREATE TABLE payment_discrepancies (
+    id BIGSERIAL PRIMARY KEY,
+    report_date VARCHAR(10) NOT NULL,
+    order_id BIGINT NOT NULL REFERENCES orders(id),
+    order_payment_id BIGINT NOT NULL REFERENCES order_payments(id),
+    transaction_number VARCHAR(255) NOT NULL,
+    payment_gateway VARCHAR(50) NOT NULL,
+    local_status VARCHAR(50) NOT NULL,
+    gateway_status VARCHAR(50),
+    local_amount NUMERIC(15, 2) NOT NULL,
+    gateway_amount NUMERIC(15, 2),
+    reason TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_payment_discrepancies_report_date ON payment_discrepancies (report_date, order_payment_id);
+CREATE INDEX idx_order_payments_created_at ON order_payments (created_at);
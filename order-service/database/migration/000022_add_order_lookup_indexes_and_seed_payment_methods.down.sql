@@ -0,0 +1,9 @@
+-- Seeded methods that orders were paid with are kept.
+DELETE FROM payment_methods pm
+WHERE pm.name IN ('Cash', 'QRIS', 'GoPay', 'BCA Virtual Account', 'BNI Virtual Account', 'BRI Virtual Account')
+    AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.payment_method_id = pm.id)
+    AND NOT EXISTS (SELECT 1 FROM order_payments op WHERE op.payment_method_id = pm.id);
+
+DROP INDEX IF EXISTS idx_orders_expired_at;
+DROP INDEX IF EXISTS idx_orders_payment_status_expired_at;
+DROP INDEX IF EXISTS idx_orders_transaction_number;
@@ -0,0 +1,18 @@
+CREATE UNIQUE INDEX idx_orders_transaction_number ON orders (transaction_number);
+CREATE INDEX idx_orders_payment_status_expired_at ON orders (payment_status, expired_at) WHERE deleted_at IS NULL;
+CREATE INDEX idx_orders_expired_at ON orders (expired_at) WHERE deleted_at IS NULL;
+
+-- Methods already set up by hand are left as they are.
+INSERT INTO payment_methods (name, channel, mdr, mdr_type, gateway, payment_type, is_active, created_at, updated_at)
+SELECT seed.name, seed.channel, seed.mdr, seed.mdr_type, seed.gateway, seed.payment_type, TRUE, EXTRACT(EPOCH FROM NOW())::BIGINT, EXTRACT(EPOCH FROM NOW())::BIGINT
+FROM (VALUES
+    ('Cash', NULL, 0, 'flat', 'cash', 'cash'),
+    ('QRIS', NULL, 0.7, 'percentage', 'midtrans', 'qris'),
+    ('GoPay', NULL, 2, 'percentage', 'midtrans', 'gopay'),
+    ('BCA Virtual Account', 'bca', 4000, 'flat', 'midtrans', 'bank_transfer'),
+    ('BNI Virtual Account', 'bni', 4000, 'flat', 'midtrans', 'bank_transfer'),
+    ('BRI Virtual Account', 'bri', 4000, 'flat', 'midtrans', 'bank_transfer')
+) AS seed (name, channel, mdr, mdr_type, gateway, payment_type)
+WHERE NOT EXISTS (
+    SELECT 1 FROM payment_methods pm WHERE LOWER(pm.name) = LOWER(seed.name)
+);
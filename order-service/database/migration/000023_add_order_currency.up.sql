@@ -0,0 +1,5 @@
+-- The NUMERIC(15, 2) amount columns hold exact decimals of the currency of
+-- their order, which the service reads and writes as whole minor units. Every
+-- order placed so far was in rupiah.
+ALTER TABLE orders
+    ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
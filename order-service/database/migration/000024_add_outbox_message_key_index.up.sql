@@ -0,0 +1,3 @@
+-- The relay looks up the oldest pending message of a key before publishing
+-- the later ones.
+CREATE INDEX idx_outbox_messages_pending_key ON outbox_messages (message_key, id) WHERE status = 'pending';
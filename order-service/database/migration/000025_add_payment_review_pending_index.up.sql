@@ -0,0 +1,3 @@
+-- Reconciliation opens the reviews left pending by a follow-up that did not
+-- finish.
+CREATE INDEX idx_payment_reviews_pending ON payment_reviews (created_at) WHERE status = 'pending';
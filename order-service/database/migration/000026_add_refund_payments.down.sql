@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS refund_payments;
+ALTER TABLE refunds DROP COLUMN IF EXISTS status;
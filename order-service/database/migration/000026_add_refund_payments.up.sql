@@ -0,0 +1,20 @@
+-- A refund is recorded as pending, along with what it gives back through each
+-- payment, before any gateway is called, and completed once every gateway
+-- refunded its part. Refunds made so far were recorded after the fact.
+ALTER TABLE refunds ADD COLUMN status VARCHAR(50) NOT NULL DEFAULT 'completed';
+ALTER TABLE refunds ALTER COLUMN status DROP DEFAULT;
+
+CREATE TABLE refund_payments (
+    id BIGSERIAL PRIMARY KEY,
+    refund_id BIGINT NOT NULL,
+    order_payment_id BIGINT NOT NULL,
+    refund_key VARCHAR(255) NOT NULL UNIQUE,
+    amount NUMERIC(15, 2) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    FOREIGN KEY (refund_id) REFERENCES refunds(id),
+    FOREIGN KEY (order_payment_id) REFERENCES order_payments(id)
+);
+
+CREATE INDEX idx_refund_payments_refund_id ON refund_payments (refund_id);
@@ -0,0 +1,4 @@
+-- A key is committed as in progress before the order is placed, and completed
+-- with its response once it is. Keys stored so far all have their response.
+ALTER TABLE idempotency_keys ADD COLUMN status VARCHAR(50) NOT NULL DEFAULT 'completed';
+ALTER TABLE idempotency_keys ALTER COLUMN status DROP DEFAULT;
@@ -0,0 +1,3 @@
+-- Orders look their customer up by email or phone before adding one.
+CREATE INDEX idx_customers_email ON customers (LOWER(email));
+CREATE INDEX idx_customers_phone ON customers (phone);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_payment_reviews_resolving;
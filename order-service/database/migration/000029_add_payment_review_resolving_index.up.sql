@@ -0,0 +1,2 @@
+-- Reconciliation opens the reviews again whose decision did not finish.
+CREATE INDEX idx_payment_reviews_resolving ON payment_reviews (updated_at) WHERE status = 'resolving';
@@ -0,0 +1,4 @@
+-- A refund retried with its key must ask for the same refund. Refunds
+-- recorded so far have no hash and are resumed whatever the retry asks for.
+ALTER TABLE refunds ADD COLUMN request_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE refunds ALTER COLUMN request_hash DROP DEFAULT;
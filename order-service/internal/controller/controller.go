@@ -1,12 +1,16 @@
 package controller
 
 import (
+	"encoding/json"
+	"io"
 	"strconv"
 
 	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
 	"github.com/alimikegami/point-of-sales/order-service/internal/service"
 	pkgdto "github.com/alimikegami/point-of-sales/order-service/pkg/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
 	"github.com/alimikegami/point-of-sales/order-service/pkg/response"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/utils"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
 )
@@ -15,15 +19,57 @@ type Controller struct {
 	service service.OrderService
 }
 
-func CreateOrderController(e *echo.Group, service service.OrderService) {
+func CreateOrderController(e *echo.Group, service service.OrderService, isLoggedIn echo.MiddlewareFunc) {
 	c := Controller{
 		service: service,
 	}
 
-	e.POST("/orders", c.AddOrder)
+	e.POST("/orders", c.AddOrder, isLoggedIn)
 	e.POST("/orders/payments/notifications", c.MidtransPaymentWebhook)
 	e.GET("/orders", c.GetOrders)
 	e.GET("/orders/:id", c.GetOrderDetails)
+	e.GET("/orders/:id/saga", c.GetOrderSaga, isLoggedIn)
+	e.GET("/orders/:id/history", c.GetOrderStatusHistory, isLoggedIn)
+	e.GET("/orders/:id/receipt", c.GetOrderReceipt, isLoggedIn)
+	e.POST("/orders/:id/cancel", c.CancelOrder, isLoggedIn)
+	e.POST("/orders/:id/refunds", c.RefundOrder, isLoggedIn)
+	e.GET("/orders/payment-reviews", c.GetPaymentReviews, isLoggedIn)
+	e.POST("/orders/payment-reviews/:id/resolve", c.ResolvePaymentReview, isLoggedIn)
+	e.POST("/promotions", c.AddPromotion, isLoggedIn)
+	e.GET("/promotions", c.GetPromotions, isLoggedIn)
+	e.GET("/promotions/:id", c.GetPromotion, isLoggedIn)
+	e.PUT("/promotions/:id", c.UpdatePromotion, isLoggedIn)
+	e.DELETE("/promotions/:id", c.DeletePromotion, isLoggedIn)
+	e.GET("/tax-settings", c.GetStoreTaxSettings, isLoggedIn)
+	e.GET("/tax-settings/:store_code", c.GetStoreTaxSetting, isLoggedIn)
+	e.PUT("/tax-settings/:store_code", c.SaveStoreTaxSetting, isLoggedIn)
+	e.DELETE("/tax-settings/:store_code", c.DeleteStoreTaxSetting, isLoggedIn)
+	e.GET("/tax-categories", c.GetProductTaxCategories, isLoggedIn)
+	e.PUT("/tax-categories/:product_id", c.SaveProductTaxCategory, isLoggedIn)
+	e.DELETE("/tax-categories/:product_id", c.DeleteProductTaxCategory, isLoggedIn)
+	e.GET("/receipt-templates", c.GetReceiptTemplates, isLoggedIn)
+	e.GET("/receipt-templates/:store_code", c.GetReceiptTemplate, isLoggedIn)
+	e.PUT("/receipt-templates/:store_code", c.SaveReceiptTemplate, isLoggedIn)
+	e.DELETE("/receipt-templates/:store_code", c.DeleteReceiptTemplate, isLoggedIn)
+	e.GET("/payment-methods", c.GetActivePaymentMethods)
+	e.GET("/payment-methods/all", c.GetPaymentMethods, isLoggedIn)
+	e.POST("/payment-methods", c.AddPaymentMethod, isLoggedIn)
+	e.GET("/payment-methods/:id", c.GetPaymentMethod, isLoggedIn)
+	e.PUT("/payment-methods/:id", c.UpdatePaymentMethod, isLoggedIn)
+	e.POST("/payment-methods/:id/enable", c.EnablePaymentMethod, isLoggedIn)
+	e.POST("/payment-methods/:id/disable", c.DisablePaymentMethod, isLoggedIn)
+	e.DELETE("/payment-methods/:id", c.DeletePaymentMethod, isLoggedIn)
+	e.GET("/reports/settlements", c.GetSettlementReport, isLoggedIn)
+	e.GET("/reports/cash", c.GetCashReport, isLoggedIn)
+	e.GET("/reports/sales", c.GetSalesReport, isLoggedIn)
+	e.GET("/reports/z", c.GetZReport, isLoggedIn)
+	e.GET("/reports/payment-discrepancies", c.GetPaymentDiscrepancyReport, isLoggedIn)
+	e.POST("/shifts/open", c.OpenShift, isLoggedIn)
+	e.GET("/shifts", c.GetShifts, isLoggedIn)
+	e.GET("/shifts/current", c.GetCurrentShift, isLoggedIn)
+	e.GET("/shifts/:id", c.GetShift, isLoggedIn)
+	e.POST("/shifts/:id/cash-movements", c.AddShiftCashMovement, isLoggedIn)
+	e.POST("/shifts/:id/close", c.CloseShift, isLoggedIn)
 }
 
 func (c *Controller) AddOrder(e echo.Context) error {
@@ -33,6 +79,9 @@ func (c *Controller) AddOrder(e echo.Context) error {
 		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "AddOrder").Msg("")
 	}
 
+	payload.IdempotencyKey = e.Request().Header.Get("Idempotency-Key")
+	payload.UserID, _, _ = utils.ExtractTokenUser(e)
+
 	resp, err := c.service.AddOrder(e.Request().Context(), payload)
 	if err != nil {
 		return response.WriteErrorResponse(e, err, nil)
@@ -43,11 +92,19 @@ func (c *Controller) AddOrder(e echo.Context) error {
 
 func (c *Controller) MidtransPaymentWebhook(e echo.Context) error {
 	payload := dto.PaymentNotification{}
-	err := e.Bind(&payload)
+	rawPayload, err := io.ReadAll(e.Request().Body)
 	if err != nil {
 		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "MidtransPaymentWebhook").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
 	}
 
+	err = json.Unmarshal(rawPayload, &payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "MidtransPaymentWebhook").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+	payload.RawPayload = rawPayload
+
 	err = c.service.MidtransPaymentWebhook(e.Request().Context(), payload)
 
 	if err != nil {
@@ -62,7 +119,7 @@ func (c *Controller) GetOrders(e echo.Context) error {
 	err := e.Bind(&filter)
 	if err != nil {
 		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetOrders").Msg("")
-		return response.WriteErrorResponse(e, err, nil)
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
 	}
 
 	responsePayload, err := c.service.GetOrders(e.Request().Context(), filter)
@@ -90,3 +147,109 @@ func (c *Controller) GetOrderDetails(e echo.Context) error {
 
 	return response.WriteSuccessResponse(e, "successfuly retrieved order details", responsePayload)
 }
+
+func (c *Controller) GetOrderStatusHistory(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetOrderStatusHistory").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.GetOrderStatusHistory(e.Request().Context(), id)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved order status history", responsePayload)
+}
+
+func (c *Controller) GetOrderSaga(e echo.Context) error {
+	responsePayload, err := c.service.GetOrderSaga(e.Request().Context(), e.Param("id"))
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved order saga", responsePayload)
+}
+
+func (c *Controller) GetPaymentReviews(e echo.Context) error {
+	responsePayload, err := c.service.GetPaymentReviews(e.Request().Context())
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved payment reviews", responsePayload)
+}
+
+func (c *Controller) ResolvePaymentReview(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "ResolvePaymentReview").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload := dto.PaymentReviewDecisionRequest{}
+	err = e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "ResolvePaymentReview").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload.ReviewedBy, _, _ = utils.ExtractTokenUser(e)
+
+	err = c.service.ResolvePaymentReview(e.Request().Context(), id, payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly resolved payment review", nil)
+}
+
+func (c *Controller) CancelOrder(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "CancelOrder").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload := dto.CancelOrderRequest{}
+	err = e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "CancelOrder").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload.CancelledBy, _, _ = utils.ExtractTokenUser(e)
+
+	err = c.service.CancelOrder(e.Request().Context(), id, payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly cancelled order", nil)
+}
+
+func (c *Controller) RefundOrder(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "RefundOrder").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload := dto.RefundOrderRequest{}
+	err = e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "RefundOrder").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload.RefundedBy, _, _ = utils.ExtractTokenUser(e)
+	payload.RefundKey = e.Request().Header.Get("Idempotency-Key")
+
+	responsePayload, err := c.service.RefundOrder(e.Request().Context(), id, payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly refunded order", responsePayload)
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/response"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func (c *Controller) AddPaymentMethod(e echo.Context) error {
+	payload := dto.PaymentMethodRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "AddPaymentMethod").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.AddPaymentMethod(e.Request().Context(), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly added payment method", responsePayload)
+}
+
+func (c *Controller) GetPaymentMethods(e echo.Context) error {
+	responsePayload, err := c.service.GetPaymentMethods(e.Request().Context())
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved payment methods", responsePayload)
+}
+
+func (c *Controller) GetActivePaymentMethods(e echo.Context) error {
+	responsePayload, err := c.service.GetActivePaymentMethods(e.Request().Context())
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved payment methods", responsePayload)
+}
+
+func (c *Controller) GetPaymentMethod(e echo.Context) error {
+	id, err := strconv.ParseUint(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetPaymentMethod").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.GetPaymentMethod(e.Request().Context(), id)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved payment method", responsePayload)
+}
+
+func (c *Controller) UpdatePaymentMethod(e echo.Context) error {
+	id, err := strconv.ParseUint(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "UpdatePaymentMethod").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload := dto.PaymentMethodRequest{}
+	err = e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "UpdatePaymentMethod").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.UpdatePaymentMethod(e.Request().Context(), id, payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly updated payment method", responsePayload)
+}
+
+func (c *Controller) EnablePaymentMethod(e echo.Context) error {
+	return c.setPaymentMethodActive(e, true, "successfuly enabled payment method")
+}
+
+func (c *Controller) DisablePaymentMethod(e echo.Context) error {
+	return c.setPaymentMethodActive(e, false, "successfuly disabled payment method")
+}
+
+func (c *Controller) setPaymentMethodActive(e echo.Context, active bool, message string) error {
+	id, err := strconv.ParseUint(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "SetPaymentMethodActive").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.SetPaymentMethodActive(e.Request().Context(), id, active)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, message, responsePayload)
+}
+
+func (c *Controller) DeletePaymentMethod(e echo.Context) error {
+	id, err := strconv.ParseUint(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "DeletePaymentMethod").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	err = c.service.DeletePaymentMethod(e.Request().Context(), id)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly deleted payment method", nil)
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/response"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func (c *Controller) AddPromotion(e echo.Context) error {
+	payload := dto.PromotionRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "AddPromotion").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.AddPromotion(e.Request().Context(), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly added promotion", responsePayload)
+}
+
+func (c *Controller) GetPromotions(e echo.Context) error {
+	responsePayload, err := c.service.GetPromotions(e.Request().Context())
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved promotions", responsePayload)
+}
+
+func (c *Controller) GetPromotion(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetPromotion").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.GetPromotion(e.Request().Context(), id)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved promotion", responsePayload)
+}
+
+func (c *Controller) UpdatePromotion(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "UpdatePromotion").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload := dto.PromotionRequest{}
+	err = e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "UpdatePromotion").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.UpdatePromotion(e.Request().Context(), id, payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly updated promotion", responsePayload)
+}
+
+func (c *Controller) DeletePromotion(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "DeletePromotion").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	err = c.service.DeletePromotion(e.Request().Context(), id)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly deleted promotion", nil)
+}
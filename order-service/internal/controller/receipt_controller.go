@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/response"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// GetOrderReceipt sends the receipt of the order as it is, rather than
+// wrapped in a JSON response, so it can go straight to the printer or be
+// attached to an email.
+func (c *Controller) GetOrderReceipt(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetOrderReceipt").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	receipt, err := c.service.GetOrderReceipt(e.Request().Context(), id, e.QueryParam("format"))
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	e.Response().Header().Set(echo.HeaderContentDisposition, `inline; filename="`+receipt.FileName+`"`)
+
+	return e.Blob(http.StatusOK, receipt.ContentType, receipt.Data)
+}
+
+func (c *Controller) GetReceiptTemplates(e echo.Context) error {
+	responsePayload, err := c.service.GetReceiptTemplates(e.Request().Context())
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved receipt templates", responsePayload)
+}
+
+func (c *Controller) GetReceiptTemplate(e echo.Context) error {
+	responsePayload, err := c.service.GetReceiptTemplate(e.Request().Context(), e.Param("store_code"))
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved receipt template", responsePayload)
+}
+
+func (c *Controller) SaveReceiptTemplate(e echo.Context) error {
+	payload := dto.ReceiptTemplateRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "SaveReceiptTemplate").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.SaveReceiptTemplate(e.Request().Context(), e.Param("store_code"), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly saved receipt template", responsePayload)
+}
+
+func (c *Controller) DeleteReceiptTemplate(e echo.Context) error {
+	err := c.service.DeleteReceiptTemplate(e.Request().Context(), e.Param("store_code"))
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly deleted receipt template", nil)
+}
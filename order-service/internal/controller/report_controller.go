@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/response"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func (c *Controller) GetSettlementReport(e echo.Context) error {
+	payload := dto.ReportRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetSettlementReport").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.GetSettlementReport(e.Request().Context(), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved settlement report", responsePayload)
+}
+
+func (c *Controller) GetCashReport(e echo.Context) error {
+	payload := dto.ReportRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetCashReport").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.GetCashReport(e.Request().Context(), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved cash report", responsePayload)
+}
+
+func (c *Controller) GetPaymentDiscrepancyReport(e echo.Context) error {
+	payload := dto.ReportRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetPaymentDiscrepancyReport").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.GetPaymentDiscrepancyReport(e.Request().Context(), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved payment discrepancy report", responsePayload)
+}
+
+func (c *Controller) GetSalesReport(e echo.Context) error {
+	payload := dto.SalesReportRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetSalesReport").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.GetSalesReport(e.Request().Context(), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	if payload.Format == dto.ReportFormatCSV {
+		return response.WriteCSVResponse(e, reportFileName("sales-report", payload), salesReportRecords(responsePayload))
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved sales report", responsePayload)
+}
+
+func (c *Controller) GetZReport(e echo.Context) error {
+	payload := dto.SalesReportRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetZReport").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.GetZReport(e.Request().Context(), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	if payload.Format == dto.ReportFormatCSV {
+		return response.WriteCSVResponse(e, reportFileName("z-report", payload), zReportRecords(responsePayload))
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved z-report", responsePayload)
+}
+
+func reportFileName(name string, req dto.SalesReportRequest) string {
+	if req.StoreCode != "" {
+		name += "-" + req.StoreCode
+	}
+
+	return name + "-" + req.StartDate + "-" + req.EndDate + ".csv"
+}
+
+var salesFiguresHeader = []string{"order_count", "item_count", "gross_sales", "discount_amount", "service_charge_amount", "tax_amount", "rounding_amount", "collected_amount", "refund_count", "refunded_amount", "net_sales", "average_basket_amount", "average_basket_items"}
+
+func salesFiguresRecord(figures dto.SalesFigures) []string {
+	return []string{
+		strconv.FormatInt(figures.OrderCount, 10),
+		strconv.FormatInt(figures.ItemCount, 10),
+		formatCSVAmount(figures.GrossSales),
+		formatCSVAmount(figures.DiscountAmount),
+		formatCSVAmount(figures.ServiceChargeAmount),
+		formatCSVAmount(figures.TaxAmount),
+		formatCSVAmount(figures.RoundingAmount),
+		formatCSVAmount(figures.CollectedAmount),
+		strconv.FormatInt(figures.RefundCount, 10),
+		formatCSVAmount(figures.RefundedAmount),
+		formatCSVAmount(figures.NetSales),
+		formatCSVAmount(figures.AverageBasketAmount),
+		formatCSVAmount(figures.AverageBasketItems),
+	}
+}
+
+// salesReportRecords lays the sales report out as sections of CSV records,
+// each with a header of its own, separated by empty records.
+func salesReportRecords(report dto.SalesReportResponse) (records [][]string) {
+	records = append(records, append([]string{"date"}, salesFiguresHeader...))
+	for _, day := range report.Days {
+		records = append(records, append([]string{day.Date}, salesFiguresRecord(day.SalesFigures)...))
+	}
+	records = append(records, append([]string{"total"}, salesFiguresRecord(report.Total)...))
+
+	records = append(records, []string{})
+	records = append(records, paymentMethodSalesRecords(report.PaymentMethods)...)
+	records = append(records, []string{})
+	records = append(records, hourlySalesRecords(report.Hours)...)
+
+	return
+}
+
+func zReportRecords(report dto.ZReportResponse) (records [][]string) {
+	records = append(records, append([]string{"store_code", "start_date", "end_date"}, salesFiguresHeader...))
+	records = append(records, append([]string{report.StoreCode, report.StartDate, report.EndDate}, salesFiguresRecord(report.Sales)...))
+
+	records = append(records, []string{})
+	records = append(records, paymentMethodSalesRecords(report.PaymentMethods)...)
+	records = append(records, []string{})
+	records = append(records, hourlySalesRecords(report.Hours)...)
+
+	records = append(records, []string{})
+	records = append(records, []string{"status", "order_count", "amount"})
+	for _, row := range report.OrderStatuses {
+		records = append(records, []string{row.Status, strconv.FormatInt(row.OrderCount, 10), formatCSVAmount(row.Amount)})
+	}
+
+	return
+}
+
+func paymentMethodSalesRecords(rows []dto.PaymentMethodSalesRow) (records [][]string) {
+	records = append(records, []string{"payment_method_id", "payment_method_name", "order_count", "amount"})
+	for _, row := range rows {
+		records = append(records, []string{strconv.FormatInt(row.PaymentMethodID, 10), row.PaymentMethodName, strconv.FormatInt(row.OrderCount, 10), formatCSVAmount(row.Amount)})
+	}
+
+	return
+}
+
+func hourlySalesRecords(rows []dto.HourlySalesRow) (records [][]string) {
+	records = append(records, []string{"hour", "order_count", "amount"})
+	for _, row := range rows {
+		records = append(records, []string{strconv.Itoa(row.Hour), strconv.FormatInt(row.OrderCount, 10), formatCSVAmount(row.Amount)})
+	}
+
+	return
+}
+
+func formatCSVAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
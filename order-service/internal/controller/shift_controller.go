@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/response"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func (c *Controller) OpenShift(e echo.Context) error {
+	payload := dto.OpenShiftRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "OpenShift").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload.CashierID, _, _ = utils.ExtractTokenUser(e)
+
+	responsePayload, err := c.service.OpenShift(e.Request().Context(), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly opened shift", responsePayload)
+}
+
+func (c *Controller) GetShifts(e echo.Context) error {
+	filter := dto.ShiftFilter{}
+	err := e.Bind(&filter)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetShifts").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.GetShifts(e.Request().Context(), filter)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved shifts", responsePayload)
+}
+
+func (c *Controller) GetCurrentShift(e echo.Context) error {
+	cashierID, _, _ := utils.ExtractTokenUser(e)
+
+	responsePayload, err := c.service.GetCurrentShift(e.Request().Context(), cashierID)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved shift", responsePayload)
+}
+
+func (c *Controller) GetShift(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "GetShift").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.GetShift(e.Request().Context(), id)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved shift", responsePayload)
+}
+
+func (c *Controller) AddShiftCashMovement(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "AddShiftCashMovement").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload := dto.CashMovementRequest{}
+	err = e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "AddShiftCashMovement").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload.CashierID, _, _ = utils.ExtractTokenUser(e)
+
+	responsePayload, err := c.service.AddShiftCashMovement(e.Request().Context(), id, payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly recorded cash movement", responsePayload)
+}
+
+func (c *Controller) CloseShift(e echo.Context) error {
+	id, err := strconv.ParseInt(e.Param("id"), 10, 64)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "CloseShift").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload := dto.CloseShiftRequest{}
+	err = e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "CloseShift").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	payload.CashierID, _, _ = utils.ExtractTokenUser(e)
+
+	responsePayload, err := c.service.CloseShift(e.Request().Context(), id, payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly closed shift", responsePayload)
+}
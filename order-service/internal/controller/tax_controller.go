@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/response"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func (c *Controller) GetStoreTaxSettings(e echo.Context) error {
+	responsePayload, err := c.service.GetStoreTaxSettings(e.Request().Context())
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved tax settings", responsePayload)
+}
+
+func (c *Controller) GetStoreTaxSetting(e echo.Context) error {
+	responsePayload, err := c.service.GetStoreTaxSetting(e.Request().Context(), e.Param("store_code"))
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved tax settings", responsePayload)
+}
+
+func (c *Controller) SaveStoreTaxSetting(e echo.Context) error {
+	payload := dto.StoreTaxSettingRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "SaveStoreTaxSetting").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.SaveStoreTaxSetting(e.Request().Context(), e.Param("store_code"), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly saved tax settings", responsePayload)
+}
+
+func (c *Controller) DeleteStoreTaxSetting(e echo.Context) error {
+	err := c.service.DeleteStoreTaxSetting(e.Request().Context(), e.Param("store_code"))
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly deleted tax settings", nil)
+}
+
+func (c *Controller) GetProductTaxCategories(e echo.Context) error {
+	responsePayload, err := c.service.GetProductTaxCategories(e.Request().Context())
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly retrieved product tax categories", responsePayload)
+}
+
+func (c *Controller) SaveProductTaxCategory(e echo.Context) error {
+	payload := dto.ProductTaxCategoryRequest{}
+	err := e.Bind(&payload)
+	if err != nil {
+		log.Ctx(e.Request().Context()).Error().Err(err).Str("component", "SaveProductTaxCategory").Msg("")
+		return response.WriteErrorResponse(e, errs.ErrClient, nil)
+	}
+
+	responsePayload, err := c.service.SaveProductTaxCategory(e.Request().Context(), e.Param("product_id"), payload)
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly saved product tax category", responsePayload)
+}
+
+func (c *Controller) DeleteProductTaxCategory(e echo.Context) error {
+	err := c.service.DeleteProductTaxCategory(e.Request().Context(), e.Param("product_id"))
+	if err != nil {
+		return response.WriteErrorResponse(e, err, nil)
+	}
+
+	return response.WriteSuccessResponse(e, "successfuly deleted product tax category", nil)
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
+const (
+	PaymentTypeCash = "cash"
+
+	CashTransactionTypeSale   = "sale"
+	CashTransactionTypeRefund = "refund"
+	// CashTransactionTypeCashIn and CashTransactionTypeCashOut are cash put
+	// into or taken out of a drawer during a shift for anything but an order,
+	// e.g. more change or a petty cash expense.
+	CashTransactionTypeCashIn  = "cash_in"
+	CashTransactionTypeCashOut = "cash_out"
+)
+
+// CashTransaction is cash going into or out of a cashier's drawer. Amount is
+// positive for cash taken in and negative for cash handed out, so the
+// transactions of a drawer add up to what it should hold. OrderID is nil for
+// the cash moved in or out during a shift. Drawers only hold rupiah.
+type CashTransaction struct {
+	ID             int64       `db:"id"`
+	OrderID        *int64      `db:"order_id"`
+	OrderPaymentID *int64      `db:"order_payment_id"`
+	RefundID       *int64      `db:"refund_id"`
+	ShiftID        *int64      `db:"shift_id"`
+	CashierID      int64       `db:"cashier_id"`
+	StoreCode      *string     `db:"store_code"`
+	Type           string      `db:"type"`
+	Amount         money.Money `db:"amount"`
+	Note           *string     `db:"note"`
+	CreatedAt      int64       `db:"created_at"`
+}
+
+// CashierCashSummary adds up the cash a cashier took in and handed out.
+type CashierCashSummary struct {
+	CashierID   int64       `db:"cashier_id"`
+	SaleCount   int64       `db:"sale_count"`
+	CashIn      money.Money `db:"cash_in"`
+	RefundCount int64       `db:"refund_count"`
+	CashOut     money.Money `db:"cash_out"`
+}
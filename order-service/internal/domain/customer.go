@@ -0,0 +1,12 @@
+package domain
+
+// Customer is who an order is placed for. Their details are passed on to the
+// payment gateways charging their orders.
+type Customer struct {
+	ID        int64   `db:"id"`
+	Name      string  `db:"name"`
+	Email     *string `db:"email"`
+	Phone     *string `db:"phone"`
+	CreatedAt int64   `db:"created_at"`
+	UpdatedAt int64   `db:"updated_at"`
+}
@@ -0,0 +1,17 @@
+package domain
+
+const (
+	IdempotencyKeyStatusInProgress = "in_progress"
+	IdempotencyKeyStatusCompleted  = "completed"
+)
+
+// IdempotencyKey remembers the response of a request made with an
+// Idempotency-Key header. Response is nil while the request is in progress.
+type IdempotencyKey struct {
+	Key         string  `db:"key"`
+	RequestHash string  `db:"request_hash"`
+	Status      string  `db:"status"`
+	Response    *string `db:"response"`
+	CreatedAt   int64   `db:"created_at"`
+	ExpiresAt   int64   `db:"expires_at"`
+}
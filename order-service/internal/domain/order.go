@@ -1,42 +1,110 @@
 package domain
 
+import (
+	"math"
+
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+const (
+	// MDRTypePercentage charges MDR percent of the order amount.
+	MDRTypePercentage = "percentage"
+	// MDRTypeFlat charges MDR for every order, whatever its amount.
+	MDRTypeFlat = "flat"
+)
+
+// PaymentMethod is a way to pay offered at checkout. Methods are soft
+// deleted, so the orders paid with them keep their name, and a method that is
+// not IsActive is hidden from the POS without being deleted.
 type PaymentMethod struct {
-	ID        uint64  `db:"id"`
-	Name      string  `db:"name"`
-	Channel   *string `db:"channel"`
-	MDR       float64 `db:"mdr"`
-	MDRType   string  `db:"mdr_type"`
-	ImgURL    *string `db:"img_url"`
-	CreatedAt int64   `db:"created_at"`
-	UpdatedAt int64   `db:"updated_at"`
-	DeletedAt *int64  `db:"deleted_at"`
+	ID          uint64  `db:"id"`
+	Name        string  `db:"name"`
+	Channel     *string `db:"channel"`
+	MDR         float64 `db:"mdr"`
+	MDRType     string  `db:"mdr_type"`
+	ImgURL      *string `db:"img_url"`
+	Gateway     *string `db:"gateway"`
+	PaymentType string  `db:"payment_type"`
+	IsActive    bool    `db:"is_active"`
+	CreatedAt   int64   `db:"created_at"`
+	UpdatedAt   int64   `db:"updated_at"`
+	DeletedAt   *int64  `db:"deleted_at"`
+}
+
+// Available reports whether new orders may be paid with the method.
+func (p PaymentMethod) Available() bool {
+	return p.IsActive && p.DeletedAt == nil
+}
+
+// CalculateMDRFee returns the merchant discount rate the payment provider
+// takes from an order of the given amount, in whole rupiah. Flat rates are
+// in rupiah as well.
+func (p PaymentMethod) CalculateMDRFee(amount money.Money) money.Money {
+	unit := money.FromFloat(1, amount.Currency).Amount
+	fee := money.New(0, amount.Currency)
+	switch p.MDRType {
+	case MDRTypePercentage:
+		fee.Amount = int64(math.Round(float64(amount.Amount)*p.MDR/100/float64(unit))) * unit
+	case MDRTypeFlat:
+		fee.Amount = int64(math.Round(p.MDR)) * unit
+	}
+
+	return fee
 }
 
 type Order struct {
-	ID                int64   `db:"id"`
-	PaymentMethodID   int64   `db:"payment_method_id"`
-	Amount            float64 `db:"amount"`
-	MDRFee            float64 `db:"mdr_fee"`
-	PaidAt            *int64  `db:"paid_at"`
-	TransactionNumber string  `db:"transaction_number"`
-	PaymentStatus     string  `db:"payment_status"`
-	ExpiredAt         int64   `db:"expired_at"`
-	CreatedAt         int64   `db:"created_at"`
-	UpdatedAt         int64   `db:"updated_at"`
-	DeletedAt         *int64  `db:"deleted_at"`
+	ID              int64  `db:"id"`
+	PaymentMethodID int64  `db:"payment_method_id"`
+	CustomerID      *int64 `db:"customer_id"`
+	// CashierID is the user who placed the order.
+	CashierID           *int64      `db:"cashier_id"`
+	ShiftID             *int64      `db:"shift_id"`
+	StoreCode           *string     `db:"store_code"`
+	PricesIncludeTax    bool        `db:"prices_include_tax"`
+	Currency            string      `db:"currency"`
+	Subtotal            money.Money `db:"subtotal"`
+	DiscountAmount      money.Money `db:"discount_amount"`
+	ServiceChargeAmount money.Money `db:"service_charge_amount"`
+	TaxAmount           money.Money `db:"tax_amount"`
+	Amount              money.Money `db:"amount"`
+	// RoundingAmount is what cash rounding added to, or took off, the amount.
+	RoundingAmount    money.Money  `db:"rounding_amount"`
+	TenderedAmount    *money.Money `db:"tendered_amount"`
+	ChangeAmount      *money.Money `db:"change_amount"`
+	MDRFee            money.Money  `db:"mdr_fee"`
+	PaidAt            *int64       `db:"paid_at"`
+	TransactionNumber string       `db:"transaction_number"`
+	PaymentStatus     string       `db:"payment_status"`
+	PaymentGateway    string       `db:"payment_gateway"`
+	ExpiredAt         int64        `db:"expired_at"`
+	CancelledBy       *int64       `db:"cancelled_by"`
+	CancelReason      *string      `db:"cancel_reason"`
+	CancelledAt       *int64       `db:"cancelled_at"`
+	CreatedAt         int64        `db:"created_at"`
+	UpdatedAt         int64        `db:"updated_at"`
+	DeletedAt         *int64       `db:"deleted_at"`
 	OrderDetail       []OrderDetail
 	PaymentMethod     PaymentMethod
 }
 
 type OrderDetail struct {
-	ID          int64   `db:"id"`
-	ProductID   string  `db:"product_id"`
-	OrderID     int64   `db:"order_id"`
-	Quantity    int64   `db:"quantity"`
-	Amount      float64 `db:"amount"`
-	ProductName string  `db:"product_name"`
-	CreatedAt   int64   `db:"created_at"`
-	UpdatedAt   int64   `db:"updated_at"`
-	DeletedAt   *int64  `db:"deleted_at"`
+	ID        int64       `db:"id"`
+	ProductID string      `db:"product_id"`
+	OrderID   int64       `db:"order_id"`
+	Quantity  int64       `db:"quantity"`
+	Amount    money.Money `db:"amount"`
+	// DiscountAmount is what the line was discounted in total, including its
+	// share of the discounts on the whole order.
+	DiscountAmount      money.Money `db:"discount_amount"`
+	ServiceChargeAmount money.Money `db:"service_charge_amount"`
+	// TaxAmount is the tax on the line, whether it was included in the price
+	// or added on top of it.
+	TaxAmount money.Money `db:"tax_amount"`
+	// TotalAmount is what the customer paid for the line.
+	TotalAmount money.Money `db:"total_amount"`
+	ProductName string      `db:"product_name"`
+	CreatedAt   int64       `db:"created_at"`
+	UpdatedAt   int64       `db:"updated_at"`
+	DeletedAt   *int64      `db:"deleted_at"`
 	Order       Order
 }
@@ -0,0 +1,38 @@
+package domain
+
+// OrderEventVersion is the version of the order event payload. It goes up
+// whenever a change to the payload would break its consumers. Version 2 gives
+// amounts as minor units with their currency.
+const OrderEventVersion = 2
+
+// Types of the order lifecycle events.
+const (
+	OrderEventCreated           = "order_created"
+	OrderEventPartiallyPaid     = "order_partially_paid"
+	OrderEventPaid              = "order_paid"
+	OrderEventChallenged        = "order_challenged"
+	OrderEventExpired           = "order_expired"
+	OrderEventCancelled         = "order_cancelled"
+	OrderEventFailed            = "order_failed"
+	OrderEventPartiallyRefunded = "order_partially_refunded"
+	OrderEventRefunded          = "order_refunded"
+)
+
+// orderStatusEvents maps the statuses an order may move to onto the event
+// published when it does.
+var orderStatusEvents = map[string]string{
+	OrderStatusPartiallyPaid:     OrderEventPartiallyPaid,
+	OrderStatusPaid:              OrderEventPaid,
+	OrderStatusChallenge:         OrderEventChallenged,
+	OrderStatusExpired:           OrderEventExpired,
+	OrderStatusCancelled:         OrderEventCancelled,
+	OrderStatusFailed:            OrderEventFailed,
+	OrderStatusPartiallyRefunded: OrderEventPartiallyRefunded,
+	OrderStatusRefunded:          OrderEventRefunded,
+}
+
+// OrderStatusEvent returns the event published when an order moves to status.
+func OrderStatusEvent(status string) (eventType string, ok bool) {
+	eventType, ok = orderStatusEvents[status]
+	return
+}
@@ -0,0 +1,93 @@
+package domain
+
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
+// OrderPayment is one of the tenders an order is paid with, each charged as a
+// transaction of its own at its gateway. A payment goes through the same
+// statuses an order paid with it alone would.
+type OrderPayment struct {
+	ID                int64       `db:"id"`
+	OrderID           int64       `db:"order_id"`
+	PaymentMethodID   int64       `db:"payment_method_id"`
+	PaymentGateway    string      `db:"payment_gateway"`
+	TransactionNumber string      `db:"transaction_number"`
+	Amount            money.Money `db:"amount"`
+	RefundedAmount    money.Money `db:"refunded_amount"`
+	MDRFee            money.Money `db:"mdr_fee"`
+	// TenderedAmount, ChangeAmount and CashierID are only set on cash
+	// payments.
+	TenderedAmount *money.Money `db:"tendered_amount"`
+	ChangeAmount   *money.Money `db:"change_amount"`
+	CashierID      *int64       `db:"cashier_id"`
+	QRCode         *string      `db:"qr_code"`
+	VANumber       *string      `db:"va_number"`
+	Status         string       `db:"status"`
+	ExpiredAt      int64        `db:"expired_at"`
+	PaidAt         *int64       `db:"paid_at"`
+	CreatedAt      int64        `db:"created_at"`
+	UpdatedAt      int64        `db:"updated_at"`
+}
+
+// Settled reports whether the money of the payment was taken, whether it was
+// refunded since or not.
+func (p OrderPayment) Settled() bool {
+	switch p.Status {
+	case OrderStatusPaid, OrderStatusPartiallyRefunded, OrderStatusRefunded:
+		return true
+	}
+
+	return false
+}
+
+// OrderStatusFromPayments works out the status of an order of the given
+// amount from the status of its payments. The order is paid once the settled
+// payments cover the amount, and goes down with the first payment that can no
+// longer be paid otherwise.
+func OrderStatusFromPayments(amount money.Money, payments []OrderPayment) string {
+	var settled int64
+	var challenge, refunded, partiallyRefunded bool
+	allRefunded := len(payments) > 0
+	closed := ""
+	for _, payment := range payments {
+		switch payment.Status {
+		case OrderStatusPaid:
+			settled += payment.Amount.Amount
+		case OrderStatusPartiallyRefunded:
+			settled += payment.Amount.Amount
+			partiallyRefunded = true
+		case OrderStatusRefunded:
+			settled += payment.Amount.Amount
+			refunded = true
+		case OrderStatusPending:
+		case OrderStatusChallenge:
+			challenge = true
+		default:
+			if closed == "" {
+				closed = payment.Status
+			}
+		}
+
+		if payment.Status != OrderStatusRefunded {
+			allRefunded = false
+		}
+	}
+
+	switch {
+	case settled >= amount.Amount:
+		if allRefunded {
+			return OrderStatusRefunded
+		}
+		if refunded || partiallyRefunded {
+			return OrderStatusPartiallyRefunded
+		}
+		return OrderStatusPaid
+	case challenge:
+		return OrderStatusChallenge
+	case closed != "":
+		return closed
+	case settled > 0:
+		return OrderStatusPartiallyPaid
+	}
+
+	return OrderStatusPending
+}
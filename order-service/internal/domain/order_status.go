@@ -0,0 +1,97 @@
+package domain
+
+const (
+	OrderStatusPending           = "pending"
+	OrderStatusPaid              = "success"
+	OrderStatusChallenge         = "challenge"
+	OrderStatusExpired           = "expired"
+	OrderStatusCancelled         = "cancelled"
+	OrderStatusFailed            = "failed"
+	OrderStatusPartiallyRefunded = "partially_refunded"
+	OrderStatusRefunded          = "refunded"
+
+	// OrderStatusPartiallyPaid is an order paid with several tenders, some of
+	// which are settled but not enough to cover it yet.
+	OrderStatusPartiallyPaid = "partially_paid"
+)
+
+// Sources of an order status change.
+const (
+	OrderStatusSourceAPI     = "api"
+	OrderStatusSourceWebhook = "webhook"
+	OrderStatusSourceCron    = "cron"
+	// OrderStatusSourceMigration starts the history of the orders placed
+	// before it was kept, with the status they were in.
+	OrderStatusSourceMigration = "migration"
+)
+
+// OrderStatusChange is who moved an order to another status and why. ActorID
+// is the user behind a change made through the API.
+type OrderStatusChange struct {
+	Source  string
+	ActorID *int64
+	Reason  *string
+}
+
+// OrderStatusHistory records a status an order entered. FromStatus is nil for
+// the status the order was placed in.
+type OrderStatusHistory struct {
+	ID         int64   `db:"id"`
+	OrderID    int64   `db:"order_id"`
+	FromStatus *string `db:"from_status"`
+	ToStatus   string  `db:"to_status"`
+	Source     string  `db:"source"`
+	ActorID    *int64  `db:"actor_id"`
+	Reason     *string `db:"reason"`
+	CreatedAt  int64   `db:"created_at"`
+}
+
+// orderStatusTransitions lists, for every status, the statuses an order may
+// move to from it. Statuses missing from the map are terminal.
+var orderStatusTransitions = map[string][]string{
+	OrderStatusPending:       {OrderStatusPartiallyPaid, OrderStatusPaid, OrderStatusChallenge, OrderStatusExpired, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusPartiallyPaid: {OrderStatusPaid, OrderStatusChallenge, OrderStatusExpired, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusChallenge:     {OrderStatusPartiallyPaid, OrderStatusPaid, OrderStatusCancelled, OrderStatusFailed},
+	// A payment settled after the expiry job released the order is either
+	// accepted once its stock is reserved again or refunded.
+	OrderStatusExpired:           {OrderStatusPaid, OrderStatusRefunded},
+	OrderStatusPaid:              {OrderStatusPartiallyRefunded, OrderStatusRefunded},
+	OrderStatusPartiallyRefunded: {OrderStatusPartiallyRefunded, OrderStatusRefunded},
+}
+
+func CanTransitionOrderStatus(from, to string) bool {
+	for _, status := range orderStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OrderStatusesLeadingTo returns every status an order may move to status
+// from, to be used as the precondition of a conditional update.
+func OrderStatusesLeadingTo(status string) []string {
+	var from []string
+	for source, targets := range orderStatusTransitions {
+		for _, target := range targets {
+			if target == status {
+				from = append(from, source)
+				break
+			}
+		}
+	}
+
+	return from
+}
+
+// OrderStatusHoldsStock reports whether the items of an order in status are
+// still reserved in the product service.
+func OrderStatusHoldsStock(status string) bool {
+	switch status {
+	case OrderStatusExpired, OrderStatusCancelled, OrderStatusFailed:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCanTransitionOrderStatus(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		From     string
+		To       string
+		Expected bool
+	}
+
+	testCases := []TestCase{
+		{Name: "Pending to paid", From: OrderStatusPending, To: OrderStatusPaid, Expected: true},
+		{Name: "Pending to partially paid", From: OrderStatusPending, To: OrderStatusPartiallyPaid, Expected: true},
+		{Name: "Partially paid to paid", From: OrderStatusPartiallyPaid, To: OrderStatusPaid, Expected: true},
+		{Name: "Challenge to paid", From: OrderStatusChallenge, To: OrderStatusPaid, Expected: true},
+		{Name: "Expired to paid", From: OrderStatusExpired, To: OrderStatusPaid, Expected: true},
+		{Name: "Paid to refunded", From: OrderStatusPaid, To: OrderStatusRefunded, Expected: true},
+		{Name: "Partially refunded again", From: OrderStatusPartiallyRefunded, To: OrderStatusPartiallyRefunded, Expected: true},
+		{Name: "Paid back to pending", From: OrderStatusPaid, To: OrderStatusPending, Expected: false},
+		{Name: "Pending to refunded", From: OrderStatusPending, To: OrderStatusRefunded, Expected: false},
+		{Name: "Partially paid back to pending", From: OrderStatusPartiallyPaid, To: OrderStatusPending, Expected: false},
+		{Name: "Paid to the same status", From: OrderStatusPaid, To: OrderStatusPaid, Expected: false},
+		{Name: "Expired to cancelled", From: OrderStatusExpired, To: OrderStatusCancelled, Expected: false},
+		{Name: "Cancelled is terminal", From: OrderStatusCancelled, To: OrderStatusPaid, Expected: false},
+		{Name: "Failed is terminal", From: OrderStatusFailed, To: OrderStatusPending, Expected: false},
+		{Name: "Refunded is terminal", From: OrderStatusRefunded, To: OrderStatusPartiallyRefunded, Expected: false},
+		{Name: "Unknown status", From: "unknown", To: OrderStatusPaid, Expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if allowed := CanTransitionOrderStatus(tc.From, tc.To); allowed != tc.Expected {
+				t.Errorf("expected %t, got %t", tc.Expected, allowed)
+			}
+		})
+	}
+}
+
+func TestOrderStatusesLeadingTo(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Status   string
+		Expected []string
+	}
+
+	testCases := []TestCase{
+		{
+			Name:     "Paid",
+			Status:   OrderStatusPaid,
+			Expected: []string{OrderStatusChallenge, OrderStatusExpired, OrderStatusPartiallyPaid, OrderStatusPending},
+		},
+		{
+			Name:     "Refunded",
+			Status:   OrderStatusRefunded,
+			Expected: []string{OrderStatusExpired, OrderStatusPartiallyRefunded, OrderStatusPaid},
+		},
+		{
+			Name:     "Partially refunded",
+			Status:   OrderStatusPartiallyRefunded,
+			Expected: []string{OrderStatusPartiallyRefunded, OrderStatusPaid},
+		},
+		{
+			Name:     "Pending is only where orders start",
+			Status:   OrderStatusPending,
+			Expected: nil,
+		},
+		{
+			Name:     "Unknown status",
+			Status:   "unknown",
+			Expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			from := OrderStatusesLeadingTo(tc.Status)
+			slices.Sort(from)
+			if !slices.Equal(from, tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, from)
+			}
+
+			for _, status := range from {
+				if !CanTransitionOrderStatus(status, tc.Status) {
+					t.Errorf("%s leads to %s but cannot transition to it", status, tc.Status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package domain
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+)
+
+type OutboxMessage struct {
+	ID            int64   `db:"id"`
+	Topic         string  `db:"topic"`
+	MessageKey    string  `db:"message_key"`
+	EventType     string  `db:"event_type"`
+	Payload       string  `db:"payload"`
+	Status        string  `db:"status"`
+	Attempts      int     `db:"attempts"`
+	LastError     *string `db:"last_error"`
+	NextAttemptAt int64   `db:"next_attempt_at"`
+	SentAt        *int64  `db:"sent_at"`
+	CreatedAt     int64   `db:"created_at"`
+	UpdatedAt     int64   `db:"updated_at"`
+}
@@ -0,0 +1,14 @@
+package domain
+
+type PaymentNotification struct {
+	ID                int64   `db:"id"`
+	OrderID           int64   `db:"order_id"`
+	OrderPaymentID    *int64  `db:"order_payment_id"`
+	TransactionID     string  `db:"transaction_id"`
+	TransactionStatus string  `db:"transaction_status"`
+	FraudStatus       *string `db:"fraud_status"`
+	StatusCode        string  `db:"status_code"`
+	GrossAmount       string  `db:"gross_amount"`
+	Payload           string  `db:"payload"`
+	CreatedAt         int64   `db:"created_at"`
+}
@@ -0,0 +1,36 @@
+package domain
+
+const (
+	PaymentReviewReasonFraudChallenge = "fraud_challenge"
+	PaymentReviewReasonLateSettlement = "late_settlement"
+	PaymentReviewStatusOpen           = "open"
+	PaymentReviewStatusResolved       = "resolved"
+	PaymentReviewDecisionAccept       = "accept"
+	PaymentReviewDecisionDeny         = "deny"
+
+	// PaymentReviewReasonUnreleasedPayment is an order that did not go
+	// through with money paid on it that could not be given back.
+	PaymentReviewReasonUnreleasedPayment = "unreleased_payment"
+
+	// PaymentReviewStatusPending is a review staged along with a payment
+	// status whose follow-up is handled automatically. It is resolved once
+	// the follow-up succeeds, and opened for a reviewer when it fails or does
+	// not finish.
+	PaymentReviewStatusPending = "pending"
+
+	// PaymentReviewStatusResolving is an open review a reviewer's decision
+	// is being applied to. It goes back to open when the decision fails.
+	PaymentReviewStatusResolving = "resolving"
+)
+
+type PaymentReview struct {
+	ID         int64   `db:"id"`
+	OrderID    int64   `db:"order_id"`
+	Reason     string  `db:"reason"`
+	Status     string  `db:"status"`
+	Decision   *string `db:"decision"`
+	Note       *string `db:"note"`
+	CreatedAt  int64   `db:"created_at"`
+	UpdatedAt  int64   `db:"updated_at"`
+	ResolvedAt *int64  `db:"resolved_at"`
+}
@@ -0,0 +1,59 @@
+package domain
+
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
+const (
+	// PromotionTypePercentage takes Value percent off the order, or off the
+	// lines of ProductID.
+	PromotionTypePercentage = "percentage"
+	// PromotionTypeFixed takes Value off the order, or off every unit of
+	// ProductID.
+	PromotionTypeFixed = "fixed"
+	// PromotionTypeBuyXGetY gives GetQuantity units of ProductID for free for
+	// every BuyQuantity units paid for.
+	PromotionTypeBuyXGetY = "buy_x_get_y"
+)
+
+// Promotion is applied automatically while it is running, unless it has a
+// Code, in which case the code has to be entered at checkout. A promotion
+// that is not Stackable is never combined with another one. MinSpend and
+// MaxDiscount are in rupiah, which promotions are set up in.
+type Promotion struct {
+	ID                    int64        `db:"id"`
+	Name                  string       `db:"name"`
+	Code                  *string      `db:"code"`
+	Type                  string       `db:"type"`
+	Value                 float64      `db:"value"`
+	ProductID             *string      `db:"product_id"`
+	BuyQuantity           *int64       `db:"buy_quantity"`
+	GetQuantity           *int64       `db:"get_quantity"`
+	MinSpend              money.Money  `db:"min_spend"`
+	MaxDiscount           *money.Money `db:"max_discount"`
+	Stackable             bool         `db:"stackable"`
+	Priority              int          `db:"priority"`
+	UsageLimit            *int64       `db:"usage_limit"`
+	UsageLimitPerCustomer *int64       `db:"usage_limit_per_customer"`
+	IsActive              bool         `db:"is_active"`
+	StartsAt              int64        `db:"starts_at"`
+	EndsAt                int64        `db:"ends_at"`
+	CreatedAt             int64        `db:"created_at"`
+	UpdatedAt             int64        `db:"updated_at"`
+	DeletedAt             *int64       `db:"deleted_at"`
+}
+
+// RunningAt reports whether the promotion can be applied at the given time.
+func (p Promotion) RunningAt(at int64) bool {
+	return p.IsActive && p.DeletedAt == nil && p.StartsAt <= at && at < p.EndsAt
+}
+
+// OrderDiscount is a promotion applied to an order. OrderDetailID is nil for
+// discounts on the whole order.
+type OrderDiscount struct {
+	ID            int64       `db:"id"`
+	OrderID       int64       `db:"order_id"`
+	OrderDetailID *int64      `db:"order_detail_id"`
+	PromotionID   int64       `db:"promotion_id"`
+	PromotionName string      `db:"promotion_name"`
+	Amount        money.Money `db:"amount"`
+	CreatedAt     int64       `db:"created_at"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+// ReceiptTemplate is how the receipts of a store look. Header and Footer are
+// printed centered, a line at a time, and Logo is a PNG printed above the
+// header. PaperWidth is the width of the paper roll of the store printers, in
+// millimetres.
+type ReceiptTemplate struct {
+	StoreCode  string `db:"store_code"`
+	Header     string `db:"header"`
+	Footer     string `db:"footer"`
+	Logo       []byte `db:"logo"`
+	PaperWidth int    `db:"paper_width"`
+	CreatedAt  int64  `db:"created_at"`
+	UpdatedAt  int64  `db:"updated_at"`
+}
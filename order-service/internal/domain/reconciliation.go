@@ -0,0 +1,23 @@
+package domain
+
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
+// PaymentDiscrepancy is a payment whose status or amount differs from what
+// its gateway reports, found by the daily reconciliation of ReportDate, a
+// YYYY-MM-DD day in WIB. GatewayStatus and GatewayAmount are nil when the
+// gateway could not tell. The amounts are in rupiah, the only currency the
+// gateways take.
+type PaymentDiscrepancy struct {
+	ID                int64        `db:"id"`
+	ReportDate        string       `db:"report_date"`
+	OrderID           int64        `db:"order_id"`
+	OrderPaymentID    int64        `db:"order_payment_id"`
+	TransactionNumber string       `db:"transaction_number"`
+	PaymentGateway    string       `db:"payment_gateway"`
+	LocalStatus       string       `db:"local_status"`
+	GatewayStatus     *string      `db:"gateway_status"`
+	LocalAmount       money.Money  `db:"local_amount"`
+	GatewayAmount     *money.Money `db:"gateway_amount"`
+	Reason            string       `db:"reason"`
+	CreatedAt         int64        `db:"created_at"`
+}
@@ -0,0 +1,47 @@
+package domain
+
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusCompleted = "completed"
+)
+
+type Refund struct {
+	ID        int64  `db:"id"`
+	OrderID   int64  `db:"order_id"`
+	RefundKey string `db:"refund_key"`
+	// RequestHash fingerprints the refund asked for, to tell a retry from a
+	// different refund sent with the same key.
+	RequestHash string      `db:"request_hash"`
+	Amount      money.Money `db:"amount"`
+	Reason      string      `db:"reason"`
+	Restock     bool        `db:"restock"`
+	RefundedBy  int64       `db:"refunded_by"`
+	Status      string      `db:"status"`
+	CreatedAt   int64       `db:"created_at"`
+	Items       []RefundItem
+}
+
+type RefundItem struct {
+	ID            int64       `db:"id"`
+	RefundID      int64       `db:"refund_id"`
+	OrderDetailID int64       `db:"order_detail_id"`
+	Quantity      int64       `db:"quantity"`
+	Amount        money.Money `db:"amount"`
+	CreatedAt     int64       `db:"created_at"`
+}
+
+// RefundPayment is what a refund gives back through one of the order's
+// payments. RefundKey is what the gateway is called with, so a refund that is
+// retried is not paid out twice.
+type RefundPayment struct {
+	ID             int64       `db:"id"`
+	RefundID       int64       `db:"refund_id"`
+	OrderPaymentID int64       `db:"order_payment_id"`
+	RefundKey      string      `db:"refund_key"`
+	Amount         money.Money `db:"amount"`
+	Status         string      `db:"status"`
+	CreatedAt      int64       `db:"created_at"`
+	UpdatedAt      int64       `db:"updated_at"`
+}
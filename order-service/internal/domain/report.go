@@ -0,0 +1,60 @@
+package domain
+
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
+// SettlementSummary adds up the paid orders of a payment method.
+type SettlementSummary struct {
+	PaymentMethodID   int64       `db:"payment_method_id"`
+	PaymentMethodName string      `db:"payment_method_name"`
+	MDR               float64     `db:"mdr"`
+	MDRType           string      `db:"mdr_type"`
+	OrderCount        int64       `db:"order_count"`
+	GrossAmount       money.Money `db:"gross_amount"`
+	RefundedAmount    money.Money `db:"refunded_amount"`
+	MDRFee            money.Money `db:"mdr_fee"`
+}
+
+// SalesSummary adds up the orders paid on a day, taken in WIB.
+type SalesSummary struct {
+	Day                 string      `db:"day"`
+	OrderCount          int64       `db:"order_count"`
+	ItemCount           int64       `db:"item_count"`
+	GrossAmount         money.Money `db:"gross_amount"`
+	DiscountAmount      money.Money `db:"discount_amount"`
+	ServiceChargeAmount money.Money `db:"service_charge_amount"`
+	TaxAmount           money.Money `db:"tax_amount"`
+	RoundingAmount      money.Money `db:"rounding_amount"`
+	Amount              money.Money `db:"amount"`
+}
+
+// RefundSummary adds up the refunds given on a day, taken in WIB.
+type RefundSummary struct {
+	Day         string      `db:"day"`
+	RefundCount int64       `db:"refund_count"`
+	Amount      money.Money `db:"amount"`
+}
+
+// HourlySalesSummary adds up the orders paid in an hour of the day, taken in
+// WIB, over every day of a report.
+type HourlySalesSummary struct {
+	Hour       int         `db:"hour"`
+	OrderCount int64       `db:"order_count"`
+	Amount     money.Money `db:"amount"`
+}
+
+// PaymentMethodSalesSummary adds up the tenders of a payment method over the
+// orders paid in a report.
+type PaymentMethodSalesSummary struct {
+	PaymentMethodID   int64       `db:"payment_method_id"`
+	PaymentMethodName string      `db:"payment_method_name"`
+	OrderCount        int64       `db:"order_count"`
+	Amount            money.Money `db:"amount"`
+}
+
+// OrderStatusSummary adds up the orders placed in a report that are in a
+// status.
+type OrderStatusSummary struct {
+	Status     string      `db:"status"`
+	OrderCount int64       `db:"order_count"`
+	Amount     money.Money `db:"amount"`
+}
@@ -0,0 +1,40 @@
+package domain
+
+const (
+	SagaStatusRunning            = "running"
+	SagaStatusCompleted          = "completed"
+	SagaStatusCompensating       = "compensating"
+	SagaStatusCompensated        = "compensated"
+	SagaStatusCompensationFailed = "compensation_failed"
+
+	SagaActionExecute    = "execute"
+	SagaActionCompensate = "compensate"
+
+	SagaStepSucceeded = "succeeded"
+	SagaStepFailed    = "failed"
+	// SagaStepStarted is recorded, along with the state the step works from,
+	// before the remote part of a step that has to be undone runs.
+	SagaStepStarted = "started"
+)
+
+type OrderSaga struct {
+	ID                int64   `db:"id"`
+	TransactionNumber string  `db:"transaction_number"`
+	OrderID           *int64  `db:"order_id"`
+	Status            string  `db:"status"`
+	CurrentStep       string  `db:"current_step"`
+	State             string  `db:"state"`
+	LastError         *string `db:"last_error"`
+	CreatedAt         int64   `db:"created_at"`
+	UpdatedAt         int64   `db:"updated_at"`
+}
+
+type OrderSagaStep struct {
+	ID        int64   `db:"id"`
+	SagaID    int64   `db:"saga_id"`
+	Step      string  `db:"step"`
+	Action    string  `db:"action"`
+	Status    string  `db:"status"`
+	Error     *string `db:"error"`
+	CreatedAt int64   `db:"created_at"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
+const (
+	ShiftStatusOpen   = "open"
+	ShiftStatusClosed = "closed"
+)
+
+// Shift is a cashier operating a drawer, from the float it was opened with
+// until the cash in it is counted. ExpectedCash, CountedCash and Variance are
+// only set once the shift is closed, Variance being what the count came short
+// of, or over, what the drawer should hold.
+type Shift struct {
+	ID           int64        `db:"id"`
+	CashierID    int64        `db:"cashier_id"`
+	StoreCode    *string      `db:"store_code"`
+	TerminalCode *string      `db:"terminal_code"`
+	OpeningFloat money.Money  `db:"opening_float"`
+	ExpectedCash *money.Money `db:"expected_cash"`
+	CountedCash  *money.Money `db:"counted_cash"`
+	Variance     *money.Money `db:"variance"`
+	Status       string       `db:"status"`
+	CloseNote    *string      `db:"close_note"`
+	OpenedAt     int64        `db:"opened_at"`
+	ClosedAt     *int64       `db:"closed_at"`
+	UpdatedAt    int64        `db:"updated_at"`
+}
+
+// ShiftCashSummary adds up the cash that went into and out of the drawer of a
+// shift, by what it was for. The amounts are all positive, in rupiah.
+type ShiftCashSummary struct {
+	SaleCount    int64       `db:"sale_count"`
+	CashSales    money.Money `db:"cash_sales"`
+	RefundCount  int64       `db:"refund_count"`
+	CashRefunds  money.Money `db:"cash_refunds"`
+	CashInCount  int64       `db:"cash_in_count"`
+	CashIn       money.Money `db:"cash_in"`
+	CashOutCount int64       `db:"cash_out_count"`
+	CashOut      money.Money `db:"cash_out"`
+}
+
+// Net is what the cash of the shift added to, or took from, its float.
+func (s ShiftCashSummary) Net() money.Money {
+	return money.New(s.CashSales.Amount-s.CashRefunds.Amount+s.CashIn.Amount-s.CashOut.Amount, money.IDR)
+}
@@ -0,0 +1,70 @@
+package domain
+
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
+const (
+	// RoundingModeHalfUp rounds charges to the nearest increment.
+	RoundingModeHalfUp = "half_up"
+	// RoundingModeUp always rounds charges up to the next increment.
+	RoundingModeUp = "up"
+	// RoundingModeDown always rounds charges down to the previous increment.
+	RoundingModeDown = "down"
+
+	OrderChargeTypeServiceCharge = "service_charge"
+	OrderChargeTypeTax           = "tax"
+)
+
+// StoreTaxSetting is how the orders of a store are taxed. When
+// PricesIncludeTax is set the product prices already contain the taxes of
+// their category, otherwise the taxes are added on top. The service charge is
+// always added on top of the discounted prices, and is taxed as well when
+// ServiceChargeTaxable is set.
+type StoreTaxSetting struct {
+	ID                   int64   `db:"id"`
+	StoreCode            string  `db:"store_code"`
+	PricesIncludeTax     bool    `db:"prices_include_tax"`
+	ServiceChargeRate    float64 `db:"service_charge_rate"`
+	ServiceChargeTaxable bool    `db:"service_charge_taxable"`
+	RoundingMode         string  `db:"rounding_mode"`
+	RoundingIncrement    float64 `db:"rounding_increment"`
+	// CashRoundingIncrement is what cash orders are rounded to, e.g. Rp100
+	// or Rp500. Zero leaves them as they are.
+	CashRoundingIncrement float64 `db:"cash_rounding_increment"`
+	CreatedAt             int64   `db:"created_at"`
+	UpdatedAt             int64   `db:"updated_at"`
+	DeletedAt             *int64  `db:"deleted_at"`
+	TaxRates              []TaxRate
+}
+
+// TaxRate applies to the products of Category. A nil Category applies to the
+// products that have no category.
+type TaxRate struct {
+	ID                int64   `db:"id"`
+	StoreTaxSettingID int64   `db:"store_tax_setting_id"`
+	Name              string  `db:"name"`
+	Category          *string `db:"category"`
+	Rate              float64 `db:"rate"`
+	CreatedAt         int64   `db:"created_at"`
+}
+
+type ProductTaxCategory struct {
+	ProductID string `db:"product_id"`
+	Category  string `db:"category"`
+	CreatedAt int64  `db:"created_at"`
+	UpdatedAt int64  `db:"updated_at"`
+}
+
+// OrderCharge is a tax or service charge line of an order. IncludedAmount is
+// the part of Amount that was already in the product prices, the rest was
+// added on top of them.
+type OrderCharge struct {
+	ID             int64       `db:"id"`
+	OrderID        int64       `db:"order_id"`
+	Type           string      `db:"type"`
+	Name           string      `db:"name"`
+	Rate           float64     `db:"rate"`
+	BaseAmount     money.Money `db:"base_amount"`
+	Amount         money.Money `db:"amount"`
+	IncludedAmount money.Money `db:"included_amount"`
+	CreatedAt      int64       `db:"created_at"`
+}
@@ -0,0 +1,74 @@
+package dto
+
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
+// OrderEvent is published on the order event topic, keyed by the transaction
+// number of the order, whenever an order is placed or changes status. Order
+// is the order as it is after the change. PreviousStatus is nil on the events
+// of the order being placed.
+type OrderEvent struct {
+	EventID        string         `json:"event_id"`
+	EventType      string         `json:"event_type"`
+	Version        int            `json:"version"`
+	OccurredAt     int64          `json:"occurred_at"`
+	PreviousStatus *string        `json:"previous_status"`
+	Source         string         `json:"source"`
+	ActorID        *int64         `json:"actor_id"`
+	Reason         *string        `json:"reason"`
+	Order          OrderEventData `json:"order"`
+}
+
+type OrderEventData struct {
+	ID                  int64               `json:"id"`
+	TransactionNumber   string              `json:"transaction_number"`
+	Status              string              `json:"status"`
+	StoreCode           *string             `json:"store_code"`
+	CashierID           *int64              `json:"cashier_id"`
+	CustomerID          *int64              `json:"customer_id"`
+	ShiftID             *int64              `json:"shift_id"`
+	PricesIncludeTax    bool                `json:"prices_include_tax"`
+	Currency            string              `json:"currency"`
+	Subtotal            money.Money         `json:"subtotal"`
+	DiscountAmount      money.Money         `json:"discount_amount"`
+	ServiceChargeAmount money.Money         `json:"service_charge_amount"`
+	TaxAmount           money.Money         `json:"tax_amount"`
+	RoundingAmount      money.Money         `json:"rounding_amount"`
+	Amount              money.Money         `json:"amount"`
+	RefundedAmount      money.Money         `json:"refunded_amount"`
+	TenderedAmount      *money.Money        `json:"tendered_amount"`
+	ChangeAmount        *money.Money        `json:"change_amount"`
+	PaidAt              *int64              `json:"paid_at"`
+	ExpiredAt           int64               `json:"expired_at"`
+	CancelledAt         *int64              `json:"cancelled_at"`
+	CreatedAt           int64               `json:"created_at"`
+	UpdatedAt           int64               `json:"updated_at"`
+	Items               []OrderEventItem    `json:"items"`
+	Payments            []OrderEventPayment `json:"payments"`
+}
+
+type OrderEventItem struct {
+	OrderDetailID       int64       `json:"order_detail_id"`
+	ProductID           string      `json:"product_id"`
+	ProductName         string      `json:"product_name"`
+	Quantity            int64       `json:"quantity"`
+	Price               money.Money `json:"price"`
+	DiscountAmount      money.Money `json:"discount_amount"`
+	ServiceChargeAmount money.Money `json:"service_charge_amount"`
+	TaxAmount           money.Money `json:"tax_amount"`
+	TotalAmount         money.Money `json:"total_amount"`
+}
+
+type OrderEventPayment struct {
+	ID                int64        `json:"id"`
+	PaymentMethodID   int64        `json:"payment_method_id"`
+	PaymentMethodName string       `json:"payment_method_name"`
+	PaymentType       string       `json:"payment_type"`
+	PaymentGateway    string       `json:"payment_gateway"`
+	TransactionNumber string       `json:"transaction_number"`
+	Status            string       `json:"status"`
+	Amount            money.Money  `json:"amount"`
+	RefundedAmount    money.Money  `json:"refunded_amount"`
+	TenderedAmount    *money.Money `json:"tendered_amount"`
+	ChangeAmount      *money.Money `json:"change_amount"`
+	PaidAt            *int64       `json:"paid_at"`
+}
@@ -5,13 +5,71 @@ type OrderItem struct {
 	Quantity  int    `json:"quantity"`
 }
 
+// OrderPaymentRequest is one tender of an order. A tender without an Amount
+// pays whatever the other tenders leave of the order total.
+type OrderPaymentRequest struct {
+	PaymentMethodID uint64   `json:"payment_method_id"`
+	Amount          *float64 `json:"amount"`
+	// TenderedAmount is the cash handed over for a cash tender.
+	TenderedAmount *float64 `json:"tendered_amount"`
+}
+
+// OrderCustomerRequest is who the order is placed for, passed on to the
+// payment gateway.
+type OrderCustomerRequest struct {
+	Name  string  `json:"name"`
+	Email *string `json:"email"`
+	Phone *string `json:"phone"`
+}
+
 type OrderRequest struct {
+	// PaymentMethodID and TenderedAmount pay the whole order with a single
+	// tender, they are ignored when Payments is set.
 	PaymentMethodID uint64 `json:"payment_method_id"`
-	UserID          uint64
-	OrderItems      []OrderItem `json:"order_items"`
+	UserID          uint64 `json:"-"`
+	// IdempotencyKey comes from the Idempotency-Key header.
+	IdempotencyKey string `json:"-"`
+	// CustomerID places the order for a known customer, as stored. Customer
+	// places it for whoever has its email or phone, or for a new customer
+	// added with the order. Customer is ignored when CustomerID is set.
+	CustomerID *uint64               `json:"customer_id"`
+	Customer   *OrderCustomerRequest `json:"customer"`
+	// StoreCode picks the tax settings of the order, the default store is
+	// used when it is empty.
+	StoreCode      string                `json:"store_code"`
+	PromoCodes     []string              `json:"promo_codes"`
+	OrderItems     []OrderItem           `json:"order_items"`
+	TenderedAmount *float64              `json:"tendered_amount"`
+	Payments       []OrderPaymentRequest `json:"payments"`
+}
+
+type CancelOrderRequest struct {
+	Reason      string `json:"reason"`
+	CancelledBy uint64
+}
+
+type RefundItemRequest struct {
+	OrderDetailID int64 `json:"order_detail_id"`
+	Quantity      int   `json:"quantity"`
+}
+
+// RefundOrderRequest refunds the given lines, or everything left to refund
+// when Items is empty. Restock gives the refunded units back to the product
+// service.
+type RefundOrderRequest struct {
+	Items      []RefundItemRequest `json:"items"`
+	Reason     string              `json:"reason"`
+	Restock    bool                `json:"restock"`
+	RefundedBy uint64
+	// RefundKey comes from the Idempotency-Key header. A refund retried with
+	// the same key is finished rather than made again.
+	RefundKey string `json:"-"`
 }
 
 type OrderProductServiceRequest struct {
-	TransactionNumber string      `json:"transaction_number"`
-	OrderItems        []OrderItem `json:"order_items"`
+	TransactionNumber string `json:"transaction_number"`
+	// ReservationKey gives back only what was reserved under it, and nothing
+	// when the reservation never went through.
+	ReservationKey string      `json:"reservation_key,omitempty"`
+	OrderItems     []OrderItem `json:"order_items"`
 }
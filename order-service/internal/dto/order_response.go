@@ -1,31 +1,127 @@
 package dto
 
 type OrderResponse struct {
-	ID                int64   `json:"id"`
-	PaymentMethodName string  `json:"payment_method_name"`
-	TransactionAmount float64 `json:"transaction_amount"`
-	PaymentStatus     string  `json:"payment_status"`
-	PaymentExpiredAt  *int64  `json:"payment_expired_at"`
-	QRCode            *string `json:"qr_code"`
-	CreatedAt         int64   `json:"created_at"`
-	TransactionNumber string  `json:"transaction_number"`
+	ID                  int64                   `json:"id"`
+	PaymentMethodName   string                  `json:"payment_method_name"`
+	Subtotal            float64                 `json:"subtotal"`
+	DiscountAmount      float64                 `json:"discount_amount"`
+	Discounts           []OrderDiscountResponse `json:"discounts,omitempty"`
+	ServiceChargeAmount float64                 `json:"service_charge_amount"`
+	TaxAmount           float64                 `json:"tax_amount"`
+	Charges             []OrderChargeResponse   `json:"charges,omitempty"`
+	RoundingAmount      float64                 `json:"rounding_amount"`
+	TransactionAmount   float64                 `json:"transaction_amount"`
+	TenderedAmount      *float64                `json:"tendered_amount,omitempty"`
+	ChangeAmount        *float64                `json:"change_amount,omitempty"`
+	PaymentStatus       string                  `json:"payment_status"`
+	PaymentExpiredAt    *int64                  `json:"payment_expired_at"`
+	QRCode              *string                 `json:"qr_code"`
+	VANumber            *string                 `json:"va_number,omitempty"`
+	Payments            []OrderPaymentResponse  `json:"payments,omitempty"`
+	PaidAt              *int64                  `json:"paid_at,omitempty"`
+	CashierID           *int64                  `json:"cashier_id,omitempty"`
+	CustomerID          *int64                  `json:"customer_id,omitempty"`
+	CreatedAt           int64                   `json:"created_at"`
+	TransactionNumber   string                  `json:"transaction_number"`
+}
+
+type OrderCustomerResponse struct {
+	ID    int64   `json:"id"`
+	Name  string  `json:"name"`
+	Email *string `json:"email"`
+	Phone *string `json:"phone"`
+}
+
+// OrderPaymentResponse is one tender of an order, TransactionNumber is what
+// its gateway knows it by.
+type OrderPaymentResponse struct {
+	ID                int64    `json:"id,omitempty"`
+	PaymentMethodID   int64    `json:"payment_method_id"`
+	PaymentMethodName string   `json:"payment_method_name"`
+	TransactionNumber string   `json:"transaction_number"`
+	Amount            float64  `json:"amount"`
+	RefundedAmount    float64  `json:"refunded_amount"`
+	TenderedAmount    *float64 `json:"tendered_amount,omitempty"`
+	ChangeAmount      *float64 `json:"change_amount,omitempty"`
+	Status            string   `json:"status"`
+	QRCode            *string  `json:"qr_code"`
+	VANumber          *string  `json:"va_number,omitempty"`
+	ExpiredAt         int64    `json:"expired_at"`
+	PaidAt            *int64   `json:"paid_at"`
 }
 
 type OrderItemResponse struct {
-	ID          int64   `json:"id"`
-	ProductName string  `json:"product_name"`
-	Quantity    int     `json:"quantity"`
-	Price       float64 `json:"price"`
+	ID                  int64   `json:"id"`
+	ProductName         string  `json:"product_name"`
+	Quantity            int     `json:"quantity"`
+	Price               float64 `json:"price"`
+	DiscountAmount      float64 `json:"discount_amount"`
+	ServiceChargeAmount float64 `json:"service_charge_amount"`
+	TaxAmount           float64 `json:"tax_amount"`
+	TotalAmount         float64 `json:"total_amount"`
+	RefundedQuantity    int     `json:"refunded_quantity"`
+	RefundedAmount      float64 `json:"refunded_amount"`
 }
 
 type OrderDetails struct {
-	ID                int64               `json:"id"`
-	PaymentMethodName string              `json:"payment_method_name"`
-	TransactionAmount float64             `json:"transaction_amount"`
-	PaymentStatus     string              `json:"payment_status"`
-	PaymentExpiredAt  *int64              `json:"payment_expired_at"`
-	QRCode            *string             `json:"qr_code"`
-	CreatedAt         int64               `json:"created_at"`
-	TransactionNumber string              `json:"transaction_number"`
-	OrderItems        []OrderItemResponse `json:"order_items"`
+	ID                  int64                   `json:"id"`
+	PaymentMethodName   string                  `json:"payment_method_name"`
+	Subtotal            float64                 `json:"subtotal"`
+	DiscountAmount      float64                 `json:"discount_amount"`
+	Discounts           []OrderDiscountResponse `json:"discounts"`
+	ServiceChargeAmount float64                 `json:"service_charge_amount"`
+	TaxAmount           float64                 `json:"tax_amount"`
+	PricesIncludeTax    bool                    `json:"prices_include_tax"`
+	Charges             []OrderChargeResponse   `json:"charges"`
+	RoundingAmount      float64                 `json:"rounding_amount"`
+	TransactionAmount   float64                 `json:"transaction_amount"`
+	TenderedAmount      *float64                `json:"tendered_amount,omitempty"`
+	ChangeAmount        *float64                `json:"change_amount,omitempty"`
+	RefundedAmount      float64                 `json:"refunded_amount"`
+	NetAmount           float64                 `json:"net_amount"`
+	MDRFee              float64                 `json:"mdr_fee"`
+	PaymentStatus       string                  `json:"payment_status"`
+	PaymentExpiredAt    *int64                  `json:"payment_expired_at"`
+	PaidAt              *int64                  `json:"paid_at"`
+	CashierID           *int64                  `json:"cashier_id"`
+	Customer            *OrderCustomerResponse  `json:"customer"`
+	ShiftID             *int64                  `json:"shift_id"`
+	CancelledBy         *int64                  `json:"cancelled_by,omitempty"`
+	CancelReason        *string                 `json:"cancel_reason,omitempty"`
+	CancelledAt         *int64                  `json:"cancelled_at,omitempty"`
+	QRCode              *string                 `json:"qr_code"`
+	Payments            []OrderPaymentResponse  `json:"payments"`
+	CreatedAt           int64                   `json:"created_at"`
+	TransactionNumber   string                  `json:"transaction_number"`
+	OrderItems          []OrderItemResponse     `json:"order_items"`
+}
+
+type RefundItemResponse struct {
+	OrderDetailID int64   `json:"order_detail_id"`
+	Quantity      int     `json:"quantity"`
+	Amount        float64 `json:"amount"`
+}
+
+type RefundResponse struct {
+	ID            int64                `json:"id"`
+	OrderID       int64                `json:"order_id"`
+	RefundKey     string               `json:"refund_key"`
+	Amount        float64              `json:"amount"`
+	Reason        string               `json:"reason"`
+	Restock       bool                 `json:"restock"`
+	PaymentStatus string               `json:"payment_status"`
+	CreatedAt     int64                `json:"created_at"`
+	Items         []RefundItemResponse `json:"items"`
+}
+
+// OrderStatusHistoryResponse is a status the order entered. Source is api,
+// webhook or cron, and FromStatus is nil for the status it was placed in.
+type OrderStatusHistoryResponse struct {
+	ID         int64   `json:"id"`
+	FromStatus *string `json:"from_status"`
+	ToStatus   string  `json:"to_status"`
+	Source     string  `json:"source"`
+	ActorID    *int64  `json:"actor_id"`
+	Reason     *string `json:"reason"`
+	CreatedAt  int64   `json:"created_at"`
 }
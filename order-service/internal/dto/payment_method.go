@@ -0,0 +1,38 @@
+package dto
+
+// PaymentMethodRequest creates or updates a payment method. IsActive defaults
+// to true when it is left out.
+type PaymentMethodRequest struct {
+	Name        string  `json:"name"`
+	Channel     *string `json:"channel"`
+	MDR         float64 `json:"mdr"`
+	MDRType     string  `json:"mdr_type"`
+	ImgURL      *string `json:"img_url"`
+	Gateway     *string `json:"gateway"`
+	PaymentType string  `json:"payment_type"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+type PaymentMethodResponse struct {
+	ID          uint64  `json:"id"`
+	Name        string  `json:"name"`
+	Channel     *string `json:"channel"`
+	MDR         float64 `json:"mdr"`
+	MDRType     string  `json:"mdr_type"`
+	ImgURL      *string `json:"img_url"`
+	Gateway     *string `json:"gateway"`
+	PaymentType string  `json:"payment_type"`
+	IsActive    bool    `json:"is_active"`
+	CreatedAt   int64   `json:"created_at"`
+	UpdatedAt   int64   `json:"updated_at"`
+}
+
+// PublicPaymentMethodResponse is what the POS screen needs to offer a payment
+// method, without the merchant's fee settings.
+type PublicPaymentMethodResponse struct {
+	ID          uint64  `json:"id"`
+	Name        string  `json:"name"`
+	Channel     *string `json:"channel"`
+	ImgURL      *string `json:"img_url"`
+	PaymentType string  `json:"payment_type"`
+}
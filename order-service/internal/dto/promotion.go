@@ -0,0 +1,50 @@
+package dto
+
+type PromotionRequest struct {
+	Name                  string   `json:"name"`
+	Code                  *string  `json:"code"`
+	Type                  string   `json:"type"`
+	Value                 float64  `json:"value"`
+	ProductID             *string  `json:"product_id"`
+	BuyQuantity           *int64   `json:"buy_quantity"`
+	GetQuantity           *int64   `json:"get_quantity"`
+	MinSpend              float64  `json:"min_spend"`
+	MaxDiscount           *float64 `json:"max_discount"`
+	Stackable             bool     `json:"stackable"`
+	Priority              int      `json:"priority"`
+	UsageLimit            *int64   `json:"usage_limit"`
+	UsageLimitPerCustomer *int64   `json:"usage_limit_per_customer"`
+	IsActive              bool     `json:"is_active"`
+	StartsAt              int64    `json:"starts_at"`
+	EndsAt                int64    `json:"ends_at"`
+}
+
+type PromotionResponse struct {
+	ID                    int64    `json:"id"`
+	Name                  string   `json:"name"`
+	Code                  *string  `json:"code"`
+	Type                  string   `json:"type"`
+	Value                 float64  `json:"value"`
+	ProductID             *string  `json:"product_id"`
+	BuyQuantity           *int64   `json:"buy_quantity"`
+	GetQuantity           *int64   `json:"get_quantity"`
+	MinSpend              float64  `json:"min_spend"`
+	MaxDiscount           *float64 `json:"max_discount"`
+	Stackable             bool     `json:"stackable"`
+	Priority              int      `json:"priority"`
+	UsageLimit            *int64   `json:"usage_limit"`
+	UsageLimitPerCustomer *int64   `json:"usage_limit_per_customer"`
+	UsageCount            int64    `json:"usage_count"`
+	IsActive              bool     `json:"is_active"`
+	StartsAt              int64    `json:"starts_at"`
+	EndsAt                int64    `json:"ends_at"`
+	CreatedAt             int64    `json:"created_at"`
+	UpdatedAt             int64    `json:"updated_at"`
+}
+
+type OrderDiscountResponse struct {
+	PromotionID   int64   `json:"promotion_id"`
+	Name          string  `json:"name"`
+	OrderDetailID *int64  `json:"order_detail_id,omitempty"`
+	Amount        float64 `json:"amount"`
+}
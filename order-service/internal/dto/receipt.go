@@ -0,0 +1,27 @@
+package dto
+
+type ReceiptTemplateRequest struct {
+	Header string `json:"header"`
+	Footer string `json:"footer"`
+	// Logo is a base64 encoded PNG, nil leaves the receipts without one.
+	Logo *string `json:"logo"`
+	// PaperWidth is 58 or 80, in millimetres. Zero is 80.
+	PaperWidth int `json:"paper_width"`
+}
+
+type ReceiptTemplateResponse struct {
+	StoreCode  string  `json:"store_code"`
+	Header     string  `json:"header"`
+	Footer     string  `json:"footer"`
+	Logo       *string `json:"logo"`
+	PaperWidth int     `json:"paper_width"`
+	CreatedAt  int64   `json:"created_at"`
+	UpdatedAt  int64   `json:"updated_at"`
+}
+
+// OrderReceipt is a rendered receipt of an order.
+type OrderReceipt struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
@@ -0,0 +1,161 @@
+package dto
+
+// ReportRequest covers the days from StartDate up to and including EndDate,
+// both in YYYY-MM-DD and taken in WIB.
+type ReportRequest struct {
+	StartDate string `query:"start_date"`
+	EndDate   string `query:"end_date"`
+}
+
+type SettlementReportRow struct {
+	PaymentMethodID   int64   `json:"payment_method_id"`
+	PaymentMethodName string  `json:"payment_method_name"`
+	MDR               float64 `json:"mdr"`
+	MDRType           string  `json:"mdr_type"`
+	OrderCount        int64   `json:"order_count"`
+	GrossAmount       float64 `json:"gross_amount"`
+	RefundedAmount    float64 `json:"refunded_amount"`
+	MDRFee            float64 `json:"mdr_fee"`
+	NetAmount         float64 `json:"net_amount"`
+}
+
+type SettlementReportTotal struct {
+	OrderCount     int64   `json:"order_count"`
+	GrossAmount    float64 `json:"gross_amount"`
+	RefundedAmount float64 `json:"refunded_amount"`
+	MDRFee         float64 `json:"mdr_fee"`
+	NetAmount      float64 `json:"net_amount"`
+}
+
+type SettlementReportResponse struct {
+	StartDate      string                `json:"start_date"`
+	EndDate        string                `json:"end_date"`
+	PaymentMethods []SettlementReportRow `json:"payment_methods"`
+	Total          SettlementReportTotal `json:"total"`
+}
+
+type CashReportRow struct {
+	CashierID   int64   `json:"cashier_id"`
+	SaleCount   int64   `json:"sale_count"`
+	CashIn      float64 `json:"cash_in"`
+	RefundCount int64   `json:"refund_count"`
+	CashOut     float64 `json:"cash_out"`
+	NetCash     float64 `json:"net_cash"`
+}
+
+type CashReportTotal struct {
+	SaleCount   int64   `json:"sale_count"`
+	CashIn      float64 `json:"cash_in"`
+	RefundCount int64   `json:"refund_count"`
+	CashOut     float64 `json:"cash_out"`
+	NetCash     float64 `json:"net_cash"`
+}
+
+type CashReportResponse struct {
+	StartDate string          `json:"start_date"`
+	EndDate   string          `json:"end_date"`
+	Cashiers  []CashReportRow `json:"cashiers"`
+	Total     CashReportTotal `json:"total"`
+}
+
+// PaymentDiscrepancyResponse is a payment whose status or amount differs from
+// what its gateway reported on the reconciliation of ReportDate.
+type PaymentDiscrepancyResponse struct {
+	ReportDate        string   `json:"report_date"`
+	OrderID           int64    `json:"order_id"`
+	OrderPaymentID    int64    `json:"order_payment_id"`
+	TransactionNumber string   `json:"transaction_number"`
+	PaymentGateway    string   `json:"payment_gateway"`
+	LocalStatus       string   `json:"local_status"`
+	GatewayStatus     *string  `json:"gateway_status"`
+	LocalAmount       float64  `json:"local_amount"`
+	GatewayAmount     *float64 `json:"gateway_amount"`
+	Reason            string   `json:"reason"`
+}
+
+type PaymentDiscrepancyReportResponse struct {
+	StartDate     string                       `json:"start_date"`
+	EndDate       string                       `json:"end_date"`
+	Discrepancies []PaymentDiscrepancyResponse `json:"discrepancies"`
+}
+
+const (
+	ReportFormatJSON = "json"
+	ReportFormatCSV  = "csv"
+)
+
+// SalesReportRequest covers the orders of StoreCode, or of every store when
+// it is empty, over the days of the report. Format is json or csv.
+type SalesReportRequest struct {
+	ReportRequest
+	StoreCode string `query:"store_code"`
+	Format    string `query:"format"`
+}
+
+// SalesFigures adds up the orders paid and the refunds given over a period.
+// GrossSales is the items at their list prices, CollectedAmount what the
+// customers paid after discounts, charges and rounding, and NetSales what is
+// left of it after refunds.
+type SalesFigures struct {
+	OrderCount          int64   `json:"order_count"`
+	ItemCount           int64   `json:"item_count"`
+	GrossSales          float64 `json:"gross_sales"`
+	DiscountAmount      float64 `json:"discount_amount"`
+	ServiceChargeAmount float64 `json:"service_charge_amount"`
+	TaxAmount           float64 `json:"tax_amount"`
+	RoundingAmount      float64 `json:"rounding_amount"`
+	CollectedAmount     float64 `json:"collected_amount"`
+	RefundCount         int64   `json:"refund_count"`
+	RefundedAmount      float64 `json:"refunded_amount"`
+	NetSales            float64 `json:"net_sales"`
+	AverageBasketAmount float64 `json:"average_basket_amount"`
+	AverageBasketItems  float64 `json:"average_basket_items"`
+}
+
+type DailySalesRow struct {
+	Date string `json:"date"`
+	SalesFigures
+}
+
+type PaymentMethodSalesRow struct {
+	PaymentMethodID   int64   `json:"payment_method_id"`
+	PaymentMethodName string  `json:"payment_method_name"`
+	OrderCount        int64   `json:"order_count"`
+	Amount            float64 `json:"amount"`
+}
+
+type HourlySalesRow struct {
+	Hour       int     `json:"hour"`
+	OrderCount int64   `json:"order_count"`
+	Amount     float64 `json:"amount"`
+}
+
+type OrderStatusRow struct {
+	Status     string  `json:"status"`
+	OrderCount int64   `json:"order_count"`
+	Amount     float64 `json:"amount"`
+}
+
+type SalesReportResponse struct {
+	StoreCode      string                  `json:"store_code,omitempty"`
+	StartDate      string                  `json:"start_date"`
+	EndDate        string                  `json:"end_date"`
+	Days           []DailySalesRow         `json:"days"`
+	PaymentMethods []PaymentMethodSalesRow `json:"payment_methods"`
+	Hours          []HourlySalesRow        `json:"hours"`
+	Total          SalesFigures            `json:"total"`
+}
+
+// ZReportResponse closes the business days of a store. OrderStatuses counts
+// every order placed over the days, so the ones that were not paid show up as
+// well.
+type ZReportResponse struct {
+	StoreCode      string                  `json:"store_code,omitempty"`
+	StartDate      string                  `json:"start_date"`
+	EndDate        string                  `json:"end_date"`
+	GeneratedAt    int64                   `json:"generated_at"`
+	Sales          SalesFigures            `json:"sales"`
+	PaymentMethods []PaymentMethodSalesRow `json:"payment_methods"`
+	Hours          []HourlySalesRow        `json:"hours"`
+	OrderStatuses  []OrderStatusRow        `json:"order_statuses"`
+}
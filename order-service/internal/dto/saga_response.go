@@ -0,0 +1,21 @@
+package dto
+
+type OrderSagaStepResponse struct {
+	Step      string  `json:"step"`
+	Action    string  `json:"action"`
+	Status    string  `json:"status"`
+	Error     *string `json:"error"`
+	CreatedAt int64   `json:"created_at"`
+}
+
+type OrderSagaResponse struct {
+	ID                int64                   `json:"id"`
+	TransactionNumber string                  `json:"transaction_number"`
+	OrderID           *int64                  `json:"order_id"`
+	Status            string                  `json:"status"`
+	CurrentStep       string                  `json:"current_step"`
+	LastError         *string                 `json:"last_error"`
+	CreatedAt         int64                   `json:"created_at"`
+	UpdatedAt         int64                   `json:"updated_at"`
+	Steps             []OrderSagaStepResponse `json:"steps"`
+}
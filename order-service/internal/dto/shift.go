@@ -0,0 +1,72 @@
+package dto
+
+// OpenShiftRequest opens a shift for the logged in cashier with the cash put
+// in the drawer to give change from.
+type OpenShiftRequest struct {
+	StoreCode    string  `json:"store_code"`
+	TerminalCode *string `json:"terminal_code"`
+	OpeningFloat float64 `json:"opening_float"`
+	CashierID    uint64
+}
+
+// CashMovementRequest puts cash into, or takes it out of, the drawer for
+// anything but an order. Type is either cash_in or cash_out.
+type CashMovementRequest struct {
+	Type      string  `json:"type"`
+	Amount    float64 `json:"amount"`
+	Note      string  `json:"note"`
+	CashierID uint64
+}
+
+// CloseShiftRequest closes the shift with the cash counted in its drawer.
+type CloseShiftRequest struct {
+	CountedCash *float64 `json:"counted_cash"`
+	Note        *string  `json:"note"`
+	CashierID   uint64
+}
+
+// ShiftFilter lists the shifts opened between the dates of ReportRequest,
+// optionally those of one cashier or in one status.
+type ShiftFilter struct {
+	ReportRequest
+	Status    string `query:"status"`
+	CashierID int64  `query:"cashier_id"`
+}
+
+type ShiftResponse struct {
+	ID           int64    `json:"id"`
+	CashierID    int64    `json:"cashier_id"`
+	StoreCode    *string  `json:"store_code"`
+	TerminalCode *string  `json:"terminal_code"`
+	Status       string   `json:"status"`
+	OpeningFloat float64  `json:"opening_float"`
+	ExpectedCash *float64 `json:"expected_cash"`
+	CountedCash  *float64 `json:"counted_cash"`
+	Variance     *float64 `json:"variance"`
+	CloseNote    *string  `json:"close_note"`
+	OpenedAt     int64    `json:"opened_at"`
+	ClosedAt     *int64   `json:"closed_at"`
+}
+
+// ShiftCashFigures is where the cash in the drawer of a shift came from and
+// went to. ExpectedCash is what the drawer should hold, the opening float
+// included.
+type ShiftCashFigures struct {
+	SaleCount    int64   `json:"sale_count"`
+	CashSales    float64 `json:"cash_sales"`
+	RefundCount  int64   `json:"refund_count"`
+	CashRefunds  float64 `json:"cash_refunds"`
+	CashInCount  int64   `json:"cash_in_count"`
+	CashIn       float64 `json:"cash_in"`
+	CashOutCount int64   `json:"cash_out_count"`
+	CashOut      float64 `json:"cash_out"`
+	ExpectedCash float64 `json:"expected_cash"`
+}
+
+// ShiftReportResponse is the shift with what went through its drawer and the
+// tenders taken for the orders placed during it.
+type ShiftReportResponse struct {
+	ShiftResponse
+	Cash           ShiftCashFigures        `json:"cash"`
+	PaymentMethods []PaymentMethodSalesRow `json:"payment_methods"`
+}
@@ -0,0 +1,59 @@
+package dto
+
+type TaxRateRequest struct {
+	Name     string  `json:"name"`
+	Category *string `json:"category"`
+	Rate     float64 `json:"rate"`
+}
+
+type StoreTaxSettingRequest struct {
+	PricesIncludeTax     bool    `json:"prices_include_tax"`
+	ServiceChargeRate    float64 `json:"service_charge_rate"`
+	ServiceChargeTaxable bool    `json:"service_charge_taxable"`
+	RoundingMode         string  `json:"rounding_mode"`
+	RoundingIncrement    float64 `json:"rounding_increment"`
+	// CashRoundingIncrement rounds the total of cash orders, e.g. to Rp100
+	// or Rp500. Zero turns cash rounding off.
+	CashRoundingIncrement float64          `json:"cash_rounding_increment"`
+	TaxRates              []TaxRateRequest `json:"tax_rates"`
+}
+
+type TaxRateResponse struct {
+	ID       int64   `json:"id"`
+	Name     string  `json:"name"`
+	Category *string `json:"category"`
+	Rate     float64 `json:"rate"`
+}
+
+type StoreTaxSettingResponse struct {
+	ID                    int64             `json:"id"`
+	StoreCode             string            `json:"store_code"`
+	PricesIncludeTax      bool              `json:"prices_include_tax"`
+	ServiceChargeRate     float64           `json:"service_charge_rate"`
+	ServiceChargeTaxable  bool              `json:"service_charge_taxable"`
+	RoundingMode          string            `json:"rounding_mode"`
+	RoundingIncrement     float64           `json:"rounding_increment"`
+	CashRoundingIncrement float64           `json:"cash_rounding_increment"`
+	TaxRates              []TaxRateResponse `json:"tax_rates"`
+	CreatedAt             int64             `json:"created_at"`
+	UpdatedAt             int64             `json:"updated_at"`
+}
+
+type ProductTaxCategoryRequest struct {
+	Category string `json:"category"`
+}
+
+type ProductTaxCategoryResponse struct {
+	ProductID string `json:"product_id"`
+	Category  string `json:"category"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type OrderChargeResponse struct {
+	Type           string  `json:"type"`
+	Name           string  `json:"name"`
+	Rate           float64 `json:"rate"`
+	BaseAmount     float64 `json:"base_amount"`
+	Amount         float64 `json:"amount"`
+	IncludedAmount float64 `json:"included_amount"`
+}
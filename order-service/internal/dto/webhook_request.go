@@ -17,4 +17,26 @@ type PaymentNotification struct {
 	FraudStatus       string `json:"fraud_status"`
 	Currency          string `json:"currency"`
 	Acquirer          string `json:"acquirer"`
+	// RawPayload is the notification body as received, kept for auditing.
+	RawPayload []byte `json:"-"`
+}
+
+type PaymentReviewDecisionRequest struct {
+	Decision   string  `json:"decision"`
+	Note       *string `json:"note"`
+	ReviewedBy uint64
+}
+
+type PaymentReviewResponse struct {
+	ID                int64   `json:"id"`
+	OrderID           int64   `json:"order_id"`
+	TransactionNumber string  `json:"transaction_number"`
+	TransactionAmount float64 `json:"transaction_amount"`
+	PaymentStatus     string  `json:"payment_status"`
+	Reason            string  `json:"reason"`
+	Status            string  `json:"status"`
+	Decision          *string `json:"decision"`
+	Note              *string `json:"note"`
+	CreatedAt         int64   `json:"created_at"`
+	ResolvedAt        *int64  `json:"resolved_at"`
 }
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratepostgres "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jmoiron/sqlx"
+)
+
+// WithMigrate runs fn with golang-migrate reading the migrations in the root
+// of fsys. It works on a connection of its own, released once fn returns, so
+// the pool stays open for the service.
+func WithMigrate(ctx context.Context, db *sqlx.DB, fsys fs.FS, fn func(m *migrate.Migrate) error) error {
+	source, err := iofs.New(fsys, ".")
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	driver, err := migratepostgres.WithConnection(ctx, conn, &migratepostgres.Config{})
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		return err
+	}
+
+	return fn(m)
+}
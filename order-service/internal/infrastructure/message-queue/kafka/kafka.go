@@ -9,25 +9,19 @@ import (
 )
 
 var (
-	KafkaConn   *kafka.Conn
-	KafkaReader *kafka.Reader
+	KafkaConn *kafka.Conn
 )
 
-func CreateKafkaReader(config *config.Config) *kafka.Reader {
-	KafkaReader = kafka.NewReader(kafka.ReaderConfig{
-		Brokers:          []string{config.KafkaConfig.BrokerAddress},
-		Topic:            config.KafkaConfig.BrokerTopic,
-		MinBytes:         1e3, // 1KB
-		MaxBytes:         1e6, // 1MB
-		MaxWait:          100 * time.Millisecond,
-		ReadLagInterval:  -1,
-		StartOffset:      kafka.LastOffset,
-		GroupID:          "order-service",
-		QueueCapacity:    1000,
-		ReadBatchTimeout: 10 * time.Millisecond,
-	})
-
-	return KafkaReader
+// CreateKafkaWriter writes to topic, spreading the messages over its
+// partitions by key so the messages of a key stay in order.
+func CreateKafkaWriter(config *config.Config, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(config.KafkaConfig.BrokerAddress),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+	}
 }
 
 func CreateKafkaProducer(config *config.Config) *kafka.Conn {
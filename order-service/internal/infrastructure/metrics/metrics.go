@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Collectors are registered on the default registry, which is the one served by
+// echoprometheus.NewHandler on the metrics port.
+var (
+	OutboxPendingMessages = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "outbox_pending_messages",
+		Help: "Number of outbox messages that have not been published to Kafka yet.",
+	})
+
+	OutboxPublishFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_publish_failures_total",
+		Help: "Number of failed attempts to publish an outbox message to Kafka.",
+	})
+
+	JobRunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "job_run_duration_seconds",
+		Help:    "Duration of the scheduled job runs that held the job lock.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"job"})
+
+	JobFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "job_failures_total",
+		Help: "Number of scheduled job runs, or items within them, that failed.",
+	}, []string{"job"})
+
+	ExpiredOrders = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "expired_orders_total",
+		Help: "Number of orders expired by the expiry job.",
+	})
+)
@@ -0,0 +1,47 @@
+package paymentgateway
+
+import (
+	"context"
+	"time"
+)
+
+// CashGateway stands in for a provider on orders paid in cash at the
+// counter. The cash changes hands before the order is placed, so every charge
+// is settled on the spot and refunds are handed out from the drawer.
+type CashGateway struct{}
+
+func CreateCashGateway() *CashGateway {
+	return &CashGateway{}
+}
+
+func (g *CashGateway) Name() string {
+	return GatewayCash
+}
+
+func (g *CashGateway) Charge(ctx context.Context, req ChargeRequest) (response ChargeResponse, err error) {
+	response.TransactionID = "cash-" + req.TransactionNumber
+	response.TransactionStatus = "settlement"
+	response.ExpiredAt = time.Now().Unix()
+
+	return response, nil
+}
+
+func (g *CashGateway) Status(ctx context.Context, transactionNumber string) (status TransactionStatus, err error) {
+	status.TransactionID = "cash-" + transactionNumber
+	status.TransactionStatus = "settlement"
+	status.FraudStatus = "accept"
+	status.PaymentType = "cash"
+
+	return status, nil
+}
+
+func (g *CashGateway) Cancel(ctx context.Context, transactionNumber string) error {
+	return nil
+}
+
+func (g *CashGateway) Refund(ctx context.Context, transactionNumber string, req RefundRequest) (response RefundResponse, err error) {
+	response.RefundKey = req.RefundKey
+	response.TransactionStatus = "refund"
+
+	return response, nil
+}
@@ -0,0 +1,144 @@
+package paymentgateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+const (
+	GatewayMidtrans = "midtrans"
+	GatewayFake     = "fake"
+	GatewayCash     = "cash"
+)
+
+// ErrChargeItemsMismatch is returned for a charge whose items do not add up
+// to its gross amount, which the gateways reject or charge wrongly.
+var ErrChargeItemsMismatch = errors.New("charge items do not add up to the gross amount")
+
+// ErrTransactionNotFound is returned by Cancel for a transaction the gateway
+// never received, which leaves nothing to cancel.
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// PaymentGateway is implemented by every provider an order can be charged
+// through. Transactions are identified by the order's transaction number.
+type PaymentGateway interface {
+	Name() string
+	Charge(ctx context.Context, req ChargeRequest) (ChargeResponse, error)
+	Status(ctx context.Context, transactionNumber string) (TransactionStatus, error)
+	Cancel(ctx context.Context, transactionNumber string) error
+	Refund(ctx context.Context, transactionNumber string, req RefundRequest) (RefundResponse, error)
+}
+
+type ChargeItem struct {
+	ID       string
+	Name     string
+	Price    money.Money
+	Quantity int32
+}
+
+type Customer struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Phone     string
+}
+
+type ChargeRequest struct {
+	TransactionNumber string
+	// PaymentType is the payment_methods.payment_type of the chosen method,
+	// e.g. qris, gopay or bank_transfer.
+	PaymentType string
+	// Channel narrows PaymentType down, e.g. the bank of a bank transfer.
+	Channel     *string
+	GrossAmount money.Money
+	Items       []ChargeItem
+	Customer    Customer
+}
+
+// Validate checks that the items add up to the gross amount exactly, in its
+// currency. A charge without items is charged its gross amount alone.
+func (r ChargeRequest) Validate() error {
+	if len(r.Items) == 0 {
+		return nil
+	}
+
+	total := money.New(0, r.GrossAmount.Currency)
+	for _, item := range r.Items {
+		var err error
+		total, err = total.Add(item.Price.Mul(int64(item.Quantity)))
+		if err != nil {
+			return fmt.Errorf("item %s: %w", item.ID, err)
+		}
+	}
+
+	if !total.Equal(r.GrossAmount) {
+		return fmt.Errorf("%w: items add up to %s, gross amount is %s", ErrChargeItemsMismatch, total, r.GrossAmount)
+	}
+
+	return nil
+}
+
+type ChargeResponse struct {
+	TransactionID     string
+	TransactionStatus string
+	QRCode            *string
+	VANumber          *string
+	ExpiredAt         int64
+}
+
+type TransactionStatus struct {
+	TransactionID     string
+	TransactionStatus string
+	FraudStatus       string
+	PaymentType       string
+	GrossAmount       string
+	SettlementTime    string
+}
+
+type RefundRequest struct {
+	RefundKey string
+	Amount    money.Money
+	Reason    string
+}
+
+type RefundResponse struct {
+	RefundKey         string
+	TransactionStatus string
+}
+
+// Registry resolves the gateway configured on a payment method, falling back
+// to the default gateway for methods that do not name one.
+type Registry struct {
+	defaultGateway string
+	gateways       map[string]PaymentGateway
+}
+
+func CreateRegistry(defaultGateway string, gateways ...PaymentGateway) *Registry {
+	registry := &Registry{
+		defaultGateway: defaultGateway,
+		gateways:       make(map[string]PaymentGateway, len(gateways)),
+	}
+
+	for _, gateway := range gateways {
+		registry.gateways[gateway.Name()] = gateway
+	}
+
+	return registry
+}
+
+func (r *Registry) Get(name *string) (PaymentGateway, error) {
+	gatewayName := r.defaultGateway
+	if name != nil && *name != "" {
+		gatewayName = *name
+	}
+
+	gateway, ok := r.gateways[gatewayName]
+	if !ok {
+		return nil, fmt.Errorf("payment gateway %q is not configured", gatewayName)
+	}
+
+	return gateway, nil
+}
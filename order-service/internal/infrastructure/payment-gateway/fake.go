@@ -0,0 +1,152 @@
+package paymentgateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+const fakePaymentTTL = 15 * time.Minute
+
+type fakeTransaction struct {
+	status      string
+	paymentType string
+	grossAmount money.Money
+	refunded    money.Money
+	refundKeys  map[string]bool
+	chargedAt   time.Time
+}
+
+// FakeGateway settles payments in-process without any network call so orders
+// can be placed end to end in local and test environments. A charge is
+// reported as pending by Charge and as settled by every Status call after it.
+// Like Midtrans, a refund key is only refunded once.
+type FakeGateway struct {
+	mu           sync.Mutex
+	transactions map[string]*fakeTransaction
+}
+
+func CreateFakeGateway() *FakeGateway {
+	return &FakeGateway{
+		transactions: make(map[string]*fakeTransaction),
+	}
+}
+
+func (g *FakeGateway) Name() string {
+	return GatewayFake
+}
+
+func (g *FakeGateway) Charge(ctx context.Context, req ChargeRequest) (response ChargeResponse, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.transactions[req.TransactionNumber]; exists {
+		return response, fmt.Errorf("transaction %s has already been charged", req.TransactionNumber)
+	}
+
+	now := time.Now()
+	g.transactions[req.TransactionNumber] = &fakeTransaction{
+		status:      "pending",
+		paymentType: req.PaymentType,
+		grossAmount: req.GrossAmount,
+		refunded:    money.New(0, req.GrossAmount.Currency),
+		chargedAt:   now,
+	}
+
+	qrCode := "FAKE-QR-" + req.TransactionNumber
+
+	response.TransactionID = "fake-" + req.TransactionNumber
+	response.TransactionStatus = "pending"
+	response.QRCode = &qrCode
+	response.ExpiredAt = now.Add(fakePaymentTTL).Unix()
+
+	return response, nil
+}
+
+func (g *FakeGateway) Status(ctx context.Context, transactionNumber string) (status TransactionStatus, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	transaction, exists := g.transactions[transactionNumber]
+	if !exists {
+		return status, fmt.Errorf("transaction %s not found", transactionNumber)
+	}
+
+	if transaction.status == "pending" {
+		transaction.status = "settlement"
+	}
+
+	status.TransactionID = "fake-" + transactionNumber
+	status.TransactionStatus = transaction.status
+	status.FraudStatus = "accept"
+	status.PaymentType = transaction.paymentType
+	status.GrossAmount = transaction.grossAmount.String()
+	status.SettlementTime = transaction.chargedAt.Format("2006-01-02 15:04:05")
+
+	return status, nil
+}
+
+func (g *FakeGateway) Cancel(ctx context.Context, transactionNumber string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	transaction, exists := g.transactions[transactionNumber]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTransactionNotFound, transactionNumber)
+	}
+
+	if transaction.status != "pending" {
+		return fmt.Errorf("transaction %s cannot be cancelled in status %s", transactionNumber, transaction.status)
+	}
+
+	transaction.status = "cancel"
+
+	return nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, transactionNumber string, req RefundRequest) (response RefundResponse, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	transaction, exists := g.transactions[transactionNumber]
+	if !exists {
+		return response, fmt.Errorf("transaction %s not found", transactionNumber)
+	}
+
+	if transaction.status != "settlement" && transaction.status != "partial_refund" {
+		return response, fmt.Errorf("transaction %s cannot be refunded in status %s", transactionNumber, transaction.status)
+	}
+
+	if transaction.refundKeys[req.RefundKey] {
+		response.RefundKey = req.RefundKey
+		response.TransactionStatus = transaction.status
+		return response, nil
+	}
+
+	refunded, err := transaction.refunded.Add(req.Amount)
+	if err != nil {
+		return
+	}
+
+	if refunded.Amount > transaction.grossAmount.Amount {
+		return response, fmt.Errorf("refund amount exceeds the remaining amount of transaction %s", transactionNumber)
+	}
+
+	if transaction.refundKeys == nil {
+		transaction.refundKeys = make(map[string]bool)
+	}
+	transaction.refundKeys[req.RefundKey] = true
+	transaction.refunded = refunded
+	transaction.status = "partial_refund"
+	if transaction.refunded.Equal(transaction.grossAmount) {
+		transaction.status = "refund"
+	}
+
+	response.RefundKey = req.RefundKey
+	response.TransactionStatus = transaction.status
+
+	return response, nil
+}
@@ -1,19 +1,235 @@
 package paymentgateway
 
 import (
+	"context"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
 	"github.com/alimikegami/point-of-sales/order-service/config"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/utils"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
 	"github.com/midtrans/midtrans-go"
 	"github.com/midtrans/midtrans-go/coreapi"
+	"go.opentelemetry.io/otel"
 )
 
-var midtransClient *coreapi.Client
+// midtransTimeout bounds every call to Midtrans, whatever the context of the
+// caller allows.
+const midtransTimeout = 30 * time.Second
+
+type MidtransGateway struct {
+	// client is copied for every call and never used as it is, see withContext.
+	client coreapi.Client
+}
+
+func CreateMidtransGateway(config *config.Config) *MidtransGateway {
+	environment := midtrans.Sandbox
+	if config.MidtransConfig.Environment == "production" {
+		environment = midtrans.Production
+	}
+
+	client := coreapi.Client{}
+	client.New(config.MidtransConfig.ServerKey, environment)
+
+	return &MidtransGateway{
+		client: client,
+	}
+}
+
+// withContext returns a client of its own for a call made under ctx. The
+// Midtrans client drops the context it is given, so its requests are sent
+// under ctx by the transport instead, with midtransTimeout on top.
+func (g *MidtransGateway) withContext(ctx context.Context) coreapi.Client {
+	client := g.client
+	client.Options = &midtrans.ConfigOptions{}
+	client.HttpClient = &midtrans.HttpClientImplementation{
+		HttpClient: &http.Client{
+			Timeout:   midtransTimeout,
+			Transport: contextTransport{ctx: ctx},
+		},
+		Logger: midtrans.GetDefaultLogger(client.Env),
+	}
+
+	return client
+}
+
+// contextTransport sends every request under ctx.
+type contextTransport struct {
+	ctx context.Context
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return http.DefaultTransport.RoundTrip(req.WithContext(t.ctx))
+}
+
+// VerifyMidtransSignature checks the signature_key of an HTTP notification,
+// which Midtrans computes as SHA512(order_id + status_code + gross_amount +
+// server key).
+func VerifyMidtransSignature(serverKey, orderID, statusCode, grossAmount, signatureKey string) bool {
+	digest := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+	expected := hex.EncodeToString(digest[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(signatureKey)) == 1
+}
+
+func (g *MidtransGateway) Name() string {
+	return GatewayMidtrans
+}
+
+// midtransAmount is the amount in whole rupiah, the only amounts Midtrans
+// takes.
+func midtransAmount(amount money.Money) (int64, error) {
+	if amount.Currency != money.IDR {
+		return 0, fmt.Errorf("midtrans only takes IDR, not %s", amount.Currency)
+	}
+
+	rupiah, exact := amount.MajorUnits()
+	if !exact {
+		return 0, fmt.Errorf("midtrans only takes whole rupiah, not %s", amount)
+	}
+
+	return rupiah, nil
+}
+
+func (g *MidtransGateway) Charge(ctx context.Context, req ChargeRequest) (response ChargeResponse, err error) {
+	grossAmount, err := midtransAmount(req.GrossAmount)
+	if err != nil {
+		return
+	}
+
+	chargeItems := make([]midtrans.ItemDetails, len(req.Items))
+	for i, item := range req.Items {
+		price, err := midtransAmount(item.Price)
+		if err != nil {
+			// Items priced in fractions of a rupiah are charged as one item
+			// for the gross amount, which is whole.
+			chargeItems = []midtrans.ItemDetails{{
+				ID:    req.TransactionNumber,
+				Price: grossAmount,
+				Qty:   1,
+				Name:  "Order payment",
+			}}
+			break
+		}
+
+		chargeItems[i] = midtrans.ItemDetails{
+			ID:    item.ID,
+			Price: price,
+			Qty:   item.Quantity,
+			Name:  item.Name,
+		}
+	}
+
+	chargeReq := &coreapi.ChargeReq{
+		PaymentType: coreapi.CoreapiPaymentType(req.PaymentType),
+		TransactionDetails: midtrans.TransactionDetails{
+			OrderID:  req.TransactionNumber,
+			GrossAmt: grossAmount,
+		},
+		Items: &chargeItems,
+	}
+
+	// Orders placed for no one in particular are charged without customer
+	// details.
+	if req.Customer != (Customer{}) {
+		chargeReq.CustomerDetails = &midtrans.CustomerDetails{
+			FName: req.Customer.FirstName,
+			LName: req.Customer.LastName,
+			Email: req.Customer.Email,
+			Phone: req.Customer.Phone,
+		}
+	}
+
+	if chargeReq.PaymentType == coreapi.PaymentTypeBankTransfer && req.Channel != nil {
+		chargeReq.BankTransfer = &coreapi.BankTransferDetails{
+			Bank: midtrans.Bank(*req.Channel),
+		}
+	}
+
+	tracer := otel.Tracer("pos-order-service")
+	_, span := tracer.Start(ctx, "midtrans charge request")
+	defer span.End()
+
+	chargeResp, midtransErr := g.withContext(ctx).ChargeTransaction(chargeReq)
+	if midtransErr != nil {
+		return response, midtransErr
+	}
+
+	if chargeResp.StatusCode != "201" {
+		return response, fmt.Errorf("payment gateway returned non-200 status: %s", chargeResp.StatusCode)
+	}
+
+	expiredAt, err := utils.ConvertDateTimeWibToUnixTimestamp(chargeResp.ExpiryTime)
+	if err != nil {
+		return response, err
+	}
+
+	response.TransactionID = chargeResp.TransactionID
+	response.TransactionStatus = chargeResp.TransactionStatus
+	response.ExpiredAt = expiredAt
+
+	if chargeResp.QRString != "" {
+		response.QRCode = &chargeResp.QRString
+	}
+
+	if len(chargeResp.VaNumbers) > 0 {
+		response.VANumber = &chargeResp.VaNumbers[0].VANumber
+	} else if chargeResp.PermataVaNumber != "" {
+		response.VANumber = &chargeResp.PermataVaNumber
+	}
+
+	return response, nil
+}
+
+func (g *MidtransGateway) Status(ctx context.Context, transactionNumber string) (status TransactionStatus, err error) {
+	statusResp, midtransErr := g.withContext(ctx).CheckTransaction(transactionNumber)
+	if midtransErr != nil {
+		return status, midtransErr
+	}
+
+	status.TransactionID = statusResp.TransactionID
+	status.TransactionStatus = statusResp.TransactionStatus
+	status.FraudStatus = statusResp.FraudStatus
+	status.PaymentType = statusResp.PaymentType
+	status.GrossAmount = statusResp.GrossAmount
+	status.SettlementTime = statusResp.SettlementTime
+
+	return status, nil
+}
+
+func (g *MidtransGateway) Cancel(ctx context.Context, transactionNumber string) error {
+	_, midtransErr := g.withContext(ctx).CancelTransaction(transactionNumber)
+	if midtransErr != nil {
+		if midtransErr.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s", ErrTransactionNotFound, transactionNumber)
+		}
+		return midtransErr
+	}
+
+	return nil
+}
+
+func (g *MidtransGateway) Refund(ctx context.Context, transactionNumber string, req RefundRequest) (response RefundResponse, err error) {
+	amount, err := midtransAmount(req.Amount)
+	if err != nil {
+		return
+	}
 
-func CreateMidtransClient(config *config.Config) *coreapi.Client {
-	midtrans.ServerKey = config.MidtransConfig.ServerKey
-	midtrans.Environment = midtrans.Sandbox // Use midtrans.Production for production
+	refundResp, midtransErr := g.withContext(ctx).RefundTransaction(transactionNumber, &coreapi.RefundReq{
+		RefundKey: req.RefundKey,
+		Amount:    amount,
+		Reason:    req.Reason,
+	})
+	if midtransErr != nil {
+		return response, midtransErr
+	}
 
-	midtransClient = &coreapi.Client{}
-	midtransClient.New(midtrans.ServerKey, midtrans.Environment)
+	response.RefundKey = req.RefundKey
+	response.TransactionStatus = refundResp.TransactionStatus
 
-	return midtransClient
+	return response, nil
 }
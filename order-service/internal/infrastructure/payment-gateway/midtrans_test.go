@@ -0,0 +1,69 @@
+package paymentgateway
+
+import (
+	"testing"
+
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+func TestVerifyMidtransSignature(t *testing.T) {
+	// SHA512("ORD-20240101-0001" + "200" + "150000.00" + "SB-Mid-server-key").
+	const signature = "17a34fa958358683eaba62f37b938e6378ac918db1c897909051969c16867fa9776d36910170fc422de4165c722923eadbdc7e534ab3fc192bc09cb4352732ec"
+
+	type TestCase struct {
+		Name         string
+		ServerKey    string
+		OrderID      string
+		StatusCode   string
+		GrossAmount  string
+		SignatureKey string
+		Expected     bool
+	}
+
+	testCases := []TestCase{
+		{Name: "Valid signature", ServerKey: "SB-Mid-server-key", OrderID: "ORD-20240101-0001", StatusCode: "200", GrossAmount: "150000.00", SignatureKey: signature, Expected: true},
+		{Name: "Other server key", ServerKey: "SB-Mid-server-other", OrderID: "ORD-20240101-0001", StatusCode: "200", GrossAmount: "150000.00", SignatureKey: signature},
+		{Name: "Other order", ServerKey: "SB-Mid-server-key", OrderID: "ORD-20240101-0002", StatusCode: "200", GrossAmount: "150000.00", SignatureKey: signature},
+		{Name: "Other status code", ServerKey: "SB-Mid-server-key", OrderID: "ORD-20240101-0001", StatusCode: "201", GrossAmount: "150000.00", SignatureKey: signature},
+		{Name: "Tampered amount", ServerKey: "SB-Mid-server-key", OrderID: "ORD-20240101-0001", StatusCode: "200", GrossAmount: "1.00", SignatureKey: signature},
+		{Name: "Amount formatted differently", ServerKey: "SB-Mid-server-key", OrderID: "ORD-20240101-0001", StatusCode: "200", GrossAmount: "150000", SignatureKey: signature},
+		{Name: "Signature in upper case", ServerKey: "SB-Mid-server-key", OrderID: "ORD-20240101-0001", StatusCode: "200", GrossAmount: "150000.00", SignatureKey: "17A34FA958358683EABA62F37B938E6378AC918DB1C897909051969C16867FA9776D36910170FC422DE4165C722923EADBDC7E534AB3FC192BC09CB4352732EC"},
+		{Name: "Missing signature", ServerKey: "SB-Mid-server-key", OrderID: "ORD-20240101-0001", StatusCode: "200", GrossAmount: "150000.00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if valid := VerifyMidtransSignature(tc.ServerKey, tc.OrderID, tc.StatusCode, tc.GrossAmount, tc.SignatureKey); valid != tc.Expected {
+				t.Errorf("expected %t, got %t", tc.Expected, valid)
+			}
+		})
+	}
+}
+
+func TestMidtransAmount(t *testing.T) {
+	type TestCase struct {
+		Name        string
+		Amount      money.Money
+		Expected    int64
+		ExpectedErr bool
+	}
+
+	testCases := []TestCase{
+		{Name: "Whole rupiah", Amount: money.New(15000000, money.IDR), Expected: 150000},
+		{Name: "Rupiah with sen", Amount: money.New(15000050, money.IDR), ExpectedErr: true},
+		{Name: "Other currency", Amount: money.New(150000, "JPY"), ExpectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			amount, err := midtransAmount(tc.Amount)
+			if (err != nil) != tc.ExpectedErr {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if amount != tc.Expected {
+				t.Errorf("expected %d, got %d", tc.Expected, amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddCashTransaction(ctx context.Context, data domain.CashTransaction) (err error) {
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO cash_transactions(order_id, order_payment_id, refund_id, shift_id, cashier_id, store_code, type, amount, note, created_at) VALUES (:order_id, :order_payment_id, :refund_id, :shift_id, :cashier_id, :store_code, :type, :amount, :note, :created_at)", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddCashTransaction").Msg("")
+		return
+	}
+
+	return nil
+}
+
+// GetCashierCashSummaries sums up the cash every cashier took in and handed
+// out between from and to (exclusive).
+func (r *OrderRepositoryImpl) GetCashierCashSummaries(ctx context.Context, from int64, to int64) (data []domain.CashierCashSummary, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT cashier_id, COUNT(*) FILTER (WHERE type = 'sale') AS sale_count, COALESCE(SUM(amount) FILTER (WHERE amount > 0), 0) AS cash_in, COUNT(*) FILTER (WHERE type = 'refund') AS refund_count, COALESCE(-SUM(amount) FILTER (WHERE amount < 0), 0) AS cash_out FROM cash_transactions WHERE created_at >= $1 AND created_at < $2 GROUP BY cashier_id ORDER BY cashier_id", from, to)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetCashierCashSummaries").Msg("")
+		return nil, err
+	}
+
+	return
+}
@@ -8,15 +8,134 @@ import (
 )
 
 type OrderRepository interface {
+	TryLockJob(ctx context.Context, job string) (unlock func(), locked bool, err error)
 	HandleTrx(ctx context.Context, fn func(ctx context.Context, repo OrderRepository) error) error
 
 	AddOrder(ctx context.Context, data domain.Order) (id int64, err error)
 	AddOrderDetails(ctx context.Context, data []domain.OrderDetail) (err error)
 	GetOrderByTransactionNumber(ctx context.Context, transactionNumber string) (data domain.Order, err error)
-	UpdateOrderPaymentStatus(ctx context.Context, data domain.Order) (err error)
+	TransitionOrderPaymentStatus(ctx context.Context, data domain.Order, from []string) (updated bool, err error)
+	UpdateOrderCancellation(ctx context.Context, data domain.Order) (err error)
 	GetOrders(ctx context.Context, filter pkgdto.Filter) (data []domain.Order, err error)
+	CountOrders(ctx context.Context, filter pkgdto.Filter) (count int64, err error)
 	GetOrderByOrderID(ctx context.Context, id int64) (data domain.Order, err error)
+	LockOrderByOrderID(ctx context.Context, id int64) (data domain.Order, err error)
 	GetOrderDetailsByOrderID(ctx context.Context, id int64) (data []domain.OrderDetail, err error)
 
+	AddOrderPayment(ctx context.Context, data domain.OrderPayment) (id int64, err error)
+	GetOrderPaymentsByOrderID(ctx context.Context, orderID int64) (data []domain.OrderPayment, err error)
+	GetOrderPaymentByTransactionNumber(ctx context.Context, transactionNumber string) (data domain.OrderPayment, err error)
+	TransitionOrderPayment(ctx context.Context, data domain.OrderPayment, from []string) (updated bool, err error)
+
 	GetPaymentMethodByID(ctx context.Context, id uint64) (data domain.PaymentMethod, err error)
+	AddPaymentMethod(ctx context.Context, data domain.PaymentMethod) (id uint64, err error)
+	UpdatePaymentMethod(ctx context.Context, data domain.PaymentMethod) (err error)
+	DeletePaymentMethod(ctx context.Context, id uint64, deletedAt int64) (err error)
+	GetPaymentMethods(ctx context.Context, activeOnly bool) (data []domain.PaymentMethod, err error)
+
+	AddOutboxMessage(ctx context.Context, data domain.OutboxMessage) (err error)
+	ClaimOutboxMessages(ctx context.Context, limit int, leaseUntil int64) (data []domain.OutboxMessage, err error)
+	MarkOutboxMessageSent(ctx context.Context, id int64) (err error)
+	RescheduleOutboxMessage(ctx context.Context, data domain.OutboxMessage) (err error)
+	CountPendingOutboxMessages(ctx context.Context) (count int64, err error)
+
+	AddPromotion(ctx context.Context, data domain.Promotion) (id int64, err error)
+	UpdatePromotion(ctx context.Context, data domain.Promotion) (err error)
+	DeletePromotion(ctx context.Context, id int64, deletedAt int64) (err error)
+	GetPromotions(ctx context.Context) (data []domain.Promotion, err error)
+	GetPromotionByID(ctx context.Context, id int64) (data domain.Promotion, err error)
+	GetPromotionByCode(ctx context.Context, code string) (data domain.Promotion, err error)
+	GetAutomaticPromotions(ctx context.Context, at int64) (data []domain.Promotion, err error)
+	LockPromotionByID(ctx context.Context, id int64) (data domain.Promotion, err error)
+	CountPromotionUsages(ctx context.Context, promotionID int64, customerID *int64) (count int64, err error)
+	AddOrderDiscounts(ctx context.Context, data []domain.OrderDiscount) (err error)
+	GetOrderDiscountsByOrderID(ctx context.Context, orderID int64) (data []domain.OrderDiscount, err error)
+
+	AddStoreTaxSetting(ctx context.Context, data domain.StoreTaxSetting) (id int64, err error)
+	UpdateStoreTaxSetting(ctx context.Context, data domain.StoreTaxSetting) (err error)
+	DeleteStoreTaxSetting(ctx context.Context, id int64, deletedAt int64) (err error)
+	GetStoreTaxSettings(ctx context.Context) (data []domain.StoreTaxSetting, err error)
+	GetStoreTaxSettingByStoreCode(ctx context.Context, storeCode string) (data domain.StoreTaxSetting, err error)
+	ReplaceTaxRates(ctx context.Context, storeTaxSettingID int64, data []domain.TaxRate) (err error)
+	GetTaxRatesByStoreTaxSettingID(ctx context.Context, storeTaxSettingID int64) (data []domain.TaxRate, err error)
+	UpsertProductTaxCategory(ctx context.Context, data domain.ProductTaxCategory) (err error)
+	DeleteProductTaxCategory(ctx context.Context, productID string) (err error)
+	GetProductTaxCategories(ctx context.Context) (data []domain.ProductTaxCategory, err error)
+	GetProductTaxCategoriesByProductIDs(ctx context.Context, productIDs []string) (data []domain.ProductTaxCategory, err error)
+	AddOrderCharges(ctx context.Context, data []domain.OrderCharge) (err error)
+	GetOrderChargesByOrderID(ctx context.Context, orderID int64) (data []domain.OrderCharge, err error)
+
+	UpsertReceiptTemplate(ctx context.Context, data domain.ReceiptTemplate) (err error)
+	DeleteReceiptTemplate(ctx context.Context, storeCode string) (err error)
+	GetReceiptTemplates(ctx context.Context) (data []domain.ReceiptTemplate, err error)
+	GetReceiptTemplateByStoreCode(ctx context.Context, storeCode string) (data domain.ReceiptTemplate, err error)
+
+	AddRefund(ctx context.Context, data domain.Refund) (id int64, err error)
+	AddRefundItems(ctx context.Context, data []domain.RefundItem) (err error)
+	GetRefundsByOrderID(ctx context.Context, orderID int64) (data []domain.Refund, err error)
+	GetRefundItemsByOrderID(ctx context.Context, orderID int64) (data []domain.RefundItem, err error)
+	GetRefundByRefundKey(ctx context.Context, refundKey string) (data domain.Refund, err error)
+	CompleteRefund(ctx context.Context, id int64) (completed bool, err error)
+	AddRefundPayments(ctx context.Context, data []domain.RefundPayment) (err error)
+	GetRefundPaymentsByRefundID(ctx context.Context, refundID int64) (data []domain.RefundPayment, err error)
+	CompleteRefundPayment(ctx context.Context, id int64, updatedAt int64) (completed bool, err error)
+
+	AddIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) (inserted bool, err error)
+	LockIdempotencyKey(ctx context.Context, key string) (data domain.IdempotencyKey, err error)
+	UpdateIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) (err error)
+	CompleteIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) (completed bool, err error)
+	ReleaseIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) (err error)
+	DeleteExpiredIdempotencyKeys(ctx context.Context, expiredBefore int64) (deleted int64, err error)
+
+	AddCashTransaction(ctx context.Context, data domain.CashTransaction) (err error)
+	GetCashierCashSummaries(ctx context.Context, from int64, to int64) (data []domain.CashierCashSummary, err error)
+
+	AddOrderStatusHistory(ctx context.Context, data domain.OrderStatusHistory) (err error)
+	GetOrderStatusHistoriesByOrderID(ctx context.Context, orderID int64) (data []domain.OrderStatusHistory, err error)
+
+	GetOrdersToReconcile(ctx context.Context, createdAfter int64, expiringBefore int64, limit int) (data []domain.Order, err error)
+	GetGatewayPaymentsCreatedBetween(ctx context.Context, from int64, to int64) (data []domain.OrderPayment, err error)
+	ReplacePaymentDiscrepancies(ctx context.Context, reportDate string, data []domain.PaymentDiscrepancy) (err error)
+	GetPaymentDiscrepancies(ctx context.Context, startDate string, endDate string) (data []domain.PaymentDiscrepancy, err error)
+
+	AddCustomer(ctx context.Context, data domain.Customer) (id int64, err error)
+	GetCustomerByID(ctx context.Context, id int64) (data domain.Customer, err error)
+	GetCustomerByContact(ctx context.Context, email *string, phone *string) (data domain.Customer, err error)
+	LockCustomerContact(ctx context.Context, contact string) (err error)
+
+	AddShift(ctx context.Context, data domain.Shift) (id int64, err error)
+	CloseShift(ctx context.Context, data domain.Shift) (updated bool, err error)
+	GetShiftByID(ctx context.Context, id int64) (data domain.Shift, err error)
+	LockShiftByID(ctx context.Context, id int64) (data domain.Shift, err error)
+	GetOpenShiftByCashierID(ctx context.Context, cashierID int64) (data domain.Shift, err error)
+	GetShifts(ctx context.Context, status string, cashierID int64, from int64, to int64) (data []domain.Shift, err error)
+	GetShiftCashSummary(ctx context.Context, shiftID int64) (data domain.ShiftCashSummary, err error)
+	GetShiftPaymentMethodSummaries(ctx context.Context, shiftID int64, statuses []string) (data []domain.PaymentMethodSalesSummary, err error)
+
+	GetSettlementSummaries(ctx context.Context, from int64, to int64, statuses []string) (data []domain.SettlementSummary, err error)
+	GetDailySalesSummaries(ctx context.Context, storeCode *string, from int64, to int64, statuses []string) (data []domain.SalesSummary, err error)
+	GetDailyRefundSummaries(ctx context.Context, storeCode *string, from int64, to int64) (data []domain.RefundSummary, err error)
+	GetHourlySalesSummaries(ctx context.Context, storeCode *string, from int64, to int64, statuses []string) (data []domain.HourlySalesSummary, err error)
+	GetPaymentMethodSalesSummaries(ctx context.Context, storeCode *string, from int64, to int64, statuses []string) (data []domain.PaymentMethodSalesSummary, err error)
+	GetOrderStatusSummaries(ctx context.Context, storeCode *string, from int64, to int64) (data []domain.OrderStatusSummary, err error)
+
+	AddPaymentNotification(ctx context.Context, data domain.PaymentNotification) (inserted bool, err error)
+
+	AddPaymentReview(ctx context.Context, data domain.PaymentReview) (id int64, err error)
+	GetPaymentReviews(ctx context.Context, status string) (data []domain.PaymentReview, err error)
+	GetPaymentReviewByID(ctx context.Context, id int64) (data domain.PaymentReview, err error)
+	ClaimPaymentReview(ctx context.Context, id int64) (claimed bool, err error)
+	ReleasePaymentReview(ctx context.Context, id int64) (err error)
+	ReleaseStalePaymentReviews(ctx context.Context, before int64) (released int64, err error)
+	ResolvePaymentReview(ctx context.Context, data domain.PaymentReview) (resolved bool, err error)
+	SettlePaymentReview(ctx context.Context, data domain.PaymentReview) (settled bool, err error)
+	OpenStalePaymentReviews(ctx context.Context, before int64, note string) (opened int64, err error)
+
+	AddOrderSaga(ctx context.Context, data domain.OrderSaga) (id int64, err error)
+	UpdateOrderSaga(ctx context.Context, data domain.OrderSaga) (err error)
+	AddOrderSagaStep(ctx context.Context, data domain.OrderSagaStep) (err error)
+	GetOrderSagaByTransactionNumber(ctx context.Context, transactionNumber string) (data domain.OrderSaga, err error)
+	GetOrderSagaSteps(ctx context.Context, sagaID int64) (data []domain.OrderSagaStep, err error)
+	GetStaleOrderSagas(ctx context.Context, updatedBefore int64, limit int) (data []domain.OrderSaga, err error)
+	ClaimOrderSaga(ctx context.Context, data domain.OrderSaga, claimedAt int64) (claimed bool, err error)
 }
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddCustomer(ctx context.Context, data domain.Customer) (id int64, err error) {
+	nstmt, err := r.tx.PrepareNamedContext(ctx, "INSERT INTO customers(name, email, phone, created_at, updated_at) VALUES (:name, :email, :phone, :created_at, :updated_at) returning id")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddCustomer").Msg("")
+		return
+	}
+
+	err = nstmt.GetContext(ctx, &id, data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddCustomer").Msg("")
+		return
+	}
+
+	return id, nil
+}
+
+func (r *OrderRepositoryImpl) GetCustomerByID(ctx context.Context, id int64) (data domain.Customer, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM customers WHERE id = $1", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetCustomerByID").Msg("")
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+// GetCustomerByContact finds the oldest customer with the email, in any case,
+// or the phone. Either may be nil.
+func (r *OrderRepositoryImpl) GetCustomerByContact(ctx context.Context, email *string, phone *string) (data domain.Customer, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM customers WHERE LOWER(email) = LOWER($1) OR phone = $2 ORDER BY id LIMIT 1", email, phone)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetCustomerByContact").Msg("")
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+// LockCustomerContact holds the advisory lock of an email or phone until the
+// surrounding transaction ends, so two orders cannot both add a customer with
+// it.
+func (r *OrderRepositoryImpl) LockCustomerContact(ctx context.Context, contact string) (err error) {
+	_, err = r.tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "customer:"+contact)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "LockCustomerContact").Msg("")
+		return
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+// AddIdempotencyKey leaves an existing key untouched, and reports whether the
+// key was inserted. A concurrent insert of the same key waits for the
+// transaction that inserted it first.
+func (r *OrderRepositoryImpl) AddIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) (inserted bool, err error) {
+	result, err := r.tx.NamedExecContext(ctx, "INSERT INTO idempotency_keys(key, request_hash, status, response, created_at, expires_at) VALUES (:key, :request_hash, :status, :response, :created_at, :expires_at) ON CONFLICT (key) DO NOTHING", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddIdempotencyKey").Msg("")
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddIdempotencyKey").Msg("")
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// LockIdempotencyKey holds a row lock on the key until the surrounding
+// transaction ends, so requests sharing a key run one after another.
+func (r *OrderRepositoryImpl) LockIdempotencyKey(ctx context.Context, key string) (data domain.IdempotencyKey, err error) {
+	err = sqlx.GetContext(ctx, r.tx, &data, "SELECT * FROM idempotency_keys WHERE key = $1 FOR UPDATE", key)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "LockIdempotencyKey").Msg("")
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) UpdateIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) (err error) {
+	_, err = sqlx.NamedExecContext(ctx, r.executor(), "UPDATE idempotency_keys SET request_hash = :request_hash, status = :status, response = :response, created_at = :created_at, expires_at = :expires_at WHERE key = :key", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "UpdateIdempotencyKey").Msg("")
+		return
+	}
+
+	return nil
+}
+
+// CompleteIdempotencyKey stores the response of the request holding the key.
+// It returns false when the key was taken over by a retry since, which is
+// told by the time the key was taken at.
+func (r *OrderRepositoryImpl) CompleteIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) (completed bool, err error) {
+	result, err := sqlx.NamedExecContext(ctx, r.executor(), "UPDATE idempotency_keys SET status = :status, response = :response, expires_at = :expires_at WHERE key = :key AND created_at = :created_at AND status = 'in_progress'", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CompleteIdempotencyKey").Msg("")
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CompleteIdempotencyKey").Msg("")
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// ReleaseIdempotencyKey deletes a key whose request did not go through, so the
+// request can be retried with it. A key taken over by a retry since is left
+// alone.
+func (r *OrderRepositoryImpl) ReleaseIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) (err error) {
+	_, err = r.executor().ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND created_at = $2 AND status = $3", data.Key, data.CreatedAt, domain.IdempotencyKeyStatusInProgress)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ReleaseIdempotencyKey").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) DeleteExpiredIdempotencyKeys(ctx context.Context, expiredBefore int64) (deleted int64, err error) {
+	result, err := r.executor().ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < $1", expiredBefore)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "DeleteExpiredIdempotencyKeys").Msg("")
+		return
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TryLockJob takes the Postgres advisory lock of job, on a connection of its
+// own, so that only one replica runs the job at a time. locked is false when
+// another replica holds it. unlock gives the lock and its connection back, a
+// connection that is lost gives the lock back by itself.
+func (r *OrderRepositoryImpl) TryLockJob(ctx context.Context, job string) (unlock func(), locked bool, err error) {
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "TryLockJob").Str("job", job).Msg("")
+		return nil, false, err
+	}
+
+	err = conn.GetContext(ctx, &locked, "SELECT pg_try_advisory_lock(hashtext($1))", job)
+	if err != nil || !locked {
+		conn.Close()
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "TryLockJob").Str("job", job).Msg("")
+		}
+		return nil, false, err
+	}
+
+	unlock = func() {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", job)
+		if err != nil {
+			log.Error().Err(err).Str("component", "TryLockJob").Str("job", job).Msg("Failed to release job lock")
+		}
+		conn.Close()
+	}
+
+	return unlock, true, nil
+}
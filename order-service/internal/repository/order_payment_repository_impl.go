@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddOrderPayment(ctx context.Context, data domain.OrderPayment) (id int64, err error) {
+	nstmt, err := r.tx.PrepareNamedContext(ctx, "INSERT INTO order_payments(order_id, payment_method_id, payment_gateway, transaction_number, amount, refunded_amount, mdr_fee, tendered_amount, change_amount, cashier_id, qr_code, va_number, status, expired_at, paid_at, created_at, updated_at) VALUES (:order_id, :payment_method_id, :payment_gateway, :transaction_number, :amount, :refunded_amount, :mdr_fee, :tendered_amount, :change_amount, :cashier_id, :qr_code, :va_number, :status, :expired_at, :paid_at, :created_at, :updated_at) returning id")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrderPayment").Msg("")
+		return
+	}
+
+	err = nstmt.GetContext(ctx, &id, data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrderPayment").Msg("")
+		return
+	}
+
+	return id, nil
+}
+
+func (r *OrderRepositoryImpl) GetOrderPaymentsByOrderID(ctx context.Context, orderID int64) (data []domain.OrderPayment, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM order_payments WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrderPaymentsByOrderID").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetOrderPaymentByTransactionNumber(ctx context.Context, transactionNumber string) (data domain.OrderPayment, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM order_payments WHERE transaction_number = $1", transactionNumber)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrderPaymentByTransactionNumber").Msg("")
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+// TransitionOrderPayment only moves the payment when its current status is
+// one of from. paid_at is only written when data carries one.
+func (r *OrderRepositoryImpl) TransitionOrderPayment(ctx context.Context, data domain.OrderPayment, from []string) (updated bool, err error) {
+	result, err := r.executor().ExecContext(ctx, "UPDATE order_payments SET status = $1, paid_at = COALESCE($2, paid_at), refunded_amount = $3, updated_at = $4 WHERE id = $5 AND status = ANY($6)", data.Status, data.PaidAt, data.RefundedAmount, data.UpdatedAt, data.ID, pq.Array(from))
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "TransitionOrderPayment").Msg("")
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "TransitionOrderPayment").Msg("")
+		return false, err
+	}
+
+	return affected == 1, nil
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddOrderStatusHistory(ctx context.Context, data domain.OrderStatusHistory) (err error) {
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO order_status_histories(order_id, from_status, to_status, source, actor_id, reason, created_at) VALUES (:order_id, :from_status, :to_status, :source, :actor_id, :reason, :created_at)", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrderStatusHistory").Msg("")
+		return
+	}
+
+	return nil
+}
+
+// GetOrderStatusHistoriesByOrderID lists the statuses the order went through,
+// oldest first.
+func (r *OrderRepositoryImpl) GetOrderStatusHistoriesByOrderID(ctx context.Context, orderID int64) (data []domain.OrderStatusHistory, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM order_status_histories WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrderStatusHistoriesByOrderID").Msg("")
+		return nil, err
+	}
+
+	return
+}
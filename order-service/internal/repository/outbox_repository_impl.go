@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddOutboxMessage(ctx context.Context, data domain.OutboxMessage) (err error) {
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO outbox_messages(topic, message_key, event_type, payload, status, attempts, next_attempt_at, created_at, updated_at) VALUES (:topic, :message_key, :event_type, :payload, :status, :attempts, :next_attempt_at, :created_at, :updated_at)", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOutboxMessage").Msg("")
+		return
+	}
+
+	return nil
+}
+
+// ClaimOutboxMessages leases the due messages until leaseUntil and returns
+// them in insertion order. Messages of a key whose oldest pending message is
+// leased or waiting for its next attempt are left out. The claim commits on
+// its own, so no lock is held while the messages are published, and messages
+// of a relay that died are due again once their lease runs out.
+func (r *OrderRepositoryImpl) ClaimOutboxMessages(ctx context.Context, limit int, leaseUntil int64) (data []domain.OutboxMessage, err error) {
+	now := time.Now().Unix()
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "UPDATE outbox_messages SET next_attempt_at = $3, updated_at = $2 WHERE id IN (SELECT m.id FROM outbox_messages m WHERE m.status = $1 AND m.next_attempt_at <= $2 AND NOT EXISTS (SELECT 1 FROM outbox_messages earlier WHERE earlier.status = $1 AND earlier.message_key = m.message_key AND earlier.id < m.id AND earlier.next_attempt_at > $2) ORDER BY m.id LIMIT $4 FOR UPDATE SKIP LOCKED) RETURNING *", domain.OutboxStatusPending, now, leaseUntil, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ClaimOutboxMessages").Msg("")
+		return nil, err
+	}
+
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].ID < data[j].ID
+	})
+
+	return
+}
+
+func (r *OrderRepositoryImpl) MarkOutboxMessageSent(ctx context.Context, id int64) (err error) {
+	now := time.Now().Unix()
+	_, err = r.executor().ExecContext(ctx, "UPDATE outbox_messages SET status = $1, sent_at = $2, updated_at = $2 WHERE id = $3", domain.OutboxStatusSent, now, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "MarkOutboxMessageSent").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) RescheduleOutboxMessage(ctx context.Context, data domain.OutboxMessage) (err error) {
+	_, err = sqlx.NamedExecContext(ctx, r.executor(), "UPDATE outbox_messages SET attempts = :attempts, last_error = :last_error, next_attempt_at = :next_attempt_at, updated_at = :updated_at WHERE id = :id", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "RescheduleOutboxMessage").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) CountPendingOutboxMessages(ctx context.Context) (count int64, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &count, "SELECT COUNT(*) FROM outbox_messages WHERE status = $1", domain.OutboxStatusPending)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CountPendingOutboxMessages").Msg("")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddPaymentMethod(ctx context.Context, data domain.PaymentMethod) (id uint64, err error) {
+	query, args, err := sqlx.Named("INSERT INTO payment_methods(name, channel, mdr, mdr_type, img_url, gateway, payment_type, is_active, created_at, updated_at) VALUES (:name, :channel, :mdr, :mdr_type, :img_url, :gateway, :payment_type, :is_active, :created_at, :updated_at) returning id", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddPaymentMethod").Msg("")
+		return
+	}
+
+	err = sqlx.GetContext(ctx, r.executor(), &id, r.executor().Rebind(query), args...)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddPaymentMethod").Msg("")
+		return
+	}
+
+	return id, nil
+}
+
+func (r *OrderRepositoryImpl) UpdatePaymentMethod(ctx context.Context, data domain.PaymentMethod) (err error) {
+	_, err = sqlx.NamedExecContext(ctx, r.executor(), "UPDATE payment_methods SET name = :name, channel = :channel, mdr = :mdr, mdr_type = :mdr_type, img_url = :img_url, gateway = :gateway, payment_type = :payment_type, is_active = :is_active, updated_at = :updated_at WHERE id = :id AND deleted_at IS NULL", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "UpdatePaymentMethod").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) DeletePaymentMethod(ctx context.Context, id uint64, deletedAt int64) (err error) {
+	_, err = r.executor().ExecContext(ctx, "UPDATE payment_methods SET deleted_at = $2, updated_at = $2 WHERE id = $1 AND deleted_at IS NULL", id, deletedAt)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "DeletePaymentMethod").Msg("")
+		return
+	}
+
+	return nil
+}
+
+// GetPaymentMethods lists the payment methods that are not deleted, leaving
+// out the disabled ones when activeOnly is set.
+func (r *OrderRepositoryImpl) GetPaymentMethods(ctx context.Context, activeOnly bool) (data []domain.PaymentMethod, err error) {
+	query := "SELECT * FROM payment_methods WHERE deleted_at IS NULL"
+	if activeOnly {
+		query += " AND is_active"
+	}
+	query += " ORDER BY name, id"
+
+	err = sqlx.SelectContext(ctx, r.executor(), &data, query)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetPaymentMethods").Msg("")
+		return nil, err
+	}
+
+	return
+}
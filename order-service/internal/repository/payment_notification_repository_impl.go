@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/rs/zerolog/log"
+)
+
+// AddPaymentNotification returns false without an error when the same
+// transaction status has already been recorded.
+func (r *OrderRepositoryImpl) AddPaymentNotification(ctx context.Context, data domain.PaymentNotification) (inserted bool, err error) {
+	nstmt, err := r.tx.PrepareNamedContext(ctx, "INSERT INTO payment_notifications(order_id, order_payment_id, transaction_id, transaction_status, fraud_status, status_code, gross_amount, payload, created_at) VALUES (:order_id, :order_payment_id, :transaction_id, :transaction_status, :fraud_status, :status_code, :gross_amount, :payload, :created_at) ON CONFLICT (transaction_id, transaction_status) DO NOTHING returning id")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddPaymentNotification").Msg("")
+		return
+	}
+
+	err = nstmt.GetContext(ctx, &data.ID, data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddPaymentNotification").Msg("")
+		return
+	}
+
+	return true, nil
+}
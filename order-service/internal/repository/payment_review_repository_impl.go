@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddPaymentReview(ctx context.Context, data domain.PaymentReview) (id int64, err error) {
+	query, args, err := sqlx.Named("INSERT INTO payment_reviews(order_id, reason, status, note, created_at, updated_at) VALUES (:order_id, :reason, :status, :note, :created_at, :updated_at) returning id", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddPaymentReview").Msg("")
+		return
+	}
+
+	err = sqlx.GetContext(ctx, r.executor(), &id, r.executor().Rebind(query), args...)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddPaymentReview").Msg("")
+		return
+	}
+
+	return id, nil
+}
+
+func (r *OrderRepositoryImpl) GetPaymentReviews(ctx context.Context, status string) (data []domain.PaymentReview, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM payment_reviews WHERE status = $1 ORDER BY id", status)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetPaymentReviews").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetPaymentReviewByID(ctx context.Context, id int64) (data domain.PaymentReview, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM payment_reviews WHERE id = $1", id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetPaymentReviewByID").Msg("")
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+// ClaimPaymentReview moves an open review to resolving, so that only one
+// reviewer acts on it. It returns false when the review is no longer open.
+func (r *OrderRepositoryImpl) ClaimPaymentReview(ctx context.Context, id int64) (claimed bool, err error) {
+	result, err := r.executor().ExecContext(ctx, "UPDATE payment_reviews SET status = 'resolving', updated_at = $1 WHERE id = $2 AND status = 'open'", time.Now().Unix(), id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ClaimPaymentReview").Msg("")
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ClaimPaymentReview").Msg("")
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// ReleasePaymentReview opens a review again that was claimed but could not be
+// resolved.
+func (r *OrderRepositoryImpl) ReleasePaymentReview(ctx context.Context, id int64) (err error) {
+	_, err = r.executor().ExecContext(ctx, "UPDATE payment_reviews SET status = 'open', updated_at = $1 WHERE id = $2 AND status = 'resolving'", time.Now().Unix(), id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ReleasePaymentReview").Msg("")
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseStalePaymentReviews opens the reviews claimed before before that
+// were neither resolved nor released, and returns how many were opened.
+func (r *OrderRepositoryImpl) ReleaseStalePaymentReviews(ctx context.Context, before int64) (released int64, err error) {
+	result, err := r.executor().ExecContext(ctx, "UPDATE payment_reviews SET status = 'open', updated_at = $1 WHERE status = 'resolving' AND updated_at < $2", time.Now().Unix(), before)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ReleaseStalePaymentReviews").Msg("")
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ResolvePaymentReview resolves a review claimed by ClaimPaymentReview. It
+// returns false when the claim has been lost.
+func (r *OrderRepositoryImpl) ResolvePaymentReview(ctx context.Context, data domain.PaymentReview) (resolved bool, err error) {
+	result, err := sqlx.NamedExecContext(ctx, r.executor(), "UPDATE payment_reviews SET status = :status, decision = :decision, note = :note, resolved_at = :resolved_at, updated_at = :updated_at WHERE id = :id AND status = 'resolving'", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ResolvePaymentReview").Msg("")
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ResolvePaymentReview").Msg("")
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// SettlePaymentReview moves a pending review to the status of data. It
+// returns false when the review is no longer pending.
+func (r *OrderRepositoryImpl) SettlePaymentReview(ctx context.Context, data domain.PaymentReview) (settled bool, err error) {
+	result, err := sqlx.NamedExecContext(ctx, r.executor(), "UPDATE payment_reviews SET status = :status, note = :note, resolved_at = :resolved_at, updated_at = :updated_at WHERE id = :id AND status = 'pending'", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "SettlePaymentReview").Msg("")
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "SettlePaymentReview").Msg("")
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// OpenStalePaymentReviews opens the reviews still pending since before, and
+// returns how many were opened.
+func (r *OrderRepositoryImpl) OpenStalePaymentReviews(ctx context.Context, before int64, note string) (opened int64, err error) {
+	result, err := r.executor().ExecContext(ctx, "UPDATE payment_reviews SET status = 'open', note = $1, updated_at = $2 WHERE status = 'pending' AND created_at < $3", note, time.Now().Unix(), before)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "OpenStalePaymentReviews").Msg("")
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddPromotion(ctx context.Context, data domain.Promotion) (id int64, err error) {
+	query, args, err := sqlx.Named("INSERT INTO promotions(name, code, type, value, product_id, buy_quantity, get_quantity, min_spend, max_discount, stackable, priority, usage_limit, usage_limit_per_customer, is_active, starts_at, ends_at, created_at, updated_at) VALUES (:name, :code, :type, :value, :product_id, :buy_quantity, :get_quantity, :min_spend, :max_discount, :stackable, :priority, :usage_limit, :usage_limit_per_customer, :is_active, :starts_at, :ends_at, :created_at, :updated_at) returning id", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddPromotion").Msg("")
+		return
+	}
+
+	err = sqlx.GetContext(ctx, r.executor(), &id, r.executor().Rebind(query), args...)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddPromotion").Msg("")
+		return
+	}
+
+	return id, nil
+}
+
+func (r *OrderRepositoryImpl) UpdatePromotion(ctx context.Context, data domain.Promotion) (err error) {
+	_, err = sqlx.NamedExecContext(ctx, r.executor(), "UPDATE promotions SET name = :name, code = :code, type = :type, value = :value, product_id = :product_id, buy_quantity = :buy_quantity, get_quantity = :get_quantity, min_spend = :min_spend, max_discount = :max_discount, stackable = :stackable, priority = :priority, usage_limit = :usage_limit, usage_limit_per_customer = :usage_limit_per_customer, is_active = :is_active, starts_at = :starts_at, ends_at = :ends_at, updated_at = :updated_at WHERE id = :id AND deleted_at IS NULL", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "UpdatePromotion").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) DeletePromotion(ctx context.Context, id int64, deletedAt int64) (err error) {
+	_, err = r.executor().ExecContext(ctx, "UPDATE promotions SET deleted_at = $2, updated_at = $2 WHERE id = $1 AND deleted_at IS NULL", id, deletedAt)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "DeletePromotion").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) GetPromotions(ctx context.Context) (data []domain.Promotion, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM promotions WHERE deleted_at IS NULL ORDER BY id DESC")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetPromotions").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetPromotionByID(ctx context.Context, id int64) (data domain.Promotion, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM promotions WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetPromotionByID").Msg("")
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetPromotionByCode(ctx context.Context, code string) (data domain.Promotion, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM promotions WHERE code = $1 AND deleted_at IS NULL", code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetPromotionByCode").Msg("")
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+// GetAutomaticPromotions returns the promotions without a code that are
+// running at the given time.
+func (r *OrderRepositoryImpl) GetAutomaticPromotions(ctx context.Context, at int64) (data []domain.Promotion, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM promotions WHERE code IS NULL AND is_active AND starts_at <= $1 AND ends_at > $1 AND deleted_at IS NULL ORDER BY priority DESC, id", at)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetAutomaticPromotions").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+// LockPromotionByID holds a row lock on the promotion until the surrounding
+// transaction ends, so its usage limits are checked one order at a time.
+func (r *OrderRepositoryImpl) LockPromotionByID(ctx context.Context, id int64) (data domain.Promotion, err error) {
+	err = sqlx.GetContext(ctx, r.tx, &data, "SELECT * FROM promotions WHERE id = $1 FOR UPDATE", id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "LockPromotionByID").Msg("")
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+// CountPromotionUsages counts the orders the promotion was applied to,
+// leaving out orders that were never paid. customerID narrows the count down
+// to the orders of one customer.
+func (r *OrderRepositoryImpl) CountPromotionUsages(ctx context.Context, promotionID int64, customerID *int64) (count int64, err error) {
+	query := "SELECT COUNT(DISTINCT od.order_id) FROM order_discounts od JOIN orders o ON o.id = od.order_id WHERE od.promotion_id = $1 AND o.payment_status NOT IN ('expired', 'cancelled', 'failed')"
+	args := []interface{}{promotionID}
+	if customerID != nil {
+		query += " AND o.customer_id = $2"
+		args = append(args, *customerID)
+	}
+
+	err = sqlx.GetContext(ctx, r.executor(), &count, query, args...)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CountPromotionUsages").Msg("")
+		return
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) AddOrderDiscounts(ctx context.Context, data []domain.OrderDiscount) (err error) {
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO order_discounts(order_id, order_detail_id, promotion_id, promotion_name, amount, created_at) VALUES (:order_id, :order_detail_id, :promotion_id, :promotion_name, :amount, :created_at)", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrderDiscounts").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) GetOrderDiscountsByOrderID(ctx context.Context, orderID int64) (data []domain.OrderDiscount, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM order_discounts WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrderDiscountsByOrderID").Msg("")
+		return nil, err
+	}
+
+	return
+}
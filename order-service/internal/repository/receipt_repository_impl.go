@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) UpsertReceiptTemplate(ctx context.Context, data domain.ReceiptTemplate) (err error) {
+	_, err = sqlx.NamedExecContext(ctx, r.executor(), "INSERT INTO receipt_templates(store_code, header, footer, logo, paper_width, created_at, updated_at) VALUES (:store_code, :header, :footer, :logo, :paper_width, :created_at, :updated_at) ON CONFLICT (store_code) DO UPDATE SET header = EXCLUDED.header, footer = EXCLUDED.footer, logo = EXCLUDED.logo, paper_width = EXCLUDED.paper_width, updated_at = EXCLUDED.updated_at", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "UpsertReceiptTemplate").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) DeleteReceiptTemplate(ctx context.Context, storeCode string) (err error) {
+	_, err = r.executor().ExecContext(ctx, "DELETE FROM receipt_templates WHERE store_code = $1", storeCode)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "DeleteReceiptTemplate").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) GetReceiptTemplates(ctx context.Context) (data []domain.ReceiptTemplate, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM receipt_templates ORDER BY store_code")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetReceiptTemplates").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetReceiptTemplateByStoreCode(ctx context.Context, storeCode string) (data domain.ReceiptTemplate, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM receipt_templates WHERE store_code = $1", storeCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetReceiptTemplateByStoreCode").Msg("")
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
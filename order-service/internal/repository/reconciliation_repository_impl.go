@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// GetOrdersToReconcile lists the orders created since createdAfter whose
+// payments may be out of step with their gateway: open orders expiring before
+// expiringBefore, open orders whose payments are all settled, and closed
+// orders with a gateway payment still pending. Cash payments, the only ones
+// with a cashier, are settled at the counter. Those expiring first come first.
+func (r *OrderRepositoryImpl) GetOrdersToReconcile(ctx context.Context, createdAfter int64, expiringBefore int64, limit int) (data []domain.Order, err error) {
+	openStatuses := pq.Array([]string{domain.OrderStatusPending, domain.OrderStatusPartiallyPaid})
+	query := "SELECT o.* FROM orders o WHERE o.deleted_at IS NULL AND o.created_at >= $1 AND ((o.payment_status = ANY($2) AND (o.expired_at < $3 OR NOT EXISTS (SELECT 1 FROM order_payments op WHERE op.order_id = o.id AND op.status <> $4))) OR (o.payment_status <> ALL($2) AND EXISTS (SELECT 1 FROM order_payments op WHERE op.order_id = o.id AND op.status = $5 AND op.cashier_id IS NULL))) ORDER BY o.expired_at, o.id LIMIT $6"
+
+	err = sqlx.SelectContext(ctx, r.executor(), &data, query, createdAfter, openStatuses, expiringBefore, domain.OrderStatusPaid, domain.OrderStatusPending, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrdersToReconcile").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+// GetGatewayPaymentsCreatedBetween lists the payments other than cash made
+// between from and to (exclusive).
+func (r *OrderRepositoryImpl) GetGatewayPaymentsCreatedBetween(ctx context.Context, from int64, to int64) (data []domain.OrderPayment, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM order_payments WHERE created_at >= $1 AND created_at < $2 AND cashier_id IS NULL ORDER BY id", from, to)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetGatewayPaymentsCreatedBetween").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+// ReplacePaymentDiscrepancies replaces the discrepancies found for the day,
+// so running the reconciliation of a day again leaves only its last result.
+func (r *OrderRepositoryImpl) ReplacePaymentDiscrepancies(ctx context.Context, reportDate string, data []domain.PaymentDiscrepancy) (err error) {
+	_, err = r.tx.ExecContext(ctx, "DELETE FROM payment_discrepancies WHERE report_date = $1", reportDate)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ReplacePaymentDiscrepancies").Msg("")
+		return
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO payment_discrepancies(report_date, order_id, order_payment_id, transaction_number, payment_gateway, local_status, gateway_status, local_amount, gateway_amount, reason, created_at) VALUES (:report_date, :order_id, :order_payment_id, :transaction_number, :payment_gateway, :local_status, :gateway_status, :local_amount, :gateway_amount, :reason, :created_at)", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ReplacePaymentDiscrepancies").Msg("")
+		return
+	}
+
+	return nil
+}
+
+// GetPaymentDiscrepancies lists the discrepancies reported for the days from
+// startDate to endDate, both YYYY-MM-DD and included.
+func (r *OrderRepositoryImpl) GetPaymentDiscrepancies(ctx context.Context, startDate string, endDate string) (data []domain.PaymentDiscrepancy, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM payment_discrepancies WHERE report_date >= $1 AND report_date <= $2 ORDER BY report_date, id", startDate, endDate)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetPaymentDiscrepancies").Msg("")
+		return nil, err
+	}
+
+	return
+}
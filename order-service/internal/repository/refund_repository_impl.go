@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddRefund(ctx context.Context, data domain.Refund) (id int64, err error) {
+	nstmt, err := r.tx.PrepareNamedContext(ctx, "INSERT INTO refunds(order_id, refund_key, request_hash, amount, reason, restock, refunded_by, status, created_at) VALUES (:order_id, :refund_key, :request_hash, :amount, :reason, :restock, :refunded_by, :status, :created_at) returning id")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddRefund").Msg("")
+		return
+	}
+
+	err = nstmt.GetContext(ctx, &id, data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddRefund").Msg("")
+		return
+	}
+
+	return id, nil
+}
+
+func (r *OrderRepositoryImpl) AddRefundItems(ctx context.Context, data []domain.RefundItem) (err error) {
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO refund_items(refund_id, order_detail_id, quantity, amount, created_at) VALUES (:refund_id, :order_detail_id, :quantity, :amount, :created_at)", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddRefundItems").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) GetRefundsByOrderID(ctx context.Context, orderID int64) (data []domain.Refund, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM refunds WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetRefundsByOrderID").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetRefundItemsByOrderID(ctx context.Context, orderID int64) (data []domain.RefundItem, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT ri.* FROM refund_items ri JOIN refunds r ON r.id = ri.refund_id WHERE r.order_id = $1 ORDER BY ri.id", orderID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetRefundItemsByOrderID").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetRefundByRefundKey(ctx context.Context, refundKey string) (data domain.Refund, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM refunds WHERE refund_key = $1", refundKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetRefundByRefundKey").Msg("")
+		return data, err
+	}
+
+	return data, nil
+}
+
+// CompleteRefund marks a pending refund as completed. It returns false when
+// the refund was completed already.
+func (r *OrderRepositoryImpl) CompleteRefund(ctx context.Context, id int64) (completed bool, err error) {
+	result, err := r.executor().ExecContext(ctx, "UPDATE refunds SET status = 'completed' WHERE id = $1 AND status = 'pending'", id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CompleteRefund").Msg("")
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CompleteRefund").Msg("")
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *OrderRepositoryImpl) AddRefundPayments(ctx context.Context, data []domain.RefundPayment) (err error) {
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO refund_payments(refund_id, order_payment_id, refund_key, amount, status, created_at, updated_at) VALUES (:refund_id, :order_payment_id, :refund_key, :amount, :status, :created_at, :updated_at)", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddRefundPayments").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) GetRefundPaymentsByRefundID(ctx context.Context, refundID int64) (data []domain.RefundPayment, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM refund_payments WHERE refund_id = $1 ORDER BY id", refundID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetRefundPaymentsByRefundID").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+// CompleteRefundPayment marks a pending refund payment as completed. It
+// returns false when it was completed already.
+func (r *OrderRepositoryImpl) CompleteRefundPayment(ctx context.Context, id int64, updatedAt int64) (completed bool, err error) {
+	result, err := r.executor().ExecContext(ctx, "UPDATE refund_payments SET status = 'completed', updated_at = $1 WHERE id = $2 AND status = 'pending'", updatedAt, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CompleteRefundPayment").Msg("")
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CompleteRefundPayment").Msg("")
+		return false, err
+	}
+
+	return affected == 1, nil
+}
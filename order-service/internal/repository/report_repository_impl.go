@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// GetSettlementSummaries sums up, per payment method, the payments settled
+// between from and to (exclusive) that are in one of statuses. An order paid
+// with several tenders counts towards every method it was paid with.
+func (r *OrderRepositoryImpl) GetSettlementSummaries(ctx context.Context, from int64, to int64, statuses []string) (data []domain.SettlementSummary, err error) {
+	query := "SELECT pm.id AS payment_method_id, pm.name AS payment_method_name, pm.mdr, pm.mdr_type, COUNT(DISTINCT op.order_id) AS order_count, COALESCE(SUM(op.amount), 0) AS gross_amount, COALESCE(SUM(op.refunded_amount), 0) AS refunded_amount, COALESCE(SUM(op.mdr_fee), 0) AS mdr_fee FROM order_payments op JOIN orders o ON o.id = op.order_id JOIN payment_methods pm ON pm.id = op.payment_method_id WHERE op.paid_at >= $1 AND op.paid_at < $2 AND op.status = ANY($3) AND o.deleted_at IS NULL GROUP BY pm.id, pm.name, pm.mdr, pm.mdr_type ORDER BY pm.name"
+
+	err = sqlx.SelectContext(ctx, r.executor(), &data, query, from, to, pq.Array(statuses))
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetSettlementSummaries").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+// GetDailySalesSummaries sums up, per day, the orders of the store paid
+// between from and to (exclusive) that are in one of statuses. A nil
+// storeCode covers every store.
+func (r *OrderRepositoryImpl) GetDailySalesSummaries(ctx context.Context, storeCode *string, from int64, to int64, statuses []string) (data []domain.SalesSummary, err error) {
+	query := "SELECT to_char(to_timestamp(o.paid_at) AT TIME ZONE 'Asia/Jakarta', 'YYYY-MM-DD') AS day, COUNT(*) AS order_count, COALESCE(SUM(od.item_count), 0) AS item_count, COALESCE(SUM(od.gross_amount), 0) AS gross_amount, COALESCE(SUM(o.discount_amount), 0) AS discount_amount, COALESCE(SUM(o.service_charge_amount), 0) AS service_charge_amount, COALESCE(SUM(o.tax_amount), 0) AS tax_amount, COALESCE(SUM(o.rounding_amount), 0) AS rounding_amount, COALESCE(SUM(o.amount), 0) AS amount FROM orders o LEFT JOIN (SELECT order_id, SUM(quantity) AS item_count, SUM(amount * quantity) AS gross_amount FROM order_details WHERE deleted_at IS NULL GROUP BY order_id) od ON od.order_id = o.id WHERE o.paid_at >= $1 AND o.paid_at < $2 AND o.payment_status = ANY($3) AND ($4::VARCHAR IS NULL OR o.store_code = $4) AND o.deleted_at IS NULL GROUP BY day ORDER BY day"
+
+	err = sqlx.SelectContext(ctx, r.executor(), &data, query, from, to, pq.Array(statuses), storeCode)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetDailySalesSummaries").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+// GetDailyRefundSummaries sums up, per day, the refunds given between from
+// and to (exclusive) on orders of the store, whenever the orders were paid.
+func (r *OrderRepositoryImpl) GetDailyRefundSummaries(ctx context.Context, storeCode *string, from int64, to int64) (data []domain.RefundSummary, err error) {
+	query := "SELECT to_char(to_timestamp(rf.created_at) AT TIME ZONE 'Asia/Jakarta', 'YYYY-MM-DD') AS day, COUNT(*) AS refund_count, COALESCE(SUM(rf.amount), 0) AS amount FROM refunds rf JOIN orders o ON o.id = rf.order_id WHERE rf.status = 'completed' AND rf.created_at >= $1 AND rf.created_at < $2 AND ($3::VARCHAR IS NULL OR o.store_code = $3) AND o.deleted_at IS NULL GROUP BY day ORDER BY day"
+
+	err = sqlx.SelectContext(ctx, r.executor(), &data, query, from, to, storeCode)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetDailyRefundSummaries").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+// GetHourlySalesSummaries sums up, per hour of the day, the orders of the
+// store paid between from and to (exclusive) that are in one of statuses.
+func (r *OrderRepositoryImpl) GetHourlySalesSummaries(ctx context.Context, storeCode *string, from int64, to int64, statuses []string) (data []domain.HourlySalesSummary, err error) {
+	query := "SELECT EXTRACT(HOUR FROM to_timestamp(o.paid_at) AT TIME ZONE 'Asia/Jakarta')::INT AS hour, COUNT(*) AS order_count, COALESCE(SUM(o.amount), 0) AS amount FROM orders o WHERE o.paid_at >= $1 AND o.paid_at < $2 AND o.payment_status = ANY($3) AND ($4::VARCHAR IS NULL OR o.store_code = $4) AND o.deleted_at IS NULL GROUP BY hour ORDER BY hour"
+
+	err = sqlx.SelectContext(ctx, r.executor(), &data, query, from, to, pq.Array(statuses), storeCode)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetHourlySalesSummaries").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+// GetPaymentMethodSalesSummaries sums up, per payment method, the tenders of
+// the orders of the store paid between from and to (exclusive) that are in
+// one of statuses.
+func (r *OrderRepositoryImpl) GetPaymentMethodSalesSummaries(ctx context.Context, storeCode *string, from int64, to int64, statuses []string) (data []domain.PaymentMethodSalesSummary, err error) {
+	query := "SELECT pm.id AS payment_method_id, pm.name AS payment_method_name, COUNT(DISTINCT op.order_id) AS order_count, COALESCE(SUM(op.amount), 0) AS amount FROM order_payments op JOIN orders o ON o.id = op.order_id JOIN payment_methods pm ON pm.id = op.payment_method_id WHERE o.paid_at >= $1 AND o.paid_at < $2 AND o.payment_status = ANY($3) AND ($4::VARCHAR IS NULL OR o.store_code = $4) AND o.deleted_at IS NULL GROUP BY pm.id, pm.name ORDER BY pm.name"
+
+	err = sqlx.SelectContext(ctx, r.executor(), &data, query, from, to, pq.Array(statuses), storeCode)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetPaymentMethodSalesSummaries").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+// GetOrderStatusSummaries sums up, per status, the orders of the store placed
+// between from and to (exclusive).
+func (r *OrderRepositoryImpl) GetOrderStatusSummaries(ctx context.Context, storeCode *string, from int64, to int64) (data []domain.OrderStatusSummary, err error) {
+	query := "SELECT o.payment_status AS status, COUNT(*) AS order_count, COALESCE(SUM(o.amount), 0) AS amount FROM orders o WHERE o.created_at >= $1 AND o.created_at < $2 AND ($3::VARCHAR IS NULL OR o.store_code = $3) AND o.deleted_at IS NULL GROUP BY o.payment_status ORDER BY o.payment_status"
+
+	err = sqlx.SelectContext(ctx, r.executor(), &data, query, from, to, storeCode)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrderStatusSummaries").Msg("")
+		return nil, err
+	}
+
+	return
+}
@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
 	pkgdto "github.com/alimikegami/point-of-sales/order-service/pkg/dto"
 	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog/log"
 )
 
@@ -22,8 +24,18 @@ func CreateOrderRepository(db *sqlx.DB) OrderRepository {
 	}
 }
 
+// executor returns the running transaction when the repository was handed out
+// by HandleTrx, and the connection pool otherwise.
+func (r *OrderRepositoryImpl) executor() sqlx.ExtContext {
+	if r.tx != nil {
+		return r.tx
+	}
+
+	return r.db
+}
+
 func (r *OrderRepositoryImpl) AddOrder(ctx context.Context, data domain.Order) (id int64, err error) {
-	nstmt, err := r.tx.PrepareNamedContext(ctx, "INSERT INTO orders(payment_method_id, amount, mdr_fee, transaction_number, payment_status, expired_at, created_at, updated_at) VALUES (:payment_method_id, :amount, :mdr_fee, :transaction_number, :payment_status, :expired_at, :created_at, :updated_at) returning id")
+	nstmt, err := r.tx.PrepareNamedContext(ctx, "INSERT INTO orders(payment_method_id, customer_id, cashier_id, shift_id, store_code, prices_include_tax, currency, subtotal, discount_amount, service_charge_amount, tax_amount, amount, rounding_amount, tendered_amount, change_amount, mdr_fee, paid_at, transaction_number, payment_status, payment_gateway, expired_at, created_at, updated_at) VALUES (:payment_method_id, :customer_id, :cashier_id, :shift_id, :store_code, :prices_include_tax, :currency, :subtotal, :discount_amount, :service_charge_amount, :tax_amount, :amount, :rounding_amount, :tendered_amount, :change_amount, :mdr_fee, :paid_at, :transaction_number, :payment_status, :payment_gateway, :expired_at, :created_at, :updated_at) returning id")
 	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("")
 		return
@@ -39,7 +51,7 @@ func (r *OrderRepositoryImpl) AddOrder(ctx context.Context, data domain.Order) (
 }
 
 func (r *OrderRepositoryImpl) AddOrderDetails(ctx context.Context, data []domain.OrderDetail) (err error) {
-	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO order_details(product_id, order_id, quantity, amount, product_name, created_at, updated_at) VALUES (:product_id, :order_id, :quantity, :amount, :product_name, :created_at, :updated_at)", data)
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO order_details(product_id, order_id, quantity, amount, discount_amount, service_charge_amount, tax_amount, total_amount, product_name, created_at, updated_at) VALUES (:product_id, :order_id, :quantity, :amount, :discount_amount, :service_charge_amount, :tax_amount, :total_amount, :product_name, :created_at, :updated_at)", data)
 	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrderDetails").Msg("")
 		return
@@ -49,7 +61,7 @@ func (r *OrderRepositoryImpl) AddOrderDetails(ctx context.Context, data []domain
 }
 
 func (r *OrderRepositoryImpl) GetOrderByTransactionNumber(ctx context.Context, transactionNumber string) (data domain.Order, err error) {
-	row := r.db.QueryRowxContext(ctx, "SELECT * FROM orders WHERE transaction_number = $1 AND deleted_at IS NULL", transactionNumber)
+	row := r.executor().QueryRowxContext(ctx, "SELECT * FROM orders WHERE transaction_number = $1 AND deleted_at IS NULL", transactionNumber)
 	err = row.StructScan(&data)
 	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Str("component", "GetUserByEmail").Msg("")
@@ -62,43 +74,143 @@ func (r *OrderRepositoryImpl) GetOrderByTransactionNumber(ctx context.Context, t
 	return
 }
 
-func (r *OrderRepositoryImpl) UpdateOrderPaymentStatus(ctx context.Context, data domain.Order) (err error) {
-	_, err = r.db.NamedExecContext(ctx, "UPDATE orders SET payment_status = :payment_status WHERE id=:id AND deleted_at IS NULL", data)
+// TransitionOrderPaymentStatus only moves the order when its current status is
+// one of from, so concurrent writers cannot overwrite each other's outcome.
+// paid_at is only written when data carries one.
+func (r *OrderRepositoryImpl) TransitionOrderPaymentStatus(ctx context.Context, data domain.Order, from []string) (updated bool, err error) {
+	result, err := r.executor().ExecContext(ctx, "UPDATE orders SET payment_status = $1, paid_at = COALESCE($2, paid_at), updated_at = $3 WHERE id = $4 AND payment_status = ANY($5) AND deleted_at IS NULL", data.PaymentStatus, data.PaidAt, data.UpdatedAt, data.ID, pq.Array(from))
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "TransitionOrderPaymentStatus").Msg("")
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "TransitionOrderPaymentStatus").Msg("")
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *OrderRepositoryImpl) UpdateOrderCancellation(ctx context.Context, data domain.Order) (err error) {
+	_, err = sqlx.NamedExecContext(ctx, r.executor(), "UPDATE orders SET cancelled_by = :cancelled_by, cancel_reason = :cancel_reason, cancelled_at = :cancelled_at WHERE id = :id AND deleted_at IS NULL", data)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Str("component", "UpdateOrderPaymentStatus").Msg("")
+		log.Ctx(ctx).Error().Err(err).Str("component", "UpdateOrderCancellation").Msg("")
 		return
 	}
 
 	return nil
 }
 
-func (r *OrderRepositoryImpl) GetOrders(ctx context.Context, filter pkgdto.Filter) (data []domain.Order, err error) {
-	query := "SELECT * FROM orders WHERE deleted_at IS NULL"
+// orderSortColumns are the columns orders can be listed by. Orders that are
+// not paid yet sort as if they were paid at 0.
+var orderSortColumns = map[string]string{
+	"id":         "orders.id",
+	"created_at": "orders.created_at",
+	"amount":     "orders.amount",
+	"paid_at":    "COALESCE(orders.paid_at, 0)",
+}
 
-	args := make(map[string]interface{})
+// orderFilterConditions turns filter into the conditions of the orders query,
+// along with their named arguments.
+func orderFilterConditions(filter pkgdto.Filter) (query string, args map[string]interface{}) {
+	query = " WHERE orders.deleted_at IS NULL"
+	args = make(map[string]interface{})
 
 	if filter.PaymentStatus != "" {
-		query += " AND payment_status = :payment_status"
+		query += " AND orders.payment_status = :payment_status"
 		args["payment_status"] = filter.PaymentStatus
 	}
 
 	if filter.Expired {
-		query += " AND expired_at < EXTRACT(EPOCH FROM NOW())"
+		query += " AND orders.expired_at < EXTRACT(EPOCH FROM NOW())"
+	}
+
+	if filter.CreatedFrom != 0 {
+		query += " AND orders.created_at >= :created_from"
+		args["created_from"] = filter.CreatedFrom
+	}
+
+	if filter.CreatedTo != 0 {
+		query += " AND orders.created_at < :created_to"
+		args["created_to"] = filter.CreatedTo
+	}
+
+	if filter.PaymentMethodID != 0 {
+		query += " AND EXISTS (SELECT 1 FROM order_payments WHERE order_payments.order_id = orders.id AND order_payments.payment_method_id = :payment_method_id)"
+		args["payment_method_id"] = filter.PaymentMethodID
+	}
+
+	if filter.CashierID != 0 {
+		query += " AND orders.cashier_id = :cashier_id"
+		args["cashier_id"] = filter.CashierID
+	}
+
+	if filter.TransactionNumber != "" {
+		query += " AND orders.transaction_number = :transaction_number"
+		args["transaction_number"] = filter.TransactionNumber
+	}
+
+	if filter.MinAmount != 0 {
+		query += " AND orders.amount >= :min_amount"
+		args["min_amount"] = filter.MinAmount
+	}
+
+	if filter.MaxAmount != 0 {
+		query += " AND orders.amount <= :max_amount"
+		args["max_amount"] = filter.MaxAmount
+	}
+
+	return
+}
+
+// GetOrders lists the orders matching filter, along with the name of the
+// payment method each was placed with. A filter with a cursor continues after
+// the order it points at, otherwise the orders are paged with an offset.
+func (r *OrderRepositoryImpl) GetOrders(ctx context.Context, filter pkgdto.Filter) (data []domain.Order, err error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = "id"
+	}
+
+	column, ok := orderSortColumns[sortBy]
+	if !ok {
+		return nil, errs.ErrClient
 	}
 
-	if filter.Limit != 0 && filter.Page != 0 {
-		offset := (filter.Page - 1) * filter.Limit
-		query += " LIMIT :limit OFFSET :offset"
+	direction, comparison := "DESC", "<"
+	if filter.SortOrder == "asc" {
+		direction, comparison = "ASC", ">"
+	}
+
+	conditions, args := orderFilterConditions(filter)
+	query := "SELECT orders.*, COALESCE(payment_methods.name, '') AS \"paymentmethod.name\" FROM orders LEFT JOIN payment_methods ON payment_methods.id = orders.payment_method_id" + conditions
+
+	if filter.After != nil {
+		query += " AND (" + column + ", orders.id) " + comparison + " (CAST(:cursor_value AS NUMERIC), :cursor_id)"
+		args["cursor_value"] = filter.After.Value
+		args["cursor_id"] = filter.After.ID
+	}
+
+	query += " ORDER BY " + column + " " + direction + ", orders.id " + direction
+
+	if filter.Limit != 0 {
+		query += " LIMIT :limit"
 		args["limit"] = filter.Limit
-		args["offset"] = offset
+
+		if filter.After == nil && filter.Page > 1 {
+			query += " OFFSET :offset"
+			args["offset"] = (filter.Page - 1) * filter.Limit
+		}
 	}
 
-	query += " ORDER BY id DESC"
 	nstmt, err := r.db.PrepareNamedContext(ctx, query)
 	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrders").Msg("")
 		return nil, err
 	}
+	defer nstmt.Close()
 
 	err = nstmt.SelectContext(ctx, &data, args)
 	if err != nil {
@@ -109,8 +221,29 @@ func (r *OrderRepositoryImpl) GetOrders(ctx context.Context, filter pkgdto.Filte
 	return
 }
 
+// CountOrders counts all the orders matching filter, whatever page is asked
+// for.
+func (r *OrderRepositoryImpl) CountOrders(ctx context.Context, filter pkgdto.Filter) (count int64, err error) {
+	conditions, args := orderFilterConditions(filter)
+
+	nstmt, err := r.db.PrepareNamedContext(ctx, "SELECT COUNT(*) FROM orders"+conditions)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CountOrders").Msg("")
+		return 0, err
+	}
+	defer nstmt.Close()
+
+	err = nstmt.GetContext(ctx, &count, args)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CountOrders").Msg("")
+		return 0, err
+	}
+
+	return
+}
+
 func (r *OrderRepositoryImpl) GetOrderByOrderID(ctx context.Context, id int64) (data domain.Order, err error) {
-	row := r.db.QueryRowxContext(ctx, "SELECT * FROM orders WHERE id = $1 AND deleted_at IS NULL", id)
+	row := r.executor().QueryRowxContext(ctx, "SELECT * FROM orders WHERE id = $1 AND deleted_at IS NULL", id)
 
 	err = row.StructScan(&data)
 	if err != nil {
@@ -124,18 +257,23 @@ func (r *OrderRepositoryImpl) GetOrderByOrderID(ctx context.Context, id int64) (
 	return
 }
 
-func (r *OrderRepositoryImpl) GetOrderDetailsByOrderID(ctx context.Context, id int64) (data []domain.OrderDetail, err error) {
-	query := "SELECT * FROM order_details WHERE order_id = :order_id"
-	args := make(map[string]interface{})
-	args["order_id"] = id
-
-	nstmt, err := r.db.PrepareNamedContext(ctx, query)
+// LockOrderByOrderID reads the order and holds a row lock on it until the
+// surrounding transaction ends.
+func (r *OrderRepositoryImpl) LockOrderByOrderID(ctx context.Context, id int64) (data domain.Order, err error) {
+	err = sqlx.GetContext(ctx, r.tx, &data, "SELECT * FROM orders WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrderDetailsByOrderID").Msg("")
-		return nil, err
+		log.Ctx(ctx).Error().Err(err).Str("component", "LockOrderByOrderID").Msg("")
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		return data, errs.ErrInternalServer
 	}
 
-	err = nstmt.SelectContext(ctx, &data, args)
+	return
+}
+
+func (r *OrderRepositoryImpl) GetOrderDetailsByOrderID(ctx context.Context, id int64) (data []domain.OrderDetail, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM order_details WHERE order_id = $1 ORDER BY id", id)
 	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrderDetailsByOrderID").Msg("")
 		return nil, err
@@ -144,22 +282,22 @@ func (r *OrderRepositoryImpl) GetOrderDetailsByOrderID(ctx context.Context, id i
 	return
 }
 
+// GetPaymentMethodByID returns the payment method even when it has been
+// deleted, since orders keep referring to it.
 func (r *OrderRepositoryImpl) GetPaymentMethodByID(ctx context.Context, id uint64) (data domain.PaymentMethod, err error) {
-	row := r.db.QueryRowxContext(ctx, "SELECT * FROM payment_methods WHERE id = $1 AND deleted_at IS NULL", id)
-
-	err = row.StructScan(&data)
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM payment_methods WHERE id = $1", id)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Str("component", "GetPaymentMethodByID").Msg("")
-		if err == sql.ErrNoRows {
-			return data, nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
 		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetPaymentMethodByID").Msg("")
 		return data, errs.ErrInternalServer
 	}
 
 	return
 }
 
-func (r *OrderRepositoryImpl) HandleTrx(ctx context.Context, fn func(ctx context.Context, repo OrderRepository) error) error {
+func (r *OrderRepositoryImpl) HandleTrx(ctx context.Context, fn func(ctx context.Context, repo OrderRepository) error) (err error) {
 	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return err
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddOrderSaga(ctx context.Context, data domain.OrderSaga) (id int64, err error) {
+	nstmt, err := r.tx.PrepareNamedContext(ctx, "INSERT INTO order_sagas(transaction_number, status, current_step, state, created_at, updated_at) VALUES (:transaction_number, :status, :current_step, :state, :created_at, :updated_at) returning id")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrderSaga").Msg("")
+		return
+	}
+
+	err = nstmt.GetContext(ctx, &id, data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrderSaga").Msg("")
+		return
+	}
+
+	return id, nil
+}
+
+func (r *OrderRepositoryImpl) UpdateOrderSaga(ctx context.Context, data domain.OrderSaga) (err error) {
+	_, err = r.tx.NamedExecContext(ctx, "UPDATE order_sagas SET order_id = :order_id, status = :status, current_step = :current_step, state = :state, last_error = :last_error, updated_at = :updated_at WHERE id = :id", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "UpdateOrderSaga").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) AddOrderSagaStep(ctx context.Context, data domain.OrderSagaStep) (err error) {
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO order_saga_steps(saga_id, step, action, status, error, created_at) VALUES (:saga_id, :step, :action, :status, :error, :created_at)", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrderSagaStep").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) GetOrderSagaByTransactionNumber(ctx context.Context, transactionNumber string) (data domain.OrderSaga, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM order_sagas WHERE transaction_number = $1", transactionNumber)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrderSagaByTransactionNumber").Msg("")
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetOrderSagaSteps(ctx context.Context, sagaID int64) (data []domain.OrderSagaStep, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM order_saga_steps WHERE saga_id = $1 ORDER BY id", sagaID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrderSagaSteps").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetStaleOrderSagas(ctx context.Context, updatedBefore int64, limit int) (data []domain.OrderSaga, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM order_sagas WHERE status IN ($1, $2) AND updated_at < $3 ORDER BY id LIMIT $4", domain.SagaStatusRunning, domain.SagaStatusCompensating, updatedBefore, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetStaleOrderSagas").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+// ClaimOrderSaga bumps updated_at only if nobody touched the saga since it was
+// read, so a single replica wins the right to resume it.
+func (r *OrderRepositoryImpl) ClaimOrderSaga(ctx context.Context, data domain.OrderSaga, claimedAt int64) (claimed bool, err error) {
+	result, err := r.executor().ExecContext(ctx, "UPDATE order_sagas SET updated_at = $1 WHERE id = $2 AND updated_at = $3", claimedAt, data.ID, data.UpdatedAt)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ClaimOrderSaga").Msg("")
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ClaimOrderSaga").Msg("")
+		return false, err
+	}
+
+	return affected == 1, nil
+}
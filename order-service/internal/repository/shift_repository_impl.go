@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// AddShift opens a shift. It returns a zero id when the cashier or the
+// terminal already has a shift open.
+func (r *OrderRepositoryImpl) AddShift(ctx context.Context, data domain.Shift) (id int64, err error) {
+	nstmt, err := r.tx.PrepareNamedContext(ctx, "INSERT INTO shifts(cashier_id, store_code, terminal_code, opening_float, status, opened_at, updated_at) VALUES (:cashier_id, :store_code, :terminal_code, :opening_float, :status, :opened_at, :updated_at) ON CONFLICT DO NOTHING returning id")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddShift").Msg("")
+		return
+	}
+
+	err = nstmt.GetContext(ctx, &id, data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddShift").Msg("")
+		return
+	}
+
+	return id, nil
+}
+
+// CloseShift records the count of a shift that is still open.
+func (r *OrderRepositoryImpl) CloseShift(ctx context.Context, data domain.Shift) (updated bool, err error) {
+	res, err := r.executor().ExecContext(ctx, "UPDATE shifts SET status = $2, expected_cash = $3, counted_cash = $4, variance = $5, close_note = $6, closed_at = $7, updated_at = $7 WHERE id = $1 AND status = $8", data.ID, domain.ShiftStatusClosed, data.ExpectedCash, data.CountedCash, data.Variance, data.CloseNote, data.ClosedAt, domain.ShiftStatusOpen)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CloseShift").Msg("")
+		return
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "CloseShift").Msg("")
+		return
+	}
+
+	return affected == 1, nil
+}
+
+func (r *OrderRepositoryImpl) GetShiftByID(ctx context.Context, id int64) (data domain.Shift, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM shifts WHERE id = $1", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetShiftByID").Msg("")
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+// LockShiftByID reads the shift and holds a row lock on it until the
+// surrounding transaction ends.
+func (r *OrderRepositoryImpl) LockShiftByID(ctx context.Context, id int64) (data domain.Shift, err error) {
+	err = sqlx.GetContext(ctx, r.tx, &data, "SELECT * FROM shifts WHERE id = $1 FOR UPDATE", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "LockShiftByID").Msg("")
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetOpenShiftByCashierID(ctx context.Context, cashierID int64) (data domain.Shift, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM shifts WHERE cashier_id = $1 AND status = $2", cashierID, domain.ShiftStatusOpen)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetOpenShiftByCashierID").Msg("")
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+// GetShifts lists the shifts opened between from and to (exclusive), latest
+// first. An empty status or a zero cashierID matches every shift.
+func (r *OrderRepositoryImpl) GetShifts(ctx context.Context, status string, cashierID int64, from int64, to int64) (data []domain.Shift, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM shifts WHERE opened_at >= $1 AND opened_at < $2 AND ($3 = '' OR status = $3) AND ($4 = 0 OR cashier_id = $4) ORDER BY opened_at DESC, id DESC", from, to, status, cashierID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetShifts").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetShiftCashSummary(ctx context.Context, shiftID int64) (data domain.ShiftCashSummary, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT COUNT(*) FILTER (WHERE type = 'sale') AS sale_count, COALESCE(SUM(amount) FILTER (WHERE type = 'sale'), 0) AS cash_sales, COUNT(*) FILTER (WHERE type = 'refund') AS refund_count, COALESCE(-SUM(amount) FILTER (WHERE type = 'refund'), 0) AS cash_refunds, COUNT(*) FILTER (WHERE type = 'cash_in') AS cash_in_count, COALESCE(SUM(amount) FILTER (WHERE type = 'cash_in'), 0) AS cash_in, COUNT(*) FILTER (WHERE type = 'cash_out') AS cash_out_count, COALESCE(-SUM(amount) FILTER (WHERE type = 'cash_out'), 0) AS cash_out FROM cash_transactions WHERE shift_id = $1", shiftID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetShiftCashSummary").Msg("")
+		return data, err
+	}
+
+	return
+}
+
+// GetShiftPaymentMethodSummaries sums up, per payment method, the tenders of
+// the orders placed during the shift that are in one of statuses.
+func (r *OrderRepositoryImpl) GetShiftPaymentMethodSummaries(ctx context.Context, shiftID int64, statuses []string) (data []domain.PaymentMethodSalesSummary, err error) {
+	query := "SELECT pm.id AS payment_method_id, pm.name AS payment_method_name, COUNT(DISTINCT op.order_id) AS order_count, COALESCE(SUM(op.amount), 0) AS amount FROM order_payments op JOIN orders o ON o.id = op.order_id JOIN payment_methods pm ON pm.id = op.payment_method_id WHERE o.shift_id = $1 AND o.payment_status = ANY($2) AND o.deleted_at IS NULL GROUP BY pm.id, pm.name ORDER BY pm.name"
+
+	err = sqlx.SelectContext(ctx, r.executor(), &data, query, shiftID, pq.Array(statuses))
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetShiftPaymentMethodSummaries").Msg("")
+		return nil, err
+	}
+
+	return
+}
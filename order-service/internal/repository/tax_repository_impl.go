@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+func (r *OrderRepositoryImpl) AddStoreTaxSetting(ctx context.Context, data domain.StoreTaxSetting) (id int64, err error) {
+	nstmt, err := r.tx.PrepareNamedContext(ctx, "INSERT INTO store_tax_settings(store_code, prices_include_tax, service_charge_rate, service_charge_taxable, rounding_mode, rounding_increment, cash_rounding_increment, created_at, updated_at) VALUES (:store_code, :prices_include_tax, :service_charge_rate, :service_charge_taxable, :rounding_mode, :rounding_increment, :cash_rounding_increment, :created_at, :updated_at) returning id")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddStoreTaxSetting").Msg("")
+		return
+	}
+
+	err = nstmt.GetContext(ctx, &id, data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddStoreTaxSetting").Msg("")
+		return
+	}
+
+	return id, nil
+}
+
+func (r *OrderRepositoryImpl) UpdateStoreTaxSetting(ctx context.Context, data domain.StoreTaxSetting) (err error) {
+	_, err = sqlx.NamedExecContext(ctx, r.executor(), "UPDATE store_tax_settings SET prices_include_tax = :prices_include_tax, service_charge_rate = :service_charge_rate, service_charge_taxable = :service_charge_taxable, rounding_mode = :rounding_mode, rounding_increment = :rounding_increment, cash_rounding_increment = :cash_rounding_increment, updated_at = :updated_at WHERE id = :id AND deleted_at IS NULL", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "UpdateStoreTaxSetting").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) DeleteStoreTaxSetting(ctx context.Context, id int64, deletedAt int64) (err error) {
+	_, err = r.executor().ExecContext(ctx, "UPDATE store_tax_settings SET deleted_at = $2, updated_at = $2 WHERE id = $1 AND deleted_at IS NULL", id, deletedAt)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "DeleteStoreTaxSetting").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) GetStoreTaxSettings(ctx context.Context) (data []domain.StoreTaxSetting, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM store_tax_settings WHERE deleted_at IS NULL ORDER BY store_code")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetStoreTaxSettings").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetStoreTaxSettingByStoreCode(ctx context.Context, storeCode string) (data domain.StoreTaxSetting, err error) {
+	err = sqlx.GetContext(ctx, r.executor(), &data, "SELECT * FROM store_tax_settings WHERE store_code = $1 AND deleted_at IS NULL", storeCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data, errs.ErrNotFound
+		}
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetStoreTaxSettingByStoreCode").Msg("")
+		return data, errs.ErrInternalServer
+	}
+
+	return
+}
+
+// ReplaceTaxRates swaps the tax rates of a store for the given ones.
+func (r *OrderRepositoryImpl) ReplaceTaxRates(ctx context.Context, storeTaxSettingID int64, data []domain.TaxRate) (err error) {
+	_, err = r.tx.ExecContext(ctx, "DELETE FROM tax_rates WHERE store_tax_setting_id = $1", storeTaxSettingID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ReplaceTaxRates").Msg("")
+		return
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO tax_rates(store_tax_setting_id, name, category, rate, created_at) VALUES (:store_tax_setting_id, :name, :category, :rate, :created_at)", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ReplaceTaxRates").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) GetTaxRatesByStoreTaxSettingID(ctx context.Context, storeTaxSettingID int64) (data []domain.TaxRate, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM tax_rates WHERE store_tax_setting_id = $1 ORDER BY id", storeTaxSettingID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetTaxRatesByStoreTaxSettingID").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) UpsertProductTaxCategory(ctx context.Context, data domain.ProductTaxCategory) (err error) {
+	_, err = sqlx.NamedExecContext(ctx, r.executor(), "INSERT INTO product_tax_categories(product_id, category, created_at, updated_at) VALUES (:product_id, :category, :created_at, :updated_at) ON CONFLICT (product_id) DO UPDATE SET category = EXCLUDED.category, updated_at = EXCLUDED.updated_at", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "UpsertProductTaxCategory").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) DeleteProductTaxCategory(ctx context.Context, productID string) (err error) {
+	_, err = r.executor().ExecContext(ctx, "DELETE FROM product_tax_categories WHERE product_id = $1", productID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "DeleteProductTaxCategory").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) GetProductTaxCategories(ctx context.Context) (data []domain.ProductTaxCategory, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM product_tax_categories ORDER BY category, product_id")
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetProductTaxCategories").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) GetProductTaxCategoriesByProductIDs(ctx context.Context, productIDs []string) (data []domain.ProductTaxCategory, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM product_tax_categories WHERE product_id = ANY($1)", pq.Array(productIDs))
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetProductTaxCategoriesByProductIDs").Msg("")
+		return nil, err
+	}
+
+	return
+}
+
+func (r *OrderRepositoryImpl) AddOrderCharges(ctx context.Context, data []domain.OrderCharge) (err error) {
+	_, err = r.tx.NamedExecContext(ctx, "INSERT INTO order_charges(order_id, type, name, rate, base_amount, amount, included_amount, created_at) VALUES (:order_id, :type, :name, :rate, :base_amount, :amount, :included_amount, :created_at)", data)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrderCharges").Msg("")
+		return
+	}
+
+	return nil
+}
+
+func (r *OrderRepositoryImpl) GetOrderChargesByOrderID(ctx context.Context, orderID int64) (data []domain.OrderCharge, err error) {
+	err = sqlx.SelectContext(ctx, r.executor(), &data, "SELECT * FROM order_charges WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "GetOrderChargesByOrderID").Msg("")
+		return nil, err
+	}
+
+	return
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+)
+
+// CancelOrder voids an order that is not paid in full at the gateways before
+// its payments expire, refunds the tenders already settled on it and gives
+// its items back to the product service.
+func (s *OrderServiceImpl) CancelOrder(ctx context.Context, id int64, req dto.CancelOrderRequest) (err error) {
+	if strings.TrimSpace(req.Reason) == "" {
+		return errs.ErrClient
+	}
+
+	order, err := s.repository.GetOrderByOrderID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	if order.ID == 0 {
+		return errs.ErrNotFound
+	}
+
+	switch order.PaymentStatus {
+	case domain.OrderStatusPaid, domain.OrderStatusPartiallyRefunded, domain.OrderStatusRefunded:
+		return errs.ErrOrderAlreadyPaid
+	}
+
+	if !domain.CanTransitionOrderStatus(order.PaymentStatus, domain.OrderStatusCancelled) {
+		return errs.ErrOrderNotCancellable
+	}
+
+	payments, err := s.repository.GetOrderPaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return
+	}
+
+	// The open tenders are voided first, so they cannot settle after the order
+	// is cancelled.
+	err = s.voidOrderPayments(ctx, payments)
+	if err != nil {
+		return
+	}
+
+	cancelledBy := int64(req.CancelledBy)
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		updated, err := s.transitionOrder(ctx, repo, order, domain.OrderStatusCancelled, nil, domain.OrderStatusChange{
+			Source:  domain.OrderStatusSourceAPI,
+			ActorID: &cancelledBy,
+			Reason:  statusReason(req.Reason),
+		})
+		if err != nil {
+			return err
+		}
+
+		// The payment settled or expired while the gateway was being called.
+		if !updated {
+			return errs.ErrOrderNotCancellable
+		}
+
+		cancelledAt := time.Now().Unix()
+		order.CancelledBy = &cancelledBy
+		order.CancelReason = &req.Reason
+		order.CancelledAt = &cancelledAt
+
+		return repo.UpdateOrderCancellation(ctx, order)
+	})
+	if err != nil {
+		return
+	}
+
+	// The tenders of a partially paid order that already settled are given
+	// back.
+	return s.releaseOrderPaymentsOrReview(ctx, order, "cancelled", req.Reason)
+}
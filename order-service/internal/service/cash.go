@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	paymentgateway "github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/payment-gateway"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	"github.com/rs/zerolog/log"
+)
+
+// applyCashRounding rounds the total of an order paid in cash to the cash
+// increment of the store. The rounding is taken off the total first, so
+// running it again on a resumed saga gives the same result.
+func applyCashRounding(state *orderSagaState) {
+	total := money.New(state.TotalAmount.Amount-state.RoundingAmount.Amount, state.currency())
+	rounded := roundCash(total, state.CashRoundingIncrement, domain.RoundingModeHalfUp)
+	state.RoundingAmount = money.New(rounded.Amount-total.Amount, total.Currency)
+	state.TotalAmount = rounded
+}
+
+// tenderCash works out the change of a cash tender from what the customer
+// handed over.
+func tenderCash(payment *orderSagaPayment) error {
+	if payment.TenderedAmount == nil {
+		return errs.ErrClient
+	}
+
+	if payment.TenderedAmount.Amount < payment.Amount.Amount {
+		return errs.ErrInsufficientTender
+	}
+
+	change := money.New(payment.TenderedAmount.Amount-payment.Amount.Amount, payment.Amount.Currency)
+	payment.ChangeAmount = &change
+
+	return nil
+}
+
+// roundCash rounds amount to what can be paid with the notes and coins in
+// circulation, e.g. Rp100 or Rp500. A zero increment only rounds to whole
+// rupiah.
+func roundCash(amount money.Money, increment float64, mode string) money.Money {
+	if increment < 1 {
+		increment = 1
+	}
+
+	rounded := roundCharge(float64(amount.Amount), amount.Currency, domain.StoreTaxSetting{RoundingMode: mode, RoundingIncrement: increment})
+	return money.New(rounded, amount.Currency)
+}
+
+// cashRoundingIncrement is the cash increment of the store, stores without
+// tax settings have none.
+func (s *OrderServiceImpl) cashRoundingIncrement(ctx context.Context, repo repository.OrderRepository, storeCode *string) (float64, error) {
+	if storeCode == nil {
+		return 0, nil
+	}
+
+	setting, err := repo.GetStoreTaxSettingByStoreCode(ctx, *storeCode)
+	if errors.Is(err, errs.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return setting.CashRoundingIncrement, nil
+}
+
+// recordCashSale puts the cash of a cash tender in the cashier's drawer. Only
+// the tender amount stays in the drawer, the change goes straight back to the
+// customer.
+func (s *OrderServiceImpl) recordCashSale(ctx context.Context, repo repository.OrderRepository, saga *orderSaga, payment domain.OrderPayment) error {
+	storeCode := saga.state.StoreCode
+	err := repo.AddCashTransaction(ctx, domain.CashTransaction{
+		OrderID:        &payment.OrderID,
+		OrderPaymentID: &payment.ID,
+		ShiftID:        saga.state.ShiftID,
+		CashierID:      *payment.CashierID,
+		StoreCode:      &storeCode,
+		Type:           domain.CashTransactionTypeSale,
+		Amount:         payment.Amount,
+		CreatedAt:      payment.CreatedAt,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "recordCashSale").Str("transaction_number", saga.TransactionNumber).Msg("")
+		return err
+	}
+
+	return nil
+}
+
+// recordCashRefund takes the cash handed back to the customer out of the
+// drawer of cashierID. refundID is nil when the cash is given back because
+// the order could not go through. The cash is taken out of the shift the
+// cashier has open, if any.
+func (s *OrderServiceImpl) recordCashRefund(ctx context.Context, repo repository.OrderRepository, order domain.Order, payment domain.OrderPayment, refundID *int64, cashierID int64, amount money.Money, createdAt int64) error {
+	shiftID, err := s.openShiftID(ctx, repo, cashierID)
+	if err != nil {
+		return err
+	}
+
+	err = repo.AddCashTransaction(ctx, domain.CashTransaction{
+		OrderID:        &order.ID,
+		OrderPaymentID: &payment.ID,
+		RefundID:       refundID,
+		ShiftID:        shiftID,
+		CashierID:      cashierID,
+		StoreCode:      order.StoreCode,
+		Type:           domain.CashTransactionTypeRefund,
+		Amount:         money.New(-amount.Amount, amount.Currency),
+		CreatedAt:      createdAt,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "recordCashRefund").Str("transaction_number", order.TransactionNumber).Msg("")
+		return err
+	}
+
+	return nil
+}
+
+// paidInCash reports whether every payment of an order was made in cash at
+// the counter.
+func paidInCash(payments []domain.OrderPayment) bool {
+	for _, payment := range payments {
+		if payment.PaymentGateway != paymentgateway.GatewayCash {
+			return false
+		}
+	}
+
+	return len(payments) > 0
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+func TestRoundCash(t *testing.T) {
+	type TestCase struct {
+		Name      string
+		Amount    money.Money
+		Increment float64
+		Mode      string
+		Expected  money.Money
+	}
+
+	testCases := []TestCase{
+		{Name: "Nearest Rp100 below", Amount: rupiah(12345), Increment: 100, Mode: domain.RoundingModeHalfUp, Expected: rupiah(12300)},
+		{Name: "Halfway to Rp100 rounds up", Amount: rupiah(12350), Increment: 100, Mode: domain.RoundingModeHalfUp, Expected: rupiah(12400)},
+		{Name: "Already payable", Amount: rupiah(12500), Increment: 500, Mode: domain.RoundingModeHalfUp, Expected: rupiah(12500)},
+		{Name: "Down to Rp500", Amount: rupiah(12499), Increment: 500, Mode: domain.RoundingModeDown, Expected: rupiah(12000)},
+		{Name: "No increment rounds to whole rupiah", Amount: money.New(1234550, money.IDR), Increment: 0, Mode: domain.RoundingModeHalfUp, Expected: rupiah(12346)},
+		{Name: "Whole rupiah down", Amount: money.New(1234599, money.IDR), Increment: 1, Mode: domain.RoundingModeDown, Expected: rupiah(12345)},
+		{Name: "Fraction of a rupiah increment", Amount: money.New(1234550, money.IDR), Increment: 0.5, Mode: domain.RoundingModeHalfUp, Expected: rupiah(12346)},
+		{Name: "Currency without minor units", Amount: money.New(1234, "JPY"), Increment: 10, Mode: domain.RoundingModeHalfUp, Expected: money.New(1230, "JPY")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if rounded := roundCash(tc.Amount, tc.Increment, tc.Mode); rounded != tc.Expected {
+				t.Errorf("expected %s, got %s", tc.Expected, rounded)
+			}
+		})
+	}
+}
+
+func TestApplyCashRounding(t *testing.T) {
+	type TestCase struct {
+		Name             string
+		Total            money.Money
+		Increment        float64
+		ExpectedTotal    money.Money
+		ExpectedRounding money.Money
+	}
+
+	testCases := []TestCase{
+		{Name: "Rounded down", Total: rupiah(12345), Increment: 100, ExpectedTotal: rupiah(12300), ExpectedRounding: rupiah(-45)},
+		{Name: "Rounded up", Total: rupiah(12375), Increment: 100, ExpectedTotal: rupiah(12400), ExpectedRounding: rupiah(25)},
+		{Name: "Nothing to round", Total: rupiah(12000), Increment: 500, ExpectedTotal: rupiah(12000), ExpectedRounding: rupiah(0)},
+		{Name: "No increment", Total: money.New(1234560, money.IDR), Increment: 0, ExpectedTotal: rupiah(12346), ExpectedRounding: money.New(40, money.IDR)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			state := orderSagaState{Currency: money.IDR, TotalAmount: tc.Total, CashRoundingIncrement: tc.Increment}
+
+			// A resumed saga rounds the order again, which must not move it.
+			for range 2 {
+				applyCashRounding(&state)
+
+				if state.TotalAmount != tc.ExpectedTotal {
+					t.Errorf("expected total %s, got %s", tc.ExpectedTotal, state.TotalAmount)
+				}
+
+				if state.RoundingAmount != tc.ExpectedRounding {
+					t.Errorf("expected rounding %s, got %s", tc.ExpectedRounding, state.RoundingAmount)
+				}
+			}
+		})
+	}
+}
+
+func TestTenderCash(t *testing.T) {
+	tendered := func(amount int64) *money.Money {
+		m := rupiah(amount)
+		return &m
+	}
+
+	type TestCase struct {
+		Name           string
+		Payment        orderSagaPayment
+		ExpectedChange *money.Money
+		ExpectedErr    error
+	}
+
+	testCases := []TestCase{
+		{
+			Name:           "Change given",
+			Payment:        orderSagaPayment{Amount: rupiah(12300), TenderedAmount: tendered(20000)},
+			ExpectedChange: tendered(7700),
+		},
+		{
+			Name:           "Exact tender",
+			Payment:        orderSagaPayment{Amount: rupiah(12300), TenderedAmount: tendered(12300)},
+			ExpectedChange: tendered(0),
+		},
+		{
+			Name:        "Tender short of the amount",
+			Payment:     orderSagaPayment{Amount: rupiah(12300), TenderedAmount: tendered(12000)},
+			ExpectedErr: errs.ErrInsufficientTender,
+		},
+		{
+			Name:        "Tender missing",
+			Payment:     orderSagaPayment{Amount: rupiah(12300)},
+			ExpectedErr: errs.ErrClient,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			payment := tc.Payment
+			err := tenderCash(&payment)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if tc.ExpectedChange == nil {
+				if payment.ChangeAmount != nil {
+					t.Errorf("expected no change, got %s", payment.ChangeAmount)
+				}
+				return
+			}
+
+			if payment.ChangeAmount == nil || *payment.ChangeAmount != *tc.ExpectedChange {
+				t.Errorf("expected change %s, got %v", tc.ExpectedChange, payment.ChangeAmount)
+			}
+		})
+	}
+}
@@ -10,7 +10,53 @@ import (
 type OrderService interface {
 	AddOrder(ctx context.Context, req dto.OrderRequest) (response dto.OrderResponse, err error)
 	MidtransPaymentWebhook(ctx context.Context, req dto.PaymentNotification) (err error)
-	GetOrders(ctx context.Context, filter pkgdto.Filter) (response pkgdto.Pagination, err error)
+	GetOrders(ctx context.Context, filter pkgdto.Filter) (response pkgdto.PaginationResponse, err error)
 	RestoreExpiredPaymentItemStocks()
+	PurgeExpiredIdempotencyKeys()
+	RelayOutboxMessages()
+	ResumeOrderSagas()
+	GetOrderSaga(ctx context.Context, id string) (response dto.OrderSagaResponse, err error)
+	GetPaymentReviews(ctx context.Context) (response []dto.PaymentReviewResponse, err error)
+	ResolvePaymentReview(ctx context.Context, id int64, req dto.PaymentReviewDecisionRequest) (err error)
 	GetOrderDetails(ctx context.Context, id int64) (response dto.OrderDetails, err error)
+	GetOrderStatusHistory(ctx context.Context, id int64) (response []dto.OrderStatusHistoryResponse, err error)
+	CancelOrder(ctx context.Context, id int64, req dto.CancelOrderRequest) (err error)
+	AddPromotion(ctx context.Context, req dto.PromotionRequest) (response dto.PromotionResponse, err error)
+	GetPromotions(ctx context.Context) (response []dto.PromotionResponse, err error)
+	GetPromotion(ctx context.Context, id int64) (response dto.PromotionResponse, err error)
+	UpdatePromotion(ctx context.Context, id int64, req dto.PromotionRequest) (response dto.PromotionResponse, err error)
+	DeletePromotion(ctx context.Context, id int64) (err error)
+	GetStoreTaxSettings(ctx context.Context) (response []dto.StoreTaxSettingResponse, err error)
+	GetStoreTaxSetting(ctx context.Context, storeCode string) (response dto.StoreTaxSettingResponse, err error)
+	SaveStoreTaxSetting(ctx context.Context, storeCode string, req dto.StoreTaxSettingRequest) (response dto.StoreTaxSettingResponse, err error)
+	DeleteStoreTaxSetting(ctx context.Context, storeCode string) (err error)
+	GetProductTaxCategories(ctx context.Context) (response []dto.ProductTaxCategoryResponse, err error)
+	SaveProductTaxCategory(ctx context.Context, productID string, req dto.ProductTaxCategoryRequest) (response dto.ProductTaxCategoryResponse, err error)
+	DeleteProductTaxCategory(ctx context.Context, productID string) (err error)
+	GetReceiptTemplates(ctx context.Context) (response []dto.ReceiptTemplateResponse, err error)
+	GetReceiptTemplate(ctx context.Context, storeCode string) (response dto.ReceiptTemplateResponse, err error)
+	SaveReceiptTemplate(ctx context.Context, storeCode string, req dto.ReceiptTemplateRequest) (response dto.ReceiptTemplateResponse, err error)
+	DeleteReceiptTemplate(ctx context.Context, storeCode string) (err error)
+	GetOrderReceipt(ctx context.Context, id int64, format string) (response dto.OrderReceipt, err error)
+	AddPaymentMethod(ctx context.Context, req dto.PaymentMethodRequest) (response dto.PaymentMethodResponse, err error)
+	GetPaymentMethods(ctx context.Context) (response []dto.PaymentMethodResponse, err error)
+	GetActivePaymentMethods(ctx context.Context) (response []dto.PublicPaymentMethodResponse, err error)
+	GetPaymentMethod(ctx context.Context, id uint64) (response dto.PaymentMethodResponse, err error)
+	UpdatePaymentMethod(ctx context.Context, id uint64, req dto.PaymentMethodRequest) (response dto.PaymentMethodResponse, err error)
+	SetPaymentMethodActive(ctx context.Context, id uint64, active bool) (response dto.PaymentMethodResponse, err error)
+	DeletePaymentMethod(ctx context.Context, id uint64) (err error)
+	GetSettlementReport(ctx context.Context, req dto.ReportRequest) (response dto.SettlementReportResponse, err error)
+	GetCashReport(ctx context.Context, req dto.ReportRequest) (response dto.CashReportResponse, err error)
+	GetSalesReport(ctx context.Context, req dto.SalesReportRequest) (response dto.SalesReportResponse, err error)
+	GetZReport(ctx context.Context, req dto.SalesReportRequest) (response dto.ZReportResponse, err error)
+	GetPaymentDiscrepancyReport(ctx context.Context, req dto.ReportRequest) (response dto.PaymentDiscrepancyReportResponse, err error)
+	ReconcilePayments()
+	ReportPaymentDiscrepancies()
+	RefundOrder(ctx context.Context, id int64, req dto.RefundOrderRequest) (response dto.RefundResponse, err error)
+	OpenShift(ctx context.Context, req dto.OpenShiftRequest) (response dto.ShiftReportResponse, err error)
+	GetCurrentShift(ctx context.Context, cashierID uint64) (response dto.ShiftReportResponse, err error)
+	GetShift(ctx context.Context, id int64) (response dto.ShiftReportResponse, err error)
+	GetShifts(ctx context.Context, filter dto.ShiftFilter) (response []dto.ShiftResponse, err error)
+	AddShiftCashMovement(ctx context.Context, id int64, req dto.CashMovementRequest) (response dto.ShiftReportResponse, err error)
+	CloseShift(ctx context.Context, id int64, req dto.CloseShiftRequest) (response dto.ShiftReportResponse, err error)
 }
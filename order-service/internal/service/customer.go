@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	paymentgateway "github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/payment-gateway"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+)
+
+const (
+	maxCustomerNameLength  = 255
+	maxCustomerPhoneLength = 50
+)
+
+// orderSagaCustomer is who the order is charged for.
+type orderSagaCustomer struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+// orderCustomer resolves who the order is placed for, without writing
+// anything. A customer id is placed for as stored, whatever details come with
+// it. Details alone are matched against the customers by email or phone, and
+// when none matches the customer is only added with the order, by
+// addOrderCustomer. Orders for no one have no customer.
+func (s *OrderServiceImpl) orderCustomer(ctx context.Context, req dto.OrderRequest) (customerID *uint64, customer *orderSagaCustomer, err error) {
+	if req.CustomerID == nil && req.Customer == nil {
+		return nil, nil, nil
+	}
+
+	var details domain.Customer
+	if req.CustomerID != nil {
+		details, err = s.repository.GetCustomerByID(ctx, int64(*req.CustomerID))
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, nil, errs.ErrUnknownCustomer
+		}
+		if err != nil {
+			return
+		}
+	} else {
+		details, err = customerFromRequest(*req.Customer)
+		if err != nil {
+			return
+		}
+
+		if details.Email != nil || details.Phone != nil {
+			existing, err := s.repository.GetCustomerByContact(ctx, details.Email, details.Phone)
+			switch {
+			case err == nil:
+				details = existing
+			case !errors.Is(err, errs.ErrNotFound):
+				return nil, nil, err
+			}
+		}
+	}
+
+	customer = &orderSagaCustomer{Name: details.Name}
+	if details.Email != nil {
+		customer.Email = *details.Email
+	}
+	if details.Phone != nil {
+		customer.Phone = *details.Phone
+	}
+
+	if details.ID == 0 {
+		return nil, customer, nil
+	}
+
+	id := uint64(details.ID)
+	return &id, customer, nil
+}
+
+// addOrderCustomer gives the order the customer its details belong to, adding
+// the customer when nobody has their email or phone yet. The contacts stay
+// locked until the order is recorded, so concurrent orders add them once.
+func (s *OrderServiceImpl) addOrderCustomer(ctx context.Context, repo repository.OrderRepository, saga *orderSaga) error {
+	if saga.state.CustomerID != nil || saga.state.Customer == nil {
+		return nil
+	}
+
+	details := domain.Customer{Name: saga.state.Customer.Name}
+	var contacts []string
+	if saga.state.Customer.Email != "" {
+		email := saga.state.Customer.Email
+		details.Email = &email
+		contacts = append(contacts, strings.ToLower(email))
+	}
+	if saga.state.Customer.Phone != "" {
+		phone := saga.state.Customer.Phone
+		details.Phone = &phone
+		contacts = append(contacts, phone)
+	}
+
+	// Locking in a fixed order keeps concurrent orders from deadlocking.
+	sort.Strings(contacts)
+	for _, contact := range contacts {
+		err := repo.LockCustomerContact(ctx, contact)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(contacts) > 0 {
+		existing, err := repo.GetCustomerByContact(ctx, details.Email, details.Phone)
+		if err == nil {
+			id := uint64(existing.ID)
+			saga.state.CustomerID = &id
+			return nil
+		}
+		if !errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+	}
+
+	now := time.Now().Unix()
+	details.CreatedAt = now
+	details.UpdatedAt = now
+	id, err := repo.AddCustomer(ctx, details)
+	if err != nil {
+		return err
+	}
+
+	customerID := uint64(id)
+	saga.state.CustomerID = &customerID
+	return nil
+}
+
+func customerFromRequest(req dto.OrderCustomerRequest) (customer domain.Customer, err error) {
+	customer.Name = strings.TrimSpace(req.Name)
+	if customer.Name == "" || len(customer.Name) > maxCustomerNameLength {
+		return customer, errs.ErrClient
+	}
+
+	if req.Email != nil && strings.TrimSpace(*req.Email) != "" {
+		address, err := mail.ParseAddress(strings.TrimSpace(*req.Email))
+		if err != nil || address.Name != "" {
+			return customer, errs.ErrClient
+		}
+		customer.Email = &address.Address
+	}
+
+	if req.Phone != nil && strings.TrimSpace(*req.Phone) != "" {
+		phone := strings.TrimSpace(*req.Phone)
+		if len(phone) > maxCustomerPhoneLength || strings.Trim(phone, "+0123456789 -") != "" {
+			return customer, errs.ErrClient
+		}
+		customer.Phone = &phone
+	}
+
+	return customer, nil
+}
+
+// chargeCustomer is the customer as the gateway takes them, the first word of
+// the name being the first name.
+func (customer *orderSagaCustomer) chargeCustomer() paymentgateway.Customer {
+	if customer == nil {
+		return paymentgateway.Customer{}
+	}
+
+	firstName, lastName, _ := strings.Cut(customer.Name, " ")
+
+	return paymentgateway.Customer{
+		FirstName: firstName,
+		LastName:  strings.TrimSpace(lastName),
+		Email:     customer.Email,
+		Phone:     customer.Phone,
+	}
+}
+
+func orderCustomerResponse(customer domain.Customer) *dto.OrderCustomerResponse {
+	return &dto.OrderCustomerResponse{
+		ID:    customer.ID,
+		Name:  customer.Name,
+		Email: customer.Email,
+		Phone: customer.Phone,
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/rs/zerolog/log"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// addOrderIdempotently places the order once per Idempotency-Key. The key is
+// committed as in progress before the order is placed, outside of any
+// transaction, and its response is stored once the order is. A retry gets a
+// conflict while the first request is in progress and its response replayed
+// after. A failed request leaves no key behind, so it can be retried with the
+// same key. The key of a request cut off by a crash is only held for
+// InProgressTTL, after which a retry takes it over, while a stored response is
+// replayed for KeyTTL.
+func (s *OrderServiceImpl) addOrderIdempotently(ctx context.Context, req dto.OrderRequest) (orderResponse dto.OrderResponse, err error) {
+	if len(req.IdempotencyKey) > maxIdempotencyKeyLength {
+		return orderResponse, errs.ErrClient
+	}
+
+	requestHash, err := hashOrderRequest(req)
+	if err != nil {
+		return
+	}
+
+	var record domain.IdempotencyKey
+	replay := false
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		now := time.Now()
+		fresh := domain.IdempotencyKey{
+			Key:         req.IdempotencyKey,
+			RequestHash: requestHash,
+			Status:      domain.IdempotencyKeyStatusInProgress,
+			CreatedAt:   now.Unix(),
+			ExpiresAt:   now.Add(s.config.IdempotencyConfig.InProgressTTL).Unix(),
+		}
+
+		inserted, err := repo.AddIdempotencyKey(ctx, fresh)
+		if err != nil {
+			return err
+		}
+
+		if inserted {
+			record = fresh
+			return nil
+		}
+
+		record, err = repo.LockIdempotencyKey(ctx, req.IdempotencyKey)
+		if err != nil {
+			return err
+		}
+
+		// An expired key is free to be used again, for any request, and so is
+		// the key of a request that did not finish in time.
+		if record.ExpiresAt <= now.Unix() {
+			record = fresh
+			return repo.UpdateIdempotencyKey(ctx, record)
+		}
+
+		if record.RequestHash != requestHash {
+			return errs.ErrIdempotencyKeyReused
+		}
+
+		if record.Status != domain.IdempotencyKeyStatusCompleted {
+			return errs.ErrIdempotencyKeyInProgress
+		}
+
+		replay = true
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	if replay {
+		log.Ctx(ctx).Info().Str("component", "addOrderIdempotently").Str("idempotency_key", req.IdempotencyKey).Msg("Replaying order response")
+		err = json.Unmarshal([]byte(*record.Response), &orderResponse)
+		return
+	}
+
+	orderResponse, err = s.placeOrder(ctx, req)
+	if err != nil {
+		releaseErr := s.repository.ReleaseIdempotencyKey(ctx, record)
+		if releaseErr != nil {
+			log.Ctx(ctx).Error().Err(releaseErr).Str("component", "addOrderIdempotently").Str("idempotency_key", req.IdempotencyKey).Msg("Failed to release idempotency key")
+		}
+		return
+	}
+
+	response, err := json.Marshal(orderResponse)
+	if err != nil {
+		return
+	}
+
+	responseString := string(response)
+	record.Response = &responseString
+	record.Status = domain.IdempotencyKeyStatusCompleted
+	record.ExpiresAt = time.Now().Add(s.config.IdempotencyConfig.KeyTTL).Unix()
+
+	// The order is placed either way, so it is returned even if its response
+	// could not be stored. Retries then get a conflict until the key can be
+	// taken over.
+	completed, storeErr := s.repository.CompleteIdempotencyKey(ctx, record)
+	if storeErr != nil {
+		log.Ctx(ctx).Error().Err(storeErr).Str("component", "addOrderIdempotently").Str("idempotency_key", req.IdempotencyKey).Msg("Failed to store order response")
+	} else if !completed {
+		log.Ctx(ctx).Warn().Str("component", "addOrderIdempotently").Str("idempotency_key", req.IdempotencyKey).Msg("Idempotency key was taken over before the order was placed")
+	}
+
+	return orderResponse, nil
+}
+
+// hashOrderRequest fingerprints the parts of the request that make up the
+// order, to tell a retry from a different order sent with the same key.
+func hashOrderRequest(req dto.OrderRequest) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *OrderServiceImpl) PurgeExpiredIdempotencyKeys() {
+	ctx := context.Background()
+
+	deleted, err := s.repository.DeleteExpiredIdempotencyKeys(ctx, time.Now().Unix())
+	if err != nil {
+		log.Error().Err(err).Str("component", "PurgeExpiredIdempotencyKeys").Msg("")
+		return
+	}
+
+	if deleted > 0 {
+		log.Info().Str("component", "PurgeExpiredIdempotencyKeys").Int64("deleted", deleted).Msg("")
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/config"
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+)
+
+// idempotencyRepository stores the keys in memory. Placing an order fails at
+// its payment method, which is all the tests need to tell whether one was
+// placed.
+type idempotencyRepository struct {
+	repository.OrderRepository
+	keys   map[string]domain.IdempotencyKey
+	placed int
+}
+
+func (r *idempotencyRepository) HandleTrx(ctx context.Context, fn func(ctx context.Context, repo repository.OrderRepository) error) error {
+	return fn(ctx, r)
+}
+
+func (r *idempotencyRepository) AddIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) (bool, error) {
+	if _, ok := r.keys[data.Key]; ok {
+		return false, nil
+	}
+
+	r.keys[data.Key] = data
+	return true, nil
+}
+
+func (r *idempotencyRepository) LockIdempotencyKey(ctx context.Context, key string) (domain.IdempotencyKey, error) {
+	return r.keys[key], nil
+}
+
+func (r *idempotencyRepository) UpdateIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) error {
+	r.keys[data.Key] = data
+	return nil
+}
+
+func (r *idempotencyRepository) ReleaseIdempotencyKey(ctx context.Context, data domain.IdempotencyKey) error {
+	if key, ok := r.keys[data.Key]; ok && key.CreatedAt == data.CreatedAt && key.Status == domain.IdempotencyKeyStatusInProgress {
+		delete(r.keys, data.Key)
+	}
+
+	return nil
+}
+
+func (r *idempotencyRepository) GetPaymentMethodByID(ctx context.Context, id uint64) (domain.PaymentMethod, error) {
+	r.placed++
+	return domain.PaymentMethod{}, errs.ErrNotFound
+}
+
+func TestAddOrderIdempotently(t *testing.T) {
+	req := dto.OrderRequest{PaymentMethodID: 1, IdempotencyKey: "key"}
+	requestHash, err := hashOrderRequest(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	response := `{"id":42}`
+	now := time.Now()
+
+	type TestCase struct {
+		Name    string
+		Key     *domain.IdempotencyKey
+		Request dto.OrderRequest
+		// ExpectedPlaced is whether the order is placed, which fails and
+		// leaves no key behind.
+		ExpectedPlaced bool
+		ExpectedID     int64
+		ExpectedErr    error
+	}
+
+	testCases := []TestCase{
+		{
+			Name:           "New key",
+			Request:        req,
+			ExpectedPlaced: true,
+			ExpectedErr:    errs.ErrPaymentMethodUnavailable,
+		},
+		{
+			Name:       "Response replayed",
+			Key:        &domain.IdempotencyKey{Key: "key", RequestHash: requestHash, Status: domain.IdempotencyKeyStatusCompleted, Response: &response, CreatedAt: now.Add(-time.Hour).Unix(), ExpiresAt: now.Add(time.Hour).Unix()},
+			Request:    req,
+			ExpectedID: 42,
+		},
+		{
+			Name:        "Key reused for another order",
+			Key:         &domain.IdempotencyKey{Key: "key", RequestHash: requestHash, Status: domain.IdempotencyKeyStatusCompleted, Response: &response, CreatedAt: now.Add(-time.Hour).Unix(), ExpiresAt: now.Add(time.Hour).Unix()},
+			Request:     dto.OrderRequest{PaymentMethodID: 2, IdempotencyKey: "key"},
+			ExpectedErr: errs.ErrIdempotencyKeyReused,
+		},
+		{
+			Name:        "First request still in progress",
+			Key:         &domain.IdempotencyKey{Key: "key", RequestHash: requestHash, Status: domain.IdempotencyKeyStatusInProgress, CreatedAt: now.Add(-time.Minute).Unix(), ExpiresAt: now.Add(4 * time.Minute).Unix()},
+			Request:     req,
+			ExpectedErr: errs.ErrIdempotencyKeyInProgress,
+		},
+		{
+			Name:           "Key of a request cut off taken over",
+			Key:            &domain.IdempotencyKey{Key: "key", RequestHash: requestHash, Status: domain.IdempotencyKeyStatusInProgress, CreatedAt: now.Add(-10 * time.Minute).Unix(), ExpiresAt: now.Add(-5 * time.Minute).Unix()},
+			Request:        req,
+			ExpectedPlaced: true,
+			ExpectedErr:    errs.ErrPaymentMethodUnavailable,
+		},
+		{
+			Name:           "Expired key used for another order",
+			Key:            &domain.IdempotencyKey{Key: "key", RequestHash: requestHash, Status: domain.IdempotencyKeyStatusCompleted, Response: &response, CreatedAt: now.Add(-25 * time.Hour).Unix(), ExpiresAt: now.Add(-time.Hour).Unix()},
+			Request:        dto.OrderRequest{PaymentMethodID: 2, IdempotencyKey: "key"},
+			ExpectedPlaced: true,
+			ExpectedErr:    errs.ErrPaymentMethodUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			repo := &idempotencyRepository{keys: map[string]domain.IdempotencyKey{}}
+			if tc.Key != nil {
+				repo.keys[tc.Key.Key] = *tc.Key
+			}
+
+			s := &OrderServiceImpl{
+				repository: repo,
+				config: &config.Config{IdempotencyConfig: config.IdempotencyConfig{
+					KeyTTL:        24 * time.Hour,
+					InProgressTTL: 5 * time.Minute,
+				}},
+			}
+
+			response, err := s.addOrderIdempotently(context.Background(), tc.Request)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if response.ID != tc.ExpectedID {
+				t.Errorf("expected order %d, got %d", tc.ExpectedID, response.ID)
+			}
+
+			if placed := repo.placed > 0; placed != tc.ExpectedPlaced {
+				t.Errorf("expected placed %t, got %t", tc.ExpectedPlaced, placed)
+			}
+
+			// A placement that fails gives the key back, anything else leaves
+			// the key as it was.
+			key, ok := repo.keys["key"]
+			if tc.ExpectedPlaced {
+				if ok {
+					t.Errorf("expected the key to be released, got %+v", key)
+				}
+				return
+			}
+
+			if !ok || key != *tc.Key {
+				t.Errorf("expected the key to be left as %+v, got %+v", *tc.Key, key)
+			}
+		})
+	}
+}
+
+// A key is only held for InProgressTTL while its order is placed, not for
+// KeyTTL.
+func TestAddOrderIdempotentlyHoldsKeyForInProgressTTL(t *testing.T) {
+	var held domain.IdempotencyKey
+	s := &OrderServiceImpl{
+		repository: &heldKeyRepository{
+			idempotencyRepository: &idempotencyRepository{keys: map[string]domain.IdempotencyKey{}},
+			held:                  &held,
+		},
+		config: &config.Config{IdempotencyConfig: config.IdempotencyConfig{
+			KeyTTL:        24 * time.Hour,
+			InProgressTTL: 5 * time.Minute,
+		}},
+	}
+
+	start := time.Now()
+	_, err := s.addOrderIdempotently(context.Background(), dto.OrderRequest{PaymentMethodID: 1, IdempotencyKey: "key"})
+	if !errors.Is(err, errs.ErrPaymentMethodUnavailable) {
+		t.Fatalf("expected error %v, got %v", errs.ErrPaymentMethodUnavailable, err)
+	}
+
+	if held.Status != domain.IdempotencyKeyStatusInProgress {
+		t.Fatalf("expected the key to be held in progress, got %+v", held)
+	}
+
+	if lease := time.Duration(held.ExpiresAt-start.Unix()) * time.Second; lease < 4*time.Minute || lease > 6*time.Minute {
+		t.Errorf("expected the key to be held for about 5m, got %s", lease)
+	}
+}
+
+// heldKeyRepository records the key as it is while the order is placed.
+type heldKeyRepository struct {
+	*idempotencyRepository
+	held *domain.IdempotencyKey
+}
+
+func (r *heldKeyRepository) HandleTrx(ctx context.Context, fn func(ctx context.Context, repo repository.OrderRepository) error) error {
+	return fn(ctx, r)
+}
+
+func (r *heldKeyRepository) GetPaymentMethodByID(ctx context.Context, id uint64) (domain.PaymentMethod, error) {
+	*r.held = r.keys["key"]
+	return r.idempotencyRepository.GetPaymentMethodByID(ctx, id)
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// runLockedJob runs the scheduled job on the one replica holding its advisory
+// lock, every replica schedules it. The duration of the run is recorded, and
+// a run that fails is counted as a failure of the job.
+func (s *OrderServiceImpl) runLockedJob(job string, run func(ctx context.Context) error) {
+	ctx := context.Background()
+
+	unlock, locked, err := s.repository.TryLockJob(ctx, job)
+	if err != nil {
+		metrics.JobFailures.WithLabelValues(job).Inc()
+		return
+	}
+
+	if !locked {
+		log.Debug().Str("component", "runLockedJob").Str("job", job).Msg("Another replica is running the job")
+		return
+	}
+	defer unlock()
+
+	start := time.Now()
+	err = run(ctx)
+	metrics.JobRunDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.JobFailures.WithLabelValues(job).Inc()
+		log.Error().Err(err).Str("component", "runLockedJob").Str("job", job).Msg("")
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	"github.com/google/uuid"
+)
+
+// enqueueOrderEvent stages the event of the order entering its current
+// status, read through repo so it carries the changes of the surrounding
+// transaction. from is the status the order left, nil when it was placed.
+func (s *OrderServiceImpl) enqueueOrderEvent(ctx context.Context, repo repository.OrderRepository, orderID int64, eventType string, from *string, change domain.OrderStatusChange) error {
+	// Without a topic to publish on, nobody consumes the events.
+	if s.config.KafkaConfig.OrderEventTopic == "" {
+		return nil
+	}
+
+	order, err := repo.GetOrderByOrderID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	data, err := s.orderEventData(ctx, repo, order)
+	if err != nil {
+		return err
+	}
+
+	eventID, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("error generating event id: %v", err)
+	}
+
+	return s.enqueueOutboxMessage(ctx, repo, s.config.KafkaConfig.OrderEventTopic, order.TransactionNumber, eventType, dto.OrderEvent{
+		EventID:        eventID.String(),
+		EventType:      eventType,
+		Version:        domain.OrderEventVersion,
+		OccurredAt:     time.Now().Unix(),
+		PreviousStatus: from,
+		Source:         change.Source,
+		ActorID:        change.ActorID,
+		Reason:         change.Reason,
+		Order:          data,
+	})
+}
+
+func (s *OrderServiceImpl) orderEventData(ctx context.Context, repo repository.OrderRepository, order domain.Order) (data dto.OrderEventData, err error) {
+	orderDetails, err := repo.GetOrderDetailsByOrderID(ctx, order.ID)
+	if err != nil {
+		return
+	}
+
+	payments, err := repo.GetOrderPaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return
+	}
+
+	data = dto.OrderEventData{
+		ID:                  order.ID,
+		TransactionNumber:   order.TransactionNumber,
+		Status:              order.PaymentStatus,
+		StoreCode:           order.StoreCode,
+		CashierID:           order.CashierID,
+		CustomerID:          order.CustomerID,
+		ShiftID:             order.ShiftID,
+		PricesIncludeTax:    order.PricesIncludeTax,
+		Currency:            order.Currency,
+		Subtotal:            order.Subtotal,
+		DiscountAmount:      order.DiscountAmount,
+		ServiceChargeAmount: order.ServiceChargeAmount,
+		TaxAmount:           order.TaxAmount,
+		RoundingAmount:      order.RoundingAmount,
+		Amount:              order.Amount,
+		RefundedAmount:      money.New(0, order.Currency),
+		TenderedAmount:      order.TenderedAmount,
+		ChangeAmount:        order.ChangeAmount,
+		PaidAt:              order.PaidAt,
+		ExpiredAt:           order.ExpiredAt,
+		CancelledAt:         order.CancelledAt,
+		CreatedAt:           order.CreatedAt,
+		UpdatedAt:           order.UpdatedAt,
+		Items:               []dto.OrderEventItem{},
+		Payments:            []dto.OrderEventPayment{},
+	}
+
+	for _, detail := range orderDetails {
+		data.Items = append(data.Items, dto.OrderEventItem{
+			OrderDetailID:       detail.ID,
+			ProductID:           detail.ProductID,
+			ProductName:         detail.ProductName,
+			Quantity:            detail.Quantity,
+			Price:               detail.Amount,
+			DiscountAmount:      detail.DiscountAmount,
+			ServiceChargeAmount: detail.ServiceChargeAmount,
+			TaxAmount:           detail.TaxAmount,
+			TotalAmount:         detail.TotalAmount,
+		})
+	}
+
+	paymentMethods := map[int64]domain.PaymentMethod{}
+	for _, payment := range payments {
+		paymentMethod, ok := paymentMethods[payment.PaymentMethodID]
+		if !ok {
+			paymentMethod, err = repo.GetPaymentMethodByID(ctx, uint64(payment.PaymentMethodID))
+			if err != nil {
+				return
+			}
+
+			paymentMethods[payment.PaymentMethodID] = paymentMethod
+		}
+
+		refundedAmount := payment.RefundedAmount
+		data.RefundedAmount, err = data.RefundedAmount.Add(refundedAmount)
+		if err != nil {
+			return
+		}
+
+		data.Payments = append(data.Payments, dto.OrderEventPayment{
+			ID:                payment.ID,
+			PaymentMethodID:   payment.PaymentMethodID,
+			PaymentMethodName: paymentMethod.Name,
+			PaymentType:       paymentMethod.PaymentType,
+			PaymentGateway:    payment.PaymentGateway,
+			TransactionNumber: payment.TransactionNumber,
+			Status:            payment.Status,
+			Amount:            payment.Amount,
+			RefundedAmount:    refundedAmount,
+			TenderedAmount:    payment.TenderedAmount,
+			ChangeAmount:      payment.ChangeAmount,
+			PaidAt:            payment.PaidAt,
+		})
+	}
+
+	return data, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/metrics"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	pkgdto "github.com/alimikegami/point-of-sales/order-service/pkg/dto"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// orderExpiryJob names the lock and the metrics of the expiry job.
+	orderExpiryJob       = "expire_orders"
+	orderExpiryBatchSize = 200
+)
+
+// RestoreExpiredPaymentItemStocks expires the orders that were not paid in
+// full in time. The tenders already settled on a partially paid order are
+// refunded, since the order will not go through.
+//
+// Every replica schedules the job, but only the one holding its advisory lock
+// runs it. Each order is expired in a transaction of its own, so an order that
+// fails is left for the next run without holding up the others.
+func (s *OrderServiceImpl) RestoreExpiredPaymentItemStocks() {
+	s.runLockedJob(orderExpiryJob, s.expireOrders)
+}
+
+func (s *OrderServiceImpl) expireOrders(ctx context.Context) error {
+	log.Info().Str("component", "RestoreExpiredPaymentItemStocks").Msg("cron starts")
+
+	var orders []domain.Order
+	for _, status := range []string{domain.OrderStatusPending, domain.OrderStatusPartiallyPaid} {
+		data, err := s.repository.GetOrders(ctx, pkgdto.Filter{
+			PaymentStatus: status,
+			Expired:       true,
+			Limit:         orderExpiryBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		orders = append(orders, data...)
+	}
+
+	expired, failed := 0, 0
+	for _, order := range orders {
+		updated, err := s.expireOrder(ctx, order)
+		if err != nil {
+			failed++
+			metrics.JobFailures.WithLabelValues(orderExpiryJob).Inc()
+			log.Error().Err(err).Str("component", "RestoreExpiredPaymentItemStocks").Str("transaction_number", order.TransactionNumber).Msg("Failed to expire order")
+			continue
+		}
+
+		if updated {
+			expired++
+			metrics.ExpiredOrders.Inc()
+		}
+	}
+
+	log.Info().Str("component", "RestoreExpiredPaymentItemStocks").Int("expired", expired).Int("failed", failed).Msg("cron ends")
+
+	return nil
+}
+
+// expireOrder moves the order to expired, unless it moved on since it was
+// read, and stages the restoration of its stock in the same transaction. The
+// settled tenders of the order are then given back.
+func (s *OrderServiceImpl) expireOrder(ctx context.Context, order domain.Order) (updated bool, err error) {
+	var release bool
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		updated, err = s.transitionOrder(ctx, repo, order, domain.OrderStatusExpired, nil, domain.OrderStatusChange{
+			Source: domain.OrderStatusSourceCron,
+			Reason: statusReason("Order was not paid in full before it expired"),
+		})
+		if err != nil || !updated {
+			return err
+		}
+
+		payments, err := repo.GetOrderPaymentsByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		// The gateways expire their transactions by themselves.
+		for i, payment := range payments {
+			if payment.Status != domain.OrderStatusPending {
+				continue
+			}
+
+			payments[i].Status = domain.OrderStatusExpired
+			payments[i].UpdatedAt = time.Now().Unix()
+			_, err = repo.TransitionOrderPayment(ctx, payments[i], []string{domain.OrderStatusPending})
+			if err != nil {
+				return err
+			}
+		}
+
+		release = hasOutstandingPayments(payments)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if release {
+		err = s.releaseOrderPaymentsOrReview(ctx, order, "expired", "Order was not paid in full before it expired")
+		if err != nil {
+			// The order is expired all the same, what could not be given back
+			// is left to a reviewer.
+			log.Error().Err(err).Str("component", "RestoreExpiredPaymentItemStocks").Str("transaction_number", order.TransactionNumber).Msg("")
+		}
+	}
+
+	return updated, nil
+}
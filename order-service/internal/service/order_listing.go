@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	pkgdto "github.com/alimikegami/point-of-sales/order-service/pkg/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/utils"
+)
+
+const (
+	defaultOrderPageSize = 20
+	maxOrderPageSize     = 100
+)
+
+// GetOrders lists the orders matching filter. Pages can be asked for by
+// number, or, for large histories, by the cursor of the previous page, which
+// does not slow down the further it goes.
+func (s *OrderServiceImpl) GetOrders(ctx context.Context, filter pkgdto.Filter) (response pkgdto.PaginationResponse, err error) {
+	filter, err = orderListFilter(filter)
+	if err != nil {
+		return
+	}
+
+	datas, err := s.repository.GetOrders(ctx, filter)
+	if err != nil {
+		return
+	}
+
+	count, err := s.repository.CountOrders(ctx, filter)
+	if err != nil {
+		return
+	}
+
+	orderResponse := []dto.OrderResponse{}
+	for _, data := range datas {
+		orderResponse = append(orderResponse, dto.OrderResponse{
+			ID:                  data.ID,
+			PaymentStatus:       data.PaymentStatus,
+			Subtotal:            data.Subtotal.Float64(),
+			DiscountAmount:      data.DiscountAmount.Float64(),
+			ServiceChargeAmount: data.ServiceChargeAmount.Float64(),
+			TaxAmount:           data.TaxAmount.Float64(),
+			RoundingAmount:      data.RoundingAmount.Float64(),
+			TransactionAmount:   data.Amount.Float64(),
+			PaymentMethodName:   data.PaymentMethod.Name,
+			PaidAt:              data.PaidAt,
+			CashierID:           data.CashierID,
+			CustomerID:          data.CustomerID,
+			CreatedAt:           data.CreatedAt,
+			TransactionNumber:   data.TransactionNumber,
+		})
+	}
+
+	response.Records = orderResponse
+	response.Metadata.TotalCount = uint64(count)
+	response.Metadata.Limit = filter.Limit
+	if filter.After == nil {
+		response.Metadata.Page = uint64(filter.Page)
+	}
+
+	if len(datas) == filter.Limit {
+		last := datas[len(datas)-1]
+		cursor, err := encodeOrderCursor(pkgdto.Cursor{
+			SortBy: filter.SortBy,
+			Value:  orderSortValue(last, filter.SortBy),
+			ID:     last.ID,
+		})
+		if err != nil {
+			return response, err
+		}
+
+		response.Metadata.NextCursor = &cursor
+	}
+
+	return
+}
+
+// orderListFilter checks the filter of an order listing and works out the
+// fields the repository needs from it.
+func orderListFilter(filter pkgdto.Filter) (pkgdto.Filter, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultOrderPageSize
+	}
+	if filter.Limit > maxOrderPageSize {
+		filter.Limit = maxOrderPageSize
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+
+	if filter.SortBy == "" {
+		filter.SortBy = "created_at"
+	}
+	switch filter.SortBy {
+	case "id", "created_at", "amount", "paid_at":
+	default:
+		return filter, errs.ErrClient
+	}
+
+	switch filter.SortOrder {
+	case "":
+		filter.SortOrder = "desc"
+	case "asc", "desc":
+	default:
+		return filter, errs.ErrClient
+	}
+
+	if filter.MinAmount < 0 || filter.MaxAmount < 0 || (filter.MaxAmount != 0 && filter.MinAmount > filter.MaxAmount) {
+		return filter, errs.ErrClient
+	}
+
+	if filter.StartDate != "" {
+		start, err := utils.ParseWIBDate(filter.StartDate)
+		if err != nil {
+			return filter, errs.ErrClient
+		}
+		filter.CreatedFrom = start.Unix()
+	}
+
+	if filter.EndDate != "" {
+		end, err := utils.ParseWIBDate(filter.EndDate)
+		if err != nil {
+			return filter, errs.ErrClient
+		}
+		filter.CreatedTo = end.AddDate(0, 0, 1).Unix()
+	}
+
+	if filter.CreatedFrom != 0 && filter.CreatedTo != 0 && filter.CreatedTo <= filter.CreatedFrom {
+		return filter, errs.ErrClient
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeOrderCursor(filter.Cursor)
+		if err != nil || cursor.SortBy != filter.SortBy {
+			return filter, errs.ErrClient
+		}
+		filter.After = &cursor
+	}
+
+	return filter, nil
+}
+
+// orderSortValue is the value order is sorted by when listing orders by
+// sortBy.
+func orderSortValue(order domain.Order, sortBy string) string {
+	switch sortBy {
+	case "created_at":
+		return strconv.FormatInt(order.CreatedAt, 10)
+	case "amount":
+		return order.Amount.String()
+	case "paid_at":
+		if order.PaidAt == nil {
+			return "0"
+		}
+		return strconv.FormatInt(*order.PaidAt, 10)
+	default:
+		return strconv.FormatInt(order.ID, 10)
+	}
+}
+
+func encodeOrderCursor(cursor pkgdto.Cursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeOrderCursor(encoded string) (cursor pkgdto.Cursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return
+	}
+
+	// Every order is sorted by a number, anything else was tampered with.
+	_, err = strconv.ParseFloat(cursor.Value, 64)
+
+	return
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	pkgdto "github.com/alimikegami/point-of-sales/order-service/pkg/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+// orderListingRepository returns the same page whatever it is asked for, and
+// records the filter it was asked with.
+type orderListingRepository struct {
+	repository.OrderRepository
+	orders []domain.Order
+	filter pkgdto.Filter
+}
+
+func (r *orderListingRepository) GetOrders(ctx context.Context, filter pkgdto.Filter) ([]domain.Order, error) {
+	r.filter = filter
+	return r.orders, nil
+}
+
+func (r *orderListingRepository) CountOrders(ctx context.Context, filter pkgdto.Filter) (int64, error) {
+	return 100, nil
+}
+
+func TestGetOrdersCursor(t *testing.T) {
+	paidAt := int64(1700000500)
+
+	type TestCase struct {
+		Name          string
+		SortBy        string
+		Orders        []domain.Order
+		Limit         int
+		ExpectedValue string
+		ExpectedID    int64
+		ExpectedNone  bool
+	}
+
+	testCases := []TestCase{
+		{
+			Name:          "By creation time",
+			Orders:        []domain.Order{{ID: 9, CreatedAt: 1700000200}, {ID: 8, CreatedAt: 1700000100}},
+			Limit:         2,
+			ExpectedValue: "1700000100",
+			ExpectedID:    8,
+		},
+		{
+			Name:          "By amount",
+			SortBy:        "amount",
+			Orders:        []domain.Order{{ID: 9, Amount: rupiah(20000)}, {ID: 4, Amount: money.New(12345, money.IDR)}},
+			Limit:         2,
+			ExpectedValue: "123.45",
+			ExpectedID:    4,
+		},
+		{
+			Name:          "By payment time",
+			SortBy:        "paid_at",
+			Orders:        []domain.Order{{ID: 9, PaidAt: &paidAt}},
+			Limit:         1,
+			ExpectedValue: "1700000500",
+			ExpectedID:    9,
+		},
+		{
+			Name:          "Unpaid by payment time",
+			SortBy:        "paid_at",
+			Orders:        []domain.Order{{ID: 9}},
+			Limit:         1,
+			ExpectedValue: "0",
+			ExpectedID:    9,
+		},
+		{
+			Name:         "Last page",
+			Orders:       []domain.Order{{ID: 9, CreatedAt: 1700000200}},
+			Limit:        2,
+			ExpectedNone: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			repo := &orderListingRepository{orders: tc.Orders}
+			s := &OrderServiceImpl{repository: repo}
+
+			response, err := s.GetOrders(context.Background(), pkgdto.Filter{Limit: tc.Limit, SortBy: tc.SortBy})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.ExpectedNone {
+				if response.Metadata.NextCursor != nil {
+					t.Errorf("expected no cursor, got %s", *response.Metadata.NextCursor)
+				}
+				return
+			}
+
+			if response.Metadata.NextCursor == nil {
+				t.Fatal("expected a cursor")
+			}
+
+			// The cursor of a page asks the repository for what comes after
+			// its last order.
+			_, err = s.GetOrders(context.Background(), pkgdto.Filter{Limit: tc.Limit, SortBy: tc.SortBy, Cursor: *response.Metadata.NextCursor})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			after := repo.filter.After
+			if after == nil || after.SortBy != repo.filter.SortBy || after.Value != tc.ExpectedValue || after.ID != tc.ExpectedID {
+				t.Errorf("expected to continue after %s %d, got %+v", tc.ExpectedValue, tc.ExpectedID, after)
+			}
+		})
+	}
+}
+
+func TestOrderListFilterCursor(t *testing.T) {
+	encode := func(cursor pkgdto.Cursor) string {
+		encoded, err := encodeOrderCursor(cursor)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return encoded
+	}
+
+	type TestCase struct {
+		Name        string
+		Filter      pkgdto.Filter
+		ExpectedErr error
+	}
+
+	testCases := []TestCase{
+		{
+			Name:   "Cursor of the same sort",
+			Filter: pkgdto.Filter{SortBy: "amount", Cursor: encode(pkgdto.Cursor{SortBy: "amount", Value: "123.45", ID: 4})},
+		},
+		{
+			Name:        "Cursor of another sort",
+			Filter:      pkgdto.Filter{SortBy: "amount", Cursor: encode(pkgdto.Cursor{SortBy: "created_at", Value: "1700000100", ID: 4})},
+			ExpectedErr: errs.ErrClient,
+		},
+		{
+			Name:        "Value that is not a number",
+			Filter:      pkgdto.Filter{SortBy: "created_at", Cursor: encode(pkgdto.Cursor{SortBy: "created_at", Value: "1 OR 1=1", ID: 4})},
+			ExpectedErr: errs.ErrClient,
+		},
+		{
+			Name:        "Not base64",
+			Filter:      pkgdto.Filter{Cursor: "not a cursor!"},
+			ExpectedErr: errs.ErrClient,
+		},
+		{
+			Name:        "Not JSON",
+			Filter:      pkgdto.Filter{Cursor: base64.RawURLEncoding.EncodeToString([]byte("created_at:1"))},
+			ExpectedErr: errs.ErrClient,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			filter, err := orderListFilter(tc.Filter)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if err == nil && filter.After == nil {
+				t.Error("expected the filter to continue after the cursor")
+			}
+		})
+	}
+}
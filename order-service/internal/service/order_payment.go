@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	paymentgateway "github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/payment-gateway"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	"github.com/rs/zerolog/log"
+)
+
+// paymentFollowUp is what is left to do once a payment status was applied to
+// its order. It runs outside of the transaction since it calls the gateways.
+type paymentFollowUp int
+
+const (
+	paymentFollowUpNone paymentFollowUp = iota
+	// paymentFollowUpLateSettlement is an order paid in full after the expiry
+	// job released it, its stock has to be reserved again.
+	paymentFollowUpLateSettlement
+	// paymentFollowUpRelease is an order that will not go through, what was
+	// paid on it has to be given back.
+	paymentFollowUpRelease
+)
+
+// orderSagaPayments turns the tenders of req into the payments of the saga,
+// after checking their methods can be paid with. A request without tenders
+// pays the whole order with its PaymentMethodID.
+func (s *OrderServiceImpl) orderSagaPayments(ctx context.Context, transactionNumber string, req dto.OrderRequest) (payments []orderSagaPayment, err error) {
+	tenders := req.Payments
+	if len(tenders) == 0 {
+		tenders = []dto.OrderPaymentRequest{{
+			PaymentMethodID: req.PaymentMethodID,
+			TenderedAmount:  req.TenderedAmount,
+		}}
+	}
+
+	remainders := 0
+	for i, tender := range tenders {
+		paymentMethod, err := s.availablePaymentMethod(ctx, tender.PaymentMethodID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Rupiah has no minor unit, so tenders are in whole rupiah.
+		if tender.Amount == nil {
+			remainders++
+		} else if *tender.Amount <= 0 || *tender.Amount != math.Trunc(*tender.Amount) {
+			return nil, errs.ErrClient
+		}
+
+		payment := orderSagaPayment{
+			PaymentMethodID:   tender.PaymentMethodID,
+			PaymentMethodName: paymentMethod.Name,
+			PaymentType:       paymentMethod.PaymentType,
+			TransactionNumber: orderPaymentTransactionNumber(transactionNumber, i),
+			RequestedAmount:   requestMoney(tender.Amount),
+		}
+
+		// Cash goes into the drawer of whoever places the order, so both the
+		// cashier and the tendered cash are needed.
+		if paymentMethod.PaymentType == domain.PaymentTypeCash {
+			if req.UserID == 0 || tender.TenderedAmount == nil || *tender.TenderedAmount <= 0 {
+				return nil, errs.ErrClient
+			}
+
+			if tender.Amount != nil && *tender.TenderedAmount < *tender.Amount {
+				return nil, errs.ErrInsufficientTender
+			}
+
+			payment.TenderedAmount = requestMoney(tender.TenderedAmount)
+		}
+
+		payments = append(payments, payment)
+	}
+
+	if remainders > 1 {
+		return nil, errs.ErrClient
+	}
+
+	return payments, nil
+}
+
+// orderPaymentTransactionNumber is what the gateway knows the tender at index
+// by. The first tender goes by the order's own transaction number, so orders
+// paid with a single tender look the same as before split payments.
+func orderPaymentTransactionNumber(transactionNumber string, index int) string {
+	if index == 0 {
+		return transactionNumber
+	}
+
+	return fmt.Sprintf("%s-%d", transactionNumber, index+1)
+}
+
+func orderPaymentResponse(payment domain.OrderPayment, paymentMethodName string) dto.OrderPaymentResponse {
+	return dto.OrderPaymentResponse{
+		ID:                payment.ID,
+		PaymentMethodID:   payment.PaymentMethodID,
+		PaymentMethodName: paymentMethodName,
+		TransactionNumber: payment.TransactionNumber,
+		Amount:            payment.Amount.Float64(),
+		RefundedAmount:    payment.RefundedAmount.Float64(),
+		TenderedAmount:    majorUnits(payment.TenderedAmount),
+		ChangeAmount:      majorUnits(payment.ChangeAmount),
+		Status:            payment.Status,
+		QRCode:            payment.QRCode,
+		VANumber:          payment.VANumber,
+		ExpiredAt:         payment.ExpiredAt,
+		PaidAt:            payment.PaidAt,
+	}
+}
+
+// requestMoney reads an amount of a request, which is in rupiah.
+func requestMoney(amount *float64) *money.Money {
+	if amount == nil {
+		return nil
+	}
+
+	m := money.FromFloat(*amount, money.IDR)
+	return &m
+}
+
+// majorUnits is amount as the responses carry it.
+func majorUnits(amount *money.Money) *float64 {
+	if amount == nil {
+		return nil
+	}
+
+	f := amount.Float64()
+	return &f
+}
+
+// syncOrderStatus moves the order to the status its payments add up to.
+// paidAt is the time the order is paid at, should the payments cover it.
+func (s *OrderServiceImpl) syncOrderStatus(ctx context.Context, repo repository.OrderRepository, order domain.Order, payments []domain.OrderPayment, paidAt *int64, change domain.OrderStatusChange) (paymentFollowUp, error) {
+	status := domain.OrderStatusFromPayments(order.Amount, payments)
+
+	// The order was already released, a payment settled on it since can only
+	// be given back, unless it completes an expired order.
+	if !domain.OrderStatusHoldsStock(order.PaymentStatus) {
+		if status == domain.OrderStatusPaid && order.PaymentStatus == domain.OrderStatusExpired {
+			return paymentFollowUpLateSettlement, nil
+		}
+
+		if hasOutstandingPayments(payments) {
+			return paymentFollowUpRelease, nil
+		}
+
+		return paymentFollowUpNone, nil
+	}
+
+	if status == order.PaymentStatus {
+		return paymentFollowUpNone, nil
+	}
+
+	// Notifications may arrive out of order, a status the order has already
+	// moved past is recorded on the payment but not applied to the order.
+	if !domain.CanTransitionOrderStatus(order.PaymentStatus, status) {
+		log.Ctx(ctx).Info().Str("component", "syncOrderStatus").Str("transaction_number", order.TransactionNumber).Str("from", order.PaymentStatus).Str("to", status).Msg("Ignoring payment status that does not apply to the order status")
+		return paymentFollowUpNone, nil
+	}
+
+	if status != domain.OrderStatusPaid {
+		paidAt = nil
+	}
+
+	updated, err := s.transitionOrder(ctx, repo, order, status, paidAt, change)
+	if err != nil {
+		return paymentFollowUpNone, err
+	}
+
+	// Someone else moved the order in the meantime.
+	if !updated {
+		return paymentFollowUpNone, errs.ErrConflict
+	}
+
+	if !domain.OrderStatusHoldsStock(status) && hasOutstandingPayments(payments) {
+		return paymentFollowUpRelease, nil
+	}
+
+	return paymentFollowUpNone, nil
+}
+
+// stagePaymentFollowUp stages the follow-up syncOrderStatus asked for as a
+// pending review, in the transaction that applied the payment status. A
+// follow-up that fails, or never runs, is then left to a reviewer rather than
+// lost, as the notification it came from is not applied again.
+func stagePaymentFollowUp(ctx context.Context, repo repository.OrderRepository, order domain.Order, followUp paymentFollowUp) (reviewID int64, err error) {
+	var reason string
+	switch followUp {
+	case paymentFollowUpLateSettlement:
+		reason = domain.PaymentReviewReasonLateSettlement
+	case paymentFollowUpRelease:
+		reason = domain.PaymentReviewReasonUnreleasedPayment
+	default:
+		return 0, nil
+	}
+
+	now := time.Now().Unix()
+	return repo.AddPaymentReview(ctx, domain.PaymentReview{
+		OrderID:   order.ID,
+		Reason:    reason,
+		Status:    domain.PaymentReviewStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+// followUpPayments does what syncOrderStatus left to do once its transaction
+// is committed.
+func (s *OrderServiceImpl) followUpPayments(ctx context.Context, order domain.Order, followUp paymentFollowUp, reviewID int64, paidAt *int64, change domain.OrderStatusChange) error {
+	var err error
+	switch followUp {
+	case paymentFollowUpLateSettlement:
+		err = s.handleLateSettlement(ctx, order, paidAt, change)
+	case paymentFollowUpRelease:
+		err = s.releaseOrderPayments(ctx, order, "release", "Order was not completed")
+	default:
+		return nil
+	}
+
+	return s.settlePaymentFollowUp(ctx, order, reviewID, err)
+}
+
+// settlePaymentFollowUp resolves the review staged for a follow-up that went
+// through, and opens it for a reviewer when the follow-up failed.
+func (s *OrderServiceImpl) settlePaymentFollowUp(ctx context.Context, order domain.Order, reviewID int64, followUpErr error) error {
+	now := time.Now().Unix()
+	note := "Handled automatically"
+	review := domain.PaymentReview{
+		ID:         reviewID,
+		Status:     domain.PaymentReviewStatusResolved,
+		ResolvedAt: &now,
+		UpdatedAt:  now,
+	}
+
+	if followUpErr != nil {
+		log.Ctx(ctx).Error().Err(followUpErr).Str("component", "followUpPayments").Str("transaction_number", order.TransactionNumber).Msg("Leaving the payment follow-up to a reviewer")
+
+		note = followUpErr.Error()
+		review.Status = domain.PaymentReviewStatusOpen
+		review.ResolvedAt = nil
+	}
+	review.Note = &note
+
+	// A review opened as stale in the meantime is left as it is.
+	_, err := s.repository.SettlePaymentReview(ctx, review)
+	return err
+}
+
+// hasOutstandingPayments reports whether any payment is still open or holds
+// money that was not given back.
+func hasOutstandingPayments(payments []domain.OrderPayment) bool {
+	for _, payment := range payments {
+		if paymentOutstanding(payment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func paymentOutstanding(payment domain.OrderPayment) bool {
+	switch payment.Status {
+	case domain.OrderStatusPending, domain.OrderStatusChallenge:
+		return true
+	case domain.OrderStatusPaid, domain.OrderStatusPartiallyRefunded:
+		return payment.Amount.Amount-payment.RefundedAmount.Amount > 0
+	}
+
+	return false
+}
+
+// releaseOrderPayments gives back everything paid on an order that will not
+// go through. The refunds go by refundKey, so releasing again does not refund
+// twice.
+func (s *OrderServiceImpl) releaseOrderPayments(ctx context.Context, order domain.Order, refundKey string, reason string) error {
+	payments, err := s.repository.GetOrderPaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.voidOrderPayments(ctx, payments)
+	if err != nil {
+		return err
+	}
+
+	return s.refundOrderPayments(ctx, order, payments, refundKey, reason)
+}
+
+// voidOrderPayments cancels the payments that are still open at their
+// gateways, so they can no longer be paid.
+func (s *OrderServiceImpl) voidOrderPayments(ctx context.Context, payments []domain.OrderPayment) error {
+	for _, payment := range payments {
+		if payment.Status != domain.OrderStatusPending && payment.Status != domain.OrderStatusChallenge {
+			continue
+		}
+
+		gateway, err := s.paymentGateways.Get(&payment.PaymentGateway)
+		if err != nil {
+			return err
+		}
+
+		err = gateway.Cancel(ctx, payment.TransactionNumber)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "voidOrderPayments").Str("transaction_number", payment.TransactionNumber).Msg("Failed to cancel payment")
+			return errs.ErrPaymentGateway
+		}
+
+		from := payment.Status
+		payment.Status = domain.OrderStatusCancelled
+		payment.UpdatedAt = time.Now().Unix()
+
+		// A payment that moved on in the meantime is left to its
+		// notification.
+		_, err = s.repository.TransitionOrderPayment(ctx, payment, []string{from})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refundOrderPayments refunds what is left of the settled payments in full.
+// Cash goes back out of the drawer it went into.
+func (s *OrderServiceImpl) refundOrderPayments(ctx context.Context, order domain.Order, payments []domain.OrderPayment, refundKey string, reason string) error {
+	for _, payment := range payments {
+		if payment.Status != domain.OrderStatusPaid && payment.Status != domain.OrderStatusPartiallyRefunded {
+			continue
+		}
+
+		amount := money.New(payment.Amount.Amount-payment.RefundedAmount.Amount, payment.Amount.Currency)
+		if amount.Amount <= 0 {
+			continue
+		}
+
+		gateway, err := s.paymentGateways.Get(&payment.PaymentGateway)
+		if err != nil {
+			return err
+		}
+
+		_, err = gateway.Refund(ctx, payment.TransactionNumber, paymentgateway.RefundRequest{
+			RefundKey: payment.TransactionNumber + "-" + refundKey,
+			Amount:    amount,
+			Reason:    reason,
+		})
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "refundOrderPayments").Str("transaction_number", payment.TransactionNumber).Msg("Failed to refund payment")
+			return err
+		}
+
+		err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+			now := time.Now().Unix()
+			err := s.refundOrderPayment(ctx, repo, payment, amount, now)
+			if err != nil {
+				return err
+			}
+
+			if payment.CashierID != nil {
+				return s.recordCashRefund(ctx, repo, order, payment, nil, *payment.CashierID, amount, now)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,1213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	paymentgateway "github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/payment-gateway"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	pb "github.com/alimikegami/pos-microservices/proto-defs/pb"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	orderSagaStepLookupPrices  = "lookup_prices"
+	orderSagaStepApplyPromos   = "apply_promotions"
+	orderSagaStepApplyTaxes    = "apply_taxes"
+	orderSagaStepReserveStock  = "reserve_stock"
+	orderSagaStepChargePayment = "charge_payment"
+	orderSagaStepCreateOrder   = "create_order"
+
+	// orderSagaStaleAfter is how long a saga may go without progress before it
+	// is considered abandoned by the replica that started it. A saga waiting
+	// on a remote step renews its claim every orderSagaHeartbeat, however long
+	// the step takes.
+	orderSagaStaleAfter      = time.Minute
+	orderSagaHeartbeat       = 15 * time.Second
+	orderSagaResumeBatchSize = 50
+	// orderSagaResumeJob names the lock and the metrics of the resume job.
+	orderSagaResumeJob = "resume_order_sagas"
+)
+
+// errOrderSagaTakenOver is returned when a saga went without progress for so
+// long that another replica claimed it. What is left of it is up to that
+// replica.
+var errOrderSagaTakenOver = errors.New("order saga was taken over by another replica")
+
+// orderSagaState is what the order placement steps hand to each other. It is
+// persisted after every step so another replica can pick the saga up.
+type orderSagaState struct {
+	// CashierID is the user placing the order, whose drawer takes the cash
+	// of cash tenders.
+	CashierID uint64 `json:"cashier_id"`
+	// ShiftID is the shift the cashier had open when the order was placed,
+	// orders taking cash cannot be placed without one.
+	ShiftID             *int64              `json:"shift_id"`
+	CustomerID          *uint64             `json:"customer_id"`
+	Customer            *orderSagaCustomer  `json:"customer"`
+	StoreCode           string              `json:"store_code"`
+	Currency            string              `json:"currency"`
+	PricesIncludeTax    bool                `json:"prices_include_tax"`
+	PromoCodes          []string            `json:"promo_codes"`
+	OrderItems          []orderSagaItem     `json:"order_items"`
+	Subtotal            money.Money         `json:"subtotal"`
+	Discounts           []orderSagaDiscount `json:"discounts"`
+	DiscountAmount      money.Money         `json:"discount_amount"`
+	Charges             []orderSagaCharge   `json:"charges"`
+	ServiceChargeAmount money.Money         `json:"service_charge_amount"`
+	TaxAmount           money.Money         `json:"tax_amount"`
+	TotalAmount         money.Money         `json:"total_amount"`
+	// CashRoundingIncrement and RoundingAmount are only used by orders paid
+	// in cash alone, whose total is rounded to what can be paid in cash.
+	CashRoundingIncrement float64            `json:"cash_rounding_increment"`
+	RoundingAmount        money.Money        `json:"rounding_amount"`
+	Payments              []orderSagaPayment `json:"payments"`
+	MDRFee                money.Money        `json:"mdr_fee"`
+	// ExpiredAt is when the last of the payments expires.
+	ExpiredAt int64 `json:"expired_at"`
+}
+
+// orderSagaPayment is one tender of the order. RequestedAmount is what the
+// customer asked to pay with it, nil for the tender taking what the others
+// leave, and Amount is what it is charged once the total is known.
+type orderSagaPayment struct {
+	PaymentMethodID   uint64       `json:"payment_method_id"`
+	PaymentMethodName string       `json:"payment_method_name"`
+	PaymentGateway    string       `json:"payment_gateway"`
+	PaymentType       string       `json:"payment_type"`
+	Channel           *string      `json:"channel"`
+	TransactionNumber string       `json:"transaction_number"`
+	RequestedAmount   *money.Money `json:"requested_amount"`
+	Amount            money.Money  `json:"amount"`
+	TenderedAmount    *money.Money `json:"tendered_amount"`
+	ChangeAmount      *money.Money `json:"change_amount"`
+	MDRFee            money.Money  `json:"mdr_fee"`
+	QRCode            *string      `json:"qr_code"`
+	VANumber          *string      `json:"va_number"`
+	ExpiredAt         int64        `json:"expired_at"`
+}
+
+// currency is the currency the order is placed in, IDR for the sagas started
+// before orders had one.
+func (state orderSagaState) currency() string {
+	if state.Currency == "" {
+		return money.IDR
+	}
+
+	return state.Currency
+}
+
+// paidInCash reports whether every tender of the order is cash.
+func (state orderSagaState) paidInCash() bool {
+	for _, payment := range state.Payments {
+		if payment.PaymentType != domain.PaymentTypeCash {
+			return false
+		}
+	}
+
+	return len(state.Payments) > 0
+}
+
+type orderSagaItem struct {
+	ProductID   string      `json:"product_id"`
+	ProductName string      `json:"product_name"`
+	Quantity    int         `json:"quantity"`
+	Price       money.Money `json:"price"`
+	// DiscountAmount is the line's discount including its share of the
+	// discounts on the whole order.
+	DiscountAmount      money.Money `json:"discount_amount"`
+	ServiceChargeAmount money.Money `json:"service_charge_amount"`
+	TaxAmount           money.Money `json:"tax_amount"`
+	// TotalAmount is what the customer pays for the line, after discounts and
+	// with the charges added on top of the price.
+	TotalAmount money.Money `json:"total_amount"`
+}
+
+type orderSagaDiscount struct {
+	PromotionID int64  `json:"promotion_id"`
+	Name        string `json:"name"`
+	// ItemIndex points into OrderItems, it is nil for discounts on the whole
+	// order.
+	ItemIndex *int        `json:"item_index"`
+	Amount    money.Money `json:"amount"`
+}
+
+// orderSagaCharge is a tax or service charge line, IncludedAmount is the part
+// of Amount that is already in the item prices.
+type orderSagaCharge struct {
+	Type           string      `json:"type"`
+	Name           string      `json:"name"`
+	Rate           float64     `json:"rate"`
+	BaseAmount     money.Money `json:"base_amount"`
+	Amount         money.Money `json:"amount"`
+	IncludedAmount money.Money `json:"included_amount"`
+}
+
+type orderSaga struct {
+	domain.OrderSaga
+	state orderSagaState
+}
+
+type orderSagaStep struct {
+	name string
+	// prepare works out locally what execute sends, before a step that has
+	// to be undone is recorded as started with it.
+	prepare func(ctx context.Context, saga *orderSaga) error
+	// execute performs the remote part of the step, if any. The failure of a
+	// step with compensation may come back after the remote side went through
+	// with it, so the step is compensated along with the ones before it. A
+	// step without compensation must leave nothing behind when it fails.
+	execute func(ctx context.Context, saga *orderSaga) error
+	// persist performs the local part of the step in the transaction that
+	// records the step as succeeded.
+	persist func(ctx context.Context, repo repository.OrderRepository, saga *orderSaga) error
+	// compensate undoes the step in the transaction that records the
+	// compensation.
+	compensate func(ctx context.Context, repo repository.OrderRepository, saga *orderSaga) error
+}
+
+func (s *OrderServiceImpl) orderSagaSteps() []orderSagaStep {
+	return []orderSagaStep{
+		{name: orderSagaStepLookupPrices, execute: s.lookupOrderPrices},
+		{name: orderSagaStepApplyPromos, execute: s.applyOrderPromotions},
+		{name: orderSagaStepApplyTaxes, execute: s.applyOrderTaxes},
+		{name: orderSagaStepReserveStock, execute: s.reserveOrderStock, compensate: s.releaseOrderStock},
+		{name: orderSagaStepChargePayment, prepare: s.prepareOrderPayment, execute: s.chargeOrderPayment, compensate: s.cancelOrderPayment},
+		{name: orderSagaStepCreateOrder, persist: s.createOrderRecords},
+	}
+}
+
+func (s *OrderServiceImpl) startOrderSaga(ctx context.Context, transactionNumber string, state orderSagaState) (*orderSaga, error) {
+	now := time.Now().Unix()
+	saga := &orderSaga{
+		OrderSaga: domain.OrderSaga{
+			TransactionNumber: transactionNumber,
+			Status:            domain.SagaStatusRunning,
+			CurrentStep:       orderSagaStepLookupPrices,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		},
+		state: state,
+	}
+
+	encodedState, err := json.Marshal(saga.state)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling saga state: %w", err)
+	}
+	saga.State = string(encodedState)
+
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		id, err := repo.AddOrderSaga(ctx, saga.OrderSaga)
+		if err != nil {
+			return err
+		}
+
+		saga.ID = id
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return saga, nil
+}
+
+// runOrderSaga executes the steps starting at index from. When a step fails,
+// the steps that already went through are compensated in reverse order and
+// the step's error is returned.
+func (s *OrderServiceImpl) runOrderSaga(ctx context.Context, saga *orderSaga, from int) error {
+	steps := s.orderSagaSteps()
+
+	for i := from; i < len(steps); i++ {
+		step := steps[i]
+		saga.CurrentStep = step.name
+
+		if step.prepare != nil {
+			err := step.prepare(ctx, saga)
+			if err != nil {
+				return s.failOrderSaga(ctx, saga, step.name, i-1, err)
+			}
+		}
+
+		// A step that has to be undone is recorded as started, with the state
+		// its remote part works from, so a saga resumed after a crash undoes
+		// it even though it never finished.
+		if step.compensate != nil {
+			err := s.saveOrderSagaStep(ctx, saga, step.name, domain.SagaActionExecute, domain.SagaStepStarted, nil, nil)
+			if err != nil {
+				return s.failOrderSaga(ctx, saga, step.name, i-1, err)
+			}
+		}
+
+		if step.execute != nil {
+			err := s.withOrderSagaHeartbeat(ctx, saga, func() error {
+				return step.execute(ctx, saga)
+			})
+			if errors.Is(err, errOrderSagaTakenOver) {
+				s.undoOrderSagaStep(ctx, saga, step)
+			}
+			if err != nil {
+				last := i - 1
+				if step.compensate != nil {
+					last = i
+				}
+				return s.failOrderSaga(ctx, saga, step.name, last, err)
+			}
+		}
+
+		if i == len(steps)-1 {
+			saga.Status = domain.SagaStatusCompleted
+		}
+
+		err := s.saveOrderSagaStep(ctx, saga, step.name, domain.SagaActionExecute, domain.SagaStepSucceeded, nil, step.persist)
+		if err != nil {
+			saga.Status = domain.SagaStatusRunning
+
+			// Only recording failed, so the remote part of this step did happen
+			// and has to be undone as well.
+			last := i - 1
+			if step.execute != nil {
+				last = i
+			}
+			if errors.Is(err, errOrderSagaTakenOver) && step.execute != nil {
+				s.undoOrderSagaStep(ctx, saga, step)
+			}
+			return s.failOrderSaga(ctx, saga, step.name, last, err)
+		}
+	}
+
+	return nil
+}
+
+// withOrderSagaHeartbeat runs fn, renewing the claim on saga every
+// orderSagaHeartbeat so a slow remote step does not make the saga look
+// abandoned. It returns errOrderSagaTakenOver, once fn is done, when another
+// replica claimed the saga all the same.
+func (s *OrderServiceImpl) withOrderSagaHeartbeat(ctx context.Context, saga *orderSaga, fn func() error) error {
+	claim := saga.OrderSaga
+	stop := make(chan struct{})
+	lost := make(chan bool, 1)
+	go func() {
+		ticker := time.NewTicker(orderSagaHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				lost <- false
+				return
+			case <-ticker.C:
+				now := time.Now().Unix()
+				claimed, err := s.repository.ClaimOrderSaga(ctx, claim, now)
+				if err != nil {
+					log.Ctx(ctx).Error().Err(err).Str("component", "withOrderSagaHeartbeat").Str("transaction_number", claim.TransactionNumber).Msg("Failed to renew the saga claim")
+					continue
+				}
+
+				if !claimed {
+					lost <- true
+					return
+				}
+
+				claim.UpdatedAt = now
+			}
+		}
+	}()
+
+	err := fn()
+	close(stop)
+	if <-lost {
+		return errOrderSagaTakenOver
+	}
+
+	saga.UpdatedAt = claim.UpdatedAt
+	return err
+}
+
+// undoOrderSagaStep undoes the remote part of step for a saga another replica
+// took over while the step ran. That replica may have compensated the step
+// before it got through, so the replica that ran it undoes it too, without
+// recording anything on a saga it no longer owns.
+func (s *OrderServiceImpl) undoOrderSagaStep(ctx context.Context, saga *orderSaga, step orderSagaStep) {
+	if step.compensate == nil {
+		return
+	}
+
+	log.Ctx(ctx).Warn().Str("component", "undoOrderSagaStep").Str("transaction_number", saga.TransactionNumber).Str("step", step.name).Msg("Undoing a step of a saga taken over by another replica")
+
+	err := s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		return step.compensate(ctx, repo, saga)
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "undoOrderSagaStep").Str("transaction_number", saga.TransactionNumber).Str("step", step.name).Msg("Failed to undo saga step")
+	}
+}
+
+// failOrderSaga records the failure of step and compensates the steps from
+// index last down, unless the saga was taken over in the meantime. It
+// returns err.
+func (s *OrderServiceImpl) failOrderSaga(ctx context.Context, saga *orderSaga, step string, last int, err error) error {
+	if errors.Is(err, errOrderSagaTakenOver) {
+		log.Ctx(ctx).Warn().Str("component", "runOrderSaga").Str("transaction_number", saga.TransactionNumber).Str("step", step).Msg("Order saga was taken over by another replica")
+		return err
+	}
+
+	s.recordOrderSagaFailure(ctx, saga, step, domain.SagaActionExecute, err)
+	s.compensateOrderSaga(ctx, saga, last, nil)
+	return err
+}
+
+// compensateOrderSaga undoes the steps from index from down to the first one,
+// skipping steps without compensation and the ones listed in compensated.
+func (s *OrderServiceImpl) compensateOrderSaga(ctx context.Context, saga *orderSaga, from int, compensated map[string]bool) {
+	steps := s.orderSagaSteps()
+	saga.Status = domain.SagaStatusCompensating
+	status := domain.SagaStatusCompensated
+
+	for i := from; i >= 0; i-- {
+		step := steps[i]
+		if step.compensate == nil || compensated[step.name] {
+			continue
+		}
+
+		saga.CurrentStep = step.name
+		err := s.saveOrderSagaStep(ctx, saga, step.name, domain.SagaActionCompensate, domain.SagaStepSucceeded, nil, step.compensate)
+		if errors.Is(err, errOrderSagaTakenOver) {
+			log.Ctx(ctx).Warn().Str("component", "compensateOrderSaga").Str("transaction_number", saga.TransactionNumber).Msg("Order saga was taken over by another replica")
+			return
+		}
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "compensateOrderSaga").Str("transaction_number", saga.TransactionNumber).Str("step", step.name).Msg("Failed to compensate saga step")
+			s.recordOrderSagaFailure(ctx, saga, step.name, domain.SagaActionCompensate, err)
+			status = domain.SagaStatusCompensationFailed
+		}
+	}
+
+	saga.Status = status
+	previous := saga.UpdatedAt
+	err := s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		return saveOrderSaga(ctx, repo, saga)
+	})
+	if err != nil {
+		saga.UpdatedAt = previous
+		log.Ctx(ctx).Error().Err(err).Str("component", "compensateOrderSaga").Str("transaction_number", saga.TransactionNumber).Msg("Failed to update saga status")
+	}
+}
+
+// saveOrderSagaStep runs local, if any, and appends the step outcome to the
+// saga log in a single transaction. A stepErr records the step as failed.
+func (s *OrderServiceImpl) saveOrderSagaStep(ctx context.Context, saga *orderSaga, step string, action string, status string, stepErr error, local func(ctx context.Context, repo repository.OrderRepository, saga *orderSaga) error) error {
+	previous := saga.UpdatedAt
+	err := s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		// Claimed first, so nothing is done on behalf of a saga another
+		// replica took over.
+		err := saveOrderSaga(ctx, repo, saga)
+		if err != nil {
+			return err
+		}
+
+		if local != nil {
+			err = local(ctx, repo, saga)
+			if err != nil {
+				return err
+			}
+		}
+
+		stepLog := domain.OrderSagaStep{
+			SagaID:    saga.ID,
+			Step:      step,
+			Action:    action,
+			Status:    status,
+			CreatedAt: saga.UpdatedAt,
+		}
+
+		if stepErr != nil {
+			errMsg := stepErr.Error()
+			stepLog.Status = domain.SagaStepFailed
+			stepLog.Error = &errMsg
+			saga.LastError = &errMsg
+		}
+
+		// Saved again, local may have changed the saga.
+		err = repo.UpdateOrderSaga(ctx, saga.OrderSaga)
+		if err != nil {
+			return err
+		}
+
+		return repo.AddOrderSagaStep(ctx, stepLog)
+	})
+	if err != nil {
+		saga.UpdatedAt = previous
+	}
+
+	return err
+}
+
+// saveOrderSaga renews the claim on the saga and saves it with its state. It
+// returns errOrderSagaTakenOver when the saga was claimed by another replica
+// since it was last saved.
+func saveOrderSaga(ctx context.Context, repo repository.OrderRepository, saga *orderSaga) error {
+	encodedState, err := json.Marshal(saga.state)
+	if err != nil {
+		return fmt.Errorf("error marshalling saga state: %w", err)
+	}
+
+	now := time.Now().Unix()
+	claimed, err := repo.ClaimOrderSaga(ctx, saga.OrderSaga, now)
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		return errOrderSagaTakenOver
+	}
+
+	saga.State = string(encodedState)
+	saga.UpdatedAt = now
+	return repo.UpdateOrderSaga(ctx, saga.OrderSaga)
+}
+
+func (s *OrderServiceImpl) recordOrderSagaFailure(ctx context.Context, saga *orderSaga, step string, action string, stepErr error) {
+	err := s.saveOrderSagaStep(ctx, saga, step, action, domain.SagaStepFailed, stepErr, nil)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "recordOrderSagaFailure").Str("transaction_number", saga.TransactionNumber).Str("step", step).Msg("Failed to record saga step failure")
+	}
+}
+
+func (s *OrderServiceImpl) lookupOrderPrices(ctx context.Context, saga *orderSaga) error {
+	productIDs := make([]string, len(saga.state.OrderItems))
+	for i, item := range saga.state.OrderItems {
+		productIDs[i] = item.ProductID
+	}
+
+	productPrices, err := s.productQueryGrpcClient.GetProductPrice(ctx, &pb.GetProductPriceRequest{
+		ProductIds: productIDs,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "lookupOrderPrices").Msg("Failed to get product price info")
+		return err
+	}
+
+	productPriceMap := make(map[string]*pb.Product, len(productPrices.Products))
+	for _, product := range productPrices.Products {
+		productPriceMap[product.ProductId] = product
+	}
+
+	for i, item := range saga.state.OrderItems {
+		productInfo, exists := productPriceMap[item.ProductID]
+		if !exists {
+			return fmt.Errorf("product info not found for product ID: %s", item.ProductID)
+		}
+
+		// Products priced before unit prices only have the float price,
+		// which is in rupiah.
+		unitPrice := money.FromProto(productInfo.GetUnitPrice())
+		if unitPrice.Currency == "" {
+			unitPrice = money.FromFloat(float64(productInfo.GetPrice()), money.IDR)
+		}
+
+		if unitPrice.Currency != saga.state.currency() {
+			return fmt.Errorf("product %s is priced in %s, not in %s: %w", item.ProductID, unitPrice.Currency, saga.state.currency(), money.ErrCurrencyMismatch)
+		}
+
+		saga.state.OrderItems[i].ProductName = productInfo.Name
+		saga.state.OrderItems[i].Price = unitPrice
+	}
+
+	saga.state.Subtotal = orderSagaSubtotal(saga.state.OrderItems, saga.state.currency())
+	saga.state.TotalAmount = saga.state.Subtotal
+
+	return nil
+}
+
+func (s *OrderServiceImpl) reserveOrderStock(ctx context.Context, saga *orderSaga) error {
+	var items []dto.OrderItem
+	for _, item := range saga.state.OrderItems {
+		items = append(items, dto.OrderItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	// Reserved under the transaction number, so releasing it after a crash
+	// gives back no more than was reserved.
+	return s.reserveStock(ctx, saga.TransactionNumber, items)
+}
+
+// reserveStock takes items out of stock. With a reservationKey, they are taken
+// out at most once under it, and only a release under the same key gives them
+// back.
+func (s *OrderServiceImpl) reserveStock(ctx context.Context, reservationKey string, items []dto.OrderItem) error {
+	var products []*pb.ProductQuantityUpdate
+	for _, item := range items {
+		products = append(products, &pb.ProductQuantityUpdate{
+			ProductId: item.ProductID,
+			Quantity:  int64(item.Quantity),
+		})
+	}
+
+	_, err := s.productService.Execute(func() ([]byte, error) {
+		_, err := s.productCommandGrpcClient.UpdateProductQuantityBatch(ctx, &pb.UpdateProductQuantityRequest{
+			Products:       products,
+			ReservationKey: reservationKey,
+		})
+
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "reserveStock").Msg("Failed to update product quantity")
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
+
+func (s *OrderServiceImpl) releaseOrderStock(ctx context.Context, repo repository.OrderRepository, saga *orderSaga) error {
+	restoreReq := dto.OrderProductServiceRequest{
+		TransactionNumber: saga.TransactionNumber,
+		ReservationKey:    saga.TransactionNumber,
+	}
+	for _, item := range saga.state.OrderItems {
+		restoreReq.OrderItems = append(restoreReq.OrderItems, dto.OrderItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	log.Ctx(ctx).Info().Msg("Restoring product stock")
+	return s.enqueueKafkaMessage(ctx, repo, saga.TransactionNumber, dto.KafkaMessage{
+		EventType: "restore_product_stock",
+		Data:      restoreReq,
+	})
+}
+
+// prepareOrderPayment works out what each tender is charged and resolves the
+// gateway it is charged through.
+func (s *OrderServiceImpl) prepareOrderPayment(ctx context.Context, saga *orderSaga) error {
+	// Checked again, a method may have been disabled while the earlier steps
+	// ran.
+	paymentMethods := make([]domain.PaymentMethod, len(saga.state.Payments))
+	for i, payment := range saga.state.Payments {
+		paymentMethod, err := s.availablePaymentMethod(ctx, payment.PaymentMethodID)
+		if err != nil {
+			return err
+		}
+
+		paymentMethods[i] = paymentMethod
+		saga.state.Payments[i].PaymentMethodName = paymentMethod.Name
+		saga.state.Payments[i].PaymentType = paymentMethod.PaymentType
+		saga.state.Payments[i].Channel = paymentMethod.Channel
+	}
+
+	// Cash rounding only applies when nothing is paid by other means.
+	if saga.state.paidInCash() {
+		applyCashRounding(&saga.state)
+	}
+
+	err := allocateOrderPayments(&saga.state)
+	if err != nil {
+		return err
+	}
+
+	saga.state.MDRFee = money.New(0, saga.state.currency())
+	for i := range saga.state.Payments {
+		payment := &saga.state.Payments[i]
+		if payment.PaymentType == domain.PaymentTypeCash {
+			err = tenderCash(payment)
+			if err != nil {
+				return err
+			}
+		}
+
+		paymentMethod := paymentMethods[i]
+		payment.MDRFee = paymentMethod.CalculateMDRFee(payment.Amount)
+		saga.state.MDRFee.Amount += payment.MDRFee.Amount
+		if paymentMethod.MDRType != domain.MDRTypePercentage && paymentMethod.MDRType != domain.MDRTypeFlat {
+			log.Ctx(ctx).Warn().Str("component", "prepareOrderPayment").Uint64("payment_method_id", paymentMethod.ID).Str("mdr_type", paymentMethod.MDRType).Msg("Unknown MDR type, no MDR fee recorded")
+		}
+
+		// Resolved before the step is recorded as started, so a compensation
+		// after a crash reaches the right gateway.
+		gateway, err := s.paymentGateways.Get(paymentMethod.Gateway)
+		if err != nil {
+			return err
+		}
+		payment.PaymentGateway = gateway.Name()
+	}
+
+	return nil
+}
+
+func (s *OrderServiceImpl) chargeOrderPayment(ctx context.Context, saga *orderSaga) error {
+	saga.state.ExpiredAt = 0
+	for i := range saga.state.Payments {
+		payment := &saga.state.Payments[i]
+
+		gateway, err := s.paymentGateways.Get(&payment.PaymentGateway)
+		if err != nil {
+			s.cancelOrderPayments(ctx, saga, i)
+			return err
+		}
+
+		chargeReq := paymentgateway.ChargeRequest{
+			TransactionNumber: payment.TransactionNumber,
+			PaymentType:       payment.PaymentType,
+			Channel:           payment.Channel,
+			GrossAmount:       payment.Amount,
+			Items:             orderChargeItems(saga, *payment),
+			Customer:          saga.state.Customer.chargeCustomer(),
+		}
+
+		// Taxes and discounts are worked out per line, so the itemisation can
+		// come off the total by a rounding. The order is then charged as one
+		// item rather than for a total its items do not show.
+		err = chargeReq.Validate()
+		if errors.Is(err, paymentgateway.ErrChargeItemsMismatch) {
+			log.Ctx(ctx).Warn().Err(err).Str("component", "chargeOrderPayment").Str("transaction_number", payment.TransactionNumber).Msg("Charging the order as one item")
+			chargeReq.Items = []paymentgateway.ChargeItem{{
+				ID:       "order-payment",
+				Name:     "Order payment",
+				Price:    chargeReq.GrossAmount,
+				Quantity: 1,
+			}}
+			err = chargeReq.Validate()
+		}
+		if err != nil {
+			s.cancelOrderPayments(ctx, saga, i)
+			return err
+		}
+
+		response, err := gateway.Charge(ctx, chargeReq)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "chargeOrderPayment").Str("gateway", gateway.Name()).Str("transaction_number", payment.TransactionNumber).Msg("")
+
+			// The request may have reached the gateway before failing, so it is
+			// voided along with the tenders charged before it right away. The
+			// compensation of the step voids them again, which is harmless.
+			s.cancelOrderPayments(ctx, saga, i+1)
+			return err
+		}
+
+		payment.QRCode = response.QRCode
+		payment.VANumber = response.VANumber
+		payment.ExpiredAt = response.ExpiredAt
+		if payment.ExpiredAt > saga.state.ExpiredAt {
+			saga.state.ExpiredAt = payment.ExpiredAt
+		}
+	}
+
+	return nil
+}
+
+// orderChargeItems lists what a tender pays for. An order paid with a single
+// tender is itemised, the tenders of a split order each pay a share of it.
+func orderChargeItems(saga *orderSaga, payment orderSagaPayment) []paymentgateway.ChargeItem {
+	currency := saga.state.currency()
+	if len(saga.state.Payments) > 1 {
+		return []paymentgateway.ChargeItem{{
+			ID:       "order-payment",
+			Name:     "Partial payment",
+			Price:    payment.Amount,
+			Quantity: 1,
+		}}
+	}
+
+	chargeItems := make([]paymentgateway.ChargeItem, len(saga.state.OrderItems))
+	for i, item := range saga.state.OrderItems {
+		chargeItems[i] = paymentgateway.ChargeItem{
+			ID:       item.ProductID,
+			Name:     item.ProductName,
+			Price:    item.Price,
+			Quantity: int32(item.Quantity),
+		}
+	}
+
+	// Discounts go in as items with a negative price, so the items still add
+	// up to the gross amount.
+	for _, discount := range saga.state.Discounts {
+		chargeItems = append(chargeItems, paymentgateway.ChargeItem{
+			ID:       fmt.Sprintf("promotion-%d", discount.PromotionID),
+			Name:     discount.Name,
+			Price:    money.New(-discount.Amount.Amount, currency),
+			Quantity: 1,
+		})
+	}
+
+	// Charges already in the item prices are left out, the rest go in as
+	// items of their own.
+	for i, charge := range saga.state.Charges {
+		added := money.New(charge.Amount.Amount-charge.IncludedAmount.Amount, currency)
+		if added.Amount <= 0 {
+			continue
+		}
+
+		chargeItems = append(chargeItems, paymentgateway.ChargeItem{
+			ID:       fmt.Sprintf("%s-%d", strings.ReplaceAll(charge.Type, "_", "-"), i+1),
+			Name:     charge.Name,
+			Price:    added,
+			Quantity: 1,
+		})
+	}
+
+	if !saga.state.RoundingAmount.IsZero() {
+		chargeItems = append(chargeItems, paymentgateway.ChargeItem{
+			ID:       "cash-rounding",
+			Name:     "Cash rounding",
+			Price:    money.New(saga.state.RoundingAmount.Amount, currency),
+			Quantity: 1,
+		})
+	}
+
+	return chargeItems
+}
+
+// allocateOrderPayments works out what each tender pays. The tender without a
+// requested amount takes what the others leave, otherwise the requested
+// amounts have to add up to the order total.
+func allocateOrderPayments(state *orderSagaState) error {
+	var requested int64
+	remainder := -1
+	for i, payment := range state.Payments {
+		if payment.RequestedAmount == nil {
+			remainder = i
+			continue
+		}
+
+		requested += payment.RequestedAmount.Amount
+		state.Payments[i].Amount = money.New(payment.RequestedAmount.Amount, state.currency())
+	}
+
+	left := state.TotalAmount.Amount - requested
+	if remainder >= 0 {
+		if left <= 0 {
+			return errs.ErrPaymentAmountMismatch
+		}
+
+		state.Payments[remainder].Amount = money.New(left, state.currency())
+		return nil
+	}
+
+	if left != 0 {
+		return errs.ErrPaymentAmountMismatch
+	}
+
+	return nil
+}
+
+func (s *OrderServiceImpl) cancelOrderPayment(ctx context.Context, repo repository.OrderRepository, saga *orderSaga) error {
+	return s.cancelOrderPayments(ctx, saga, len(saga.state.Payments))
+}
+
+// cancelOrderPayments voids the first count tenders of the order at their
+// gateways, by transaction number. A tender the gateway never received or
+// that is no longer open is left as it is, so a compensation can be repeated.
+// Every tender is tried, the first error is returned.
+func (s *OrderServiceImpl) cancelOrderPayments(ctx context.Context, saga *orderSaga, count int) (err error) {
+	for _, payment := range saga.state.Payments[:count] {
+		if payment.PaymentGateway == "" {
+			continue
+		}
+
+		gateway, getErr := s.paymentGateways.Get(&payment.PaymentGateway)
+		if getErr != nil {
+			if err == nil {
+				err = getErr
+			}
+			continue
+		}
+
+		cancelErr := gateway.Cancel(ctx, payment.TransactionNumber)
+		if errors.Is(cancelErr, paymentgateway.ErrTransactionNotFound) {
+			continue
+		}
+		if cancelErr != nil && paymentClosed(ctx, gateway, payment.TransactionNumber) {
+			continue
+		}
+		if cancelErr != nil {
+			log.Ctx(ctx).Error().Err(cancelErr).Str("component", "cancelOrderPayments").Str("transaction_number", payment.TransactionNumber).Msg("")
+			if err == nil {
+				err = cancelErr
+			}
+		}
+	}
+
+	return err
+}
+
+// paymentClosed reports whether the gateway reports a transaction as
+// cancelled, expired or failed, so there is nothing left to cancel.
+func paymentClosed(ctx context.Context, gateway paymentgateway.PaymentGateway, transactionNumber string) bool {
+	status, err := gateway.Status(ctx, transactionNumber)
+	if err != nil {
+		return false
+	}
+
+	orderStatus, _ := midtransOrderStatus(status.TransactionStatus, status.FraudStatus)
+	return orderStatus == domain.OrderStatusCancelled || orderStatus == domain.OrderStatusExpired || orderStatus == domain.OrderStatusFailed
+}
+
+func (s *OrderServiceImpl) createOrderRecords(ctx context.Context, repo repository.OrderRepository, saga *orderSaga) error {
+	err := s.addOrderCustomer(ctx, repo, saga)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	var customerID *int64
+	if saga.state.CustomerID != nil {
+		id := int64(*saga.state.CustomerID)
+		customerID = &id
+	}
+
+	var cashierID *int64
+	if saga.state.CashierID != 0 {
+		id := int64(saga.state.CashierID)
+		cashierID = &id
+	}
+
+	payments := saga.state.orderPayments(now)
+	var tenderedAmount, changeAmount *money.Money
+	for _, payment := range payments {
+		tenderedAmount = addAmount(tenderedAmount, payment.TenderedAmount)
+		changeAmount = addAmount(changeAmount, payment.ChangeAmount)
+	}
+
+	status := domain.OrderStatusFromPayments(saga.state.TotalAmount, payments)
+	var paidAt *int64
+	if status == domain.OrderStatusPaid {
+		paidAt = &now
+	}
+
+	// The shift is locked so it cannot be closed before the cash of the order
+	// is in its drawer.
+	if saga.state.ShiftID != nil && saga.state.takesCash() {
+		shift, err := repo.LockShiftByID(ctx, *saga.state.ShiftID)
+		if err != nil {
+			return err
+		}
+
+		if shift.Status != domain.ShiftStatusOpen {
+			return errs.ErrShiftClosed
+		}
+	}
+
+	primary := saga.state.Payments[0]
+	orderID, err := repo.AddOrder(ctx, domain.Order{
+		PaymentMethodID:     int64(primary.PaymentMethodID),
+		CustomerID:          customerID,
+		CashierID:           cashierID,
+		ShiftID:             saga.state.ShiftID,
+		StoreCode:           &saga.state.StoreCode,
+		PricesIncludeTax:    saga.state.PricesIncludeTax,
+		Currency:            saga.state.currency(),
+		Subtotal:            saga.state.Subtotal,
+		DiscountAmount:      saga.state.DiscountAmount,
+		ServiceChargeAmount: saga.state.ServiceChargeAmount,
+		TaxAmount:           saga.state.TaxAmount,
+		RoundingAmount:      saga.state.RoundingAmount,
+		Amount:              saga.state.TotalAmount,
+		TenderedAmount:      tenderedAmount,
+		ChangeAmount:        changeAmount,
+		MDRFee:              saga.state.MDRFee,
+		PaymentStatus:       status,
+		PaymentGateway:      primary.PaymentGateway,
+		TransactionNumber:   saga.TransactionNumber,
+		ExpiredAt:           saga.state.ExpiredAt,
+		PaidAt:              paidAt,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "createOrderRecords").Msg("")
+		return err
+	}
+
+	// The history starts with the status the order is placed in.
+	err = repo.AddOrderStatusHistory(ctx, domain.OrderStatusHistory{
+		OrderID:   orderID,
+		ToStatus:  status,
+		Source:    domain.OrderStatusSourceAPI,
+		ActorID:   cashierID,
+		Reason:    statusReason("Order placed"),
+		CreatedAt: now,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "createOrderRecords").Msg("")
+		return err
+	}
+
+	var orderDetails []domain.OrderDetail
+	for _, item := range saga.state.OrderItems {
+		orderDetails = append(orderDetails, domain.OrderDetail{
+			ProductID:           item.ProductID,
+			OrderID:             orderID,
+			Quantity:            int64(item.Quantity),
+			Amount:              item.Price,
+			DiscountAmount:      item.DiscountAmount,
+			ServiceChargeAmount: item.ServiceChargeAmount,
+			TaxAmount:           item.TaxAmount,
+			TotalAmount:         item.TotalAmount,
+			ProductName:         item.ProductName,
+			CreatedAt:           now,
+			UpdatedAt:           now,
+		})
+	}
+
+	err = repo.AddOrderDetails(ctx, orderDetails)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "createOrderRecords").Msg("")
+		return err
+	}
+
+	for _, payment := range payments {
+		payment.OrderID = orderID
+		payment.ID, err = repo.AddOrderPayment(ctx, payment)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "createOrderRecords").Msg("")
+			return err
+		}
+
+		if payment.CashierID != nil {
+			err = s.recordCashSale(ctx, repo, saga, payment)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = s.recordOrderDiscounts(ctx, repo, saga, orderID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "createOrderRecords").Msg("")
+		return err
+	}
+
+	err = s.recordOrderCharges(ctx, repo, saga, orderID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "createOrderRecords").Msg("")
+		return err
+	}
+
+	change := domain.OrderStatusChange{Source: domain.OrderStatusSourceAPI, ActorID: cashierID}
+	err = s.enqueueOrderEvent(ctx, repo, orderID, domain.OrderEventCreated, nil, change)
+	if err != nil {
+		return err
+	}
+
+	// Cash is settled at the counter, so an order may be placed paid, in part
+	// or in full.
+	eventType, ok := domain.OrderStatusEvent(status)
+	if ok {
+		err = s.enqueueOrderEvent(ctx, repo, orderID, eventType, nil, change)
+		if err != nil {
+			return err
+		}
+	}
+
+	saga.OrderID = &orderID
+
+	return nil
+}
+
+// orderPayments are the payment records of the tenders. Cash changes hands at
+// the counter, so cash tenders are settled as soon as the order is placed.
+func (state orderSagaState) orderPayments(now int64) []domain.OrderPayment {
+	payments := make([]domain.OrderPayment, len(state.Payments))
+	for i, payment := range state.Payments {
+		payments[i] = domain.OrderPayment{
+			PaymentMethodID:   int64(payment.PaymentMethodID),
+			PaymentGateway:    payment.PaymentGateway,
+			TransactionNumber: payment.TransactionNumber,
+			Amount:            payment.Amount,
+			MDRFee:            payment.MDRFee,
+			QRCode:            payment.QRCode,
+			VANumber:          payment.VANumber,
+			Status:            domain.OrderStatusPending,
+			ExpiredAt:         payment.ExpiredAt,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}
+
+		if payment.PaymentType == domain.PaymentTypeCash {
+			cashierID := int64(state.CashierID)
+			payments[i].TenderedAmount = payment.TenderedAmount
+			payments[i].ChangeAmount = payment.ChangeAmount
+			payments[i].CashierID = &cashierID
+			payments[i].Status = domain.OrderStatusPaid
+			payments[i].PaidAt = &now
+		}
+	}
+
+	return payments
+}
+
+// takesCash reports whether any of the tenders of the order is cash.
+func (state orderSagaState) takesCash() bool {
+	for _, payment := range state.Payments {
+		if payment.PaymentType == domain.PaymentTypeCash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addAmount adds amount to total, either of which may be missing.
+func addAmount(total *money.Money, amount *money.Money) *money.Money {
+	if amount == nil {
+		return total
+	}
+
+	sum := *amount
+	if total != nil {
+		sum.Amount += total.Amount
+	}
+
+	return &sum
+}
+
+// ResumeOrderSagas picks up sagas abandoned mid-flight, typically by a replica
+// that restarted. Sagas that already charged the customer are rolled forward
+// into a pending order, which the expiry job cleans up if it is never paid.
+// Everything else is compensated.
+func (s *OrderServiceImpl) ResumeOrderSagas() {
+	s.runLockedJob(orderSagaResumeJob, s.resumeOrderSagas)
+}
+
+func (s *OrderServiceImpl) resumeOrderSagas(ctx context.Context) error {
+	sagas, err := s.repository.GetStaleOrderSagas(ctx, time.Now().Add(-orderSagaStaleAfter).Unix(), orderSagaResumeBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, data := range sagas {
+		now := time.Now().Unix()
+		claimed, err := s.repository.ClaimOrderSaga(ctx, data, now)
+		if err != nil {
+			log.Error().Err(err).Str("component", "ResumeOrderSagas").Str("transaction_number", data.TransactionNumber).Msg("Failed to claim saga")
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+		data.UpdatedAt = now
+
+		saga := &orderSaga{OrderSaga: data}
+		err = json.Unmarshal([]byte(data.State), &saga.state)
+		if err != nil {
+			log.Error().Err(err).Str("component", "ResumeOrderSagas").Str("transaction_number", data.TransactionNumber).Msg("Failed to decode saga state")
+			continue
+		}
+
+		// The saga is claimed but left as it is, it goes stale again and is
+		// retried by a later run.
+		history, err := s.repository.GetOrderSagaSteps(ctx, data.ID)
+		if err != nil {
+			log.Error().Err(err).Str("component", "ResumeOrderSagas").Str("transaction_number", data.TransactionNumber).Msg("Failed to get saga steps")
+			continue
+		}
+
+		log.Info().Str("component", "ResumeOrderSagas").Str("transaction_number", data.TransactionNumber).Str("status", data.Status).Msg("Resuming order saga")
+		s.resumeOrderSaga(ctx, saga, history)
+	}
+
+	return nil
+}
+
+func (s *OrderServiceImpl) resumeOrderSaga(ctx context.Context, saga *orderSaga, history []domain.OrderSagaStep) {
+	steps := s.orderSagaSteps()
+	stepIndex := make(map[string]int, len(steps))
+	for i, step := range steps {
+		stepIndex[step.name] = i
+	}
+
+	// A step recorded as started but not as succeeded was cut off mid-flight,
+	// its remote part may or may not have happened.
+	executed, started := -1, -1
+	compensated := make(map[string]bool)
+	for _, entry := range history {
+		idx, ok := stepIndex[entry.Step]
+		if !ok {
+			continue
+		}
+
+		switch {
+		case entry.Action == domain.SagaActionCompensate && entry.Status == domain.SagaStepSucceeded:
+			compensated[entry.Step] = true
+		case entry.Action == domain.SagaActionExecute && entry.Status == domain.SagaStepSucceeded && idx > executed:
+			executed = idx
+		case entry.Action == domain.SagaActionExecute && entry.Status == domain.SagaStepStarted && idx > started:
+			started = idx
+		}
+	}
+
+	if saga.Status == domain.SagaStatusRunning && executed == stepIndex[orderSagaStepChargePayment] {
+		err := s.runOrderSaga(ctx, saga, executed+1)
+		if err != nil {
+			log.Error().Err(err).Str("component", "resumeOrderSaga").Str("transaction_number", saga.TransactionNumber).Msg("")
+		}
+		return
+	}
+
+	// The step cut off is compensated along with the ones before it. Its
+	// compensation goes by the transaction number, so it holds whether or
+	// not the step got through.
+	s.compensateOrderSaga(ctx, saga, max(executed, started), compensated)
+}
+
+func (s *OrderServiceImpl) GetOrderSaga(ctx context.Context, id string) (response dto.OrderSagaResponse, err error) {
+	transactionNumber := id
+	if orderID, parseErr := strconv.ParseInt(id, 10, 64); parseErr == nil {
+		order, err := s.repository.GetOrderByOrderID(ctx, orderID)
+		if err != nil {
+			return response, err
+		}
+
+		if order.ID == 0 {
+			return response, errs.ErrNotFound
+		}
+
+		transactionNumber = order.TransactionNumber
+	}
+
+	saga, err := s.repository.GetOrderSagaByTransactionNumber(ctx, transactionNumber)
+	if err != nil {
+		return
+	}
+
+	steps, err := s.repository.GetOrderSagaSteps(ctx, saga.ID)
+	if err != nil {
+		return
+	}
+
+	response.ID = saga.ID
+	response.TransactionNumber = saga.TransactionNumber
+	response.OrderID = saga.OrderID
+	response.Status = saga.Status
+	response.CurrentStep = saga.CurrentStep
+	response.LastError = saga.LastError
+	response.CreatedAt = saga.CreatedAt
+	response.UpdatedAt = saga.UpdatedAt
+
+	for _, step := range steps {
+		response.Steps = append(response.Steps, dto.OrderSagaStepResponse{
+			Step:      step.Step,
+			Action:    step.Action,
+			Status:    step.Status,
+			Error:     step.Error,
+			CreatedAt: step.CreatedAt,
+		})
+	}
+
+	return
+}
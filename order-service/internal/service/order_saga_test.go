@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/alimikegami/point-of-sales/order-service/config"
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	pb "github.com/alimikegami/pos-microservices/proto-defs/pb"
+	"github.com/sony/gobreaker/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// sagaRepository keeps a single saga, its log and the outbox in memory.
+type sagaRepository struct {
+	repository.OrderRepository
+	saga       domain.OrderSaga
+	steps      []domain.OrderSagaStep
+	outbox     []domain.OutboxMessage
+	takenOver  bool
+	staleSagas []domain.OrderSaga
+}
+
+func (r *sagaRepository) HandleTrx(ctx context.Context, fn func(ctx context.Context, repo repository.OrderRepository) error) error {
+	return fn(ctx, r)
+}
+
+func (r *sagaRepository) ClaimOrderSaga(ctx context.Context, data domain.OrderSaga, claimedAt int64) (bool, error) {
+	if r.takenOver {
+		return false, nil
+	}
+
+	r.saga.UpdatedAt = claimedAt
+	return true, nil
+}
+
+func (r *sagaRepository) UpdateOrderSaga(ctx context.Context, data domain.OrderSaga) error {
+	r.saga = data
+	return nil
+}
+
+func (r *sagaRepository) AddOrderSagaStep(ctx context.Context, data domain.OrderSagaStep) error {
+	r.steps = append(r.steps, data)
+	return nil
+}
+
+func (r *sagaRepository) GetOrderSagaSteps(ctx context.Context, sagaID int64) ([]domain.OrderSagaStep, error) {
+	return r.steps, nil
+}
+
+func (r *sagaRepository) GetStaleOrderSagas(ctx context.Context, before int64, limit int) ([]domain.OrderSaga, error) {
+	return r.staleSagas, nil
+}
+
+func (r *sagaRepository) AddOutboxMessage(ctx context.Context, data domain.OutboxMessage) error {
+	r.outbox = append(r.outbox, data)
+	return nil
+}
+
+func (r *sagaRepository) TryLockJob(ctx context.Context, job string) (func(), bool, error) {
+	return func() {}, true, nil
+}
+
+// stockReleases are the stock releases the saga queued, by reservation key.
+func (r *sagaRepository) stockReleases(t *testing.T) (keys []string) {
+	for _, message := range r.outbox {
+		if message.EventType != "restore_product_stock" {
+			continue
+		}
+
+		var payload struct {
+			Data dto.OrderProductServiceRequest `json:"data"`
+		}
+		err := json.Unmarshal([]byte(message.Payload), &payload)
+		if err != nil {
+			t.Fatalf("invalid outbox payload: %v", err)
+		}
+
+		keys = append(keys, payload.Data.ReservationKey)
+	}
+
+	return keys
+}
+
+// productCommandClient takes the stock and then returns err, as a call that
+// timed out after the product service went through with it would.
+type productCommandClient struct {
+	pb.ProductCommandServiceClient
+	reserved []string
+	err      error
+	// reserve runs once the stock is taken.
+	reserve func()
+}
+
+func (c *productCommandClient) UpdateProductQuantityBatch(ctx context.Context, in *pb.UpdateProductQuantityRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	c.reserved = append(c.reserved, in.ReservationKey)
+	if c.reserve != nil {
+		c.reserve()
+	}
+
+	return &emptypb.Empty{}, c.err
+}
+
+func createSagaTestService(repo *sagaRepository, products *productCommandClient) *OrderServiceImpl {
+	return &OrderServiceImpl{
+		repository:               repo,
+		config:                   &config.Config{},
+		productService:           gobreaker.NewCircuitBreaker[[]byte](gobreaker.Settings{}),
+		productCommandGrpcClient: products,
+	}
+}
+
+func orderSagaStepIndex(t *testing.T, s *OrderServiceImpl, name string) int {
+	for i, step := range s.orderSagaSteps() {
+		if step.name == name {
+			return i
+		}
+	}
+
+	t.Fatalf("unknown saga step %s", name)
+	return 0
+}
+
+func TestRunOrderSagaCompensatesFailedReservation(t *testing.T) {
+	repo := &sagaRepository{}
+	products := &productCommandClient{err: errors.New("context deadline exceeded")}
+	s := createSagaTestService(repo, products)
+
+	saga := &orderSaga{
+		OrderSaga: domain.OrderSaga{ID: 1, TransactionNumber: "trx-1", Status: domain.SagaStatusRunning},
+		state: orderSagaState{
+			OrderItems: []orderSagaItem{{ProductID: "a", Quantity: 2, Price: rupiah(10000)}},
+		},
+	}
+
+	err := s.runOrderSaga(context.Background(), saga, orderSagaStepIndex(t, s, orderSagaStepReserveStock))
+	if !errors.Is(err, products.err) {
+		t.Fatalf("expected the reservation error, got %v", err)
+	}
+
+	if len(products.reserved) != 1 {
+		t.Fatalf("expected one reservation, got %v", products.reserved)
+	}
+
+	releases := repo.stockReleases(t)
+	if len(releases) != 1 || releases[0] != saga.TransactionNumber {
+		t.Errorf("expected the stock reserved under %s to be released, got %v", saga.TransactionNumber, releases)
+	}
+
+	if saga.Status != domain.SagaStatusCompensated {
+		t.Errorf("expected saga %s, got %s", domain.SagaStatusCompensated, saga.Status)
+	}
+
+	var compensated bool
+	for _, step := range repo.steps {
+		if step.Step == orderSagaStepReserveStock && step.Action == domain.SagaActionCompensate && step.Status == domain.SagaStepSucceeded {
+			compensated = true
+		}
+	}
+	if !compensated {
+		t.Errorf("expected the reservation compensation in the saga log, got %+v", repo.steps)
+	}
+}
+
+// A replica that finds its saga taken over once a step went through undoes the
+// step itself, the replica that took over may have compensated it too early.
+func TestRunOrderSagaUndoesStepAfterTakeover(t *testing.T) {
+	repo := &sagaRepository{}
+	products := &productCommandClient{reserve: func() { repo.takenOver = true }}
+	s := createSagaTestService(repo, products)
+
+	saga := &orderSaga{
+		OrderSaga: domain.OrderSaga{ID: 1, TransactionNumber: "trx-1", Status: domain.SagaStatusRunning},
+		state: orderSagaState{
+			OrderItems: []orderSagaItem{{ProductID: "a", Quantity: 2, Price: rupiah(10000)}},
+		},
+	}
+
+	err := s.runOrderSaga(context.Background(), saga, orderSagaStepIndex(t, s, orderSagaStepReserveStock))
+	if !errors.Is(err, errOrderSagaTakenOver) {
+		t.Fatalf("expected %v, got %v", errOrderSagaTakenOver, err)
+	}
+
+	releases := repo.stockReleases(t)
+	if len(releases) != 1 || releases[0] != saga.TransactionNumber {
+		t.Errorf("expected the stock reserved under %s to be released, got %v", saga.TransactionNumber, releases)
+	}
+
+	for _, step := range repo.steps {
+		if step.Action == domain.SagaActionCompensate || step.Status != domain.SagaStepStarted {
+			t.Errorf("expected nothing recorded on the saga after the takeover, got %+v", step)
+		}
+	}
+}
+
+func TestResumeOrderSagas(t *testing.T) {
+	executed := func(step string, status string) domain.OrderSagaStep {
+		return domain.OrderSagaStep{SagaID: 1, Step: step, Action: domain.SagaActionExecute, Status: status}
+	}
+
+	type TestCase struct {
+		Name             string
+		History          []domain.OrderSagaStep
+		ExpectedReleases int
+	}
+
+	testCases := []TestCase{
+		{
+			Name: "Cut off while reserving stock",
+			History: []domain.OrderSagaStep{
+				executed(orderSagaStepLookupPrices, domain.SagaStepSucceeded),
+				executed(orderSagaStepApplyPromos, domain.SagaStepSucceeded),
+				executed(orderSagaStepApplyTaxes, domain.SagaStepSucceeded),
+				executed(orderSagaStepReserveStock, domain.SagaStepStarted),
+			},
+			ExpectedReleases: 1,
+		},
+		{
+			Name: "Cut off while charging",
+			History: []domain.OrderSagaStep{
+				executed(orderSagaStepReserveStock, domain.SagaStepStarted),
+				executed(orderSagaStepReserveStock, domain.SagaStepSucceeded),
+				executed(orderSagaStepChargePayment, domain.SagaStepStarted),
+			},
+			ExpectedReleases: 1,
+		},
+		{
+			Name: "Stock already released",
+			History: []domain.OrderSagaStep{
+				executed(orderSagaStepReserveStock, domain.SagaStepStarted),
+				{SagaID: 1, Step: orderSagaStepReserveStock, Action: domain.SagaActionCompensate, Status: domain.SagaStepSucceeded},
+			},
+			ExpectedReleases: 0,
+		},
+		{
+			Name: "Cut off before anything to undo",
+			History: []domain.OrderSagaStep{
+				executed(orderSagaStepLookupPrices, domain.SagaStepSucceeded),
+			},
+			ExpectedReleases: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			state, err := json.Marshal(orderSagaState{
+				OrderItems: []orderSagaItem{{ProductID: "a", Quantity: 2, Price: rupiah(10000)}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stale := domain.OrderSaga{ID: 1, TransactionNumber: "trx-1", Status: domain.SagaStatusRunning, State: string(state)}
+			repo := &sagaRepository{saga: stale, steps: tc.History, staleSagas: []domain.OrderSaga{stale}}
+			s := createSagaTestService(repo, &productCommandClient{})
+
+			s.ResumeOrderSagas()
+
+			if releases := repo.stockReleases(t); len(releases) != tc.ExpectedReleases {
+				t.Errorf("expected %d stock releases, got %v", tc.ExpectedReleases, releases)
+			}
+
+			if repo.saga.Status != domain.SagaStatusCompensated {
+				t.Errorf("expected saga %s, got %s", domain.SagaStatusCompensated, repo.saga.Status)
+			}
+		})
+	}
+}
+
+func TestAllocateOrderPayments(t *testing.T) {
+	requested := func(amount int64) *money.Money {
+		m := rupiah(amount)
+		return &m
+	}
+
+	type TestCase struct {
+		Name            string
+		Total           money.Money
+		Requested       []*money.Money
+		ExpectedAmounts []money.Money
+		ExpectedErr     error
+	}
+
+	testCases := []TestCase{
+		{
+			Name:            "Single tender takes the total",
+			Total:           rupiah(50000),
+			Requested:       []*money.Money{nil},
+			ExpectedAmounts: []money.Money{rupiah(50000)},
+		},
+		{
+			Name:            "Remainder tender takes what is left",
+			Total:           rupiah(50000),
+			Requested:       []*money.Money{requested(20000), nil},
+			ExpectedAmounts: []money.Money{rupiah(20000), rupiah(30000)},
+		},
+		{
+			Name:            "Remainder tender first",
+			Total:           rupiah(50000),
+			Requested:       []*money.Money{nil, requested(15000), requested(5000)},
+			ExpectedAmounts: []money.Money{rupiah(30000), rupiah(15000), rupiah(5000)},
+		},
+		{
+			Name:            "Requested amounts add up to the total",
+			Total:           rupiah(50000),
+			Requested:       []*money.Money{requested(20000), requested(30000)},
+			ExpectedAmounts: []money.Money{rupiah(20000), rupiah(30000)},
+		},
+		{
+			Name:        "Requested amounts short of the total",
+			Total:       rupiah(50000),
+			Requested:   []*money.Money{requested(20000), requested(20000)},
+			ExpectedErr: errs.ErrPaymentAmountMismatch,
+		},
+		{
+			Name:        "Requested amounts over the total",
+			Total:       rupiah(50000),
+			Requested:   []*money.Money{requested(20000), requested(40000)},
+			ExpectedErr: errs.ErrPaymentAmountMismatch,
+		},
+		{
+			Name:        "Nothing left for the remainder tender",
+			Total:       rupiah(50000),
+			Requested:   []*money.Money{requested(50000), nil},
+			ExpectedErr: errs.ErrPaymentAmountMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			state := orderSagaState{Currency: money.IDR, TotalAmount: tc.Total}
+			for _, amount := range tc.Requested {
+				state.Payments = append(state.Payments, orderSagaPayment{RequestedAmount: amount})
+			}
+
+			err := allocateOrderPayments(&state)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			for i, payment := range state.Payments {
+				if payment.Amount != tc.ExpectedAmounts[i] {
+					t.Errorf("expected tender %d to pay %s, got %s", i, tc.ExpectedAmounts[i], payment.Amount)
+				}
+			}
+		})
+	}
+}
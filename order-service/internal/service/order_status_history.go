@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+)
+
+// GetOrderStatusHistory lists the statuses the order went through, oldest
+// first.
+func (s *OrderServiceImpl) GetOrderStatusHistory(ctx context.Context, id int64) (response []dto.OrderStatusHistoryResponse, err error) {
+	order, err := s.repository.GetOrderByOrderID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	if order.ID == 0 {
+		return nil, errs.ErrNotFound
+	}
+
+	histories, err := s.repository.GetOrderStatusHistoriesByOrderID(ctx, order.ID)
+	if err != nil {
+		return
+	}
+
+	response = []dto.OrderStatusHistoryResponse{}
+	for _, history := range histories {
+		response = append(response, dto.OrderStatusHistoryResponse{
+			ID:         history.ID,
+			FromStatus: history.FromStatus,
+			ToStatus:   history.ToStatus,
+			Source:     history.Source,
+			ActorID:    history.ActorID,
+			Reason:     history.Reason,
+			CreatedAt:  history.CreatedAt,
+		})
+	}
+
+	return
+}
+
+// statusReason is reason as it is recorded in the status history, where an
+// empty reason is left out.
+func statusReason(reason string) *string {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil
+	}
+
+	return &reason
+}
+
+// midtransStatusReason describes the notification that changed the order.
+func midtransStatusReason(req dto.PaymentNotification) string {
+	reason := "Midtrans " + req.TransactionStatus + " notification for " + req.OrderID
+	if req.FraudStatus != "" {
+		reason += ", fraud status " + req.FraudStatus
+	}
+
+	return reason
+}
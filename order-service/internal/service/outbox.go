@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/metrics"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// outboxRelayJob names the lock and the metrics of the relay.
+	outboxRelayJob       = "relay_outbox"
+	outboxRelayBatchSize = 100
+	outboxMaxBackoff     = 5 * time.Minute
+	// outboxLease is how long a claimed batch is left to the relay that
+	// claimed it, and how long that relay spends publishing it.
+	outboxLease = time.Minute
+)
+
+// enqueueKafkaMessage stages msg in the outbox instead of writing it to Kafka.
+// repo must be the repository handed out by HandleTrx so the message is only
+// relayed once the surrounding changes are committed.
+func (s *OrderServiceImpl) enqueueKafkaMessage(ctx context.Context, repo repository.OrderRepository, key string, msg dto.KafkaMessage) error {
+	return s.enqueueOutboxMessage(ctx, repo, s.config.KafkaConfig.BrokerTopic, key, msg.EventType, msg)
+}
+
+// enqueueOutboxMessage stages payload in the outbox, to be published on topic.
+func (s *OrderServiceImpl) enqueueOutboxMessage(ctx context.Context, repo repository.OrderRepository, topic string, key string, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error marshalling %s message: %w", eventType, err)
+	}
+
+	now := time.Now().Unix()
+
+	return repo.AddOutboxMessage(ctx, domain.OutboxMessage{
+		Topic:         topic,
+		MessageKey:    key,
+		EventType:     eventType,
+		Payload:       string(data),
+		Status:        domain.OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+}
+
+// RelayOutboxMessages publishes due outbox messages in insertion order. A
+// message is marked sent only after Kafka acknowledged it, so consumers may see
+// a message more than once but never miss one.
+//
+// Messages of a key are published one after the other: a message that failed
+// holds back the later ones of its key until it is published, so they are
+// never seen ahead of it. Every replica schedules the relay, but only the one
+// holding its advisory lock runs it.
+func (s *OrderServiceImpl) RelayOutboxMessages() {
+	s.runLockedJob(outboxRelayJob, s.relayOutboxMessages)
+}
+
+// relayOutboxMessages claims a batch, publishes it without holding a
+// transaction, and records every message's outcome as it goes. Publishing
+// stops at the end of the lease, after which another run may claim the
+// messages again.
+func (s *OrderServiceImpl) relayOutboxMessages(ctx context.Context) error {
+	messages, err := s.repository.ClaimOutboxMessages(ctx, outboxRelayBatchSize, time.Now().Add(outboxLease).Unix())
+	if err != nil {
+		return err
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, outboxLease)
+	defer cancel()
+
+	err = relayOutboxBatch(ctx, s.repository, messages, func(message domain.OutboxMessage) error {
+		return s.publishOutboxMessage(publishCtx, message)
+	})
+	if err != nil {
+		return err
+	}
+
+	backlog, err := s.repository.CountPendingOutboxMessages(ctx)
+	if err != nil {
+		return err
+	}
+
+	metrics.OutboxPendingMessages.Set(float64(backlog))
+
+	return nil
+}
+
+// relayOutboxBatch publishes the claimed messages in order. The later messages
+// of a key whose message failed are released rather than published, and wait
+// behind it for its next attempt.
+func relayOutboxBatch(ctx context.Context, repo repository.OrderRepository, messages []domain.OutboxMessage, publish func(message domain.OutboxMessage) error) error {
+	// failedKeys are the keys whose oldest message failed in this run.
+	failedKeys := map[string]bool{}
+	for _, message := range messages {
+		now := time.Now()
+		if failedKeys[message.MessageKey] {
+			message.NextAttemptAt = now.Unix()
+			message.UpdatedAt = now.Unix()
+
+			err := repo.RescheduleOutboxMessage(ctx, message)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err := publish(message)
+		if err != nil {
+			metrics.OutboxPublishFailures.Inc()
+			log.Error().Err(err).Str("component", "RelayOutboxMessages").Int64("outbox_id", message.ID).Msg("Failed to publish outbox message")
+
+			lastError := err.Error()
+			message.Attempts++
+			message.LastError = &lastError
+			message.NextAttemptAt = now.Add(outboxBackoff(message.Attempts)).Unix()
+			message.UpdatedAt = now.Unix()
+
+			failedKeys[message.MessageKey] = true
+			err = repo.RescheduleOutboxMessage(ctx, message)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err = repo.MarkOutboxMessageSent(ctx, message.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publishOutboxMessage writes the message to its topic. Order events go
+// through a writer of their own, which spreads them over the partitions of
+// their topic by key.
+func (s *OrderServiceImpl) publishOutboxMessage(ctx context.Context, message domain.OutboxMessage) error {
+	if message.Topic == s.config.KafkaConfig.OrderEventTopic {
+		return s.orderEventWriter.WriteMessages(ctx, kafka.Message{
+			Key:   []byte(message.MessageKey),
+			Value: []byte(message.Payload),
+			Headers: []kafka.Header{
+				{Key: "event_type", Value: []byte(message.EventType)},
+			},
+		})
+	}
+
+	// The connection takes no context, the deadline of ctx bounds the write
+	// instead.
+	deadline, _ := ctx.Deadline()
+	err := s.kafkaProducer.SetWriteDeadline(deadline)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.kafkaProducer.WriteMessages(kafka.Message{
+		Key:   []byte(message.MessageKey),
+		Value: []byte(message.Payload),
+	})
+	return err
+}
+
+// outboxBackoff doubles the delay for every failed attempt, starting at one
+// second and capped at outboxMaxBackoff.
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 16 {
+		return outboxMaxBackoff
+	}
+
+	backoff := time.Second * time.Duration(1<<(attempts-1))
+	if backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+
+	return backoff
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+)
+
+// outboxRepository records what the relay does with the claimed messages.
+type outboxRepository struct {
+	repository.OrderRepository
+	sent        []int64
+	rescheduled []domain.OutboxMessage
+}
+
+func (r *outboxRepository) MarkOutboxMessageSent(ctx context.Context, id int64) error {
+	r.sent = append(r.sent, id)
+	return nil
+}
+
+func (r *outboxRepository) RescheduleOutboxMessage(ctx context.Context, data domain.OutboxMessage) error {
+	r.rescheduled = append(r.rescheduled, data)
+	return nil
+}
+
+func TestRelayOutboxBatch(t *testing.T) {
+	type TestCase struct {
+		Name              string
+		Messages          []domain.OutboxMessage
+		Failing           []int64
+		ExpectedPublished []int64
+		ExpectedSent      []int64
+		// ExpectedRetried are the failed messages, rescheduled with one more
+		// attempt, and ExpectedReleased the ones held back behind them.
+		ExpectedRetried  []int64
+		ExpectedReleased []int64
+	}
+
+	testCases := []TestCase{
+		{
+			Name: "Every message published",
+			Messages: []domain.OutboxMessage{
+				{ID: 1, MessageKey: "a"},
+				{ID: 2, MessageKey: "b"},
+				{ID: 3, MessageKey: "a"},
+			},
+			ExpectedPublished: []int64{1, 2, 3},
+			ExpectedSent:      []int64{1, 2, 3},
+		},
+		{
+			Name: "Failed message holds back the later ones of its key",
+			Messages: []domain.OutboxMessage{
+				{ID: 1, MessageKey: "a"},
+				{ID: 2, MessageKey: "b"},
+				{ID: 3, MessageKey: "a"},
+				{ID: 4, MessageKey: "a"},
+				{ID: 5, MessageKey: "b"},
+			},
+			Failing:           []int64{1},
+			ExpectedPublished: []int64{1, 2, 5},
+			ExpectedSent:      []int64{2, 5},
+			ExpectedRetried:   []int64{1},
+			ExpectedReleased:  []int64{3, 4},
+		},
+		{
+			Name: "Messages before the failure stay sent",
+			Messages: []domain.OutboxMessage{
+				{ID: 1, MessageKey: "a"},
+				{ID: 2, MessageKey: "a"},
+				{ID: 3, MessageKey: "a"},
+			},
+			Failing:           []int64{2},
+			ExpectedPublished: []int64{1, 2},
+			ExpectedSent:      []int64{1},
+			ExpectedRetried:   []int64{2},
+			ExpectedReleased:  []int64{3},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			repo := &outboxRepository{}
+			var published []int64
+			start := time.Now().Unix()
+
+			err := relayOutboxBatch(context.Background(), repo, tc.Messages, func(message domain.OutboxMessage) error {
+				published = append(published, message.ID)
+				if slices.Contains(tc.Failing, message.ID) {
+					return errors.New("broker unavailable")
+				}
+
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(published, tc.ExpectedPublished) {
+				t.Errorf("expected published %v, got %v", tc.ExpectedPublished, published)
+			}
+
+			if !slices.Equal(repo.sent, tc.ExpectedSent) {
+				t.Errorf("expected sent %v, got %v", tc.ExpectedSent, repo.sent)
+			}
+
+			var retried, released []int64
+			for _, message := range repo.rescheduled {
+				if message.Attempts == 0 {
+					released = append(released, message.ID)
+					if message.NextAttemptAt < start || message.NextAttemptAt > time.Now().Unix() {
+						t.Errorf("expected held back message %d to be released now, got %d", message.ID, message.NextAttemptAt)
+					}
+					continue
+				}
+
+				retried = append(retried, message.ID)
+				if message.Attempts != 1 || message.LastError == nil || message.NextAttemptAt <= start {
+					t.Errorf("expected message %d to be retried later with its error, got %+v", message.ID, message)
+				}
+			}
+
+			if !slices.Equal(retried, tc.ExpectedRetried) {
+				t.Errorf("expected retried %v, got %v", tc.ExpectedRetried, retried)
+			}
+
+			if !slices.Equal(released, tc.ExpectedReleased) {
+				t.Errorf("expected released %v, got %v", tc.ExpectedReleased, released)
+			}
+		})
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Attempts int
+		Expected time.Duration
+	}
+
+	testCases := []TestCase{
+		{Name: "First retry", Attempts: 1, Expected: time.Second},
+		{Name: "Doubles", Attempts: 4, Expected: 8 * time.Second},
+		{Name: "Capped", Attempts: 10, Expected: outboxMaxBackoff},
+		{Name: "Capped without overflowing", Attempts: 100, Expected: outboxMaxBackoff},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if backoff := outboxBackoff(tc.Attempts); backoff != tc.Expected {
+				t.Errorf("expected %s, got %s", tc.Expected, backoff)
+			}
+		})
+	}
+}
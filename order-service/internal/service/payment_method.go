@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	paymentgateway "github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/payment-gateway"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+)
+
+func (s *OrderServiceImpl) AddPaymentMethod(ctx context.Context, req dto.PaymentMethodRequest) (response dto.PaymentMethodResponse, err error) {
+	paymentMethod, err := s.paymentMethodFromRequest(req, true)
+	if err != nil {
+		return
+	}
+
+	now := time.Now().Unix()
+	paymentMethod.CreatedAt = now
+	paymentMethod.UpdatedAt = now
+
+	paymentMethod.ID, err = s.repository.AddPaymentMethod(ctx, paymentMethod)
+	if err != nil {
+		return
+	}
+
+	return paymentMethodResponse(paymentMethod), nil
+}
+
+func (s *OrderServiceImpl) GetPaymentMethods(ctx context.Context) (response []dto.PaymentMethodResponse, err error) {
+	paymentMethods, err := s.repository.GetPaymentMethods(ctx, false)
+	if err != nil {
+		return
+	}
+
+	response = []dto.PaymentMethodResponse{}
+	for _, paymentMethod := range paymentMethods {
+		response = append(response, paymentMethodResponse(paymentMethod))
+	}
+
+	return
+}
+
+// GetActivePaymentMethods lists the methods the POS may offer at checkout.
+func (s *OrderServiceImpl) GetActivePaymentMethods(ctx context.Context) (response []dto.PublicPaymentMethodResponse, err error) {
+	paymentMethods, err := s.repository.GetPaymentMethods(ctx, true)
+	if err != nil {
+		return
+	}
+
+	response = []dto.PublicPaymentMethodResponse{}
+	for _, paymentMethod := range paymentMethods {
+		response = append(response, dto.PublicPaymentMethodResponse{
+			ID:          paymentMethod.ID,
+			Name:        paymentMethod.Name,
+			Channel:     paymentMethod.Channel,
+			ImgURL:      paymentMethod.ImgURL,
+			PaymentType: paymentMethod.PaymentType,
+		})
+	}
+
+	return
+}
+
+func (s *OrderServiceImpl) GetPaymentMethod(ctx context.Context, id uint64) (response dto.PaymentMethodResponse, err error) {
+	paymentMethod, err := s.getPaymentMethod(ctx, id)
+	if err != nil {
+		return
+	}
+
+	return paymentMethodResponse(paymentMethod), nil
+}
+
+func (s *OrderServiceImpl) UpdatePaymentMethod(ctx context.Context, id uint64, req dto.PaymentMethodRequest) (response dto.PaymentMethodResponse, err error) {
+	existing, err := s.getPaymentMethod(ctx, id)
+	if err != nil {
+		return
+	}
+
+	paymentMethod, err := s.paymentMethodFromRequest(req, existing.IsActive)
+	if err != nil {
+		return
+	}
+
+	paymentMethod.ID = existing.ID
+	paymentMethod.CreatedAt = existing.CreatedAt
+	paymentMethod.UpdatedAt = time.Now().Unix()
+
+	err = s.repository.UpdatePaymentMethod(ctx, paymentMethod)
+	if err != nil {
+		return
+	}
+
+	return paymentMethodResponse(paymentMethod), nil
+}
+
+// SetPaymentMethodActive enables or disables the method, leaving the rest of
+// its settings as they are.
+func (s *OrderServiceImpl) SetPaymentMethodActive(ctx context.Context, id uint64, active bool) (response dto.PaymentMethodResponse, err error) {
+	paymentMethod, err := s.getPaymentMethod(ctx, id)
+	if err != nil {
+		return
+	}
+
+	paymentMethod.IsActive = active
+	paymentMethod.UpdatedAt = time.Now().Unix()
+
+	err = s.repository.UpdatePaymentMethod(ctx, paymentMethod)
+	if err != nil {
+		return
+	}
+
+	return paymentMethodResponse(paymentMethod), nil
+}
+
+func (s *OrderServiceImpl) DeletePaymentMethod(ctx context.Context, id uint64) (err error) {
+	_, err = s.getPaymentMethod(ctx, id)
+	if err != nil {
+		return
+	}
+
+	return s.repository.DeletePaymentMethod(ctx, id, time.Now().Unix())
+}
+
+// getPaymentMethod looks up a payment method that has not been deleted.
+func (s *OrderServiceImpl) getPaymentMethod(ctx context.Context, id uint64) (paymentMethod domain.PaymentMethod, err error) {
+	paymentMethod, err = s.repository.GetPaymentMethodByID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	if paymentMethod.DeletedAt != nil {
+		return paymentMethod, errs.ErrNotFound
+	}
+
+	return
+}
+
+// availablePaymentMethod looks up the method an order is paid with, which
+// has to exist and be enabled.
+func (s *OrderServiceImpl) availablePaymentMethod(ctx context.Context, id uint64) (paymentMethod domain.PaymentMethod, err error) {
+	paymentMethod, err = s.repository.GetPaymentMethodByID(ctx, id)
+	if errors.Is(err, errs.ErrNotFound) {
+		return paymentMethod, errs.ErrPaymentMethodUnavailable
+	}
+	if err != nil {
+		return
+	}
+
+	if !paymentMethod.Available() {
+		return paymentMethod, errs.ErrPaymentMethodUnavailable
+	}
+
+	return
+}
+
+func (s *OrderServiceImpl) paymentMethodFromRequest(req dto.PaymentMethodRequest, defaultActive bool) (paymentMethod domain.PaymentMethod, err error) {
+	paymentMethod = domain.PaymentMethod{
+		Name:        strings.TrimSpace(req.Name),
+		Channel:     trimmedOrNil(req.Channel),
+		MDR:         req.MDR,
+		MDRType:     req.MDRType,
+		ImgURL:      trimmedOrNil(req.ImgURL),
+		Gateway:     trimmedOrNil(req.Gateway),
+		PaymentType: strings.TrimSpace(req.PaymentType),
+		IsActive:    defaultActive,
+	}
+
+	if req.IsActive != nil {
+		paymentMethod.IsActive = *req.IsActive
+	}
+
+	if paymentMethod.Name == "" || paymentMethod.PaymentType == "" || paymentMethod.MDR < 0 {
+		return paymentMethod, errs.ErrClient
+	}
+
+	switch paymentMethod.MDRType {
+	case domain.MDRTypePercentage:
+		if paymentMethod.MDR > 100 {
+			return paymentMethod, errs.ErrClient
+		}
+	case domain.MDRTypeFlat:
+	default:
+		return paymentMethod, errs.ErrClient
+	}
+
+	// Cash never goes through a provider, whatever gateway was asked for.
+	if paymentMethod.PaymentType == domain.PaymentTypeCash {
+		gateway := paymentgateway.GatewayCash
+		paymentMethod.Gateway = &gateway
+	}
+
+	// Orders cannot be charged through a gateway this service has no client
+	// for.
+	_, err = s.paymentGateways.Get(paymentMethod.Gateway)
+	if err != nil {
+		return paymentMethod, errs.ErrClient
+	}
+
+	return paymentMethod, nil
+}
+
+func paymentMethodResponse(paymentMethod domain.PaymentMethod) dto.PaymentMethodResponse {
+	return dto.PaymentMethodResponse{
+		ID:          paymentMethod.ID,
+		Name:        paymentMethod.Name,
+		Channel:     paymentMethod.Channel,
+		MDR:         paymentMethod.MDR,
+		MDRType:     paymentMethod.MDRType,
+		ImgURL:      paymentMethod.ImgURL,
+		Gateway:     paymentMethod.Gateway,
+		PaymentType: paymentMethod.PaymentType,
+		IsActive:    paymentMethod.IsActive,
+		CreatedAt:   paymentMethod.CreatedAt,
+		UpdatedAt:   paymentMethod.UpdatedAt,
+	}
+}
+
+func trimmedOrNil(value *string) *string {
+	if value == nil {
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+
+	return &trimmed
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// midtransOrderStatus maps a Midtrans transaction_status and fraud_status pair
+// onto the order status it leads to. ok is false for statuses that do not
+// change the order, such as pending.
+func midtransOrderStatus(transactionStatus, fraudStatus string) (status string, ok bool) {
+	switch transactionStatus {
+	case "capture", "settlement":
+		if fraudStatus == "challenge" {
+			return domain.OrderStatusChallenge, true
+		}
+		if fraudStatus == "deny" {
+			return domain.OrderStatusFailed, true
+		}
+		return domain.OrderStatusPaid, true
+	case "deny", "failure":
+		return domain.OrderStatusFailed, true
+	case "cancel":
+		return domain.OrderStatusCancelled, true
+	case "expire":
+		return domain.OrderStatusExpired, true
+	case "refund":
+		return domain.OrderStatusRefunded, true
+	case "partial_refund":
+		return domain.OrderStatusPartiallyRefunded, true
+	}
+
+	return "", false
+}
+
+// midtransPaidAt prefers settlement_time and falls back to transaction_time,
+// which is the only timestamp card captures carry.
+func midtransPaidAt(req dto.PaymentNotification) *int64 {
+	for _, datetime := range []string{req.SettlementTime, req.TransactionTime} {
+		if datetime == "" {
+			continue
+		}
+
+		paidAt, err := utils.ConvertDateTimeWibToUnixTimestamp(datetime)
+		if err == nil {
+			return &paidAt
+		}
+	}
+
+	now := time.Now().Unix()
+	return &now
+}
+
+// transitionOrder moves order from the status it was read with to status,
+// records the change in its history and performs the side effects of entering
+// status in the same transaction. It returns false when the order is no
+// longer in the status it was read with.
+func (s *OrderServiceImpl) transitionOrder(ctx context.Context, repo repository.OrderRepository, order domain.Order, status string, paidAt *int64, change domain.OrderStatusChange) (updated bool, err error) {
+	if !domain.CanTransitionOrderStatus(order.PaymentStatus, status) {
+		return false, fmt.Errorf("order %d cannot move from %s to %s", order.ID, order.PaymentStatus, status)
+	}
+
+	from := order.PaymentStatus
+	order.PaymentStatus = status
+	order.PaidAt = paidAt
+	order.UpdatedAt = time.Now().Unix()
+
+	updated, err = repo.TransitionOrderPaymentStatus(ctx, order, []string{from})
+	if err != nil || !updated {
+		return updated, err
+	}
+
+	err = repo.AddOrderStatusHistory(ctx, domain.OrderStatusHistory{
+		OrderID:    order.ID,
+		FromStatus: &from,
+		ToStatus:   status,
+		Source:     change.Source,
+		ActorID:    change.ActorID,
+		Reason:     change.Reason,
+		CreatedAt:  order.UpdatedAt,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	eventType, ok := domain.OrderStatusEvent(status)
+	if ok {
+		err = s.enqueueOrderEvent(ctx, repo, order.ID, eventType, &from, change)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if domain.OrderStatusHoldsStock(from) && !domain.OrderStatusHoldsStock(status) {
+		err = s.enqueueStockRestoration(ctx, repo, order)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if status == domain.OrderStatusChallenge {
+		_, err = repo.AddPaymentReview(ctx, domain.PaymentReview{
+			OrderID:   order.ID,
+			Reason:    domain.PaymentReviewReasonFraudChallenge,
+			Status:    domain.PaymentReviewStatusOpen,
+			CreatedAt: order.UpdatedAt,
+			UpdatedAt: order.UpdatedAt,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// handleLateSettlement deals with a payment settled after the expiry job gave
+// the order's items back. The items are reserved again when they are still in
+// stock, otherwise the payment is refunded. An error leaves the order to the
+// review staged for it.
+func (s *OrderServiceImpl) handleLateSettlement(ctx context.Context, order domain.Order, paidAt *int64, change domain.OrderStatusChange) error {
+	err := s.reserveOrderDetailsStock(ctx, order)
+	if err == nil {
+		log.Ctx(ctx).Info().Str("component", "handleLateSettlement").Str("transaction_number", order.TransactionNumber).Msg("Reserved stock again for a late settlement")
+		err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+			updated, err := s.transitionOrder(ctx, repo, order, domain.OrderStatusPaid, paidAt, change)
+			if err != nil {
+				return err
+			}
+
+			// The order moved on in the meantime, give back what was just
+			// reserved for it.
+			if !updated {
+				return s.enqueueStockRestoration(ctx, repo, order)
+			}
+
+			return nil
+		})
+		if err != nil {
+			s.restoreReservedStock(ctx, order)
+		}
+
+		return err
+	}
+
+	log.Ctx(ctx).Warn().Err(err).Str("component", "handleLateSettlement").Str("transaction_number", order.TransactionNumber).Msg("Refunding a late settlement that can no longer be fulfilled")
+
+	err = s.releaseOrderPayments(ctx, order, "late-settlement", "Payment settled after the order expired")
+	if err != nil {
+		return err
+	}
+
+	return s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		change.Reason = statusReason("Payment settled after the order expired could not be fulfilled")
+		_, err := s.transitionOrder(ctx, repo, order, domain.OrderStatusRefunded, nil, change)
+		return err
+	})
+}
+
+// reserveOrderDetailsStock reserves the items of a placed order again, once
+// its stock was given back.
+func (s *OrderServiceImpl) reserveOrderDetailsStock(ctx context.Context, order domain.Order) error {
+	orderDetails, err := s.repository.GetOrderDetailsByOrderID(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	var items []dto.OrderItem
+	for _, detail := range orderDetails {
+		items = append(items, dto.OrderItem{
+			ProductID: detail.ProductID,
+			Quantity:  int(detail.Quantity),
+		})
+	}
+
+	return s.reserveStock(ctx, "", items)
+}
+
+// restoreReservedStock gives back the stock reserved again for an order that
+// was not paid after all.
+func (s *OrderServiceImpl) restoreReservedStock(ctx context.Context, order domain.Order) {
+	err := s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		return s.enqueueStockRestoration(ctx, repo, order)
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "restoreReservedStock").Str("transaction_number", order.TransactionNumber).Msg("Failed to give back reserved stock")
+	}
+}
+
+// releaseOrderPaymentsOrReview releases the payments of an order that did not
+// go through, and leaves it to a reviewer when that fails.
+func (s *OrderServiceImpl) releaseOrderPaymentsOrReview(ctx context.Context, order domain.Order, refundKey string, reason string) error {
+	err := s.releaseOrderPayments(ctx, order, refundKey, reason)
+	if err == nil {
+		return nil
+	}
+
+	log.Ctx(ctx).Error().Err(err).Str("component", "releaseOrderPaymentsOrReview").Str("transaction_number", order.TransactionNumber).Msg("Failed to release order payments")
+
+	note := err.Error()
+	now := time.Now().Unix()
+	_, err = s.repository.AddPaymentReview(ctx, domain.PaymentReview{
+		OrderID:   order.ID,
+		Reason:    domain.PaymentReviewReasonUnreleasedPayment,
+		Status:    domain.PaymentReviewStatusOpen,
+		Note:      &note,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	return err
+}
+
+func (s *OrderServiceImpl) GetPaymentReviews(ctx context.Context) (response []dto.PaymentReviewResponse, err error) {
+	reviews, err := s.repository.GetPaymentReviews(ctx, domain.PaymentReviewStatusOpen)
+	if err != nil {
+		return
+	}
+
+	for _, review := range reviews {
+		order, err := s.repository.GetOrderByOrderID(ctx, review.OrderID)
+		if err != nil {
+			return nil, err
+		}
+
+		response = append(response, dto.PaymentReviewResponse{
+			ID:                review.ID,
+			OrderID:           review.OrderID,
+			TransactionNumber: order.TransactionNumber,
+			TransactionAmount: order.Amount.Float64(),
+			PaymentStatus:     order.PaymentStatus,
+			Reason:            review.Reason,
+			Status:            review.Status,
+			Decision:          review.Decision,
+			Note:              review.Note,
+			CreatedAt:         review.CreatedAt,
+			ResolvedAt:        review.ResolvedAt,
+		})
+	}
+
+	return
+}
+
+// paymentReviewOutcomes words each decision a reviewer can take for the
+// status history.
+var paymentReviewOutcomes = map[string]string{
+	domain.PaymentReviewDecisionAccept: "accepted",
+	domain.PaymentReviewDecisionDeny:   "denied",
+}
+
+// ResolvePaymentReview applies a reviewer's decision. Accepting marks the
+// payment as paid. Denying a fraud challenge cancels the order and gives back
+// what was paid on it, denying a late settlement or an unreleased payment
+// retries its refund.
+func (s *OrderServiceImpl) ResolvePaymentReview(ctx context.Context, id int64, req dto.PaymentReviewDecisionRequest) (err error) {
+	outcome, ok := paymentReviewOutcomes[req.Decision]
+	if !ok {
+		return errs.ErrClient
+	}
+
+	review, err := s.repository.GetPaymentReviewByID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	if review.Status != domain.PaymentReviewStatusOpen {
+		return errs.ErrConflict
+	}
+
+	order, err := s.repository.GetOrderByOrderID(ctx, review.OrderID)
+	if err != nil {
+		return
+	}
+
+	if order.ID == 0 {
+		return errs.ErrNotFound
+	}
+
+	// The review is claimed before anything is sent to the gateways, so two
+	// reviewers cannot both act on it. A decision that fails gives the review
+	// back to the reviewers.
+	claimed, err := s.repository.ClaimPaymentReview(ctx, review.ID)
+	if err != nil {
+		return
+	}
+
+	if !claimed {
+		return errs.ErrConflict
+	}
+
+	// lost is set when the claim was taken as abandoned and the review may
+	// already be claimed by another reviewer.
+	lost := false
+	defer func() {
+		if err == nil || lost {
+			return
+		}
+
+		releaseErr := s.repository.ReleasePaymentReview(ctx, review.ID)
+		if releaseErr != nil {
+			log.Ctx(ctx).Error().Err(releaseErr).Str("component", "ResolvePaymentReview").Int64("review_id", review.ID).Msg("Failed to release payment review")
+		}
+	}()
+
+	status := domain.OrderStatusPaid
+	var paidAt *int64
+	reserved := false
+	switch {
+	case review.Reason == domain.PaymentReviewReasonUnreleasedPayment:
+		// The order keeps its status, accepting only records that the money
+		// was given back by other means.
+		status = order.PaymentStatus
+		if req.Decision == domain.PaymentReviewDecisionDeny {
+			err = s.releaseOrderPayments(ctx, order, "release", "Order was not completed")
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Str("component", "ResolvePaymentReview").Str("transaction_number", order.TransactionNumber).Msg("")
+				return err
+			}
+		}
+	case req.Decision == domain.PaymentReviewDecisionAccept:
+		now := time.Now().Unix()
+		paidAt = &now
+
+		// The expiry job gave the stock of a late settlement back, the order
+		// can only be paid once it is reserved again. When it is gone, the
+		// payment has to be denied and refunded instead.
+		if review.Reason == domain.PaymentReviewReasonLateSettlement && !domain.OrderStatusHoldsStock(order.PaymentStatus) {
+			err = s.reserveOrderDetailsStock(ctx, order)
+			if err != nil {
+				log.Ctx(ctx).Warn().Err(err).Str("component", "ResolvePaymentReview").Str("transaction_number", order.TransactionNumber).Msg("Failed to reserve stock for an accepted late settlement")
+				return errs.ErrOutOfStock
+			}
+
+			reserved = true
+		}
+	case review.Reason == domain.PaymentReviewReasonFraudChallenge:
+		status = domain.OrderStatusCancelled
+
+		err = s.releaseOrderPayments(ctx, order, "fraud-review", "Payment denied after a fraud review")
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "ResolvePaymentReview").Str("transaction_number", order.TransactionNumber).Msg("")
+			return err
+		}
+	default:
+		status = domain.OrderStatusRefunded
+
+		err = s.releaseOrderPayments(ctx, order, "late-settlement", "Payment settled after the order expired")
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "ResolvePaymentReview").Str("transaction_number", order.TransactionNumber).Msg("")
+			return err
+		}
+	}
+
+	reviewedBy := int64(req.ReviewedBy)
+	change := domain.OrderStatusChange{
+		Source:  domain.OrderStatusSourceAPI,
+		ActorID: &reviewedBy,
+		Reason:  statusReason("Payment review " + outcome),
+	}
+	if req.Note != nil && strings.TrimSpace(*req.Note) != "" {
+		change.Reason = statusReason("Payment review " + outcome + ": " + strings.TrimSpace(*req.Note))
+	}
+
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		now := time.Now().Unix()
+		review.Status = domain.PaymentReviewStatusResolved
+		review.Decision = &req.Decision
+		review.Note = req.Note
+		review.ResolvedAt = &now
+		review.UpdatedAt = now
+
+		resolved, err := repo.ResolvePaymentReview(ctx, review)
+		if err != nil {
+			return err
+		}
+
+		if !resolved {
+			lost = true
+			return errs.ErrConflict
+		}
+
+		// Accepting a challenged tender settles it, the order is only paid
+		// once its other tenders are too.
+		if review.Reason == domain.PaymentReviewReasonFraudChallenge && req.Decision == domain.PaymentReviewDecisionAccept {
+			return s.acceptChallengedPayments(ctx, repo, order, paidAt, change)
+		}
+
+		if order.PaymentStatus == status {
+			return nil
+		}
+
+		updated, err := s.transitionOrder(ctx, repo, order, status, paidAt, change)
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			return errs.ErrConflict
+		}
+
+		return nil
+	})
+	// The order was not paid after all, give back what was just reserved for
+	// it.
+	if err != nil && reserved {
+		s.restoreReservedStock(ctx, order)
+	}
+
+	return err
+}
+
+func (s *OrderServiceImpl) acceptChallengedPayments(ctx context.Context, repo repository.OrderRepository, order domain.Order, paidAt *int64, change domain.OrderStatusChange) error {
+	payments, err := repo.GetOrderPaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	for i, payment := range payments {
+		if payment.Status != domain.OrderStatusChallenge {
+			continue
+		}
+
+		payment.Status = domain.OrderStatusPaid
+		payment.PaidAt = paidAt
+		payment.UpdatedAt = time.Now().Unix()
+
+		updated, err := repo.TransitionOrderPayment(ctx, payment, []string{domain.OrderStatusChallenge})
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			return errs.ErrConflict
+		}
+
+		payments[i] = payment
+	}
+
+	_, err = s.syncOrderStatus(ctx, repo, order, payments, paidAt, change)
+	return err
+}
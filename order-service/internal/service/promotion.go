@@ -0,0 +1,554 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *OrderServiceImpl) AddPromotion(ctx context.Context, req dto.PromotionRequest) (response dto.PromotionResponse, err error) {
+	promotion, err := s.promotionFromRequest(ctx, 0, req)
+	if err != nil {
+		return
+	}
+
+	now := time.Now().Unix()
+	promotion.CreatedAt = now
+	promotion.UpdatedAt = now
+
+	promotion.ID, err = s.repository.AddPromotion(ctx, promotion)
+	if err != nil {
+		return
+	}
+
+	return promotionResponse(promotion, 0), nil
+}
+
+func (s *OrderServiceImpl) GetPromotions(ctx context.Context) (response []dto.PromotionResponse, err error) {
+	promotions, err := s.repository.GetPromotions(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, promotion := range promotions {
+		usageCount, err := s.repository.CountPromotionUsages(ctx, promotion.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		response = append(response, promotionResponse(promotion, usageCount))
+	}
+
+	return
+}
+
+func (s *OrderServiceImpl) GetPromotion(ctx context.Context, id int64) (response dto.PromotionResponse, err error) {
+	promotion, err := s.repository.GetPromotionByID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	usageCount, err := s.repository.CountPromotionUsages(ctx, promotion.ID, nil)
+	if err != nil {
+		return
+	}
+
+	return promotionResponse(promotion, usageCount), nil
+}
+
+func (s *OrderServiceImpl) UpdatePromotion(ctx context.Context, id int64, req dto.PromotionRequest) (response dto.PromotionResponse, err error) {
+	existing, err := s.repository.GetPromotionByID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	promotion, err := s.promotionFromRequest(ctx, id, req)
+	if err != nil {
+		return
+	}
+
+	promotion.ID = existing.ID
+	promotion.CreatedAt = existing.CreatedAt
+	promotion.UpdatedAt = time.Now().Unix()
+
+	err = s.repository.UpdatePromotion(ctx, promotion)
+	if err != nil {
+		return
+	}
+
+	usageCount, err := s.repository.CountPromotionUsages(ctx, promotion.ID, nil)
+	if err != nil {
+		return
+	}
+
+	return promotionResponse(promotion, usageCount), nil
+}
+
+func (s *OrderServiceImpl) DeletePromotion(ctx context.Context, id int64) (err error) {
+	_, err = s.repository.GetPromotionByID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	return s.repository.DeletePromotion(ctx, id, time.Now().Unix())
+}
+
+// promotionFromRequest validates req and makes sure its code is not taken by
+// another promotion than the one with id.
+func (s *OrderServiceImpl) promotionFromRequest(ctx context.Context, id int64, req dto.PromotionRequest) (promotion domain.Promotion, err error) {
+	promotion = domain.Promotion{
+		Name:                  strings.TrimSpace(req.Name),
+		Type:                  req.Type,
+		Value:                 req.Value,
+		ProductID:             req.ProductID,
+		BuyQuantity:           req.BuyQuantity,
+		GetQuantity:           req.GetQuantity,
+		MinSpend:              money.FromFloat(req.MinSpend, money.IDR),
+		MaxDiscount:           requestMoney(req.MaxDiscount),
+		Stackable:             req.Stackable,
+		Priority:              req.Priority,
+		UsageLimit:            req.UsageLimit,
+		UsageLimitPerCustomer: req.UsageLimitPerCustomer,
+		IsActive:              req.IsActive,
+		StartsAt:              req.StartsAt,
+		EndsAt:                req.EndsAt,
+	}
+
+	if req.Code != nil {
+		code := normalizePromoCode(*req.Code)
+		if code != "" {
+			promotion.Code = &code
+		}
+	}
+
+	if promotion.Name == "" || promotion.EndsAt <= promotion.StartsAt || promotion.MinSpend.Amount < 0 {
+		return promotion, errs.ErrClient
+	}
+
+	if promotion.MaxDiscount != nil && promotion.MaxDiscount.Amount <= 0 {
+		return promotion, errs.ErrClient
+	}
+
+	if (promotion.UsageLimit != nil && *promotion.UsageLimit <= 0) || (promotion.UsageLimitPerCustomer != nil && *promotion.UsageLimitPerCustomer <= 0) {
+		return promotion, errs.ErrClient
+	}
+
+	switch promotion.Type {
+	case domain.PromotionTypePercentage:
+		if promotion.Value <= 0 || promotion.Value > 100 {
+			return promotion, errs.ErrClient
+		}
+	case domain.PromotionTypeFixed:
+		if promotion.Value <= 0 {
+			return promotion, errs.ErrClient
+		}
+	case domain.PromotionTypeBuyXGetY:
+		if promotion.ProductID == nil || promotion.BuyQuantity == nil || promotion.GetQuantity == nil || *promotion.BuyQuantity <= 0 || *promotion.GetQuantity <= 0 {
+			return promotion, errs.ErrClient
+		}
+	default:
+		return promotion, errs.ErrClient
+	}
+
+	if promotion.Code != nil {
+		existing, err := s.repository.GetPromotionByCode(ctx, *promotion.Code)
+		if err == nil && existing.ID != id {
+			return promotion, errs.ErrConflict
+		}
+
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			return promotion, err
+		}
+	}
+
+	return promotion, nil
+}
+
+func promotionResponse(promotion domain.Promotion, usageCount int64) dto.PromotionResponse {
+	return dto.PromotionResponse{
+		ID:                    promotion.ID,
+		Name:                  promotion.Name,
+		Code:                  promotion.Code,
+		Type:                  promotion.Type,
+		Value:                 promotion.Value,
+		ProductID:             promotion.ProductID,
+		BuyQuantity:           promotion.BuyQuantity,
+		GetQuantity:           promotion.GetQuantity,
+		MinSpend:              promotion.MinSpend.Float64(),
+		MaxDiscount:           majorUnits(promotion.MaxDiscount),
+		Stackable:             promotion.Stackable,
+		Priority:              promotion.Priority,
+		UsageLimit:            promotion.UsageLimit,
+		UsageLimitPerCustomer: promotion.UsageLimitPerCustomer,
+		UsageCount:            usageCount,
+		IsActive:              promotion.IsActive,
+		StartsAt:              promotion.StartsAt,
+		EndsAt:                promotion.EndsAt,
+		CreatedAt:             promotion.CreatedAt,
+		UpdatedAt:             promotion.UpdatedAt,
+	}
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// promotionCandidate is what a promotion would take off the order if it were
+// applied on its own.
+type promotionCandidate struct {
+	promotion domain.Promotion
+	discounts []orderSagaDiscount
+	// total is in minor units of the order's currency.
+	total int64
+}
+
+// applyOrderPromotions prices the order with the running automatic
+// promotions and the promo codes entered at checkout. A code that does not
+// exist or cannot be applied fails the order, so the cashier finds out before
+// the customer pays.
+func (s *OrderServiceImpl) applyOrderPromotions(ctx context.Context, saga *orderSaga) error {
+	now := time.Now().Unix()
+	promotions, err := s.repository.GetAutomaticPromotions(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	requested := make(map[int64]bool)
+	for _, code := range saga.state.PromoCodes {
+		promotion, err := s.repository.GetPromotionByCode(ctx, normalizePromoCode(code))
+		if errors.Is(err, errs.ErrNotFound) {
+			return errs.ErrPromotionNotApplicable
+		}
+		if err != nil {
+			return err
+		}
+
+		if !promotion.RunningAt(now) {
+			return errs.ErrPromotionNotApplicable
+		}
+
+		if requested[promotion.ID] {
+			continue
+		}
+
+		requested[promotion.ID] = true
+		promotions = append(promotions, promotion)
+	}
+
+	sort.SliceStable(promotions, func(i, j int) bool {
+		if promotions[i].Priority != promotions[j].Priority {
+			return promotions[i].Priority > promotions[j].Priority
+		}
+		return promotions[i].ID < promotions[j].ID
+	})
+
+	subtotal := orderSagaSubtotal(saga.state.OrderItems, saga.state.currency())
+
+	var candidates []promotionCandidate
+	for _, promotion := range promotions {
+		available, err := s.promotionAvailable(ctx, s.repository, promotion, saga.state.CustomerID)
+		if err != nil {
+			return err
+		}
+
+		candidate := evaluatePromotion(promotion, saga.state.OrderItems, subtotal)
+		if !available || candidate.total <= 0 {
+			if requested[promotion.ID] {
+				return errs.ErrPromotionNotApplicable
+			}
+			continue
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	applyPromotionCandidates(&saga.state, subtotal, choosePromotions(candidates))
+
+	if len(saga.state.Discounts) > 0 {
+		log.Ctx(ctx).Info().Str("component", "applyOrderPromotions").Str("transaction_number", saga.TransactionNumber).Stringer("discount_amount", saga.state.DiscountAmount).Msg("Applied promotions")
+	}
+
+	return nil
+}
+
+// promotionAvailable checks the usage limits of the promotion. Promotions
+// limited per customer are only available to identified customers.
+func (s *OrderServiceImpl) promotionAvailable(ctx context.Context, repo repository.OrderRepository, promotion domain.Promotion, customerID *uint64) (bool, error) {
+	if promotion.UsageLimit != nil {
+		count, err := repo.CountPromotionUsages(ctx, promotion.ID, nil)
+		if err != nil {
+			return false, err
+		}
+
+		if count >= *promotion.UsageLimit {
+			return false, nil
+		}
+	}
+
+	if promotion.UsageLimitPerCustomer != nil {
+		if customerID == nil {
+			return false, nil
+		}
+
+		id := int64(*customerID)
+		count, err := repo.CountPromotionUsages(ctx, promotion.ID, &id)
+		if err != nil {
+			return false, err
+		}
+
+		if count >= *promotion.UsageLimitPerCustomer {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func orderSagaSubtotal(items []orderSagaItem, currency string) money.Money {
+	subtotal := money.New(0, currency)
+	for _, item := range items {
+		subtotal.Amount += item.Price.Mul(int64(item.Quantity)).Amount
+	}
+
+	return subtotal
+}
+
+// promotionAmount is an amount of a promotion in minor units of currency. An
+// order in another currency than rupiah takes the amount as is, in its own
+// major units.
+func promotionAmount(amount money.Money, currency string) int64 {
+	if amount.Currency == currency {
+		return amount.Amount
+	}
+
+	return money.FromFloat(amount.Float64(), currency).Amount
+}
+
+// evaluatePromotion works out the discounts of the promotion in minor units
+// of the subtotal's currency. The value of the promotion is in major units
+// and every discount is rounded to a whole one.
+func evaluatePromotion(promotion domain.Promotion, items []orderSagaItem, subtotal money.Money) (candidate promotionCandidate) {
+	candidate.promotion = promotion
+	currency := subtotal.Currency
+	if subtotal.Amount < promotionAmount(promotion.MinSpend, currency) {
+		return
+	}
+
+	remainingCap := int64(math.MaxInt64)
+	if promotion.MaxDiscount != nil {
+		remainingCap = promotionAmount(*promotion.MaxDiscount, currency)
+	}
+
+	add := func(itemIndex *int, amount float64) {
+		discount := min(roundMajorUnits(amount, currency), remainingCap)
+		if discount <= 0 {
+			return
+		}
+
+		remainingCap -= discount
+		candidate.total += discount
+		candidate.discounts = append(candidate.discounts, orderSagaDiscount{
+			PromotionID: promotion.ID,
+			Name:        promotion.Name,
+			ItemIndex:   itemIndex,
+			Amount:      money.New(discount, currency),
+		})
+	}
+
+	value := float64(money.FromFloat(promotion.Value, currency).Amount)
+	if promotion.ProductID == nil {
+		switch promotion.Type {
+		case domain.PromotionTypePercentage:
+			add(nil, float64(subtotal.Amount)*promotion.Value/100)
+		case domain.PromotionTypeFixed:
+			add(nil, math.Min(value, float64(subtotal.Amount)))
+		}
+
+		return
+	}
+
+	for i, item := range items {
+		if item.ProductID != *promotion.ProductID {
+			continue
+		}
+
+		lineTotal := float64(item.Price.Mul(int64(item.Quantity)).Amount)
+		var amount float64
+		switch promotion.Type {
+		case domain.PromotionTypePercentage:
+			amount = lineTotal * promotion.Value / 100
+		case domain.PromotionTypeFixed:
+			amount = value * float64(item.Quantity)
+		case domain.PromotionTypeBuyXGetY:
+			bundle := *promotion.BuyQuantity + *promotion.GetQuantity
+			free := int64(item.Quantity) / bundle * *promotion.GetQuantity
+			amount = float64(item.Price.Mul(free).Amount)
+		}
+
+		itemIndex := i
+		add(&itemIndex, math.Min(amount, lineTotal))
+	}
+
+	return
+}
+
+// choosePromotions applies the stacking rules: every stackable promotion is
+// combined, while a promotion that is not stackable is applied on its own,
+// and whichever gives the customer the larger discount wins.
+func choosePromotions(candidates []promotionCandidate) []promotionCandidate {
+	var stacked []promotionCandidate
+	var stackedTotal int64
+	for _, candidate := range candidates {
+		if candidate.promotion.Stackable {
+			stacked = append(stacked, candidate)
+			stackedTotal += candidate.total
+		}
+	}
+
+	chosen, chosenTotal := stacked, stackedTotal
+	for _, candidate := range candidates {
+		if !candidate.promotion.Stackable && candidate.total > chosenTotal {
+			chosen, chosenTotal = []promotionCandidate{candidate}, candidate.total
+		}
+	}
+
+	return chosen
+}
+
+// applyPromotionCandidates records the chosen discounts in the saga state,
+// making sure no line and no order goes below zero. Discounts on the whole
+// order are spread over the lines in proportion to what is left of them, so
+// every line knows its net amount when it is refunded.
+func applyPromotionCandidates(state *orderSagaState, subtotal money.Money, chosen []promotionCandidate) {
+	currency := subtotal.Currency
+	lineLeft := make([]int64, len(state.OrderItems))
+	lineDiscounts := make([]int64, len(state.OrderItems))
+	for i, item := range state.OrderItems {
+		lineLeft[i] = item.Price.Mul(int64(item.Quantity)).Amount
+	}
+
+	orderLeft := subtotal.Amount
+	var orderDiscount int64
+	state.Discounts = nil
+
+	for _, candidate := range chosen {
+		for _, discount := range candidate.discounts {
+			amount := min(discount.Amount.Amount, orderLeft)
+			if discount.ItemIndex != nil {
+				amount = min(amount, lineLeft[*discount.ItemIndex])
+			}
+
+			if amount <= 0 {
+				continue
+			}
+
+			orderLeft -= amount
+			if discount.ItemIndex != nil {
+				lineLeft[*discount.ItemIndex] -= amount
+				lineDiscounts[*discount.ItemIndex] += amount
+			} else {
+				orderDiscount += amount
+			}
+
+			discount.Amount = money.New(amount, currency)
+			state.Discounts = append(state.Discounts, discount)
+		}
+	}
+
+	var netTotal int64
+	last := -1
+	for i, left := range lineLeft {
+		netTotal += left
+		if left > 0 {
+			last = i
+		}
+	}
+
+	remaining := orderDiscount
+	for i := range state.OrderItems {
+		var share int64
+		if i == last {
+			share = remaining
+		} else if netTotal > 0 {
+			share = min(int64(math.Round(float64(orderDiscount)*float64(lineLeft[i])/float64(netTotal))), remaining)
+		}
+
+		remaining -= share
+		state.OrderItems[i].DiscountAmount = money.New(lineDiscounts[i]+share, currency)
+	}
+
+	state.Subtotal = subtotal
+	state.DiscountAmount = money.New(subtotal.Amount-orderLeft, currency)
+	state.TotalAmount = money.New(orderLeft, currency)
+}
+
+// recordOrderDiscounts stores the discounts applied to the order. The usage
+// limits are checked again under the promotions' row locks, since other
+// orders may have used them up while this one was being charged.
+func (s *OrderServiceImpl) recordOrderDiscounts(ctx context.Context, repo repository.OrderRepository, saga *orderSaga, orderID int64) error {
+	if len(saga.state.Discounts) == 0 {
+		return nil
+	}
+
+	var promotionIDs []int64
+	locked := make(map[int64]bool)
+	for _, discount := range saga.state.Discounts {
+		if !locked[discount.PromotionID] {
+			locked[discount.PromotionID] = true
+			promotionIDs = append(promotionIDs, discount.PromotionID)
+		}
+	}
+
+	// Locking in a fixed order keeps concurrent orders from deadlocking.
+	sort.Slice(promotionIDs, func(i, j int) bool { return promotionIDs[i] < promotionIDs[j] })
+	for _, id := range promotionIDs {
+		promotion, err := repo.LockPromotionByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		available, err := s.promotionAvailable(ctx, repo, promotion, saga.state.CustomerID)
+		if err != nil {
+			return err
+		}
+
+		if !available {
+			return errs.ErrPromotionNotApplicable
+		}
+	}
+
+	orderDetails, err := repo.GetOrderDetailsByOrderID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	var discounts []domain.OrderDiscount
+	for _, discount := range saga.state.Discounts {
+		orderDiscount := domain.OrderDiscount{
+			OrderID:       orderID,
+			PromotionID:   discount.PromotionID,
+			PromotionName: discount.Name,
+			Amount:        discount.Amount,
+			CreatedAt:     now,
+		}
+
+		if discount.ItemIndex != nil && *discount.ItemIndex < len(orderDetails) {
+			orderDiscount.OrderDetailID = &orderDetails[*discount.ItemIndex].ID
+		}
+
+		discounts = append(discounts, orderDiscount)
+	}
+
+	return repo.AddOrderDiscounts(ctx, discounts)
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+func TestEvaluatePromotionMinSpendAndMaxDiscount(t *testing.T) {
+	maxDiscount := rupiah(15000)
+
+	type TestCase struct {
+		Name     string
+		Subtotal money.Money
+		Expected money.Money
+	}
+
+	testCases := []TestCase{
+		{Name: "Below min spend", Subtotal: money.New(9999999, money.IDR), Expected: rupiah(0)},
+		{Name: "At min spend", Subtotal: rupiah(100000), Expected: rupiah(10000)},
+		{Name: "Capped by max discount", Subtotal: rupiah(200000), Expected: rupiah(15000)},
+		{Name: "Order in yen", Subtotal: money.New(200000, "JPY"), Expected: money.New(15000, "JPY")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			promotion := domain.Promotion{ID: 1, Type: domain.PromotionTypePercentage, Value: 10, MinSpend: rupiah(100000), MaxDiscount: &maxDiscount}
+			items := []orderSagaItem{{ProductID: "P1", Quantity: 1, Price: tc.Subtotal}}
+
+			candidate := evaluatePromotion(promotion, items, tc.Subtotal)
+			if total := money.New(candidate.total, tc.Subtotal.Currency); total != tc.Expected {
+				t.Errorf("expected discount %s, got %s", tc.Expected, total)
+			}
+		})
+	}
+}
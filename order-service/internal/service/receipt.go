@@ -0,0 +1,314 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/receipt"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	"github.com/rs/zerolog/log"
+)
+
+// maxReceiptLogoSize keeps the logos small enough to be sent to the printers
+// with every receipt.
+const maxReceiptLogoSize = 256 * 1024
+
+// maxReceiptLogoWidth is the widest line of dots the printers print, and
+// maxReceiptLogoHeight keeps a small PNG from declaring an image too large to
+// decode with every receipt.
+const (
+	maxReceiptLogoWidth  = 576
+	maxReceiptLogoHeight = 576
+)
+
+func (s *OrderServiceImpl) GetReceiptTemplates(ctx context.Context) (response []dto.ReceiptTemplateResponse, err error) {
+	templates, err := s.repository.GetReceiptTemplates(ctx)
+	if err != nil {
+		return
+	}
+
+	response = []dto.ReceiptTemplateResponse{}
+	for _, template := range templates {
+		response = append(response, receiptTemplateResponse(template))
+	}
+
+	return
+}
+
+func (s *OrderServiceImpl) GetReceiptTemplate(ctx context.Context, storeCode string) (response dto.ReceiptTemplateResponse, err error) {
+	template, err := s.repository.GetReceiptTemplateByStoreCode(ctx, storeCode)
+	if err != nil {
+		return
+	}
+
+	return receiptTemplateResponse(template), nil
+}
+
+// SaveReceiptTemplate creates the receipt template of the store, or replaces
+// the one it has.
+func (s *OrderServiceImpl) SaveReceiptTemplate(ctx context.Context, storeCode string, req dto.ReceiptTemplateRequest) (response dto.ReceiptTemplateResponse, err error) {
+	template, err := receiptTemplateFromRequest(storeCode, req)
+	if err != nil {
+		return
+	}
+
+	now := time.Now().Unix()
+	template.CreatedAt = now
+	template.UpdatedAt = now
+
+	err = s.repository.UpsertReceiptTemplate(ctx, template)
+	if err != nil {
+		return
+	}
+
+	return s.GetReceiptTemplate(ctx, template.StoreCode)
+}
+
+func (s *OrderServiceImpl) DeleteReceiptTemplate(ctx context.Context, storeCode string) (err error) {
+	template, err := s.repository.GetReceiptTemplateByStoreCode(ctx, storeCode)
+	if err != nil {
+		return
+	}
+
+	return s.repository.DeleteReceiptTemplate(ctx, template.StoreCode)
+}
+
+// GetOrderReceipt renders the receipt of an order with the template of its
+// store. Orders of stores without a template get a plain receipt on 80mm
+// paper.
+func (s *OrderServiceImpl) GetOrderReceipt(ctx context.Context, id int64, format string) (response dto.OrderReceipt, err error) {
+	if format == "" {
+		format = receipt.FormatText
+	}
+
+	order, err := s.repository.GetOrderByOrderID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	if order.ID == 0 {
+		return response, errs.ErrNotFound
+	}
+
+	r, err := s.orderReceipt(ctx, order)
+	if err != nil {
+		return
+	}
+
+	data, contentType, err := receipt.Render(r, format)
+	if errors.Is(err, receipt.ErrUnknownFormat) {
+		return response, errs.ErrClient
+	}
+	if err != nil {
+		return
+	}
+
+	extension := map[string]string{receipt.FormatText: "txt", receipt.FormatESCPOS: "bin", receipt.FormatPDF: "pdf"}[format]
+
+	return dto.OrderReceipt{
+		FileName:    "receipt-" + order.TransactionNumber + "." + extension,
+		ContentType: contentType,
+		Data:        data,
+	}, nil
+}
+
+// orderReceipt gathers what is printed on the receipt of the order.
+func (s *OrderServiceImpl) orderReceipt(ctx context.Context, order domain.Order) (r receipt.Receipt, err error) {
+	r = receipt.Receipt{
+		PaperWidth:        receipt.PaperWidth80,
+		TransactionNumber: order.TransactionNumber,
+		CreatedAt:         time.Unix(order.CreatedAt, 0).In(wib),
+		Subtotal:          order.Subtotal.Float64(),
+		Total:             order.Amount.Float64(),
+	}
+
+	if order.PaidAt != nil {
+		r.CreatedAt = time.Unix(*order.PaidAt, 0).In(wib)
+	}
+
+	if order.PaymentStatus != domain.OrderStatusPaid {
+		r.Status = strings.ReplaceAll(order.PaymentStatus, "_", " ")
+	}
+
+	if order.StoreCode != nil {
+		r.StoreCode = *order.StoreCode
+
+		err = s.applyReceiptTemplate(ctx, &r, *order.StoreCode)
+		if err != nil {
+			return
+		}
+	}
+
+	details, err := s.repository.GetOrderDetailsByOrderID(ctx, order.ID)
+	if err != nil {
+		return
+	}
+
+	for _, detail := range details {
+		r.Items = append(r.Items, receipt.Item{
+			Name:     detail.ProductName,
+			Quantity: detail.Quantity,
+			Price:    detail.Amount.Float64(),
+			Amount:   detail.Amount.Mul(detail.Quantity).Float64(),
+		})
+	}
+
+	discounts, err := s.repository.GetOrderDiscountsByOrderID(ctx, order.ID)
+	if err != nil {
+		return
+	}
+
+	for _, discount := range discounts {
+		r.Adjustments = append(r.Adjustments, receipt.Line{Label: discount.PromotionName, Amount: -discount.Amount.Float64()})
+	}
+
+	charges, err := s.repository.GetOrderChargesByOrderID(ctx, order.ID)
+	if err != nil {
+		return
+	}
+
+	// Only what was added on top of the prices goes towards the total, the
+	// taxes already in them are noted under it.
+	for _, charge := range charges {
+		label := charge.Name + " " + strconv.FormatFloat(charge.Rate, 'f', -1, 64) + "%"
+		if added := charge.Amount.Amount - charge.IncludedAmount.Amount; added != 0 {
+			r.Adjustments = append(r.Adjustments, receipt.Line{Label: label, Amount: money.New(added, charge.Amount.Currency).Float64()})
+		}
+		if !charge.IncludedAmount.IsZero() {
+			r.Notes = append(r.Notes, receipt.Line{Label: "Incl. " + label, Amount: charge.IncludedAmount.Float64()})
+		}
+	}
+
+	if !order.RoundingAmount.IsZero() {
+		r.Adjustments = append(r.Adjustments, receipt.Line{Label: "Rounding", Amount: order.RoundingAmount.Float64()})
+	}
+
+	payments, err := s.repository.GetOrderPaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return
+	}
+
+	for _, payment := range payments {
+		if !payment.Settled() {
+			continue
+		}
+
+		paymentMethod, err := s.repository.GetPaymentMethodByID(ctx, uint64(payment.PaymentMethodID))
+		if err != nil {
+			return r, err
+		}
+
+		r.Payments = append(r.Payments, receipt.Payment{
+			Name:     paymentMethod.Name,
+			Amount:   payment.Amount.Float64(),
+			Tendered: majorUnits(payment.TenderedAmount),
+			Change:   majorUnits(payment.ChangeAmount),
+		})
+		r.Refunded += payment.RefundedAmount.Float64()
+	}
+
+	return r, nil
+}
+
+// applyReceiptTemplate puts the header, footer and logo of the store on the
+// receipt.
+func (s *OrderServiceImpl) applyReceiptTemplate(ctx context.Context, r *receipt.Receipt, storeCode string) error {
+	template, err := s.repository.GetReceiptTemplateByStoreCode(ctx, storeCode)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.PaperWidth = template.PaperWidth
+	r.Header = receiptTemplateLines(template.Header)
+	r.Footer = receiptTemplateLines(template.Footer)
+
+	if len(template.Logo) > 0 {
+		// A logo that no longer decodes should not keep the receipt from
+		// being printed.
+		r.Logo, err = png.Decode(bytes.NewReader(template.Logo))
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "applyReceiptTemplate").Str("store_code", storeCode).Msg("")
+			r.Logo = nil
+		}
+	}
+
+	return nil
+}
+
+func receiptTemplateLines(text string) (lines []string) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(text, "\n")
+}
+
+func receiptTemplateFromRequest(storeCode string, req dto.ReceiptTemplateRequest) (template domain.ReceiptTemplate, err error) {
+	template = domain.ReceiptTemplate{
+		StoreCode:  strings.TrimSpace(storeCode),
+		Header:     strings.TrimSpace(req.Header),
+		Footer:     strings.TrimSpace(req.Footer),
+		PaperWidth: req.PaperWidth,
+	}
+
+	if template.StoreCode == "" {
+		return template, errs.ErrClient
+	}
+
+	if template.PaperWidth == 0 {
+		template.PaperWidth = receipt.PaperWidth80
+	}
+
+	if !receipt.ValidPaperWidth(template.PaperWidth) {
+		return template, errs.ErrClient
+	}
+
+	if req.Logo != nil {
+		template.Logo, err = base64.StdEncoding.DecodeString(*req.Logo)
+		if err != nil || len(template.Logo) == 0 || len(template.Logo) > maxReceiptLogoSize {
+			return template, errs.ErrClient
+		}
+
+		config, err := png.DecodeConfig(bytes.NewReader(template.Logo))
+		if err != nil {
+			return template, errs.ErrClient
+		}
+
+		if config.Width <= 0 || config.Width > maxReceiptLogoWidth || config.Height <= 0 || config.Height > maxReceiptLogoHeight {
+			return template, errs.ErrClient
+		}
+	}
+
+	return template, nil
+}
+
+func receiptTemplateResponse(template domain.ReceiptTemplate) dto.ReceiptTemplateResponse {
+	response := dto.ReceiptTemplateResponse{
+		StoreCode:  template.StoreCode,
+		Header:     template.Header,
+		Footer:     template.Footer,
+		PaperWidth: template.PaperWidth,
+		CreatedAt:  template.CreatedAt,
+		UpdatedAt:  template.UpdatedAt,
+	}
+
+	if len(template.Logo) > 0 {
+		logo := base64.StdEncoding.EncodeToString(template.Logo)
+		response.Logo = &logo
+	}
+
+	return response
+}
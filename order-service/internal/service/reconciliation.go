@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// paymentReconciliationJob and paymentDiscrepancyJob name the locks and
+	// the metrics of the reconciliation jobs.
+	paymentReconciliationJob = "reconcile_payments"
+	paymentDiscrepancyJob    = "report_payment_discrepancies"
+
+	paymentReconciliationBatchSize = 100
+	// paymentReconciliationLookback bounds how old an order the worker still
+	// polls the gateway for.
+	paymentReconciliationLookback = 7 * 24 * time.Hour
+	// paymentReconciliationLead is how long before its expiry an open order
+	// is polled, so a payment whose notification was lost is applied before
+	// the expiry job gives the order up.
+	paymentReconciliationLead = 5 * time.Minute
+	// paymentFollowUpTimeout is how long a payment follow-up may run before
+	// its staged review is taken as abandoned and opened for a reviewer.
+	paymentFollowUpTimeout = 10 * time.Minute
+	// paymentReviewClaimTimeout is how long a reviewer's decision may take
+	// before its review is taken as abandoned and opened again.
+	paymentReviewClaimTimeout = 10 * time.Minute
+)
+
+// ReconcilePayments polls the gateways for the payments whose notification
+// may have been lost, and applies what they report the way the webhook does.
+// Orders whose payments are all settled but that did not follow are moved to
+// the status their payments add up to, and the follow-ups of payments that
+// did not finish, or whose decision did not, are opened for a reviewer.
+//
+// Every replica schedules the job, but only the one holding its advisory lock
+// runs it. An order that fails is left for the next run.
+func (s *OrderServiceImpl) ReconcilePayments() {
+	s.runLockedJob(paymentReconciliationJob, s.reconcilePayments)
+}
+
+func (s *OrderServiceImpl) reconcilePayments(ctx context.Context) error {
+	now := time.Now()
+
+	opened, err := s.repository.OpenStalePaymentReviews(ctx, now.Add(-paymentFollowUpTimeout).Unix(), "Automatic handling of the payment did not finish")
+	if err != nil {
+		return err
+	}
+
+	if opened > 0 {
+		log.Warn().Str("component", "ReconcilePayments").Int64("reviews", opened).Msg("Opened payment reviews whose follow-up did not finish")
+	}
+
+	released, err := s.repository.ReleaseStalePaymentReviews(ctx, now.Add(-paymentReviewClaimTimeout).Unix())
+	if err != nil {
+		return err
+	}
+
+	if released > 0 {
+		log.Warn().Str("component", "ReconcilePayments").Int64("reviews", released).Msg("Opened payment reviews whose decision did not finish")
+	}
+
+	orders, err := s.repository.GetOrdersToReconcile(ctx, now.Add(-paymentReconciliationLookback).Unix(), now.Add(paymentReconciliationLead).Unix(), paymentReconciliationBatchSize)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, order := range orders {
+		err := s.reconcileOrder(ctx, order)
+		if err != nil {
+			failed++
+			log.Error().Err(err).Str("component", "ReconcilePayments").Str("transaction_number", order.TransactionNumber).Msg("Failed to reconcile order")
+		}
+	}
+
+	log.Info().Str("component", "ReconcilePayments").Int("orders", len(orders)).Int("failed", failed).Msg("cron ends")
+
+	return nil
+}
+
+// reconcileOrder applies the gateway status of the pending payments of the
+// order, then brings an open order in line with its payments.
+func (s *OrderServiceImpl) reconcileOrder(ctx context.Context, order domain.Order) error {
+	payments, err := s.repository.GetOrderPaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		// Cash is settled at the counter, and a challenged payment waits for
+		// a reviewer rather than for the gateway.
+		if payment.CashierID != nil || payment.Status != domain.OrderStatusPending {
+			continue
+		}
+
+		notification, err := s.gatewayPaymentStatus(ctx, payment)
+		if err != nil {
+			return err
+		}
+
+		err = s.applyPaymentNotification(ctx, payment, notification, domain.OrderStatusChange{
+			Source: domain.OrderStatusSourceCron,
+			Reason: statusReason(fmt.Sprintf("Reconciled with %s status %s for %s", payment.PaymentGateway, notification.TransactionStatus, payment.TransactionNumber)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.resyncOrderStatus(ctx, order.ID)
+}
+
+// resyncOrderStatus moves an open order to the status its payments add up to,
+// should it have fallen behind them.
+func (s *OrderServiceImpl) resyncOrderStatus(ctx context.Context, orderID int64) error {
+	change := domain.OrderStatusChange{
+		Source: domain.OrderStatusSourceCron,
+		Reason: statusReason("Reconciled with the status of its payments"),
+	}
+
+	var order domain.Order
+	var paidAt *int64
+	var reviewID int64
+	followUp := paymentFollowUpNone
+	err := s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) (err error) {
+		order, err = repo.LockOrderByOrderID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		// A closed order is released by what closed it, and retrying that
+		// here would open a review on every run.
+		if !domain.OrderStatusHoldsStock(order.PaymentStatus) {
+			return nil
+		}
+
+		payments, err := repo.GetOrderPaymentsByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		paidAt = lastPaidAt(payments)
+		followUp, err = s.syncOrderStatus(ctx, repo, order, payments, paidAt, change)
+		if err != nil {
+			return err
+		}
+
+		reviewID, err = stagePaymentFollowUp(ctx, repo, order, followUp)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.followUpPayments(ctx, order, followUp, reviewID, paidAt, change)
+}
+
+// lastPaidAt is the time the last of the payments was paid at, or now when
+// none of them tells.
+func lastPaidAt(payments []domain.OrderPayment) *int64 {
+	var paidAt *int64
+	for _, payment := range payments {
+		if payment.PaidAt != nil && (paidAt == nil || *payment.PaidAt > *paidAt) {
+			paidAt = payment.PaidAt
+		}
+	}
+
+	if paidAt == nil {
+		now := time.Now().Unix()
+		paidAt = &now
+	}
+
+	return paidAt
+}
+
+// gatewayPaymentStatus asks the gateway of the payment for its status, in the
+// shape of the notification the gateway would have sent about it.
+func (s *OrderServiceImpl) gatewayPaymentStatus(ctx context.Context, payment domain.OrderPayment) (notification dto.PaymentNotification, err error) {
+	gateway, err := s.paymentGateways.Get(&payment.PaymentGateway)
+	if err != nil {
+		return
+	}
+
+	status, err := gateway.Status(ctx, payment.TransactionNumber)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "gatewayPaymentStatus").Str("transaction_number", payment.TransactionNumber).Msg("Failed to get payment status")
+		return notification, errs.ErrPaymentGateway
+	}
+
+	notification = dto.PaymentNotification{
+		TransactionStatus: status.TransactionStatus,
+		TransactionID:     status.TransactionID,
+		StatusCode:        "200",
+		SettlementTime:    status.SettlementTime,
+		PaymentType:       status.PaymentType,
+		OrderID:           payment.TransactionNumber,
+		GrossAmount:       status.GrossAmount,
+		FraudStatus:       status.FraudStatus,
+	}
+
+	notification.RawPayload, err = json.Marshal(notification)
+	if err != nil {
+		return
+	}
+
+	return notification, nil
+}
+
+// ReportPaymentDiscrepancies compares the gateway payments made the day
+// before, in WIB, with what their gateways report, and records those that
+// differ for the discrepancy report.
+func (s *OrderServiceImpl) ReportPaymentDiscrepancies() {
+	s.runLockedJob(paymentDiscrepancyJob, func(ctx context.Context) error {
+		day := time.Now().In(wib).AddDate(0, 0, -1)
+		return s.reportPaymentDiscrepancies(ctx, time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, wib))
+	})
+}
+
+func (s *OrderServiceImpl) reportPaymentDiscrepancies(ctx context.Context, day time.Time) error {
+	reportDate := day.Format("2006-01-02")
+
+	payments, err := s.repository.GetGatewayPaymentsCreatedBetween(ctx, day.Unix(), day.AddDate(0, 0, 1).Unix())
+	if err != nil {
+		return err
+	}
+
+	var discrepancies []domain.PaymentDiscrepancy
+	for _, payment := range payments {
+		discrepancy, found := s.paymentDiscrepancy(ctx, payment)
+		if !found {
+			continue
+		}
+
+		discrepancy.ReportDate = reportDate
+		discrepancies = append(discrepancies, discrepancy)
+	}
+
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		return repo.ReplacePaymentDiscrepancies(ctx, reportDate, discrepancies)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("component", "ReportPaymentDiscrepancies").Str("report_date", reportDate).Int("payments", len(payments)).Int("discrepancies", len(discrepancies)).Msg("cron ends")
+
+	return nil
+}
+
+// paymentDiscrepancy compares the payment with what its gateway reports. A
+// gateway that cannot be asked is a discrepancy of its own.
+func (s *OrderServiceImpl) paymentDiscrepancy(ctx context.Context, payment domain.OrderPayment) (discrepancy domain.PaymentDiscrepancy, found bool) {
+	discrepancy = domain.PaymentDiscrepancy{
+		OrderID:           payment.OrderID,
+		OrderPaymentID:    payment.ID,
+		TransactionNumber: payment.TransactionNumber,
+		PaymentGateway:    payment.PaymentGateway,
+		LocalStatus:       payment.Status,
+		LocalAmount:       payment.Amount,
+		CreatedAt:         time.Now().Unix(),
+	}
+
+	notification, err := s.gatewayPaymentStatus(ctx, payment)
+	if err != nil {
+		discrepancy.Reason = "Gateway status is unavailable"
+		return discrepancy, true
+	}
+
+	gatewayStatus, known := midtransOrderStatus(notification.TransactionStatus, notification.FraudStatus)
+	if !known {
+		gatewayStatus = notification.TransactionStatus
+		if gatewayStatus == "pending" {
+			gatewayStatus = domain.OrderStatusPending
+		}
+	}
+	discrepancy.GatewayStatus = &gatewayStatus
+
+	gatewayAmount, err := money.Parse(notification.GrossAmount, money.IDR)
+	if err == nil {
+		discrepancy.GatewayAmount = &gatewayAmount
+	}
+
+	switch {
+	case !gatewayAmountMatches(notification.GrossAmount, payment.Amount):
+		discrepancy.Reason = "Amount differs from the gateway"
+	case gatewayStatus != payment.Status:
+		discrepancy.Reason = "Status differs from the gateway"
+	default:
+		return discrepancy, false
+	}
+
+	return discrepancy, true
+}
+
+// gatewayAmountMatches reports whether the gross amount a gateway reports, a
+// decimal of major units, is exactly the amount of the payment. The gateways
+// only take rupiah.
+func gatewayAmountMatches(grossAmount string, amount money.Money) bool {
+	gross, err := money.Parse(grossAmount, money.IDR)
+	return err == nil && gross.Equal(amount)
+}
+
+func (s *OrderServiceImpl) GetPaymentDiscrepancyReport(ctx context.Context, req dto.ReportRequest) (response dto.PaymentDiscrepancyReportResponse, err error) {
+	_, _, err = reportRange(req)
+	if err != nil {
+		return
+	}
+
+	discrepancies, err := s.repository.GetPaymentDiscrepancies(ctx, req.StartDate, req.EndDate)
+	if err != nil {
+		return
+	}
+
+	response = dto.PaymentDiscrepancyReportResponse{
+		StartDate:     req.StartDate,
+		EndDate:       req.EndDate,
+		Discrepancies: []dto.PaymentDiscrepancyResponse{},
+	}
+
+	for _, discrepancy := range discrepancies {
+		response.Discrepancies = append(response.Discrepancies, dto.PaymentDiscrepancyResponse{
+			ReportDate:        discrepancy.ReportDate,
+			OrderID:           discrepancy.OrderID,
+			OrderPaymentID:    discrepancy.OrderPaymentID,
+			TransactionNumber: discrepancy.TransactionNumber,
+			PaymentGateway:    discrepancy.PaymentGateway,
+			LocalStatus:       discrepancy.LocalStatus,
+			GatewayStatus:     discrepancy.GatewayStatus,
+			LocalAmount:       discrepancy.LocalAmount.Float64(),
+			GatewayAmount:     majorUnits(discrepancy.GatewayAmount),
+			Reason:            discrepancy.Reason,
+		})
+	}
+
+	return response, nil
+}
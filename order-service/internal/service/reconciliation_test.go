@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+func TestGatewayAmountMatches(t *testing.T) {
+	type TestCase struct {
+		Name        string
+		GrossAmount string
+		Amount      money.Money
+		Expected    bool
+	}
+
+	testCases := []TestCase{
+		{Name: "Same amount", GrossAmount: "150000.00", Amount: rupiah(150000), Expected: true},
+		{Name: "Without sen", GrossAmount: "150000", Amount: rupiah(150000), Expected: true},
+		{Name: "Other amount", GrossAmount: "150000.01", Amount: rupiah(150000)},
+		{Name: "Other currency", GrossAmount: "150000", Amount: money.New(150000, "JPY")},
+		{Name: "Not an amount", GrossAmount: "150.000,00", Amount: rupiah(150000)},
+		{Name: "Missing amount", Amount: rupiah(150000)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if matches := gatewayAmountMatches(tc.GrossAmount, tc.Amount); matches != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, matches)
+			}
+		})
+	}
+}
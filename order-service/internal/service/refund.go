@@ -0,0 +1,553 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	paymentgateway "github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/payment-gateway"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	"github.com/rs/zerolog/log"
+)
+
+// RefundOrder refunds whole orders or selected lines of a paid order through
+// the gateways of the tenders it was paid with. The refund is recorded as
+// pending before any gateway is called, and completed once every gateway
+// refunded its part, so a refund that fails halfway is finished by retrying
+// it with the same key rather than made again.
+func (s *OrderServiceImpl) RefundOrder(ctx context.Context, id int64, req dto.RefundOrderRequest) (response dto.RefundResponse, err error) {
+	if strings.TrimSpace(req.Reason) == "" {
+		return response, errs.ErrClient
+	}
+
+	order, refund, err := s.startRefund(ctx, id, req)
+	if err != nil {
+		return
+	}
+
+	err = s.refundPayments(ctx, order, refund)
+	if err != nil {
+		return
+	}
+
+	return s.completeRefund(ctx, refund)
+}
+
+// startRefund records the pending refund asked for, with what it gives back
+// through each payment, or returns the refund already recorded under its key
+// when it was asked for with the same items, reason and restock. Without a
+// key from the client, the key is derived from the order and the number of
+// refunds completed on it, so a retry finds the refund it started.
+func (s *OrderServiceImpl) startRefund(ctx context.Context, id int64, req dto.RefundOrderRequest) (order domain.Order, refund domain.Refund, err error) {
+	requestHash, err := hashRefundRequest(req)
+	if err != nil {
+		return
+	}
+
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		// The order stays locked until the refund is recorded, so concurrent
+		// refunds are checked against what the previous one left.
+		order, err = repo.LockOrderByOrderID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		refunds, err := repo.GetRefundsByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		refundKey := req.RefundKey
+		if refundKey == "" {
+			completed := 0
+			for _, previous := range refunds {
+				if previous.Status == domain.RefundStatusCompleted {
+					completed++
+				}
+			}
+			refundKey = fmt.Sprintf("%s-refund-%d", order.TransactionNumber, completed+1)
+		}
+
+		refund, err = repo.GetRefundByRefundKey(ctx, refundKey)
+		if err == nil {
+			if refund.OrderID != order.ID {
+				return errs.ErrIdempotencyKeyReused
+			}
+
+			if refund.RequestHash != "" && refund.RequestHash != requestHash {
+				// A derived key only finds the pending refund of another
+				// request, which has to finish first.
+				if req.RefundKey == "" {
+					return errs.ErrRefundInProgress
+				}
+
+				return errs.ErrIdempotencyKeyReused
+			}
+
+			return nil
+		}
+
+		if !errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+
+		for _, previous := range refunds {
+			if previous.Status == domain.RefundStatusPending {
+				return errs.ErrRefundInProgress
+			}
+		}
+
+		if order.PaymentStatus != domain.OrderStatusPaid && order.PaymentStatus != domain.OrderStatusPartiallyRefunded {
+			return errs.ErrOrderNotRefundable
+		}
+
+		orderDetails, err := repo.GetOrderDetailsByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		refundedItems, err := repo.GetRefundItemsByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		payments, err := repo.GetOrderPaymentsByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		items, complete, err := buildRefundItems(orderDetails, refundedItems, req.Items)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		refund = domain.Refund{
+			OrderID:     order.ID,
+			RefundKey:   refundKey,
+			RequestHash: requestHash,
+			Reason:      req.Reason,
+			Restock:     req.Restock,
+			RefundedBy:  int64(req.RefundedBy),
+			Status:      domain.RefundStatusPending,
+			Amount:      money.New(0, order.Amount.Currency),
+			CreatedAt:   now,
+		}
+		for _, item := range items {
+			refund.Amount.Amount += item.Amount.Amount
+		}
+
+		// Cash is handed back in what the drawer can pay out, rounded down so
+		// the customer never gets more than they paid. Whatever is rounded
+		// off comes back with the last refund.
+		if paidInCash(payments) {
+			increment, err := s.cashRoundingIncrement(ctx, repo, order.StoreCode)
+			if err != nil {
+				return err
+			}
+
+			refund.Amount = roundCash(refund.Amount, increment, domain.RoundingModeDown)
+		}
+
+		// The last refund takes whatever is left of the order, so amounts that
+		// are not spread over the lines are not left behind.
+		if complete {
+			refund.Amount = order.Amount
+			for _, previous := range refunds {
+				refund.Amount.Amount -= previous.Amount.Amount
+			}
+		}
+
+		// Rupiah are refunded whole, which is all Midtrans takes. The amount
+		// is rounded down like cash, and what is rounded off comes back with
+		// the last refund.
+		if order.Currency == money.IDR {
+			refund.Amount = roundCash(refund.Amount, 1, domain.RoundingModeDown)
+		}
+
+		portions, err := allocateRefund(payments, refund.Amount)
+		if err != nil {
+			return err
+		}
+
+		refund.ID, err = repo.AddRefund(ctx, refund)
+		if err != nil {
+			return err
+		}
+
+		for i := range items {
+			items[i].RefundID = refund.ID
+			items[i].CreatedAt = now
+		}
+
+		err = repo.AddRefundItems(ctx, items)
+		if err != nil {
+			return err
+		}
+
+		refundPayments := make([]domain.RefundPayment, 0, len(portions))
+		for _, portion := range portions {
+			payment := payments[portion.index]
+			refundPayments = append(refundPayments, domain.RefundPayment{
+				RefundID:       refund.ID,
+				OrderPaymentID: payment.ID,
+				RefundKey:      fmt.Sprintf("%s-%d", refund.RefundKey, payment.ID),
+				Amount:         portion.amount,
+				Status:         domain.RefundStatusPending,
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			})
+		}
+
+		return repo.AddRefundPayments(ctx, refundPayments)
+	})
+
+	return
+}
+
+// hashRefundRequest fingerprints the parts of the request that make up the
+// refund, leaving out who asked for it so another cashier can finish it.
+func hashRefundRequest(req dto.RefundOrderRequest) (string, error) {
+	body, err := json.Marshal(dto.RefundOrderRequest{
+		Items:   req.Items,
+		Reason:  req.Reason,
+		Restock: req.Restock,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// refundPayments calls the gateway of every part of the refund not given back
+// yet, with the key recorded for it, and records each part as soon as its
+// gateway refunded it.
+func (s *OrderServiceImpl) refundPayments(ctx context.Context, order domain.Order, refund domain.Refund) error {
+	refundPayments, err := s.repository.GetRefundPaymentsByRefundID(ctx, refund.ID)
+	if err != nil {
+		return err
+	}
+
+	payments, err := s.repository.GetOrderPaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	paymentsByID := make(map[int64]domain.OrderPayment, len(payments))
+	for _, payment := range payments {
+		paymentsByID[payment.ID] = payment
+	}
+
+	for _, refundPayment := range refundPayments {
+		if refundPayment.Status == domain.RefundStatusCompleted {
+			continue
+		}
+
+		payment := paymentsByID[refundPayment.OrderPaymentID]
+		gateway, err := s.paymentGateways.Get(&payment.PaymentGateway)
+		if err != nil {
+			return err
+		}
+
+		_, err = gateway.Refund(ctx, payment.TransactionNumber, paymentgateway.RefundRequest{
+			RefundKey: refundPayment.RefundKey,
+			Amount:    refundPayment.Amount,
+			Reason:    refund.Reason,
+		})
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("component", "RefundOrder").Str("transaction_number", payment.TransactionNumber).Msg("Failed to refund payment")
+			return errs.ErrPaymentGateway
+		}
+
+		err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+			now := time.Now().Unix()
+			completed, err := repo.CompleteRefundPayment(ctx, refundPayment.ID, now)
+			if err != nil {
+				return err
+			}
+
+			// A retry of the same refund recorded it in the meantime.
+			if !completed {
+				return nil
+			}
+
+			payment, err := repo.GetOrderPaymentByTransactionNumber(ctx, payment.TransactionNumber)
+			if err != nil {
+				return err
+			}
+
+			err = s.refundOrderPayment(ctx, repo, payment, refundPayment.Amount, now)
+			if err != nil {
+				return err
+			}
+
+			if payment.PaymentGateway == paymentgateway.GatewayCash {
+				return s.recordCashRefund(ctx, repo, order, payment, &refund.ID, refund.RefundedBy, refundPayment.Amount, now)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// completeRefund marks a refund whose payments were all given back as
+// completed, and moves the order along with it. A refund completed already is
+// returned as it was.
+func (s *OrderServiceImpl) completeRefund(ctx context.Context, refund domain.Refund) (response dto.RefundResponse, err error) {
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		order, err := repo.LockOrderByOrderID(ctx, refund.OrderID)
+		if err != nil {
+			return err
+		}
+
+		completed, err := repo.CompleteRefund(ctx, refund.ID)
+		if err != nil {
+			return err
+		}
+
+		orderDetails, err := repo.GetOrderDetailsByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		refundedItems, err := repo.GetRefundItemsByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		var items []domain.RefundItem
+		for _, item := range refundedItems {
+			if item.RefundID == refund.ID {
+				items = append(items, item)
+			}
+		}
+
+		status := order.PaymentStatus
+		if completed {
+			status = domain.OrderStatusPartiallyRefunded
+			if refundedInFull(orderDetails, refundedItems) {
+				status = domain.OrderStatusRefunded
+			}
+
+			updated, err := s.transitionOrder(ctx, repo, order, status, order.PaidAt, domain.OrderStatusChange{
+				Source:  domain.OrderStatusSourceAPI,
+				ActorID: &refund.RefundedBy,
+				Reason:  statusReason(refund.Reason),
+			})
+			if err != nil {
+				return err
+			}
+
+			if !updated {
+				return errs.ErrConflict
+			}
+
+			if refund.Restock {
+				err = s.enqueueRefundRestock(ctx, repo, order, orderDetails, items)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		response = dto.RefundResponse{
+			ID:            refund.ID,
+			OrderID:       order.ID,
+			RefundKey:     refund.RefundKey,
+			Amount:        refund.Amount.Float64(),
+			Reason:        refund.Reason,
+			Restock:       refund.Restock,
+			PaymentStatus: status,
+			CreatedAt:     refund.CreatedAt,
+		}
+		for _, item := range items {
+			response.Items = append(response.Items, dto.RefundItemResponse{
+				OrderDetailID: item.OrderDetailID,
+				Quantity:      int(item.Quantity),
+				Amount:        item.Amount.Float64(),
+			})
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// refundedInFull reports whether every unit of the order has been refunded.
+func refundedInFull(orderDetails []domain.OrderDetail, refundedItems []domain.RefundItem) bool {
+	remaining := make(map[int64]int64, len(orderDetails))
+	for _, detail := range orderDetails {
+		remaining[detail.ID] = detail.Quantity
+	}
+
+	for _, item := range refundedItems {
+		remaining[item.OrderDetailID] -= item.Quantity
+	}
+
+	for _, left := range remaining {
+		if left > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+type refundPortion struct {
+	index  int
+	amount money.Money
+}
+
+// allocateRefund spreads amount over the settled payments, as indexes into
+// payments. The last tender is given back first, each up to what is left of
+// it.
+func allocateRefund(payments []domain.OrderPayment, amount money.Money) (portions []refundPortion, err error) {
+	left := amount.Amount
+	for i := len(payments) - 1; i >= 0 && left > 0; i-- {
+		payment := payments[i]
+		if payment.Status != domain.OrderStatusPaid && payment.Status != domain.OrderStatusPartiallyRefunded {
+			continue
+		}
+
+		portion := min(left, payment.Amount.Amount-payment.RefundedAmount.Amount)
+		if portion <= 0 {
+			continue
+		}
+
+		portions = append(portions, refundPortion{index: i, amount: money.New(portion, amount.Currency)})
+		left -= portion
+	}
+
+	if left > 0 {
+		return nil, errs.ErrRefundQuantityExceeded
+	}
+
+	return portions, nil
+}
+
+// refundOrderPayment records amount as given back on payment.
+func (s *OrderServiceImpl) refundOrderPayment(ctx context.Context, repo repository.OrderRepository, payment domain.OrderPayment, amount money.Money, now int64) error {
+	from := payment.Status
+	payment.RefundedAmount = money.New(payment.RefundedAmount.Amount+amount.Amount, payment.Amount.Currency)
+	payment.Status = domain.OrderStatusPartiallyRefunded
+	if payment.RefundedAmount.Amount >= payment.Amount.Amount {
+		payment.Status = domain.OrderStatusRefunded
+	}
+	payment.UpdatedAt = now
+
+	updated, err := repo.TransitionOrderPayment(ctx, payment, []string{from})
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return errs.ErrConflict
+	}
+
+	return nil
+}
+
+// buildRefundItems turns the requested lines into refund items, or refunds
+// everything left when none are requested. complete reports whether nothing
+// is left to refund afterwards.
+func buildRefundItems(orderDetails []domain.OrderDetail, refundedItems []domain.RefundItem, requested []dto.RefundItemRequest) (items []domain.RefundItem, complete bool, err error) {
+	remaining := make(map[int64]int64, len(orderDetails))
+	// Refunds are made at what the customer paid for the line, net of
+	// discounts and with its service charge and taxes.
+	details := make(map[int64]domain.OrderDetail, len(orderDetails))
+	for _, detail := range orderDetails {
+		remaining[detail.ID] = detail.Quantity
+		details[detail.ID] = detail
+	}
+
+	for _, item := range refundedItems {
+		remaining[item.OrderDetailID] -= item.Quantity
+	}
+
+	if len(requested) == 0 {
+		for _, detail := range orderDetails {
+			if remaining[detail.ID] > 0 {
+				requested = append(requested, dto.RefundItemRequest{
+					OrderDetailID: detail.ID,
+					Quantity:      int(remaining[detail.ID]),
+				})
+			}
+		}
+	}
+
+	if len(requested) == 0 {
+		return nil, false, errs.ErrRefundQuantityExceeded
+	}
+
+	for _, req := range requested {
+		left, exists := remaining[req.OrderDetailID]
+		if !exists || req.Quantity <= 0 {
+			return nil, false, errs.ErrClient
+		}
+
+		if int64(req.Quantity) > left {
+			return nil, false, errs.ErrRefundQuantityExceeded
+		}
+
+		remaining[req.OrderDetailID] -= int64(req.Quantity)
+		detail := details[req.OrderDetailID]
+		amount := float64(detail.TotalAmount.Amount) * float64(req.Quantity) / float64(detail.Quantity)
+		items = append(items, domain.RefundItem{
+			OrderDetailID: req.OrderDetailID,
+			Quantity:      int64(req.Quantity),
+			Amount:        money.New(int64(math.Round(amount)), detail.TotalAmount.Currency),
+		})
+	}
+
+	complete = true
+	for _, left := range remaining {
+		if left > 0 {
+			complete = false
+			break
+		}
+	}
+
+	return items, complete, nil
+}
+
+// enqueueRefundRestock gives the refunded units back to the product service.
+func (s *OrderServiceImpl) enqueueRefundRestock(ctx context.Context, repo repository.OrderRepository, order domain.Order, orderDetails []domain.OrderDetail, items []domain.RefundItem) error {
+	productIDs := make(map[int64]string, len(orderDetails))
+	for _, detail := range orderDetails {
+		productIDs[detail.ID] = detail.ProductID
+	}
+
+	orderRequest := dto.OrderProductServiceRequest{
+		TransactionNumber: order.TransactionNumber,
+	}
+	for _, item := range items {
+		orderRequest.OrderItems = append(orderRequest.OrderItems, dto.OrderItem{
+			ProductID: productIDs[item.OrderDetailID],
+			Quantity:  int(item.Quantity),
+		})
+	}
+
+	return s.enqueueKafkaMessage(ctx, repo, order.TransactionNumber, dto.KafkaMessage{
+		EventType: "restore_product_stock",
+		Data:      orderRequest,
+	})
+}
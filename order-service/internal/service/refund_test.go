@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+// refundRepository holds one order and the refunds recorded on it.
+type refundRepository struct {
+	repository.OrderRepository
+	order   domain.Order
+	refunds []domain.Refund
+}
+
+func (r *refundRepository) HandleTrx(ctx context.Context, fn func(ctx context.Context, repo repository.OrderRepository) error) error {
+	return fn(ctx, r)
+}
+
+func (r *refundRepository) LockOrderByOrderID(ctx context.Context, id int64) (domain.Order, error) {
+	return r.order, nil
+}
+
+func (r *refundRepository) GetRefundsByOrderID(ctx context.Context, orderID int64) ([]domain.Refund, error) {
+	return r.refunds, nil
+}
+
+func (r *refundRepository) GetRefundByRefundKey(ctx context.Context, refundKey string) (domain.Refund, error) {
+	for _, refund := range r.refunds {
+		if refund.RefundKey == refundKey {
+			return refund, nil
+		}
+	}
+
+	return domain.Refund{}, errs.ErrNotFound
+}
+
+func TestStartRefundResumesRefundOfItsKey(t *testing.T) {
+	request := dto.RefundOrderRequest{
+		Items:  []dto.RefundItemRequest{{OrderDetailID: 1, Quantity: 1}},
+		Reason: "Damaged",
+	}
+
+	requestHash, err := hashRefundRequest(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type TestCase struct {
+		Name        string
+		Refund      domain.Refund
+		Request     dto.RefundOrderRequest
+		ExpectedErr error
+	}
+
+	testCases := []TestCase{
+		{
+			Name:    "Same refund retried",
+			Refund:  domain.Refund{ID: 7, OrderID: 1, RefundKey: "key", RequestHash: requestHash},
+			Request: dto.RefundOrderRequest{Items: request.Items, Reason: request.Reason, RefundKey: "key"},
+		},
+		{
+			Name:    "Retried by another cashier",
+			Refund:  domain.Refund{ID: 7, OrderID: 1, RefundKey: "key", RequestHash: requestHash},
+			Request: dto.RefundOrderRequest{Items: request.Items, Reason: request.Reason, RefundedBy: 2, RefundKey: "key"},
+		},
+		{
+			Name:        "Key reused for other items",
+			Refund:      domain.Refund{ID: 7, OrderID: 1, RefundKey: "key", RequestHash: requestHash},
+			Request:     dto.RefundOrderRequest{Items: []dto.RefundItemRequest{{OrderDetailID: 2, Quantity: 1}}, Reason: request.Reason, RefundKey: "key"},
+			ExpectedErr: errs.ErrIdempotencyKeyReused,
+		},
+		{
+			Name:        "Key reused to restock",
+			Refund:      domain.Refund{ID: 7, OrderID: 1, RefundKey: "key", RequestHash: requestHash},
+			Request:     dto.RefundOrderRequest{Items: request.Items, Reason: request.Reason, Restock: true, RefundKey: "key"},
+			ExpectedErr: errs.ErrIdempotencyKeyReused,
+		},
+		{
+			Name:        "Key reused for another reason",
+			Refund:      domain.Refund{ID: 7, OrderID: 1, RefundKey: "key", RequestHash: requestHash},
+			Request:     dto.RefundOrderRequest{Items: request.Items, Reason: "Wrong size", RefundKey: "key"},
+			ExpectedErr: errs.ErrIdempotencyKeyReused,
+		},
+		{
+			Name:        "Key reused on another order",
+			Refund:      domain.Refund{ID: 7, OrderID: 2, RefundKey: "key", RequestHash: requestHash},
+			Request:     dto.RefundOrderRequest{Items: request.Items, Reason: request.Reason, RefundKey: "key"},
+			ExpectedErr: errs.ErrIdempotencyKeyReused,
+		},
+		{
+			Name:    "Derived key resumes the same refund",
+			Refund:  domain.Refund{ID: 7, OrderID: 1, RefundKey: "ORD-1-refund-1", RequestHash: requestHash},
+			Request: dto.RefundOrderRequest{Items: request.Items, Reason: request.Reason},
+		},
+		{
+			Name:        "Derived key finds another pending refund",
+			Refund:      domain.Refund{ID: 7, OrderID: 1, RefundKey: "ORD-1-refund-1", RequestHash: requestHash},
+			Request:     dto.RefundOrderRequest{Items: request.Items, Reason: "Wrong size"},
+			ExpectedErr: errs.ErrRefundInProgress,
+		},
+		{
+			Name:    "Refund recorded without a hash",
+			Refund:  domain.Refund{ID: 7, OrderID: 1, RefundKey: "key"},
+			Request: dto.RefundOrderRequest{Items: request.Items, Reason: "Wrong size", RefundKey: "key"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Refund.Status = domain.RefundStatusPending
+			repo := &refundRepository{
+				order:   domain.Order{ID: 1, TransactionNumber: "ORD-1", PaymentStatus: domain.OrderStatusPaid},
+				refunds: []domain.Refund{tc.Refund},
+			}
+			s := &OrderServiceImpl{repository: repo}
+
+			_, refund, err := s.startRefund(context.Background(), 1, tc.Request)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if err == nil && refund.ID != tc.Refund.ID {
+				t.Errorf("expected refund %d to be resumed, got %d", tc.Refund.ID, refund.ID)
+			}
+		})
+	}
+}
+
+func TestAllocateRefund(t *testing.T) {
+	payment := func(id int64, status string, amount, refunded int64) domain.OrderPayment {
+		return domain.OrderPayment{ID: id, Status: status, Amount: rupiah(amount), RefundedAmount: rupiah(refunded)}
+	}
+
+	type TestCase struct {
+		Name        string
+		Payments    []domain.OrderPayment
+		Amount      money.Money
+		Expected    []refundPortion
+		ExpectedErr error
+	}
+
+	testCases := []TestCase{
+		{
+			Name:     "Single tender",
+			Payments: []domain.OrderPayment{payment(1, domain.OrderStatusPaid, 10000, 0)},
+			Amount:   rupiah(4000),
+			Expected: []refundPortion{{index: 0, amount: rupiah(4000)}},
+		},
+		{
+			Name: "Last tender given back first",
+			Payments: []domain.OrderPayment{
+				payment(1, domain.OrderStatusPaid, 10000, 0),
+				payment(2, domain.OrderStatusPaid, 5000, 0),
+			},
+			Amount:   rupiah(3000),
+			Expected: []refundPortion{{index: 1, amount: rupiah(3000)}},
+		},
+		{
+			Name: "Spills over to the earlier tender",
+			Payments: []domain.OrderPayment{
+				payment(1, domain.OrderStatusPaid, 10000, 0),
+				payment(2, domain.OrderStatusPaid, 5000, 0),
+			},
+			Amount:   rupiah(8000),
+			Expected: []refundPortion{{index: 1, amount: rupiah(5000)}, {index: 0, amount: rupiah(3000)}},
+		},
+		{
+			Name: "Only what is left of a partly refunded tender",
+			Payments: []domain.OrderPayment{
+				payment(1, domain.OrderStatusPaid, 10000, 0),
+				payment(2, domain.OrderStatusPartiallyRefunded, 5000, 4000),
+			},
+			Amount:   rupiah(3000),
+			Expected: []refundPortion{{index: 1, amount: rupiah(1000)}, {index: 0, amount: rupiah(2000)}},
+		},
+		{
+			Name: "Unsettled and refunded tenders skipped",
+			Payments: []domain.OrderPayment{
+				payment(1, domain.OrderStatusPaid, 10000, 0),
+				payment(2, domain.OrderStatusRefunded, 5000, 5000),
+				payment(3, domain.OrderStatusExpired, 5000, 0),
+			},
+			Amount:   rupiah(6000),
+			Expected: []refundPortion{{index: 0, amount: rupiah(6000)}},
+		},
+		{
+			Name: "More than was paid",
+			Payments: []domain.OrderPayment{
+				payment(1, domain.OrderStatusPaid, 10000, 0),
+				payment(2, domain.OrderStatusPaid, 5000, 0),
+			},
+			Amount:      rupiah(15001),
+			ExpectedErr: errs.ErrRefundQuantityExceeded,
+		},
+		{
+			Name:     "Nothing to refund",
+			Payments: []domain.OrderPayment{payment(1, domain.OrderStatusPaid, 10000, 0)},
+			Amount:   rupiah(0),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			portions, err := allocateRefund(tc.Payments, tc.Amount)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if !slices.Equal(portions, tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, portions)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/utils"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+// settledOrderStatuses are the statuses of payments the payment provider
+// settles to the merchant, refunded or not.
+var settledOrderStatuses = []string{
+	domain.OrderStatusPaid,
+	domain.OrderStatusPartiallyRefunded,
+	domain.OrderStatusRefunded,
+}
+
+// wib is the time zone the days of the reports are taken in.
+var wib = time.FixedZone("WIB", 7*60*60)
+
+// reportRange turns the dates of req into the Unix range [from, to) covering
+// both days in full.
+func reportRange(req dto.ReportRequest) (from int64, to int64, err error) {
+	start, err := utils.ParseWIBDate(req.StartDate)
+	if err != nil {
+		return 0, 0, errs.ErrClient
+	}
+
+	end, err := utils.ParseWIBDate(req.EndDate)
+	if err != nil || end.Before(start) {
+		return 0, 0, errs.ErrClient
+	}
+
+	return start.Unix(), end.AddDate(0, 0, 1).Unix(), nil
+}
+
+// GetSettlementReport sums up what each payment method settles over the
+// orders paid in the date range. The MDR fee is kept by the provider even
+// when the order is refunded, so the net amount is what is left after both.
+func (s *OrderServiceImpl) GetSettlementReport(ctx context.Context, req dto.ReportRequest) (response dto.SettlementReportResponse, err error) {
+	from, to, err := reportRange(req)
+	if err != nil {
+		return
+	}
+
+	summaries, err := s.repository.GetSettlementSummaries(ctx, from, to, settledOrderStatuses)
+	if err != nil {
+		return
+	}
+
+	response.StartDate = req.StartDate
+	response.EndDate = req.EndDate
+	response.PaymentMethods = []dto.SettlementReportRow{}
+	gross, refunded, fee := money.New(0, money.IDR), money.New(0, money.IDR), money.New(0, money.IDR)
+	for _, summary := range summaries {
+		response.PaymentMethods = append(response.PaymentMethods, dto.SettlementReportRow{
+			PaymentMethodID:   summary.PaymentMethodID,
+			PaymentMethodName: summary.PaymentMethodName,
+			MDR:               summary.MDR,
+			MDRType:           summary.MDRType,
+			OrderCount:        summary.OrderCount,
+			GrossAmount:       summary.GrossAmount.Float64(),
+			RefundedAmount:    summary.RefundedAmount.Float64(),
+			MDRFee:            summary.MDRFee.Float64(),
+			NetAmount:         money.New(summary.GrossAmount.Amount-summary.RefundedAmount.Amount-summary.MDRFee.Amount, summary.GrossAmount.Currency).Float64(),
+		})
+		response.Total.OrderCount += summary.OrderCount
+		gross.Amount += summary.GrossAmount.Amount
+		refunded.Amount += summary.RefundedAmount.Amount
+		fee.Amount += summary.MDRFee.Amount
+	}
+
+	response.Total.GrossAmount = gross.Float64()
+	response.Total.RefundedAmount = refunded.Float64()
+	response.Total.MDRFee = fee.Float64()
+	response.Total.NetAmount = money.New(gross.Amount-refunded.Amount-fee.Amount, money.IDR).Float64()
+
+	return
+}
+
+// GetCashReport sums up the cash each cashier took in and handed back over
+// the date range, net cash being what their drawer should have gained.
+func (s *OrderServiceImpl) GetCashReport(ctx context.Context, req dto.ReportRequest) (response dto.CashReportResponse, err error) {
+	from, to, err := reportRange(req)
+	if err != nil {
+		return
+	}
+
+	summaries, err := s.repository.GetCashierCashSummaries(ctx, from, to)
+	if err != nil {
+		return
+	}
+
+	response.StartDate = req.StartDate
+	response.EndDate = req.EndDate
+	response.Cashiers = []dto.CashReportRow{}
+	cashIn, cashOut := money.New(0, money.IDR), money.New(0, money.IDR)
+	for _, summary := range summaries {
+		response.Cashiers = append(response.Cashiers, dto.CashReportRow{
+			CashierID:   summary.CashierID,
+			SaleCount:   summary.SaleCount,
+			CashIn:      summary.CashIn.Float64(),
+			RefundCount: summary.RefundCount,
+			CashOut:     summary.CashOut.Float64(),
+			NetCash:     money.New(summary.CashIn.Amount-summary.CashOut.Amount, summary.CashIn.Currency).Float64(),
+		})
+		response.Total.SaleCount += summary.SaleCount
+		response.Total.RefundCount += summary.RefundCount
+		cashIn.Amount += summary.CashIn.Amount
+		cashOut.Amount += summary.CashOut.Amount
+	}
+
+	response.Total.CashIn = cashIn.Float64()
+	response.Total.CashOut = cashOut.Float64()
+	response.Total.NetCash = money.New(cashIn.Amount-cashOut.Amount, money.IDR).Float64()
+
+	return
+}
+
+// salesReportScope checks the request of a sales report and works out the
+// store and the Unix range it covers.
+func salesReportScope(req dto.SalesReportRequest) (storeCode *string, from int64, to int64, err error) {
+	switch req.Format {
+	case "", dto.ReportFormatJSON, dto.ReportFormatCSV:
+	default:
+		return nil, 0, 0, errs.ErrClient
+	}
+
+	from, to, err = reportRange(req.ReportRequest)
+	if err != nil {
+		return
+	}
+
+	if code := strings.TrimSpace(req.StoreCode); code != "" {
+		storeCode = &code
+	}
+
+	return
+}
+
+// GetSalesReport sums up the sales of a store day by day, along with how they
+// were paid and at what time of the day.
+func (s *OrderServiceImpl) GetSalesReport(ctx context.Context, req dto.SalesReportRequest) (response dto.SalesReportResponse, err error) {
+	storeCode, from, to, err := salesReportScope(req)
+	if err != nil {
+		return
+	}
+
+	sales, err := s.repository.GetDailySalesSummaries(ctx, storeCode, from, to, settledOrderStatuses)
+	if err != nil {
+		return
+	}
+
+	refunds, err := s.repository.GetDailyRefundSummaries(ctx, storeCode, from, to)
+	if err != nil {
+		return
+	}
+
+	response.StoreCode = strings.TrimSpace(req.StoreCode)
+	response.StartDate = req.StartDate
+	response.EndDate = req.EndDate
+
+	// A day can have refunds of orders paid on earlier days and no sales of
+	// its own, so the days of both are merged.
+	days := make(map[string]*salesTotals)
+	day := func(date string) *salesTotals {
+		if days[date] == nil {
+			days[date] = newSalesTotals()
+		}
+		return days[date]
+	}
+
+	total := newSalesTotals()
+	for _, summary := range sales {
+		day(summary.Day).addSales(summary)
+		total.addSales(summary)
+	}
+
+	for _, summary := range refunds {
+		day(summary.Day).addRefunds(summary)
+		total.addRefunds(summary)
+	}
+
+	response.Days = []dto.DailySalesRow{}
+	for date := time.Unix(from, 0).In(wib); date.Unix() < to; date = date.AddDate(0, 0, 1) {
+		if totals, ok := days[date.Format("2006-01-02")]; ok {
+			response.Days = append(response.Days, dto.DailySalesRow{Date: date.Format("2006-01-02"), SalesFigures: totals.figures()})
+		}
+	}
+	response.Total = total.figures()
+
+	response.PaymentMethods, err = s.paymentMethodSales(ctx, storeCode, from, to)
+	if err != nil {
+		return
+	}
+
+	response.Hours, err = s.hourlySales(ctx, storeCode, from, to)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetZReport closes the business days of a store: what was sold and refunded
+// over them, how it was paid, at what time of the day, and what became of
+// every order placed.
+func (s *OrderServiceImpl) GetZReport(ctx context.Context, req dto.SalesReportRequest) (response dto.ZReportResponse, err error) {
+	storeCode, from, to, err := salesReportScope(req)
+	if err != nil {
+		return
+	}
+
+	sales, err := s.repository.GetDailySalesSummaries(ctx, storeCode, from, to, settledOrderStatuses)
+	if err != nil {
+		return
+	}
+
+	refunds, err := s.repository.GetDailyRefundSummaries(ctx, storeCode, from, to)
+	if err != nil {
+		return
+	}
+
+	response.StoreCode = strings.TrimSpace(req.StoreCode)
+	response.StartDate = req.StartDate
+	response.EndDate = req.EndDate
+	response.GeneratedAt = time.Now().Unix()
+
+	total := newSalesTotals()
+	for _, summary := range sales {
+		total.addSales(summary)
+	}
+	for _, summary := range refunds {
+		total.addRefunds(summary)
+	}
+	response.Sales = total.figures()
+
+	response.PaymentMethods, err = s.paymentMethodSales(ctx, storeCode, from, to)
+	if err != nil {
+		return
+	}
+
+	response.Hours, err = s.hourlySales(ctx, storeCode, from, to)
+	if err != nil {
+		return
+	}
+
+	statuses, err := s.repository.GetOrderStatusSummaries(ctx, storeCode, from, to)
+	if err != nil {
+		return
+	}
+
+	response.OrderStatuses = []dto.OrderStatusRow{}
+	for _, summary := range statuses {
+		response.OrderStatuses = append(response.OrderStatuses, dto.OrderStatusRow{
+			Status:     summary.Status,
+			OrderCount: summary.OrderCount,
+			Amount:     summary.Amount.Float64(),
+		})
+	}
+
+	return
+}
+
+func (s *OrderServiceImpl) paymentMethodSales(ctx context.Context, storeCode *string, from int64, to int64) (rows []dto.PaymentMethodSalesRow, err error) {
+	summaries, err := s.repository.GetPaymentMethodSalesSummaries(ctx, storeCode, from, to, settledOrderStatuses)
+	if err != nil {
+		return
+	}
+
+	rows = []dto.PaymentMethodSalesRow{}
+	for _, summary := range summaries {
+		rows = append(rows, dto.PaymentMethodSalesRow{
+			PaymentMethodID:   summary.PaymentMethodID,
+			PaymentMethodName: summary.PaymentMethodName,
+			OrderCount:        summary.OrderCount,
+			Amount:            summary.Amount.Float64(),
+		})
+	}
+
+	return
+}
+
+// hourlySales spreads the sales over the 24 hours of the day, the hours
+// without any are kept so the distribution can be charted as it is.
+func (s *OrderServiceImpl) hourlySales(ctx context.Context, storeCode *string, from int64, to int64) (rows []dto.HourlySalesRow, err error) {
+	summaries, err := s.repository.GetHourlySalesSummaries(ctx, storeCode, from, to, settledOrderStatuses)
+	if err != nil {
+		return
+	}
+
+	rows = make([]dto.HourlySalesRow, 24)
+	for hour := range rows {
+		rows[hour].Hour = hour
+	}
+
+	for _, summary := range summaries {
+		if summary.Hour < 0 || summary.Hour >= len(rows) {
+			continue
+		}
+
+		rows[summary.Hour].OrderCount = summary.OrderCount
+		rows[summary.Hour].Amount = summary.Amount.Float64()
+	}
+
+	return
+}
+
+// salesTotals adds up the sales and refunds of a report exactly, before they
+// are turned into the figures of its response.
+type salesTotals struct {
+	orderCount    int64
+	itemCount     int64
+	refundCount   int64
+	gross         money.Money
+	discount      money.Money
+	serviceCharge money.Money
+	tax           money.Money
+	rounding      money.Money
+	collected     money.Money
+	refunded      money.Money
+}
+
+func newSalesTotals() *salesTotals {
+	zero := money.New(0, money.IDR)
+	return &salesTotals{gross: zero, discount: zero, serviceCharge: zero, tax: zero, rounding: zero, collected: zero, refunded: zero}
+}
+
+func (t *salesTotals) addSales(summary domain.SalesSummary) {
+	t.orderCount += summary.OrderCount
+	t.itemCount += summary.ItemCount
+	t.gross.Amount += summary.GrossAmount.Amount
+	t.discount.Amount += summary.DiscountAmount.Amount
+	t.serviceCharge.Amount += summary.ServiceChargeAmount.Amount
+	t.tax.Amount += summary.TaxAmount.Amount
+	t.rounding.Amount += summary.RoundingAmount.Amount
+	t.collected.Amount += summary.Amount.Amount
+}
+
+func (t *salesTotals) addRefunds(summary domain.RefundSummary) {
+	t.refundCount += summary.RefundCount
+	t.refunded.Amount += summary.Amount.Amount
+}
+
+// figures works out the figures of the totals, along with those that follow
+// from the sums. The average basket is rounded to the nearest minor unit.
+func (t *salesTotals) figures() dto.SalesFigures {
+	figures := dto.SalesFigures{
+		OrderCount:          t.orderCount,
+		ItemCount:           t.itemCount,
+		GrossSales:          t.gross.Float64(),
+		DiscountAmount:      t.discount.Float64(),
+		ServiceChargeAmount: t.serviceCharge.Float64(),
+		TaxAmount:           t.tax.Float64(),
+		RoundingAmount:      t.rounding.Float64(),
+		CollectedAmount:     t.collected.Float64(),
+		RefundCount:         t.refundCount,
+		RefundedAmount:      t.refunded.Float64(),
+		NetSales:            money.New(t.collected.Amount-t.refunded.Amount, t.collected.Currency).Float64(),
+	}
+
+	if t.orderCount > 0 {
+		basket := math.Round(float64(t.collected.Amount) / float64(t.orderCount))
+		figures.AverageBasketAmount = money.New(int64(basket), t.collected.Currency).Float64()
+		figures.AverageBasketItems = math.Round(float64(t.itemCount)/float64(t.orderCount)*100) / 100
+	}
+
+	return figures
+}
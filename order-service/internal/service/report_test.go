@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+func TestSalesTotalsFigures(t *testing.T) {
+	sen := func(amount int64) money.Money {
+		return money.New(amount, money.IDR)
+	}
+
+	totals := newSalesTotals()
+	totals.addSales(domain.SalesSummary{OrderCount: 2, ItemCount: 3, GrossAmount: sen(10), TaxAmount: sen(1), Amount: sen(10)})
+	totals.addSales(domain.SalesSummary{OrderCount: 1, ItemCount: 2, GrossAmount: sen(20), TaxAmount: sen(2), Amount: sen(20)})
+	totals.addRefunds(domain.RefundSummary{RefundCount: 1, Amount: sen(30)})
+
+	// Added up as floats, 0.1 + 0.2 - 0.3 is not 0.
+	expected := dto.SalesFigures{
+		OrderCount:          3,
+		ItemCount:           5,
+		GrossSales:          0.3,
+		TaxAmount:           0.03,
+		CollectedAmount:     0.3,
+		RefundCount:         1,
+		RefundedAmount:      0.3,
+		NetSales:            0,
+		AverageBasketAmount: 0.1,
+		AverageBasketItems:  1.67,
+	}
+
+	if figures := totals.figures(); figures != expected {
+		t.Errorf("expected %+v, got %+v", expected, figures)
+	}
+}
+
+func TestSalesTotalsAverageBasket(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Orders   int64
+		Amount   money.Money
+		Expected float64
+	}
+
+	testCases := []TestCase{
+		{Name: "No orders", Orders: 0, Amount: rupiah(0), Expected: 0},
+		{Name: "Even split", Orders: 4, Amount: rupiah(100000), Expected: 25000},
+		{Name: "Rounded to the nearest sen", Orders: 3, Amount: rupiah(100000), Expected: 33333.33},
+		{Name: "Halfway rounds up", Orders: 2, Amount: money.New(1, money.IDR), Expected: 0.01},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			totals := newSalesTotals()
+			totals.addSales(domain.SalesSummary{OrderCount: tc.Orders, Amount: tc.Amount})
+
+			if average := totals.figures().AverageBasketAmount; average != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, average)
+			}
+		})
+	}
+}
@@ -2,46 +2,42 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/sony/gobreaker/v2"
-	"go.opentelemetry.io/otel"
 
 	"github.com/alimikegami/point-of-sales/order-service/config"
 	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
 	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	paymentgateway "github.com/alimikegami/point-of-sales/order-service/internal/infrastructure/payment-gateway"
 	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
-	pkgdto "github.com/alimikegami/point-of-sales/order-service/pkg/dto"
 	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
-	"github.com/alimikegami/point-of-sales/order-service/pkg/utils"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
 	pb "github.com/alimikegami/pos-microservices/proto-defs/pb"
 	"github.com/google/uuid"
-	"github.com/midtrans/midtrans-go"
-	"github.com/midtrans/midtrans-go/coreapi"
 	"github.com/segmentio/kafka-go"
 )
 
 type OrderServiceImpl struct {
 	repository               repository.OrderRepository
-	midtransClient           *coreapi.Client
-	kafkaReader              *kafka.Reader
+	paymentGateways          *paymentgateway.Registry
 	kafkaProducer            *kafka.Conn
+	orderEventWriter         *kafka.Writer
 	config                   *config.Config
 	productService           *gobreaker.CircuitBreaker[[]byte]
 	productCommandGrpcClient pb.ProductCommandServiceClient
 	productQueryGrpcClient   pb.ProductQueryServiceClient
 }
 
-func CreateOrderService(repository repository.OrderRepository, midtransClient *coreapi.Client, kafkaReader *kafka.Reader, kafkaProducer *kafka.Conn, config *config.Config, productService *gobreaker.CircuitBreaker[[]byte], productCommandGrpcClient pb.ProductCommandServiceClient, productQueryGrpcClient pb.ProductQueryServiceClient) OrderService {
+func CreateOrderService(repository repository.OrderRepository, paymentGateways *paymentgateway.Registry, kafkaProducer *kafka.Conn, orderEventWriter *kafka.Writer, config *config.Config, productService *gobreaker.CircuitBreaker[[]byte], productCommandGrpcClient pb.ProductCommandServiceClient, productQueryGrpcClient pb.ProductQueryServiceClient) OrderService {
 	return &OrderServiceImpl{
 		repository:               repository,
-		midtransClient:           midtransClient,
-		kafkaReader:              kafkaReader,
+		paymentGateways:          paymentGateways,
 		kafkaProducer:            kafkaProducer,
+		orderEventWriter:         orderEventWriter,
 		config:                   config,
 		productService:           productService,
 		productCommandGrpcClient: productCommandGrpcClient,
@@ -50,329 +46,248 @@ func CreateOrderService(repository repository.OrderRepository, midtransClient *c
 }
 
 func (s *OrderServiceImpl) AddOrder(ctx context.Context, req dto.OrderRequest) (orderResponse dto.OrderResponse, err error) {
+	if req.IdempotencyKey != "" {
+		return s.addOrderIdempotently(ctx, req)
+	}
+
+	return s.placeOrder(ctx, req)
+}
+
+func (s *OrderServiceImpl) placeOrder(ctx context.Context, req dto.OrderRequest) (orderResponse dto.OrderResponse, err error) {
 	trxNumber, err := uuid.NewV7()
 	if err != nil {
 		return orderResponse, fmt.Errorf("error generating transaction number: %v", err)
 	}
 
-	var productReqPayload dto.OrderProductServiceRequest
-	var products []*pb.ProductQuantityUpdate
-	productIDs := make([]string, len(req.OrderItems))
-
-	productReqPayload.TransactionNumber = trxNumber.String()
-	for i, item := range req.OrderItems {
-		productReqPayload.OrderItems = append(productReqPayload.OrderItems, dto.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-		})
+	// Rejected before anything is reserved, rather than at the charge step.
+	payments, err := s.orderSagaPayments(ctx, trxNumber.String(), req)
+	if err != nil {
+		return
+	}
 
-		productIDs[i] = item.ProductID
-		products = append(products, &pb.ProductQuantityUpdate{
-			ProductId: item.ProductID,
-			Quantity:  int64(item.Quantity),
-		})
+	if req.StoreCode == "" {
+		req.StoreCode = s.config.TaxConfig.DefaultStoreCode
 	}
 
-	productPrices, err := s.productQueryGrpcClient.GetProductPrice(ctx, &pb.GetProductPriceRequest{
-		ProductIds: productIDs,
-	})
+	customerID, customer, err := s.orderCustomer(ctx, req)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("Failed to get product price info")
-		return orderResponse, err
+		return
 	}
 
-	productPriceMap := make(map[string]*pb.Product, len(productPrices.Products))
-	for _, product := range productPrices.Products {
-		productPriceMap[product.ProductId] = product
+	state := orderSagaState{
+		CashierID:  req.UserID,
+		CustomerID: customerID,
+		Customer:   customer,
+		StoreCode:  req.StoreCode,
+		Currency:   money.IDR,
+		PromoCodes: req.PromoCodes,
+		Payments:   payments,
 	}
 
-	_, err = s.productService.Execute(func() ([]byte, error) {
-		_, err := s.productCommandGrpcClient.UpdateProductQuantityBatch(ctx, &pb.UpdateProductQuantityRequest{
-			Products: products,
-		})
-
-		if err != nil {
-			log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("Failed to update product quantity")
-			return nil, err
-		}
-
-		return nil, nil
-	})
-
+	state.ShiftID, err = s.openShiftID(ctx, s.repository, int64(req.UserID))
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("Failed to update product quantity")
-		return orderResponse, err
+		return
 	}
 
-	restoreProductMsg := dto.KafkaMessage{
-		EventType: "restore_product_stock",
-		Data:      productReqPayload,
+	if state.ShiftID == nil && state.takesCash() {
+		return orderResponse, errs.ErrShiftNotOpen
+	}
+	for _, item := range req.OrderItems {
+		state.OrderItems = append(state.OrderItems, orderSagaItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		})
 	}
 
-	restoreProductMsgParsed, err := json.Marshal(restoreProductMsg)
+	saga, err := s.startOrderSaga(ctx, trxNumber.String(), state)
 	if err != nil {
-		log.Ctx(ctx).Info().Msg("Restoring product stock")
-		go func() {
-			err = s.WriteKafkaMessageWithKey(restoreProductMsgParsed, trxNumber.String())
-			if err != nil {
-				log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("Failed to write restore product stock message")
-			}
-		}()
-
-		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("Failed to marshal restore product stock message")
-
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("Failed to start order saga")
 		return orderResponse, err
 	}
 
-	var paymentType coreapi.CoreapiPaymentType
-	var orderDetails []domain.OrderDetail
-
-	var totalAmount float64
-	chargeItems := make([]midtrans.ItemDetails, len(req.OrderItems))
-	for i, item := range req.OrderItems {
-		productInfo, exists := productPriceMap[item.ProductID]
-		if exists {
-			orderDetails = append(orderDetails, domain.OrderDetail{
-				ProductID:   productInfo.ProductId,
-				Quantity:    int64(item.Quantity),
-				Amount:      float64(productInfo.Price),
-				ProductName: productInfo.Name,
-				CreatedAt:   time.Now().Unix(),
-				UpdatedAt:   time.Now().Unix(),
-			})
-
-			itemTotal := float64(productInfo.Price) * float64(item.Quantity)
-			totalAmount += itemTotal
-			chargeItems[i] = midtrans.ItemDetails{
-				ID:    item.ProductID,
-				Price: int64(productInfo.Price),
-				Qty:   int32(item.Quantity),
-				Name:  productInfo.Name,
-			}
-		} else {
-			log.Ctx(ctx).Info().Msg("Restoring product stock")
-			go func() {
-				err = s.WriteKafkaMessageWithKey(restoreProductMsgParsed, trxNumber.String())
-				if err != nil {
-					log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("")
-				}
-			}()
-
-			return orderResponse, fmt.Errorf("product info not found for product ID: %s", item.ProductID)
-		}
-	}
-
-	paymentMethod, err := s.repository.GetPaymentMethodByID(ctx, req.PaymentMethodID)
+	err = s.runOrderSaga(ctx, saga, 0)
 	if err != nil {
-		log.Ctx(ctx).Info().Msg("Restoring product stock")
-		go func() {
-			err = s.WriteKafkaMessageWithKey(restoreProductMsgParsed, trxNumber.String())
-			if err != nil {
-				log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("Failed to write restore product stock message")
-			}
-		}()
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Str("transaction_number", saga.TransactionNumber).Msg("Order saga failed")
 		return orderResponse, err
 	}
 
-	if strings.ToLower(paymentMethod.Name) == "qris" {
-		paymentType = coreapi.PaymentTypeQris
+	orderResponse.ID = *saga.OrderID
+	orderResponse.Subtotal = saga.state.Subtotal.Float64()
+	orderResponse.DiscountAmount = saga.state.DiscountAmount.Float64()
+	orderResponse.TransactionAmount = saga.state.TotalAmount.Float64()
+	for _, discount := range saga.state.Discounts {
+		orderResponse.Discounts = append(orderResponse.Discounts, dto.OrderDiscountResponse{
+			PromotionID: discount.PromotionID,
+			Name:        discount.Name,
+			Amount:      discount.Amount.Float64(),
+		})
 	}
-
-	tracer := otel.Tracer("pos-order-service")
-	_, span := tracer.Start(ctx, "midtrans charge request")
-
-	chargeReq := &coreapi.ChargeReq{
-		PaymentType: paymentType,
-		TransactionDetails: midtrans.TransactionDetails{
-			OrderID:  trxNumber.String(),
-			GrossAmt: int64(totalAmount),
-		},
-		CustomerDetails: &midtrans.CustomerDetails{
-			FName: "John",
-			LName: "Doe",
-			Email: "john@example.com",
-			Phone: "081234567890",
-		},
-		Items: &chargeItems,
+	orderResponse.ServiceChargeAmount = saga.state.ServiceChargeAmount.Float64()
+	orderResponse.TaxAmount = saga.state.TaxAmount.Float64()
+	for _, charge := range saga.state.Charges {
+		orderResponse.Charges = append(orderResponse.Charges, dto.OrderChargeResponse{
+			Type:           charge.Type,
+			Name:           charge.Name,
+			Rate:           charge.Rate,
+			BaseAmount:     charge.BaseAmount.Float64(),
+			Amount:         charge.Amount.Float64(),
+			IncludedAmount: charge.IncludedAmount.Float64(),
+		})
+	}
+	orderResponse.RoundingAmount = saga.state.RoundingAmount.Float64()
+	orderResponse.PaymentExpiredAt = &saga.state.ExpiredAt
+
+	orderPayments := saga.state.orderPayments(time.Now().Unix())
+	orderResponse.PaymentStatus = domain.OrderStatusFromPayments(saga.state.TotalAmount, orderPayments)
+	var tenderedAmount, changeAmount *money.Money
+	for i, payment := range orderPayments {
+		tenderedAmount = addAmount(tenderedAmount, payment.TenderedAmount)
+		changeAmount = addAmount(changeAmount, payment.ChangeAmount)
+		orderResponse.Payments = append(orderResponse.Payments, orderPaymentResponse(payment, saga.state.Payments[i].PaymentMethodName))
 	}
 
-	s.midtransClient.Options.SetContext(ctx)
-
-	response, _ := s.midtransClient.ChargeTransaction(chargeReq)
+	orderResponse.TenderedAmount = majorUnits(tenderedAmount)
+	orderResponse.ChangeAmount = majorUnits(changeAmount)
 
-	span.End()
+	// The first tender stands for the order, for clients that only ever pay
+	// with one.
+	primary := saga.state.Payments[0]
+	orderResponse.PaymentMethodName = primary.PaymentMethodName
+	orderResponse.QRCode = primary.QRCode
+	orderResponse.VANumber = primary.VANumber
+	orderResponse.TransactionNumber = saga.TransactionNumber
 
-	log.Ctx(ctx).Info().Msg("Midtrans response status: " + response.StatusCode)
-	if response.StatusCode != "201" {
-		log.Ctx(ctx).Info().Msg("Restoring product stock")
-		go func() {
-			err = s.WriteKafkaMessageWithKey(restoreProductMsgParsed, trxNumber.String())
-			if err != nil {
-				log.Error().Err(err).Str("component", "AddOrder").Msg("")
-			}
-		}()
+	return orderResponse, nil
+}
 
-		return orderResponse, fmt.Errorf("payment gateway returned non-200 status: %s", response.StatusCode)
+func (s *OrderServiceImpl) MidtransPaymentWebhook(ctx context.Context, req dto.PaymentNotification) (err error) {
+	if !paymentgateway.VerifyMidtransSignature(s.config.MidtransConfig.ServerKey, req.OrderID, req.StatusCode, req.GrossAmount, req.SignatureKey) {
+		log.Ctx(ctx).Warn().Str("component", "MidtransPaymentWebhook").Str("transaction_number", req.OrderID).Msg("Rejected payment notification with an invalid signature")
+		return errs.ErrInvalidSignature
 	}
 
-	if strings.ToLower(paymentMethod.Name) == "qris" {
-		orderResponse.QRCode = &response.QRString
+	// Every tender is a transaction of its own at Midtrans, the notification
+	// is about one of them.
+	payment, err := s.repository.GetOrderPaymentByTransactionNumber(ctx, req.OrderID)
+	if err != nil {
+		return
 	}
 
-	expiredAt, err := utils.ConvertDateTimeWibToUnixTimestamp(response.ExpiryTime)
-	if err != nil {
-		log.Ctx(ctx).Info().Msg("Restoring product stock")
-		go func() {
-			err = s.WriteKafkaMessageWithKey(restoreProductMsgParsed, trxNumber.String())
-			if err != nil {
-				log.Error().Err(err).Str("component", "AddOrder").Msg("")
-			}
-		}()
+	return s.applyPaymentNotification(ctx, payment, req, domain.OrderStatusChange{
+		Source: domain.OrderStatusSourceWebhook,
+		Reason: statusReason(midtransStatusReason(req)),
+	})
+}
 
-		log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("")
-		return orderResponse, err
+// applyPaymentNotification moves the payment, and the order it belongs to, to
+// the status the gateway reports for it. The status is recorded whether it
+// applies or not, and a status recorded before is not applied again.
+func (s *OrderServiceImpl) applyPaymentNotification(ctx context.Context, payment domain.OrderPayment, req dto.PaymentNotification, change domain.OrderStatusChange) (err error) {
+	if !gatewayAmountMatches(req.GrossAmount, payment.Amount) {
+		log.Ctx(ctx).Warn().Str("component", "applyPaymentNotification").Str("transaction_number", req.OrderID).Str("gross_amount", req.GrossAmount).Stringer("payment_amount", payment.Amount).Msg("Rejected payment notification with a mismatching amount")
+		return errs.ErrPaymentAmountMismatch
 	}
 
+	status, known := midtransOrderStatus(req.TransactionStatus, req.FraudStatus)
+
+	var order domain.Order
+	var reviewID int64
+	followUp := paymentFollowUpNone
 	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
-		orderID, err := repo.AddOrder(ctx, domain.Order{
-			PaymentMethodID:   int64(req.PaymentMethodID),
-			Amount:            totalAmount,
-			PaymentStatus:     "pending",
-			TransactionNumber: trxNumber.String(),
-			ExpiredAt:         expiredAt,
+		notification := domain.PaymentNotification{
+			OrderID:           payment.OrderID,
+			OrderPaymentID:    &payment.ID,
+			TransactionID:     req.TransactionID,
+			TransactionStatus: req.TransactionStatus,
+			StatusCode:        req.StatusCode,
+			GrossAmount:       req.GrossAmount,
+			Payload:           string(req.RawPayload),
 			CreatedAt:         time.Now().Unix(),
-			UpdatedAt:         time.Now().Unix(),
-		})
-		if err != nil {
-			log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("")
-			log.Ctx(ctx).Info().Msg("Restoring product stock")
-			go func() {
-				err = s.WriteKafkaMessageWithKey(restoreProductMsgParsed, trxNumber.String())
-				if err != nil {
-					log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("")
-				}
-			}()
+		}
+		if req.FraudStatus != "" {
+			notification.FraudStatus = &req.FraudStatus
+		}
 
+		inserted, err := repo.AddPaymentNotification(ctx, notification)
+		if err != nil {
 			return err
 		}
 
-		for idx := range orderDetails {
-			orderDetails[idx].OrderID = orderID
+		// Midtrans retries until it gets a 2xx, so a status that has already
+		// been handled is acknowledged without being applied again.
+		if !inserted {
+			log.Ctx(ctx).Info().Str("component", "applyPaymentNotification").Str("transaction_number", req.OrderID).Str("transaction_status", req.TransactionStatus).Msg("Ignoring duplicate payment notification")
+			return nil
+		}
+
+		if !known {
+			log.Ctx(ctx).Info().Str("component", "applyPaymentNotification").Str("transaction_number", req.OrderID).Str("transaction_status", req.TransactionStatus).Msg("Recorded payment notification without a status change")
+			return nil
 		}
 
-		err = repo.AddOrderDetails(ctx, orderDetails)
+		// The order is locked first, so the notifications of the other
+		// tenders of the order are applied one after the other.
+		order, err = repo.LockOrderByOrderID(ctx, payment.OrderID)
 		if err != nil {
-			log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("")
-			log.Ctx(ctx).Info().Msg("Restoring product stock")
-			go func() {
-				err = s.WriteKafkaMessageWithKey(restoreProductMsgParsed, trxNumber.String())
-				if err != nil {
-					log.Ctx(ctx).Error().Err(err).Str("component", "AddOrder").Msg("")
-				}
-			}()
 			return err
 		}
 
-		orderResponse.ID = orderID
-		orderResponse.TransactionAmount = totalAmount
-		orderResponse.PaymentStatus = "pending"
-		orderResponse.PaymentMethodName = paymentMethod.Name
-		orderResponse.PaymentExpiredAt = &expiredAt
-
-		return nil
-	})
-
-	return orderResponse, err
-}
+		payments, err := repo.GetOrderPaymentsByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
 
-func (s *OrderServiceImpl) MidtransPaymentWebhook(ctx context.Context, req dto.PaymentNotification) (err error) {
-	order, err := s.repository.GetOrderByTransactionNumber(ctx, req.OrderID)
-	if err != nil {
-		return
-	}
+		for i := range payments {
+			if payments[i].ID != payment.ID {
+				continue
+			}
 
-	if order.ExpiredAt < time.Now().Unix() {
-		return errs.ErrPaymentExpired
-	}
+			current := payments[i]
+			if current.Status == status {
+				return nil
+			}
 
-	fmt.Printf("%+v\n", req)
-	if req.TransactionStatus == "settlement" {
-		if req.FraudStatus == "accept" {
-			err = s.repository.UpdateOrderPaymentStatus(ctx, domain.Order{
-				ID:            order.ID,
-				PaymentStatus: "success",
-			})
-		}
-	} else if req.TransactionStatus == "cancel" || req.TransactionStatus == "deny" || req.TransactionStatus == "expire" {
-		err = s.repository.UpdateOrderPaymentStatus(ctx, domain.Order{
-			ID:            order.ID,
-			PaymentStatus: "expired",
-		})
-	}
+			// Notifications may arrive out of order, a status the payment has
+			// already moved past is recorded but not applied.
+			if !domain.CanTransitionOrderStatus(current.Status, status) {
+				log.Ctx(ctx).Info().Str("component", "applyPaymentNotification").Str("transaction_number", req.OrderID).Str("from", current.Status).Str("to", status).Msg("Ignoring payment notification that does not apply to the payment status")
+				return nil
+			}
 
-	return
-}
+			current.Status = status
+			current.UpdatedAt = time.Now().Unix()
+			if status == domain.OrderStatusPaid {
+				current.PaidAt = midtransPaidAt(req)
+			}
 
-func (s *OrderServiceImpl) WriteKafkaMessageWithKey(msg []byte, key string) error {
-	maxRetries := 3
-	var lastErr error
+			updated, err := repo.TransitionOrderPayment(ctx, current, []string{payments[i].Status})
+			if err != nil {
+				return err
+			}
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		_, err := s.kafkaProducer.WriteMessages(
-			kafka.Message{
-				Key:   []byte(key),
-				Value: msg,
-			},
-		)
+			// Someone else moved the payment in the meantime. Rolling back
+			// makes Midtrans deliver the notification again, to be applied on
+			// top of it.
+			if !updated {
+				return errs.ErrConflict
+			}
 
-		if err == nil {
-			return nil
+			payments[i] = current
 		}
 
-		lastErr = err
-		retryCount := attempt + 1
-		log.Printf("Failed to write Kafka message (attempt %d/%d): %v", retryCount, maxRetries, err)
-
-		if attempt < maxRetries-1 {
-			// Exponential backoff: 1s, 2s, 4s...
-			backoffDuration := time.Second * time.Duration(1<<attempt)
-			time.Sleep(backoffDuration)
+		followUp, err = s.syncOrderStatus(ctx, repo, order, payments, midtransPaidAt(req), change)
+		if err != nil {
+			return err
 		}
-	}
-
-	return fmt.Errorf("failed to write Kafka message after %d attempts: %w", maxRetries, lastErr)
-}
-
-func (s *OrderServiceImpl) writeKafkaMessage(msg []byte) error {
-	_, err := s.kafkaProducer.WriteMessages(
-		kafka.Message{
-			Value: msg,
-		},
-	)
-	return err
-}
 
-func (s *OrderServiceImpl) GetOrders(ctx context.Context, filter pkgdto.Filter) (response pkgdto.Pagination, err error) {
-	var orderResponse []dto.OrderResponse
-	datas, err := s.repository.GetOrders(ctx, filter)
+		reviewID, err = stagePaymentFollowUp(ctx, repo, order, followUp)
+		return err
+	})
 	if err != nil {
-		return
+		return err
 	}
 
-	for _, data := range datas {
-		orderResponse = append(orderResponse, dto.OrderResponse{
-			ID:                data.ID,
-			PaymentStatus:     data.PaymentStatus,
-			TransactionAmount: data.Amount,
-			PaymentMethodName: data.PaymentMethod.Name,
-			CreatedAt:         data.CreatedAt,
-			TransactionNumber: data.TransactionNumber,
-		})
-	}
-
-	response.Records = orderResponse
-
-	return
+	return s.followUpPayments(ctx, order, followUp, reviewID, midtransPaidAt(req), change)
 }
 
 func (s *OrderServiceImpl) GetOrderDetails(ctx context.Context, id int64) (response dto.OrderDetails, err error) {
@@ -388,82 +303,159 @@ func (s *OrderServiceImpl) GetOrderDetails(ctx context.Context, id int64) (respo
 
 	response.ID = order.ID
 	response.PaymentStatus = order.PaymentStatus
-	response.TransactionAmount = order.Amount
+	response.PaidAt = order.PaidAt
+	response.CashierID = order.CashierID
+	response.ShiftID = order.ShiftID
+	if order.CustomerID != nil {
+		customer, err := s.repository.GetCustomerByID(ctx, *order.CustomerID)
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			return response, err
+		}
+		if err == nil {
+			response.Customer = orderCustomerResponse(customer)
+		}
+	}
+	response.CancelledBy = order.CancelledBy
+	response.CancelReason = order.CancelReason
+	response.CancelledAt = order.CancelledAt
+	response.Subtotal = order.Subtotal.Float64()
+	response.DiscountAmount = order.DiscountAmount.Float64()
+	response.ServiceChargeAmount = order.ServiceChargeAmount.Float64()
+	response.TaxAmount = order.TaxAmount.Float64()
+	response.PricesIncludeTax = order.PricesIncludeTax
+	response.RoundingAmount = order.RoundingAmount.Float64()
+	response.TransactionAmount = order.Amount.Float64()
+	response.TenderedAmount = majorUnits(order.TenderedAmount)
+	response.ChangeAmount = majorUnits(order.ChangeAmount)
 	response.PaymentMethodName = paymentMethod.Name
 	response.CreatedAt = order.CreatedAt
 	response.TransactionNumber = order.TransactionNumber
 
+	payments, err := s.repository.GetOrderPaymentsByOrderID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	response.Payments = []dto.OrderPaymentResponse{}
+	for _, payment := range payments {
+		paymentMethod, err := s.repository.GetPaymentMethodByID(ctx, uint64(payment.PaymentMethodID))
+		if err != nil {
+			return response, err
+		}
+
+		response.Payments = append(response.Payments, orderPaymentResponse(payment, paymentMethod.Name))
+	}
+
 	orderItems, err := s.repository.GetOrderDetailsByOrderID(ctx, id)
 	if err != nil {
 		return
 	}
 
-	for _, orderItem := range orderItems {
-		response.OrderItems = append(response.OrderItems, dto.OrderItemResponse{
-			ID:          orderItem.ID,
-			ProductName: orderItem.ProductName,
-			Quantity:    int(orderItem.Quantity),
-			Price:       orderItem.Amount,
+	discounts, err := s.repository.GetOrderDiscountsByOrderID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	for _, discount := range discounts {
+		response.Discounts = append(response.Discounts, dto.OrderDiscountResponse{
+			PromotionID:   discount.PromotionID,
+			Name:          discount.PromotionName,
+			OrderDetailID: discount.OrderDetailID,
+			Amount:        discount.Amount.Float64(),
 		})
 	}
 
-	return
-}
+	charges, err := s.repository.GetOrderChargesByOrderID(ctx, id)
+	if err != nil {
+		return
+	}
 
-func (s *OrderServiceImpl) RestoreExpiredPaymentItemStocks() {
-	log.Info().Str("component", "RestoreExpiredPaymentItemStocks").Msg("cron starts")
-	orders, err := s.repository.GetOrders(context.Background(), pkgdto.Filter{
-		PaymentStatus: "pending",
-		Expired:       true,
-	})
+	for _, charge := range charges {
+		response.Charges = append(response.Charges, dto.OrderChargeResponse{
+			Type:           charge.Type,
+			Name:           charge.Name,
+			Rate:           charge.Rate,
+			BaseAmount:     charge.BaseAmount.Float64(),
+			Amount:         charge.Amount.Float64(),
+			IncludedAmount: charge.IncludedAmount.Float64(),
+		})
+	}
 
+	refunds, err := s.repository.GetRefundsByOrderID(ctx, id)
 	if err != nil {
 		return
 	}
 
-	for _, order := range orders {
-		order.PaymentStatus = "expired"
-		err = s.repository.UpdateOrderPaymentStatus(context.Background(), order)
-		if err != nil {
-			return
+	// Refunds still being made are left out until their payments went back.
+	completedRefunds := make(map[int64]bool, len(refunds))
+	refundedAmount := money.New(0, order.Amount.Currency)
+	for _, refund := range refunds {
+		if refund.Status != domain.RefundStatusCompleted {
+			continue
 		}
 
-		orderDetails, err := s.repository.GetOrderDetailsByOrderID(context.Background(), order.ID)
-		if err != nil {
-			return
-		}
+		completedRefunds[refund.ID] = true
+		refundedAmount.Amount += refund.Amount.Amount
+	}
+	response.RefundedAmount = refundedAmount.Float64()
+	response.NetAmount = money.New(order.Amount.Amount-refundedAmount.Amount, order.Amount.Currency).Float64()
+	response.MDRFee = order.MDRFee.Float64()
 
-		var orderRequest dto.OrderProductServiceRequest
-		for _, item := range orderDetails {
-			orderRequest.OrderItems = append(orderRequest.OrderItems, dto.OrderItem{
-				ProductID: item.ProductID,
-				Quantity:  int(item.Quantity),
-			})
-		}
+	refundedItems, err := s.repository.GetRefundItemsByOrderID(ctx, id)
+	if err != nil {
+		return
+	}
 
-		kafkaMsg := dto.KafkaMessage{
-			EventType: "restore_product_stock",
-			Data:      orderRequest,
+	refundedQuantities := make(map[int64]int64)
+	refundedAmounts := make(map[int64]money.Money)
+	for _, item := range refundedItems {
+		if !completedRefunds[item.RefundID] {
+			continue
 		}
 
-		fmt.Printf("%+v\n", kafkaMsg)
-		jsonMsg, err := json.Marshal(kafkaMsg)
-		if err != nil {
-			log.Error().Err(err).Str("component", "RestoreExpiredPaymentItemStocks").Msg("")
-			return
-		}
+		refundedQuantities[item.OrderDetailID] += item.Quantity
+		refunded := refundedAmounts[item.OrderDetailID]
+		refundedAmounts[item.OrderDetailID] = money.New(refunded.Amount+item.Amount.Amount, item.Amount.Currency)
+	}
 
-		maxRetries := 3
+	for _, orderItem := range orderItems {
+		response.OrderItems = append(response.OrderItems, dto.OrderItemResponse{
+			ID:                  orderItem.ID,
+			ProductName:         orderItem.ProductName,
+			Quantity:            int(orderItem.Quantity),
+			Price:               orderItem.Amount.Float64(),
+			DiscountAmount:      orderItem.DiscountAmount.Float64(),
+			ServiceChargeAmount: orderItem.ServiceChargeAmount.Float64(),
+			TaxAmount:           orderItem.TaxAmount.Float64(),
+			TotalAmount:         orderItem.TotalAmount.Float64(),
+			RefundedQuantity:    int(refundedQuantities[orderItem.ID]),
+			RefundedAmount:      refundedAmounts[orderItem.ID].Float64(),
+		})
+	}
 
-		for i := 0; i < maxRetries; i++ {
-			err = s.writeKafkaMessage(jsonMsg)
-			if err == nil {
-				break
-			}
-			log.Error().Err(err).Str("component", "RestoreExpiredPaymentItemStocks").Msg("")
-			time.Sleep(time.Second * time.Duration(i+1)) // Exponential backoff
-		}
+	return
+}
+
+// enqueueStockRestoration stages the message that gives the order's items back
+// to the product service, in the transaction that releases the order.
+func (s *OrderServiceImpl) enqueueStockRestoration(ctx context.Context, repo repository.OrderRepository, order domain.Order) error {
+	orderDetails, err := repo.GetOrderDetailsByOrderID(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	orderRequest := dto.OrderProductServiceRequest{
+		TransactionNumber: order.TransactionNumber,
+	}
+	for _, item := range orderDetails {
+		orderRequest.OrderItems = append(orderRequest.OrderItems, dto.OrderItem{
+			ProductID: item.ProductID,
+			Quantity:  int(item.Quantity),
+		})
 	}
 
-	log.Info().Str("component", "RestoreExpiredPaymentItemStocks").Msg("cron ends")
+	return s.enqueueKafkaMessage(ctx, repo, order.TransactionNumber, dto.KafkaMessage{
+		EventType: "restore_product_stock",
+		Data:      orderRequest,
+	})
 }
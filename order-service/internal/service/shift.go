@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+// OpenShift opens a shift for the cashier, who can only have one open at a
+// time, as can the terminal.
+func (s *OrderServiceImpl) OpenShift(ctx context.Context, req dto.OpenShiftRequest) (response dto.ShiftReportResponse, err error) {
+	// Rupiah has no minor unit, so the float is in whole rupiah.
+	if req.CashierID == 0 || req.OpeningFloat < 0 || req.OpeningFloat != math.Trunc(req.OpeningFloat) {
+		return response, errs.ErrClient
+	}
+
+	storeCode := strings.TrimSpace(req.StoreCode)
+	if storeCode == "" {
+		storeCode = s.config.TaxConfig.DefaultStoreCode
+	}
+
+	var terminalCode *string
+	if req.TerminalCode != nil && strings.TrimSpace(*req.TerminalCode) != "" {
+		code := strings.TrimSpace(*req.TerminalCode)
+		terminalCode = &code
+	}
+
+	now := time.Now().Unix()
+	var id int64
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		id, err = repo.AddShift(ctx, domain.Shift{
+			CashierID:    int64(req.CashierID),
+			StoreCode:    &storeCode,
+			TerminalCode: terminalCode,
+			OpeningFloat: money.FromFloat(req.OpeningFloat, money.IDR),
+			Status:       domain.ShiftStatusOpen,
+			OpenedAt:     now,
+			UpdatedAt:    now,
+		})
+		if err != nil {
+			return err
+		}
+
+		if id == 0 {
+			return errs.ErrShiftAlreadyOpen
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	return s.GetShift(ctx, id)
+}
+
+// GetCurrentShift is the shift the cashier has open.
+func (s *OrderServiceImpl) GetCurrentShift(ctx context.Context, cashierID uint64) (response dto.ShiftReportResponse, err error) {
+	shift, err := s.repository.GetOpenShiftByCashierID(ctx, int64(cashierID))
+	if err != nil {
+		return
+	}
+
+	return s.shiftReport(ctx, shift)
+}
+
+// GetShift is the shift with the cash that went through its drawer so far,
+// or its report once it is closed.
+func (s *OrderServiceImpl) GetShift(ctx context.Context, id int64) (response dto.ShiftReportResponse, err error) {
+	shift, err := s.repository.GetShiftByID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	return s.shiftReport(ctx, shift)
+}
+
+func (s *OrderServiceImpl) GetShifts(ctx context.Context, filter dto.ShiftFilter) (response []dto.ShiftResponse, err error) {
+	if filter.Status != "" && filter.Status != domain.ShiftStatusOpen && filter.Status != domain.ShiftStatusClosed {
+		return nil, errs.ErrClient
+	}
+
+	from, to, err := reportRange(filter.ReportRequest)
+	if err != nil {
+		return
+	}
+
+	shifts, err := s.repository.GetShifts(ctx, filter.Status, filter.CashierID, from, to)
+	if err != nil {
+		return
+	}
+
+	response = []dto.ShiftResponse{}
+	for _, shift := range shifts {
+		response = append(response, shiftResponse(shift))
+	}
+
+	return
+}
+
+// AddShiftCashMovement records cash put into or taken out of the drawer of
+// an open shift, e.g. more change or paying a supplier, by the cashier of the
+// shift.
+func (s *OrderServiceImpl) AddShiftCashMovement(ctx context.Context, id int64, req dto.CashMovementRequest) (response dto.ShiftReportResponse, err error) {
+	note := strings.TrimSpace(req.Note)
+	if req.Amount <= 0 || req.Amount != math.Trunc(req.Amount) || note == "" {
+		return response, errs.ErrClient
+	}
+
+	amount := money.FromFloat(req.Amount, money.IDR)
+	switch req.Type {
+	case domain.CashTransactionTypeCashIn:
+	case domain.CashTransactionTypeCashOut:
+		amount.Amount = -amount.Amount
+	default:
+		return response, errs.ErrClient
+	}
+
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		shift, err := lockCashierShift(ctx, repo, id, req.CashierID)
+		if err != nil {
+			return err
+		}
+
+		return repo.AddCashTransaction(ctx, domain.CashTransaction{
+			ShiftID:   &shift.ID,
+			CashierID: shift.CashierID,
+			StoreCode: shift.StoreCode,
+			Type:      req.Type,
+			Amount:    amount,
+			Note:      &note,
+			CreatedAt: time.Now().Unix(),
+		})
+	})
+	if err != nil {
+		return
+	}
+
+	return s.GetShift(ctx, id)
+}
+
+// CloseShift closes the shift of the cashier with the cash counted in its
+// drawer, and gives back its report. The shift is locked while it is closed,
+// so no cash order can go into its drawer once it has been counted.
+func (s *OrderServiceImpl) CloseShift(ctx context.Context, id int64, req dto.CloseShiftRequest) (response dto.ShiftReportResponse, err error) {
+	if req.CountedCash == nil || *req.CountedCash < 0 {
+		return response, errs.ErrClient
+	}
+
+	var note *string
+	if req.Note != nil && strings.TrimSpace(*req.Note) != "" {
+		trimmed := strings.TrimSpace(*req.Note)
+		note = &trimmed
+	}
+
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		shift, err := lockCashierShift(ctx, repo, id, req.CashierID)
+		if err != nil {
+			return err
+		}
+
+		summary, err := repo.GetShiftCashSummary(ctx, shift.ID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		counted := requestMoney(req.CountedCash)
+		expected := money.New(shift.OpeningFloat.Amount+summary.Net().Amount, shift.OpeningFloat.Currency)
+		variance := money.New(counted.Amount-expected.Amount, expected.Currency)
+		shift.ExpectedCash = &expected
+		shift.CountedCash = counted
+		shift.Variance = &variance
+		shift.CloseNote = note
+		shift.ClosedAt = &now
+
+		updated, err := repo.CloseShift(ctx, shift)
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			return errs.ErrShiftClosed
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	return s.GetShift(ctx, id)
+}
+
+// lockCashierShift locks the shift for a change to its drawer, which only the
+// cashier of the shift can make while it is open.
+func lockCashierShift(ctx context.Context, repo repository.OrderRepository, id int64, cashierID uint64) (shift domain.Shift, err error) {
+	shift, err = repo.LockShiftByID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	if shift.CashierID != int64(cashierID) {
+		return shift, errs.ErrUnauthorized
+	}
+
+	if shift.Status != domain.ShiftStatusOpen {
+		return shift, errs.ErrShiftClosed
+	}
+
+	return shift, nil
+}
+
+// openShiftID is the id of the shift the cashier has open, nil when there is
+// none.
+func (s *OrderServiceImpl) openShiftID(ctx context.Context, repo repository.OrderRepository, cashierID int64) (*int64, error) {
+	if cashierID == 0 {
+		return nil, nil
+	}
+
+	shift, err := repo.GetOpenShiftByCashierID(ctx, cashierID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &shift.ID, nil
+}
+
+func (s *OrderServiceImpl) shiftReport(ctx context.Context, shift domain.Shift) (response dto.ShiftReportResponse, err error) {
+	summary, err := s.repository.GetShiftCashSummary(ctx, shift.ID)
+	if err != nil {
+		return
+	}
+
+	// An open shift is expected to hold what went through it so far, a closed
+	// one what it was closed with.
+	expected := money.New(shift.OpeningFloat.Amount+summary.Net().Amount, shift.OpeningFloat.Currency)
+	if shift.ExpectedCash != nil {
+		expected = *shift.ExpectedCash
+	}
+
+	response = dto.ShiftReportResponse{
+		ShiftResponse: shiftResponse(shift),
+		Cash: dto.ShiftCashFigures{
+			SaleCount:    summary.SaleCount,
+			CashSales:    summary.CashSales.Float64(),
+			RefundCount:  summary.RefundCount,
+			CashRefunds:  summary.CashRefunds.Float64(),
+			CashInCount:  summary.CashInCount,
+			CashIn:       summary.CashIn.Float64(),
+			CashOutCount: summary.CashOutCount,
+			CashOut:      summary.CashOut.Float64(),
+			ExpectedCash: expected.Float64(),
+		},
+		PaymentMethods: []dto.PaymentMethodSalesRow{},
+	}
+
+	summaries, err := s.repository.GetShiftPaymentMethodSummaries(ctx, shift.ID, settledOrderStatuses)
+	if err != nil {
+		return
+	}
+
+	for _, summary := range summaries {
+		response.PaymentMethods = append(response.PaymentMethods, dto.PaymentMethodSalesRow{
+			PaymentMethodID:   summary.PaymentMethodID,
+			PaymentMethodName: summary.PaymentMethodName,
+			OrderCount:        summary.OrderCount,
+			Amount:            summary.Amount.Float64(),
+		})
+	}
+
+	return response, nil
+}
+
+func shiftResponse(shift domain.Shift) dto.ShiftResponse {
+	return dto.ShiftResponse{
+		ID:           shift.ID,
+		CashierID:    shift.CashierID,
+		StoreCode:    shift.StoreCode,
+		TerminalCode: shift.TerminalCode,
+		Status:       shift.Status,
+		OpeningFloat: shift.OpeningFloat.Float64(),
+		ExpectedCash: majorUnits(shift.ExpectedCash),
+		CountedCash:  majorUnits(shift.CountedCash),
+		Variance:     majorUnits(shift.Variance),
+		CloseNote:    shift.CloseNote,
+		OpenedAt:     shift.OpenedAt,
+		ClosedAt:     shift.ClosedAt,
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+// shiftRepository holds one shift and the cash that went through its drawer.
+type shiftRepository struct {
+	repository.OrderRepository
+	shift   domain.Shift
+	summary domain.ShiftCashSummary
+}
+
+func (r *shiftRepository) HandleTrx(ctx context.Context, fn func(ctx context.Context, repo repository.OrderRepository) error) error {
+	return fn(ctx, r)
+}
+
+func (r *shiftRepository) LockShiftByID(ctx context.Context, id int64) (domain.Shift, error) {
+	return r.shift, nil
+}
+
+func (r *shiftRepository) GetShiftByID(ctx context.Context, id int64) (domain.Shift, error) {
+	return r.shift, nil
+}
+
+func (r *shiftRepository) CloseShift(ctx context.Context, data domain.Shift) (bool, error) {
+	data.Status = domain.ShiftStatusClosed
+	r.shift = data
+	return true, nil
+}
+
+func (r *shiftRepository) GetShiftCashSummary(ctx context.Context, shiftID int64) (domain.ShiftCashSummary, error) {
+	return r.summary, nil
+}
+
+func (r *shiftRepository) GetShiftPaymentMethodSummaries(ctx context.Context, shiftID int64, statuses []string) ([]domain.PaymentMethodSalesSummary, error) {
+	return nil, nil
+}
+
+func TestCloseShift(t *testing.T) {
+	sen := func(amount int64) money.Money {
+		return money.New(amount, money.IDR)
+	}
+
+	type TestCase struct {
+		Name             string
+		Summary          domain.ShiftCashSummary
+		CountedCash      float64
+		ExpectedExpected money.Money
+		ExpectedVariance money.Money
+	}
+
+	testCases := []TestCase{
+		{
+			Name:             "Drawer balances",
+			Summary:          domain.ShiftCashSummary{CashSales: rupiah(150000), CashRefunds: rupiah(20000), CashIn: rupiah(50000), CashOut: rupiah(10000)},
+			CountedCash:      270000,
+			ExpectedExpected: rupiah(270000),
+			ExpectedVariance: rupiah(0),
+		},
+		{
+			Name:             "Drawer short",
+			Summary:          domain.ShiftCashSummary{CashSales: rupiah(150000)},
+			CountedCash:      249500,
+			ExpectedExpected: rupiah(250000),
+			ExpectedVariance: rupiah(-500),
+		},
+		{
+			Name:             "Drawer over",
+			Summary:          domain.ShiftCashSummary{CashSales: rupiah(150000)},
+			CountedCash:      250100,
+			ExpectedExpected: rupiah(250000),
+			ExpectedVariance: rupiah(100),
+		},
+		{
+			// Added up as floats, 0.1 + 0.2 is not 0.3.
+			Name:             "Amounts in sen add up exactly",
+			Summary:          domain.ShiftCashSummary{CashSales: sen(10), CashIn: sen(20)},
+			CountedCash:      100000.3,
+			ExpectedExpected: sen(10000030),
+			ExpectedVariance: rupiah(0),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			repo := &shiftRepository{
+				shift:   domain.Shift{ID: 1, CashierID: 7, OpeningFloat: rupiah(100000), Status: domain.ShiftStatusOpen},
+				summary: tc.Summary,
+			}
+			s := &OrderServiceImpl{repository: repo}
+
+			response, err := s.CloseShift(context.Background(), 1, dto.CloseShiftRequest{CountedCash: &tc.CountedCash, CashierID: 7})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if repo.shift.ExpectedCash == nil || *repo.shift.ExpectedCash != tc.ExpectedExpected {
+				t.Errorf("expected cash %s, got %v", tc.ExpectedExpected, repo.shift.ExpectedCash)
+			}
+
+			if repo.shift.Variance == nil || *repo.shift.Variance != tc.ExpectedVariance {
+				t.Errorf("expected variance %s, got %v", tc.ExpectedVariance, repo.shift.Variance)
+			}
+
+			if response.Variance == nil || *response.Variance != tc.ExpectedVariance.Float64() {
+				t.Errorf("expected variance %v in the report, got %v", tc.ExpectedVariance.Float64(), response.Variance)
+			}
+		})
+	}
+}
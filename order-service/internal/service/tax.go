@@ -0,0 +1,555 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/point-of-sales/order-service/internal/dto"
+	"github.com/alimikegami/point-of-sales/order-service/internal/repository"
+	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	"github.com/rs/zerolog/log"
+)
+
+const serviceChargeName = "Service charge"
+
+func (s *OrderServiceImpl) GetStoreTaxSettings(ctx context.Context) (response []dto.StoreTaxSettingResponse, err error) {
+	settings, err := s.repository.GetStoreTaxSettings(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, setting := range settings {
+		setting.TaxRates, err = s.repository.GetTaxRatesByStoreTaxSettingID(ctx, setting.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		response = append(response, storeTaxSettingResponse(setting))
+	}
+
+	return
+}
+
+func (s *OrderServiceImpl) GetStoreTaxSetting(ctx context.Context, storeCode string) (response dto.StoreTaxSettingResponse, err error) {
+	setting, err := s.repository.GetStoreTaxSettingByStoreCode(ctx, storeCode)
+	if err != nil {
+		return
+	}
+
+	setting.TaxRates, err = s.repository.GetTaxRatesByStoreTaxSettingID(ctx, setting.ID)
+	if err != nil {
+		return
+	}
+
+	return storeTaxSettingResponse(setting), nil
+}
+
+// SaveStoreTaxSetting creates the tax settings of the store, or replaces them
+// together with its tax rates when the store already has some.
+func (s *OrderServiceImpl) SaveStoreTaxSetting(ctx context.Context, storeCode string, req dto.StoreTaxSettingRequest) (response dto.StoreTaxSettingResponse, err error) {
+	setting, err := storeTaxSettingFromRequest(storeCode, req)
+	if err != nil {
+		return
+	}
+
+	err = s.repository.HandleTrx(ctx, func(ctx context.Context, repo repository.OrderRepository) error {
+		now := time.Now().Unix()
+		existing, err := repo.GetStoreTaxSettingByStoreCode(ctx, setting.StoreCode)
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			setting.CreatedAt = now
+			setting.UpdatedAt = now
+			setting.ID, err = repo.AddStoreTaxSetting(ctx, setting)
+			if err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			setting.ID = existing.ID
+			setting.CreatedAt = existing.CreatedAt
+			setting.UpdatedAt = now
+			err = repo.UpdateStoreTaxSetting(ctx, setting)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i := range setting.TaxRates {
+			setting.TaxRates[i].StoreTaxSettingID = setting.ID
+			setting.TaxRates[i].CreatedAt = now
+		}
+
+		err = repo.ReplaceTaxRates(ctx, setting.ID, setting.TaxRates)
+		if err != nil {
+			return err
+		}
+
+		setting.TaxRates, err = repo.GetTaxRatesByStoreTaxSettingID(ctx, setting.ID)
+		return err
+	})
+	if err != nil {
+		return
+	}
+
+	return storeTaxSettingResponse(setting), nil
+}
+
+func (s *OrderServiceImpl) DeleteStoreTaxSetting(ctx context.Context, storeCode string) (err error) {
+	setting, err := s.repository.GetStoreTaxSettingByStoreCode(ctx, storeCode)
+	if err != nil {
+		return
+	}
+
+	return s.repository.DeleteStoreTaxSetting(ctx, setting.ID, time.Now().Unix())
+}
+
+func (s *OrderServiceImpl) GetProductTaxCategories(ctx context.Context) (response []dto.ProductTaxCategoryResponse, err error) {
+	categories, err := s.repository.GetProductTaxCategories(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, category := range categories {
+		response = append(response, dto.ProductTaxCategoryResponse{
+			ProductID: category.ProductID,
+			Category:  category.Category,
+			UpdatedAt: category.UpdatedAt,
+		})
+	}
+
+	return
+}
+
+func (s *OrderServiceImpl) SaveProductTaxCategory(ctx context.Context, productID string, req dto.ProductTaxCategoryRequest) (response dto.ProductTaxCategoryResponse, err error) {
+	category := normalizeTaxCategory(req.Category)
+	if strings.TrimSpace(productID) == "" || category == "" {
+		return response, errs.ErrClient
+	}
+
+	now := time.Now().Unix()
+	err = s.repository.UpsertProductTaxCategory(ctx, domain.ProductTaxCategory{
+		ProductID: productID,
+		Category:  category,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return
+	}
+
+	return dto.ProductTaxCategoryResponse{
+		ProductID: productID,
+		Category:  category,
+		UpdatedAt: now,
+	}, nil
+}
+
+func (s *OrderServiceImpl) DeleteProductTaxCategory(ctx context.Context, productID string) (err error) {
+	return s.repository.DeleteProductTaxCategory(ctx, productID)
+}
+
+func storeTaxSettingFromRequest(storeCode string, req dto.StoreTaxSettingRequest) (setting domain.StoreTaxSetting, err error) {
+	setting = domain.StoreTaxSetting{
+		StoreCode:             strings.TrimSpace(storeCode),
+		PricesIncludeTax:      req.PricesIncludeTax,
+		ServiceChargeRate:     req.ServiceChargeRate,
+		ServiceChargeTaxable:  req.ServiceChargeTaxable,
+		RoundingMode:          req.RoundingMode,
+		RoundingIncrement:     req.RoundingIncrement,
+		CashRoundingIncrement: req.CashRoundingIncrement,
+	}
+
+	if setting.RoundingMode == "" {
+		setting.RoundingMode = domain.RoundingModeHalfUp
+	}
+
+	if setting.RoundingIncrement == 0 {
+		setting.RoundingIncrement = 1
+	}
+
+	if setting.StoreCode == "" || setting.ServiceChargeRate < 0 || setting.ServiceChargeRate > 100 {
+		return setting, errs.ErrClient
+	}
+
+	switch setting.RoundingMode {
+	case domain.RoundingModeHalfUp, domain.RoundingModeUp, domain.RoundingModeDown:
+	default:
+		return setting, errs.ErrClient
+	}
+
+	// Rupiah has no minor unit, so charges are rounded to whole rupiah at
+	// least.
+	if setting.RoundingIncrement < 1 {
+		return setting, errs.ErrClient
+	}
+
+	if setting.CashRoundingIncrement != 0 && setting.CashRoundingIncrement < 1 {
+		return setting, errs.ErrClient
+	}
+
+	for _, rate := range req.TaxRates {
+		taxRate := domain.TaxRate{
+			Name: strings.TrimSpace(rate.Name),
+			Rate: rate.Rate,
+		}
+
+		if rate.Category != nil {
+			category := normalizeTaxCategory(*rate.Category)
+			if category != "" {
+				taxRate.Category = &category
+			}
+		}
+
+		if taxRate.Name == "" || taxRate.Rate <= 0 || taxRate.Rate > 100 {
+			return setting, errs.ErrClient
+		}
+
+		setting.TaxRates = append(setting.TaxRates, taxRate)
+	}
+
+	return setting, nil
+}
+
+func storeTaxSettingResponse(setting domain.StoreTaxSetting) dto.StoreTaxSettingResponse {
+	response := dto.StoreTaxSettingResponse{
+		ID:                    setting.ID,
+		StoreCode:             setting.StoreCode,
+		PricesIncludeTax:      setting.PricesIncludeTax,
+		ServiceChargeRate:     setting.ServiceChargeRate,
+		ServiceChargeTaxable:  setting.ServiceChargeTaxable,
+		RoundingMode:          setting.RoundingMode,
+		RoundingIncrement:     setting.RoundingIncrement,
+		CashRoundingIncrement: setting.CashRoundingIncrement,
+		TaxRates:              []dto.TaxRateResponse{},
+		CreatedAt:             setting.CreatedAt,
+		UpdatedAt:             setting.UpdatedAt,
+	}
+
+	for _, rate := range setting.TaxRates {
+		response.TaxRates = append(response.TaxRates, dto.TaxRateResponse{
+			ID:       rate.ID,
+			Name:     rate.Name,
+			Category: rate.Category,
+			Rate:     rate.Rate,
+		})
+	}
+
+	return response
+}
+
+func normalizeTaxCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// applyOrderTaxes adds the service charge and the taxes of the store to the
+// discounted order. A store without tax settings is only accepted when it is
+// the default one, so orders keep going through before taxes are set up.
+func (s *OrderServiceImpl) applyOrderTaxes(ctx context.Context, saga *orderSaga) error {
+	setting, err := s.repository.GetStoreTaxSettingByStoreCode(ctx, saga.state.StoreCode)
+	if errors.Is(err, errs.ErrNotFound) {
+		if saga.state.StoreCode != s.config.TaxConfig.DefaultStoreCode {
+			return errs.ErrUnknownStore
+		}
+
+		calculateOrderCharges(&saga.state, domain.StoreTaxSetting{RoundingMode: domain.RoundingModeHalfUp, RoundingIncrement: 1}, nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	setting.TaxRates, err = s.repository.GetTaxRatesByStoreTaxSettingID(ctx, setting.ID)
+	if err != nil {
+		return err
+	}
+
+	productIDs := make([]string, len(saga.state.OrderItems))
+	for i, item := range saga.state.OrderItems {
+		productIDs[i] = item.ProductID
+	}
+
+	productCategories, err := s.repository.GetProductTaxCategoriesByProductIDs(ctx, productIDs)
+	if err != nil {
+		return err
+	}
+
+	categories := make(map[string]string, len(productCategories))
+	for _, category := range productCategories {
+		categories[category.ProductID] = category.Category
+	}
+
+	calculateOrderCharges(&saga.state, setting, categories)
+	saga.state.CashRoundingIncrement = setting.CashRoundingIncrement
+
+	log.Ctx(ctx).Info().Str("component", "applyOrderTaxes").Str("transaction_number", saga.TransactionNumber).Str("store_code", setting.StoreCode).Stringer("service_charge_amount", saga.state.ServiceChargeAmount).Stringer("tax_amount", saga.state.TaxAmount).Msg("Applied taxes")
+
+	return nil
+}
+
+// calculateOrderCharges works out the service charge and taxes of every line
+// from its discounted amount. With tax-inclusive prices the taxes are taken
+// out of the line first, so the service charge is not charged on them. The
+// totals are rounded once per charge and then spread back over the lines,
+// which is what keeps the lines adding up to what the customer is charged.
+// The lines' shares are worked out in fractions of a minor unit, every amount
+// that is kept is a whole number of them.
+func calculateOrderCharges(state *orderSagaState, setting domain.StoreTaxSetting, categories map[string]string) {
+	currency := state.currency()
+	items := state.OrderItems
+	net := make([]int64, len(items))
+	preTax := make([]float64, len(items))
+	serviceCharges := make([]float64, len(items))
+	for i, item := range items {
+		net[i] = item.Price.Amount*int64(item.Quantity) - item.DiscountAmount.Amount
+
+		var totalRate float64
+		for _, rate := range setting.TaxRates {
+			if taxRateApplies(rate, categories[item.ProductID]) {
+				totalRate += rate.Rate
+			}
+		}
+
+		preTax[i] = float64(net[i])
+		if setting.PricesIncludeTax {
+			preTax[i] = float64(net[i]) / (1 + totalRate/100)
+		}
+
+		serviceCharges[i] = preTax[i] * setting.ServiceChargeRate / 100
+	}
+
+	state.Charges = nil
+	lineServiceCharges := make([]int64, len(items))
+	lineTaxes := make([]int64, len(items))
+	lineAddedTaxes := make([]int64, len(items))
+
+	if setting.ServiceChargeRate > 0 {
+		amount := roundCharge(sumAmounts(serviceCharges), currency, setting)
+		if amount > 0 {
+			lineServiceCharges = allocateAmount(amount, serviceCharges)
+			state.Charges = append(state.Charges, orderSagaCharge{
+				Type:       domain.OrderChargeTypeServiceCharge,
+				Name:       serviceChargeName,
+				Rate:       setting.ServiceChargeRate,
+				BaseAmount: money.New(roundMajorUnits(sumAmounts(preTax), currency), currency),
+				Amount:     money.New(amount, currency),
+			})
+		}
+	}
+
+	// Rates sharing a name and a rate make up a single tax line, whichever
+	// categories they apply to.
+	type taxGroup struct {
+		name     string
+		rate     float64
+		rates    []domain.TaxRate
+		base     []float64
+		tax      []float64
+		included []float64
+	}
+	var groups []*taxGroup
+	groupIndex := make(map[string]*taxGroup)
+	for _, rate := range setting.TaxRates {
+		key := fmt.Sprintf("%s|%g", rate.Name, rate.Rate)
+		group, exists := groupIndex[key]
+		if !exists {
+			group = &taxGroup{
+				name:     rate.Name,
+				rate:     rate.Rate,
+				base:     make([]float64, len(items)),
+				tax:      make([]float64, len(items)),
+				included: make([]float64, len(items)),
+			}
+			groupIndex[key] = group
+			groups = append(groups, group)
+		}
+		group.rates = append(group.rates, rate)
+	}
+
+	for _, group := range groups {
+		for i, item := range items {
+			applies := false
+			for _, rate := range group.rates {
+				if taxRateApplies(rate, categories[item.ProductID]) {
+					applies = true
+					break
+				}
+			}
+
+			if !applies {
+				continue
+			}
+
+			group.base[i] = preTax[i]
+			if setting.ServiceChargeTaxable {
+				group.base[i] += serviceCharges[i]
+			}
+
+			group.tax[i] = group.base[i] * group.rate / 100
+			if setting.PricesIncludeTax {
+				group.included[i] = preTax[i] * group.rate / 100
+			}
+		}
+
+		included := roundCharge(sumAmounts(group.included), currency, setting)
+		added := roundCharge(sumAmounts(group.tax)-sumAmounts(group.included), currency, setting)
+		if included+added <= 0 {
+			continue
+		}
+
+		addedWeights := make([]float64, len(items))
+		for i := range items {
+			addedWeights[i] = group.tax[i] - group.included[i]
+		}
+
+		includedShares := allocateAmount(included, group.included)
+		addedShares := allocateAmount(added, addedWeights)
+		for i := range items {
+			lineTaxes[i] += includedShares[i] + addedShares[i]
+			lineAddedTaxes[i] += addedShares[i]
+		}
+
+		state.Charges = append(state.Charges, orderSagaCharge{
+			Type:           domain.OrderChargeTypeTax,
+			Name:           group.name,
+			Rate:           group.rate,
+			BaseAmount:     money.New(roundMajorUnits(sumAmounts(group.base), currency), currency),
+			Amount:         money.New(included+added, currency),
+			IncludedAmount: money.New(included, currency),
+		})
+	}
+
+	var serviceChargeTotal, taxTotal, addedTotal int64
+	for _, charge := range state.Charges {
+		if charge.Type == domain.OrderChargeTypeServiceCharge {
+			serviceChargeTotal += charge.Amount.Amount
+		} else {
+			taxTotal += charge.Amount.Amount
+		}
+		addedTotal += charge.Amount.Amount - charge.IncludedAmount.Amount
+	}
+
+	for i := range items {
+		items[i].ServiceChargeAmount = money.New(lineServiceCharges[i], currency)
+		items[i].TaxAmount = money.New(lineTaxes[i], currency)
+		items[i].TotalAmount = money.New(net[i]+lineServiceCharges[i]+lineAddedTaxes[i], currency)
+	}
+
+	state.PricesIncludeTax = setting.PricesIncludeTax
+	state.ServiceChargeAmount = money.New(serviceChargeTotal, currency)
+	state.TaxAmount = money.New(taxTotal, currency)
+	state.TotalAmount = money.New(state.Subtotal.Amount-state.DiscountAmount.Amount+addedTotal, currency)
+}
+
+// taxRateApplies matches the rate against the product's category. Rates
+// without a category cover the products that have none.
+func taxRateApplies(rate domain.TaxRate, category string) bool {
+	if rate.Category == nil {
+		return category == ""
+	}
+
+	return *rate.Category == category
+}
+
+// roundCharge rounds amount, in minor units of currency, to the rounding
+// increment of the setting, which is in major units.
+func roundCharge(amount float64, currency string, setting domain.StoreTaxSetting) int64 {
+	increment := setting.RoundingIncrement
+	if increment <= 0 {
+		increment = 1
+	}
+	minorIncrement := float64(money.FromFloat(increment, currency).Amount)
+
+	// The tolerance keeps floating point noise from pushing an exact amount
+	// to the next increment.
+	steps := amount / minorIncrement
+	switch setting.RoundingMode {
+	case domain.RoundingModeUp:
+		steps = math.Ceil(steps - 1e-9)
+	case domain.RoundingModeDown:
+		steps = math.Floor(steps + 1e-9)
+	default:
+		steps = math.Round(steps)
+	}
+
+	return int64(steps) * int64(minorIncrement)
+}
+
+// roundMajorUnits rounds amount, in minor units of currency, to whole major
+// units.
+func roundMajorUnits(amount float64, currency string) int64 {
+	return roundCharge(amount, currency, domain.StoreTaxSetting{RoundingMode: domain.RoundingModeHalfUp, RoundingIncrement: 1})
+}
+
+// allocateAmount spreads total minor units over the lines in proportion to
+// weights. The last line with a weight takes what is left, so the shares
+// always add up to total.
+func allocateAmount(total int64, weights []float64) []int64 {
+	shares := make([]int64, len(weights))
+	weightTotal := sumAmounts(weights)
+	if total == 0 || weightTotal <= 0 {
+		return shares
+	}
+
+	last := -1
+	for i, weight := range weights {
+		if weight > 0 {
+			last = i
+		}
+	}
+
+	remaining := total
+	for i, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+
+		share := remaining
+		if i != last {
+			share = min(int64(math.Round(float64(total)*weight/weightTotal)), remaining)
+		}
+
+		remaining -= share
+		shares[i] = share
+	}
+
+	return shares
+}
+
+func sumAmounts(amounts []float64) (total float64) {
+	for _, amount := range amounts {
+		total += amount
+	}
+
+	return
+}
+
+// recordOrderCharges stores the tax and service charge lines of the order.
+func (s *OrderServiceImpl) recordOrderCharges(ctx context.Context, repo repository.OrderRepository, saga *orderSaga, orderID int64) error {
+	if len(saga.state.Charges) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	var charges []domain.OrderCharge
+	for _, charge := range saga.state.Charges {
+		charges = append(charges, domain.OrderCharge{
+			OrderID:        orderID,
+			Type:           charge.Type,
+			Name:           charge.Name,
+			Rate:           charge.Rate,
+			BaseAmount:     charge.BaseAmount,
+			Amount:         charge.Amount,
+			IncludedAmount: charge.IncludedAmount,
+			CreatedAt:      now,
+		})
+	}
+
+	return repo.AddOrderCharges(ctx, charges)
+}
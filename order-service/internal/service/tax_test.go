@@ -0,0 +1,294 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/alimikegami/point-of-sales/order-service/internal/domain"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+)
+
+func rupiah(amount int64) money.Money {
+	return money.New(amount*100, money.IDR)
+}
+
+func TestCalculateOrderCharges(t *testing.T) {
+	food := "food"
+
+	type TestCase struct {
+		Name                string
+		Items               []orderSagaItem
+		Setting             domain.StoreTaxSetting
+		Categories          map[string]string
+		ExpectedCharges     []orderSagaCharge
+		ExpectedServiceFee  money.Money
+		ExpectedTax         money.Money
+		ExpectedTotal       money.Money
+		ExpectedLineTotals  []money.Money
+		ExpectedLineTaxes   []money.Money
+		ExpectedLineCharges []money.Money
+	}
+
+	testCases := []TestCase{
+		{
+			Name:  "Tax-exclusive prices",
+			Items: []orderSagaItem{{ProductID: "a", Quantity: 2, Price: rupiah(10000)}},
+			Setting: domain.StoreTaxSetting{
+				TaxRates: []domain.TaxRate{{Name: "PPN", Rate: 11}},
+			},
+			ExpectedCharges: []orderSagaCharge{
+				{Type: domain.OrderChargeTypeTax, Name: "PPN", Rate: 11, BaseAmount: rupiah(20000), Amount: rupiah(2200), IncludedAmount: rupiah(0)},
+			},
+			ExpectedServiceFee:  rupiah(0),
+			ExpectedTax:         rupiah(2200),
+			ExpectedTotal:       rupiah(22200),
+			ExpectedLineTotals:  []money.Money{rupiah(22200)},
+			ExpectedLineTaxes:   []money.Money{rupiah(2200)},
+			ExpectedLineCharges: []money.Money{rupiah(0)},
+		},
+		{
+			Name:  "Tax-inclusive prices",
+			Items: []orderSagaItem{{ProductID: "a", Quantity: 2, Price: rupiah(10000)}},
+			Setting: domain.StoreTaxSetting{
+				PricesIncludeTax: true,
+				TaxRates:         []domain.TaxRate{{Name: "PPN", Rate: 11}},
+			},
+			// The tax is taken out of the Rp20,000 the customer pays,
+			// Rp20,000 / 1.11 * 0.11 = Rp1,981.98.
+			ExpectedCharges: []orderSagaCharge{
+				{Type: domain.OrderChargeTypeTax, Name: "PPN", Rate: 11, BaseAmount: rupiah(18018), Amount: rupiah(1982), IncludedAmount: rupiah(1982)},
+			},
+			ExpectedServiceFee:  rupiah(0),
+			ExpectedTax:         rupiah(1982),
+			ExpectedTotal:       rupiah(20000),
+			ExpectedLineTotals:  []money.Money{rupiah(20000)},
+			ExpectedLineTaxes:   []money.Money{rupiah(1982)},
+			ExpectedLineCharges: []money.Money{rupiah(0)},
+		},
+		{
+			Name:  "Taxable service charge",
+			Items: []orderSagaItem{{ProductID: "a", Quantity: 1, Price: rupiah(20000)}},
+			Setting: domain.StoreTaxSetting{
+				ServiceChargeRate:    5,
+				ServiceChargeTaxable: true,
+				TaxRates:             []domain.TaxRate{{Name: "PB1", Rate: 10}},
+			},
+			ExpectedCharges: []orderSagaCharge{
+				{Type: domain.OrderChargeTypeServiceCharge, Name: serviceChargeName, Rate: 5, BaseAmount: rupiah(20000), Amount: rupiah(1000)},
+				{Type: domain.OrderChargeTypeTax, Name: "PB1", Rate: 10, BaseAmount: rupiah(21000), Amount: rupiah(2100), IncludedAmount: rupiah(0)},
+			},
+			ExpectedServiceFee:  rupiah(1000),
+			ExpectedTax:         rupiah(2100),
+			ExpectedTotal:       rupiah(23100),
+			ExpectedLineTotals:  []money.Money{rupiah(23100)},
+			ExpectedLineTaxes:   []money.Money{rupiah(2100)},
+			ExpectedLineCharges: []money.Money{rupiah(1000)},
+		},
+		{
+			Name: "Tax-inclusive prices keep taxes out of the service charge",
+			Items: []orderSagaItem{
+				{ProductID: "a", Quantity: 1, Price: rupiah(11000)},
+			},
+			Setting: domain.StoreTaxSetting{
+				PricesIncludeTax:  true,
+				ServiceChargeRate: 10,
+				TaxRates:          []domain.TaxRate{{Name: "PPN", Rate: 10}},
+			},
+			ExpectedCharges: []orderSagaCharge{
+				{Type: domain.OrderChargeTypeServiceCharge, Name: serviceChargeName, Rate: 10, BaseAmount: rupiah(10000), Amount: rupiah(1000)},
+				{Type: domain.OrderChargeTypeTax, Name: "PPN", Rate: 10, BaseAmount: rupiah(10000), Amount: rupiah(1000), IncludedAmount: rupiah(1000)},
+			},
+			ExpectedServiceFee:  rupiah(1000),
+			ExpectedTax:         rupiah(1000),
+			ExpectedTotal:       rupiah(12000),
+			ExpectedLineTotals:  []money.Money{rupiah(12000)},
+			ExpectedLineTaxes:   []money.Money{rupiah(1000)},
+			ExpectedLineCharges: []money.Money{rupiah(1000)},
+		},
+		{
+			// Every line owes Rp36.63 of tax, the Rp110 the order is charged
+			// after rounding is spread over them with the remainder on the
+			// last one.
+			Name: "Rounded tax spread over the lines",
+			Items: []orderSagaItem{
+				{ProductID: "a", Quantity: 1, Price: rupiah(333)},
+				{ProductID: "b", Quantity: 1, Price: rupiah(333)},
+				{ProductID: "c", Quantity: 1, Price: rupiah(333)},
+			},
+			Setting: domain.StoreTaxSetting{
+				TaxRates: []domain.TaxRate{{Name: "PPN", Rate: 11}},
+			},
+			ExpectedCharges: []orderSagaCharge{
+				{Type: domain.OrderChargeTypeTax, Name: "PPN", Rate: 11, BaseAmount: rupiah(999), Amount: rupiah(110), IncludedAmount: rupiah(0)},
+			},
+			ExpectedServiceFee:  rupiah(0),
+			ExpectedTax:         rupiah(110),
+			ExpectedTotal:       rupiah(1109),
+			ExpectedLineTotals:  []money.Money{money.New(36967, money.IDR), money.New(36967, money.IDR), money.New(36966, money.IDR)},
+			ExpectedLineTaxes:   []money.Money{money.New(3667, money.IDR), money.New(3667, money.IDR), money.New(3666, money.IDR)},
+			ExpectedLineCharges: []money.Money{rupiah(0), rupiah(0), rupiah(0)},
+		},
+		{
+			Name: "Rates by category",
+			Items: []orderSagaItem{
+				{ProductID: "meal", Quantity: 1, Price: rupiah(10000)},
+				{ProductID: "bag", Quantity: 1, Price: rupiah(5000)},
+			},
+			Setting: domain.StoreTaxSetting{
+				TaxRates: []domain.TaxRate{
+					{Name: "PB1", Rate: 10, Category: &food},
+					{Name: "PPN", Rate: 11},
+				},
+			},
+			Categories: map[string]string{"meal": food},
+			ExpectedCharges: []orderSagaCharge{
+				{Type: domain.OrderChargeTypeTax, Name: "PB1", Rate: 10, BaseAmount: rupiah(10000), Amount: rupiah(1000), IncludedAmount: rupiah(0)},
+				{Type: domain.OrderChargeTypeTax, Name: "PPN", Rate: 11, BaseAmount: rupiah(5000), Amount: rupiah(550), IncludedAmount: rupiah(0)},
+			},
+			ExpectedServiceFee:  rupiah(0),
+			ExpectedTax:         rupiah(1550),
+			ExpectedTotal:       rupiah(16550),
+			ExpectedLineTotals:  []money.Money{rupiah(11000), rupiah(5550)},
+			ExpectedLineTaxes:   []money.Money{rupiah(1000), rupiah(550)},
+			ExpectedLineCharges: []money.Money{rupiah(0), rupiah(0)},
+		},
+		{
+			Name:  "Discounted line",
+			Items: []orderSagaItem{{ProductID: "a", Quantity: 1, Price: rupiah(10000), DiscountAmount: rupiah(2000)}},
+			Setting: domain.StoreTaxSetting{
+				TaxRates: []domain.TaxRate{{Name: "PPN", Rate: 10}},
+			},
+			ExpectedCharges: []orderSagaCharge{
+				{Type: domain.OrderChargeTypeTax, Name: "PPN", Rate: 10, BaseAmount: rupiah(8000), Amount: rupiah(800), IncludedAmount: rupiah(0)},
+			},
+			ExpectedServiceFee:  rupiah(0),
+			ExpectedTax:         rupiah(800),
+			ExpectedTotal:       rupiah(8800),
+			ExpectedLineTotals:  []money.Money{rupiah(8800)},
+			ExpectedLineTaxes:   []money.Money{rupiah(800)},
+			ExpectedLineCharges: []money.Money{rupiah(0)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			state := orderSagaState{Currency: money.IDR, OrderItems: tc.Items}
+			var subtotal, discount int64
+			for _, item := range tc.Items {
+				subtotal += item.Price.Amount * int64(item.Quantity)
+				discount += item.DiscountAmount.Amount
+			}
+			state.Subtotal = money.New(subtotal, money.IDR)
+			state.DiscountAmount = money.New(discount, money.IDR)
+
+			calculateOrderCharges(&state, tc.Setting, tc.Categories)
+
+			if !slices.Equal(state.Charges, tc.ExpectedCharges) {
+				t.Errorf("expected charges %+v, got %+v", tc.ExpectedCharges, state.Charges)
+			}
+
+			if state.ServiceChargeAmount != tc.ExpectedServiceFee {
+				t.Errorf("expected service charge %s, got %s", tc.ExpectedServiceFee, state.ServiceChargeAmount)
+			}
+
+			if state.TaxAmount != tc.ExpectedTax {
+				t.Errorf("expected tax %s, got %s", tc.ExpectedTax, state.TaxAmount)
+			}
+
+			if state.TotalAmount != tc.ExpectedTotal {
+				t.Errorf("expected total %s, got %s", tc.ExpectedTotal, state.TotalAmount)
+			}
+
+			if state.PricesIncludeTax != tc.Setting.PricesIncludeTax {
+				t.Errorf("expected prices include tax %t, got %t", tc.Setting.PricesIncludeTax, state.PricesIncludeTax)
+			}
+
+			var lineTotal, lineTax, lineCharge int64
+			for i, item := range state.OrderItems {
+				if item.TotalAmount != tc.ExpectedLineTotals[i] {
+					t.Errorf("expected line %d total %s, got %s", i, tc.ExpectedLineTotals[i], item.TotalAmount)
+				}
+
+				if item.TaxAmount != tc.ExpectedLineTaxes[i] {
+					t.Errorf("expected line %d tax %s, got %s", i, tc.ExpectedLineTaxes[i], item.TaxAmount)
+				}
+
+				if item.ServiceChargeAmount != tc.ExpectedLineCharges[i] {
+					t.Errorf("expected line %d service charge %s, got %s", i, tc.ExpectedLineCharges[i], item.ServiceChargeAmount)
+				}
+
+				lineTotal += item.TotalAmount.Amount
+				lineTax += item.TaxAmount.Amount
+				lineCharge += item.ServiceChargeAmount.Amount
+			}
+
+			if lineTotal != state.TotalAmount.Amount || lineTax != state.TaxAmount.Amount || lineCharge != state.ServiceChargeAmount.Amount {
+				t.Errorf("lines add up to total %d, tax %d and service charge %d, the order is %s, %s and %s", lineTotal, lineTax, lineCharge, state.TotalAmount, state.TaxAmount, state.ServiceChargeAmount)
+			}
+		})
+	}
+}
+
+func TestRoundCharge(t *testing.T) {
+	type TestCase struct {
+		Name      string
+		Amount    float64
+		Currency  string
+		Mode      string
+		Increment float64
+		Expected  int64
+	}
+
+	testCases := []TestCase{
+		{Name: "Whole rupiah by default", Amount: 12345, Currency: money.IDR, Expected: 12300},
+		{Name: "Half up", Amount: 12350, Currency: money.IDR, Mode: domain.RoundingModeHalfUp, Increment: 1, Expected: 12400},
+		{Name: "Up", Amount: 12301, Currency: money.IDR, Mode: domain.RoundingModeUp, Increment: 1, Expected: 12400},
+		{Name: "Up on an exact amount", Amount: 12300, Currency: money.IDR, Mode: domain.RoundingModeUp, Increment: 1, Expected: 12300},
+		{Name: "Up ignores floating point noise", Amount: 12300.0000001, Currency: money.IDR, Mode: domain.RoundingModeUp, Increment: 1, Expected: 12300},
+		{Name: "Down", Amount: 12399, Currency: money.IDR, Mode: domain.RoundingModeDown, Increment: 1, Expected: 12300},
+		{Name: "Down ignores floating point noise", Amount: 12399.9999999999, Currency: money.IDR, Mode: domain.RoundingModeDown, Increment: 1, Expected: 12400},
+		{Name: "Increment of Rp100", Amount: 1234500, Currency: money.IDR, Mode: domain.RoundingModeHalfUp, Increment: 100, Expected: 1230000},
+		{Name: "Increment of Rp500 up", Amount: 1200100, Currency: money.IDR, Mode: domain.RoundingModeUp, Increment: 500, Expected: 1250000},
+		{Name: "Currency without minor units", Amount: 1234.4, Currency: "JPY", Mode: domain.RoundingModeHalfUp, Increment: 1, Expected: 1234},
+		{Name: "Negative amount", Amount: -12360, Currency: money.IDR, Mode: domain.RoundingModeHalfUp, Increment: 1, Expected: -12400},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			setting := domain.StoreTaxSetting{RoundingMode: tc.Mode, RoundingIncrement: tc.Increment}
+			if rounded := roundCharge(tc.Amount, tc.Currency, setting); rounded != tc.Expected {
+				t.Errorf("expected %d, got %d", tc.Expected, rounded)
+			}
+		})
+	}
+}
+
+func TestAllocateAmount(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Total    int64
+		Weights  []float64
+		Expected []int64
+	}
+
+	testCases := []TestCase{
+		{Name: "Even split", Total: 90, Weights: []float64{1, 1, 1}, Expected: []int64{30, 30, 30}},
+		{Name: "Remainder on the last line", Total: 100, Weights: []float64{1, 1, 1}, Expected: []int64{33, 33, 34}},
+		{Name: "Proportional", Total: 1000, Weights: []float64{1, 3}, Expected: []int64{250, 750}},
+		{Name: "Lines without a weight get nothing", Total: 10, Weights: []float64{0, 1, 1}, Expected: []int64{0, 5, 5}},
+		{Name: "Remainder skips lines without a weight", Total: 5, Weights: []float64{1, 1, 0}, Expected: []int64{3, 2, 0}},
+		{Name: "Fractional weights", Total: 11000, Weights: []float64{3663, 3663, 3663}, Expected: []int64{3667, 3667, 3666}},
+		{Name: "Nothing to spread", Total: 0, Weights: []float64{1, 2}, Expected: []int64{0, 0}},
+		{Name: "No weights", Total: 100, Weights: []float64{0, 0}, Expected: []int64{0, 0}},
+		{Name: "No lines", Total: 100, Weights: nil, Expected: []int64{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			shares := allocateAmount(tc.Total, tc.Weights)
+			if !slices.Equal(shares, tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, shares)
+			}
+		})
+	}
+}
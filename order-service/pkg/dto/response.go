@@ -17,6 +17,9 @@ type PaginationMetadata struct {
 	TotalCount uint64 `json:"total_count"`
 	Page       uint64 `json:"page"`
 	Limit      int    `json:"limit"`
+	// NextCursor continues a keyset paginated listing, it is nil on the last
+	// page.
+	NextCursor *string `json:"next_cursor"`
 }
 
 type PaginationResponse struct {
@@ -42,10 +45,38 @@ type ErrorResponse struct {
 }
 
 type Filter struct {
-	Limit         int `query:"limit"`
-	Page          int `query:"page"`
-	PaymentStatus string
+	Limit         int    `query:"limit"`
+	Page          int    `query:"page"`
+	PaymentStatus string `query:"payment_status"`
 	Expired       bool
+	// StartDate and EndDate are YYYY-MM-DD dates in WIB, both included.
+	StartDate         string `query:"start_date"`
+	EndDate           string `query:"end_date"`
+	PaymentMethodID   int64  `query:"payment_method_id"`
+	CashierID         int64  `query:"cashier_id"`
+	TransactionNumber string `query:"transaction_number"`
+	// MinAmount and MaxAmount bound the amount when they are not zero.
+	MinAmount float64 `query:"min_amount"`
+	MaxAmount float64 `query:"max_amount"`
+	SortBy    string  `query:"sort_by"`
+	SortOrder string  `query:"sort_order"`
+	// Cursor is the NextCursor of the previous page, it is used instead of
+	// Page to go through large listings.
+	Cursor string `query:"cursor"`
+
+	// CreatedFrom, CreatedTo and After are worked out from the fields above
+	// before the filter reaches the repository.
+	CreatedFrom int64
+	CreatedTo   int64
+	After       *Cursor
+}
+
+// Cursor is the position of the last record of a keyset paginated page: the
+// value it was sorted by and its ID, which breaks ties.
+type Cursor struct {
+	SortBy string `json:"s"`
+	Value  string `json:"v"`
+	ID     int64  `json:"id"`
 }
 
 func WriteSuccessResponse(c echo.Context, message string) error {
@@ -15,6 +15,7 @@ const (
 	ErrStatusEmailAlreadyUsed       = http.StatusBadRequest
 	ErrStatusFileSizeExceedingLimit = http.StatusRequestEntityTooLarge
 	ErrStatusConflict               = http.StatusConflict
+	ErrStatusUnprocessableEntity    = http.StatusUnprocessableEntity
 	ErrBadGateway                   = http.StatusBadGateway
 	ErrStatusPropertyBlockIsSold    = http.StatusGone
 )
@@ -40,6 +41,25 @@ var (
 	ErrPaymentExpired              = errors.New("Payment for this order has expired")
 	ErrPropertyBlockIsSold         = errors.New("property block has been sold")
 	ErrDuplicateName               = errors.New("Duplicate name found")
+	ErrInvalidSignature            = errors.New("Invalid signature")
+	ErrPaymentAmountMismatch       = errors.New("Payment amount does not match the order amount")
+	ErrOrderAlreadyPaid            = errors.New("Order has already been paid, refund it instead")
+	ErrOrderNotCancellable         = errors.New("Order can no longer be cancelled")
+	ErrPaymentGateway              = errors.New("Payment gateway request failed")
+	ErrOrderNotRefundable          = errors.New("Only paid orders can be refunded")
+	ErrRefundQuantityExceeded      = errors.New("Refund quantity exceeds the quantity left to refund")
+	ErrIdempotencyKeyReused        = errors.New("Idempotency key has already been used with a different request")
+	ErrPromotionNotApplicable      = errors.New("Promo code is invalid or cannot be applied to this order")
+	ErrUnknownStore                = errors.New("Store has no tax settings")
+	ErrPaymentMethodUnavailable    = errors.New("Payment method does not exist or is disabled")
+	ErrInsufficientTender          = errors.New("Tendered cash does not cover the order amount")
+	ErrShiftNotOpen                = errors.New("Open a shift before taking cash")
+	ErrShiftAlreadyOpen            = errors.New("A shift is already open for this cashier or terminal")
+	ErrShiftClosed                 = errors.New("Shift has already been closed")
+	ErrUnknownCustomer             = errors.New("Customer does not exist")
+	ErrOutOfStock                  = errors.New("Items of the order are no longer in stock")
+	ErrRefundInProgress            = errors.New("Another refund of this order is still being processed, retry it with its key")
+	ErrIdempotencyKeyInProgress    = errors.New("A request with this idempotency key is still being processed")
 )
 
 var errorMap = map[error]int{
@@ -62,6 +82,25 @@ var errorMap = map[error]int{
 	ErrPaymentExpired:              ErrStatusNoPermission,
 	ErrPropertyBlockIsSold:         ErrStatusPropertyBlockIsSold,
 	ErrDuplicateName:               ErrStatusConflict,
+	ErrInvalidSignature:            ErrStatusUnauthorized,
+	ErrPaymentAmountMismatch:       ErrStatusClient,
+	ErrOrderAlreadyPaid:            ErrStatusConflict,
+	ErrOrderNotCancellable:         ErrStatusConflict,
+	ErrPaymentGateway:              ErrBadGateway,
+	ErrOrderNotRefundable:          ErrStatusConflict,
+	ErrRefundQuantityExceeded:      ErrStatusClient,
+	ErrIdempotencyKeyReused:        ErrStatusUnprocessableEntity,
+	ErrPromotionNotApplicable:      ErrStatusUnprocessableEntity,
+	ErrUnknownStore:                ErrStatusUnprocessableEntity,
+	ErrPaymentMethodUnavailable:    ErrStatusUnprocessableEntity,
+	ErrInsufficientTender:          ErrStatusUnprocessableEntity,
+	ErrShiftNotOpen:                ErrStatusConflict,
+	ErrShiftAlreadyOpen:            ErrStatusConflict,
+	ErrShiftClosed:                 ErrStatusConflict,
+	ErrUnknownCustomer:             ErrStatusUnprocessableEntity,
+	ErrOutOfStock:                  ErrStatusConflict,
+	ErrRefundInProgress:            ErrStatusConflict,
+	ErrIdempotencyKeyInProgress:    ErrStatusConflict,
 }
 
 func GetErrorStatusCode(err error) int {
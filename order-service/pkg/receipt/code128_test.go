@@ -0,0 +1,66 @@
+package receipt
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCode128(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Data     string
+		Expected []int
+	}
+
+	testCases := []TestCase{
+		{
+			// Start B, "A", checksum (104 + 33) % 103 = 34 and stop.
+			Name:     "Single character",
+			Data:     "A",
+			Expected: []int{2, 1, 1, 2, 1, 4, 1, 1, 1, 3, 2, 3, 1, 3, 1, 1, 2, 3, 2, 3, 3, 1, 1, 1, 2},
+		},
+		{
+			// Start B, "1", "2", checksum (104 + 17 + 2*18) % 103 = 54 and
+			// stop.
+			Name:     "Digits",
+			Data:     "12",
+			Expected: []int{2, 1, 1, 2, 1, 4, 1, 2, 3, 2, 2, 1, 2, 2, 3, 2, 1, 1, 3, 1, 1, 1, 2, 3, 2, 3, 3, 1, 1, 1, 2},
+		},
+		{Name: "Empty", Data: "", Expected: nil},
+		{Name: "Control character", Data: "ORD\n1", Expected: nil},
+		{Name: "Outside ASCII", Data: "Café", Expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if widths := code128(tc.Data); !slices.Equal(widths, tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, widths)
+			}
+		})
+	}
+}
+
+// Every symbol is 11 modules wide and the stop symbol 13, with the bars and
+// spaces alternating from a bar to a closing bar.
+func TestCode128Modules(t *testing.T) {
+	for _, data := range []string{"ORD-20240101-0001", " ~", "receipt 42"} {
+		t.Run(data, func(t *testing.T) {
+			widths := code128(data)
+			if len(widths)%2 == 0 {
+				t.Fatalf("expected an odd number of bars and spaces, got %d", len(widths))
+			}
+
+			var modules int
+			for _, width := range widths {
+				if width < 1 || width > 4 {
+					t.Fatalf("invalid width %d", width)
+				}
+				modules += width
+			}
+
+			if expected := 11*(len(data)+2) + 13; modules != expected {
+				t.Errorf("expected %d modules, got %d", expected, modules)
+			}
+		})
+	}
+}
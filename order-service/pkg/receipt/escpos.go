@@ -0,0 +1,146 @@
+package receipt
+
+import (
+	"bytes"
+	"image"
+)
+
+// ESC/POS commands of the thermal printers.
+var (
+	escposInit        = []byte{0x1b, 0x40}
+	escposAlignLeft   = []byte{0x1b, 0x61, 0x00}
+	escposAlignCenter = []byte{0x1b, 0x61, 0x01}
+	escposBoldOn      = []byte{0x1b, 0x45, 0x01}
+	escposBoldOff     = []byte{0x1b, 0x45, 0x00}
+	escposFeed        = []byte{0x1b, 0x64, 0x04}
+	escposCut         = []byte{0x1d, 0x56, 0x42, 0x00}
+)
+
+// RenderESCPOS renders the receipt as ESC/POS commands, which can be sent to
+// the thermal printer as they are. The transaction number is printed as a QR
+// code by the printer itself.
+func RenderESCPOS(r Receipt) []byte {
+	columns := r.columns()
+	header, body, footer := r.lines(columns)
+
+	var b bytes.Buffer
+	b.Write(escposInit)
+
+	if r.Logo != nil {
+		b.Write(escposAlignCenter)
+		writeESCPOSImage(&b, r.Logo, r.printableDots())
+		b.Write(escposAlignLeft)
+	}
+
+	writeESCPOSLines(&b, header)
+	writeESCPOSLines(&b, body)
+
+	b.Write(escposAlignCenter)
+	writeESCPOSQRCode(&b, r.TransactionNumber)
+	b.Write(escposAlignLeft)
+
+	writeESCPOSLines(&b, footer)
+
+	b.Write(escposFeed)
+	b.Write(escposCut)
+
+	return b.Bytes()
+}
+
+// printableDots is how many dots the printer prints on a line of the paper.
+func (r Receipt) printableDots() int {
+	if r.PaperWidth == PaperWidth58 {
+		return 384
+	}
+
+	return 576
+}
+
+func writeESCPOSLines(b *bytes.Buffer, lines []line) {
+	for _, l := range lines {
+		if l.bold {
+			b.Write(escposBoldOn)
+		}
+
+		b.Write(asciiText(l.text))
+		b.WriteByte('\n')
+
+		if l.bold {
+			b.Write(escposBoldOff)
+		}
+	}
+}
+
+// writeESCPOSQRCode prints data as a model 2 QR code with the GS ( k
+// commands.
+func writeESCPOSQRCode(b *bytes.Buffer, data string) {
+	text := asciiText(data)
+	size := len(text) + 3
+
+	// Model 2, 6 dot modules and error correction level M.
+	b.Write([]byte{0x1d, 0x28, 0x6b, 0x04, 0x00, 0x31, 0x41, 0x32, 0x00})
+	b.Write([]byte{0x1d, 0x28, 0x6b, 0x03, 0x00, 0x31, 0x43, 0x06})
+	b.Write([]byte{0x1d, 0x28, 0x6b, 0x03, 0x00, 0x31, 0x45, 0x31})
+
+	b.Write([]byte{0x1d, 0x28, 0x6b, byte(size), byte(size >> 8), 0x31, 0x50, 0x30})
+	b.Write(text)
+	b.Write([]byte{0x1d, 0x28, 0x6b, 0x03, 0x00, 0x31, 0x51, 0x30})
+	b.WriteByte('\n')
+}
+
+// writeESCPOSImage prints img in black and white with the GS v 0 raster
+// command, scaled down to maxWidth dots when it is wider.
+func writeESCPOSImage(b *bytes.Buffer, img image.Image, maxWidth int) {
+	width, height, dark := monochrome(img, maxWidth)
+	if width == 0 || height == 0 {
+		return
+	}
+
+	rowBytes := (width + 7) / 8
+	b.Write([]byte{0x1d, 0x76, 0x30, 0x00, byte(rowBytes), byte(rowBytes >> 8), byte(height), byte(height >> 8)})
+
+	for y := 0; y < height; y++ {
+		row := make([]byte, rowBytes)
+		for x := 0; x < width; x++ {
+			if dark(x, y) {
+				row[x/8] |= 0x80 >> (x % 8)
+			}
+		}
+		b.Write(row)
+	}
+}
+
+// monochrome scales img down to maxWidth pixels when it is wider, and tells
+// which of its pixels are dark enough to be printed.
+func monochrome(img image.Image, maxWidth int) (width int, height int, dark func(x, y int) bool) {
+	bounds := img.Bounds()
+	width, height = bounds.Dx(), bounds.Dy()
+	scale := 1.0
+	if width > maxWidth {
+		scale = float64(width) / float64(maxWidth)
+		width = maxWidth
+		height = int(float64(height) / scale)
+	}
+
+	return width, height, func(x, y int) bool {
+		r, g, b, a := img.At(bounds.Min.X+int(float64(x)*scale), bounds.Min.Y+int(float64(y)*scale)).RGBA()
+		if a < 0x8000 {
+			return false
+		}
+
+		return (299*r+587*g+114*b)/1000 < 0x8000
+	}
+}
+
+// asciiText replaces the characters the printer code page may not have.
+func asciiText(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r < 0x20 || r > 0x7e {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+
+	return out
+}
@@ -0,0 +1,212 @@
+package receipt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"strings"
+)
+
+const (
+	pdfPointsPerMM = 72 / 25.4
+	pdfMargin      = 8.0
+	// pdfCourierWidth is the width of every Courier character, per point of
+	// font size.
+	pdfCourierWidth  = 0.6
+	pdfLineSpacing   = 1.25
+	pdfBarcodeHeight = 36.0
+)
+
+// RenderPDF renders the receipt as a PDF of a single page as wide as the
+// paper roll, to be emailed to the customer. The transaction number is drawn
+// as a Code 128 barcode.
+func RenderPDF(r Receipt) []byte {
+	columns := r.columns()
+	header, body, footer := r.lines(columns)
+
+	pageWidth := float64(r.PaperWidth) * pdfPointsPerMM
+	if !ValidPaperWidth(r.PaperWidth) {
+		pageWidth = PaperWidth80 * pdfPointsPerMM
+	}
+	contentWidth := pageWidth - 2*pdfMargin
+	fontSize := contentWidth / (float64(columns) * pdfCourierWidth)
+	leading := fontSize * pdfLineSpacing
+
+	var logo *pdfImage
+	logoWidth, logoHeight := 0.0, 0.0
+	if r.Logo != nil {
+		logo = newPDFImage(r.Logo, r.printableDots())
+		if logo != nil {
+			logoWidth = float64(logo.width) * pdfPointsPerMM / 8
+			if logoWidth > contentWidth {
+				logoWidth = contentWidth
+			}
+			logoHeight = logoWidth * float64(logo.height) / float64(logo.width)
+		}
+	}
+
+	bars := code128(r.TransactionNumber)
+	lineCount := len(header) + len(body) + len(footer) + 1
+	pageHeight := 2*pdfMargin + float64(lineCount)*leading
+	if logo != nil {
+		pageHeight += logoHeight + leading
+	}
+	if bars != nil {
+		pageHeight += pdfBarcodeHeight + leading/2
+	}
+
+	var content bytes.Buffer
+	y := pageHeight - pdfMargin
+
+	if logo != nil {
+		y -= logoHeight
+		fmt.Fprintf(&content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im1 Do Q\n", logoWidth, logoHeight, (pageWidth-logoWidth)/2, y)
+		y -= leading
+	}
+
+	writeLines := func(lines []line) {
+		for _, l := range lines {
+			y -= leading
+			font := "F1"
+			if l.bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, fontSize, pdfMargin, y, pdfText(l.text))
+		}
+	}
+
+	writeLines(header)
+	writeLines(body)
+
+	if bars != nil {
+		modules := 0
+		for _, width := range bars {
+			modules += width
+		}
+
+		module := contentWidth / float64(modules)
+		x := pdfMargin
+		y -= pdfBarcodeHeight + leading/2
+		for i, width := range bars {
+			if i%2 == 0 {
+				fmt.Fprintf(&content, "%.3f %.2f %.3f %.2f re\n", x, y, float64(width)*module, pdfBarcodeHeight)
+			}
+			x += float64(width) * module
+		}
+		content.WriteString("f\n")
+	}
+
+	writeLines(centered(r.TransactionNumber, columns))
+	writeLines(footer)
+
+	return writePDF(pageWidth, pageHeight, content.Bytes(), logo)
+}
+
+// pdfImage is a grayscale image, compressed to be embedded in a PDF.
+type pdfImage struct {
+	width  int
+	height int
+	data   []byte
+}
+
+// newPDFImage turns img into a grayscale image no wider than maxWidth pixels,
+// transparent pixels become white.
+func newPDFImage(img image.Image, maxWidth int) *pdfImage {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil
+	}
+
+	scale := 1.0
+	if width > maxWidth {
+		scale = float64(width) / float64(maxWidth)
+		width = maxWidth
+		height = int(float64(height) / scale)
+		if height == 0 {
+			return nil
+		}
+	}
+
+	var data bytes.Buffer
+	w := zlib.NewWriter(&data)
+	row := make([]byte, width)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, a := img.At(bounds.Min.X+int(float64(x)*scale), bounds.Min.Y+int(float64(y)*scale)).RGBA()
+			// The colors are premultiplied, so the missing alpha is added back
+			// as white.
+			gray := (299*r+587*g+114*b)/1000 + (0xffff - a)
+			row[x] = byte(gray >> 8)
+		}
+		w.Write(row)
+	}
+	w.Close()
+
+	return &pdfImage{width: width, height: height, data: data.Bytes()}
+}
+
+// writePDF writes a PDF 1.4 document of a single page, with the standard
+// Courier fonts as F1 and F2 and logo, when there is one, as Im1.
+func writePDF(width float64, height float64, content []byte, logo *pdfImage) []byte {
+	var b bytes.Buffer
+	var offsets []int
+
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			b.WriteString("stream\n")
+			b.Write(stream)
+			b.WriteString("\nendstream\n")
+		}
+		b.WriteString("endobj\n")
+	}
+
+	resources := "/Font << /F1 5 0 R /F2 6 0 R >>"
+	if logo != nil {
+		resources += " /XObject << /Im1 7 0 R >>"
+	}
+
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	object("<< /Type /Pages /Kids [3 0 R] /Count 1 >>", nil)
+	object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << %s >> /Contents 4 0 R >>", width, height, resources), nil)
+	object(fmt.Sprintf("<< /Length %d >>", len(content)), content)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>", nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>", nil)
+	if logo != nil {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>", logo.width, logo.height, len(logo.data)), logo.data)
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return b.Bytes()
+}
+
+// pdfText escapes text for a PDF string in WinAnsiEncoding, the characters it
+// does not have are replaced.
+func pdfText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || (r > 0x7e && r < 0xa0) || r > 0xff:
+			b.WriteByte('?')
+		case r > 0x7e:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,248 @@
+package receipt
+
+import (
+	"errors"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	FormatText   = "text"
+	FormatESCPOS = "escpos"
+	FormatPDF    = "pdf"
+
+	// PaperWidth58 and PaperWidth80 are the thermal paper rolls we print on,
+	// in millimetres.
+	PaperWidth58 = 58
+	PaperWidth80 = 80
+)
+
+var ErrUnknownFormat = errors.New("unknown receipt format")
+
+// Receipt is what is printed on the receipt of an order, with every amount
+// already worked out. Adjustments are the lines between the subtotal and the
+// total, e.g. discounts, service charges and taxes, while Notes are printed
+// under the total without changing it, e.g. the taxes included in the prices.
+type Receipt struct {
+	PaperWidth        int
+	Logo              image.Image
+	Header            []string
+	StoreCode         string
+	TransactionNumber string
+	// Status is printed on receipts of orders that are not simply paid, e.g.
+	// refunded or cancelled ones.
+	Status      string
+	CreatedAt   time.Time
+	Items       []Item
+	Subtotal    float64
+	Adjustments []Line
+	Total       float64
+	Notes       []Line
+	Payments    []Payment
+	Refunded    float64
+	Footer      []string
+}
+
+type Item struct {
+	Name     string
+	Quantity int64
+	Price    float64
+	Amount   float64
+}
+
+type Line struct {
+	Label  string
+	Amount float64
+}
+
+// Payment is a tender of the order. Tendered and Change are only set on cash
+// tenders.
+type Payment struct {
+	Name     string
+	Amount   float64
+	Tendered *float64
+	Change   *float64
+}
+
+// Render renders the receipt in the given format, and returns it together
+// with its content type.
+func Render(r Receipt, format string) (data []byte, contentType string, err error) {
+	switch format {
+	case FormatText:
+		return RenderText(r), "text/plain; charset=utf-8", nil
+	case FormatESCPOS:
+		return RenderESCPOS(r), "application/octet-stream", nil
+	case FormatPDF:
+		return RenderPDF(r), "application/pdf", nil
+	default:
+		return nil, "", ErrUnknownFormat
+	}
+}
+
+// ValidPaperWidth reports whether the receipts can be printed on the paper.
+func ValidPaperWidth(width int) bool {
+	return width == PaperWidth58 || width == PaperWidth80
+}
+
+// columns is how many characters of the printer font fit on a line of the
+// paper.
+func (r Receipt) columns() int {
+	if r.PaperWidth == PaperWidth58 {
+		return 32
+	}
+
+	return 48
+}
+
+// line is a line of text of the receipt, already padded to the width of the
+// paper.
+type line struct {
+	text string
+	bold bool
+}
+
+// lines lays the text of the receipt out in columns columns. The logo and the
+// transaction number code are left to the renderers.
+func (r Receipt) lines(columns int) (header []line, body []line, footer []line) {
+	for _, text := range r.Header {
+		header = append(header, centered(text, columns)...)
+	}
+
+	rule := line{text: strings.Repeat("-", columns)}
+
+	if r.StoreCode != "" {
+		body = append(body, line{text: pad("Store : "+r.StoreCode, columns)})
+	}
+	body = append(body, wrap("No    : "+r.TransactionNumber, columns)...)
+	body = append(body, line{text: pad("Date  : "+r.CreatedAt.Format("02/01/2006 15:04 MST"), columns)})
+	if r.Status != "" {
+		body = append(body, centered("*** "+strings.ToUpper(r.Status)+" ***", columns)...)
+	}
+	body = append(body, rule)
+
+	for _, item := range r.Items {
+		body = append(body, wrap(item.Name, columns)...)
+		body = append(body, columned("  "+strconv.FormatInt(item.Quantity, 10)+" x "+FormatAmount(item.Price), FormatAmount(item.Amount), columns, false))
+	}
+	body = append(body, rule)
+
+	body = append(body, columned("Subtotal", FormatAmount(r.Subtotal), columns, false))
+	for _, adjustment := range r.Adjustments {
+		body = append(body, columned(adjustment.Label, FormatAmount(adjustment.Amount), columns, false))
+	}
+	body = append(body, columned("TOTAL", FormatAmount(r.Total), columns, true))
+	for _, note := range r.Notes {
+		body = append(body, columned(note.Label, FormatAmount(note.Amount), columns, false))
+	}
+
+	if len(r.Payments) > 0 {
+		body = append(body, rule)
+	}
+	for _, payment := range r.Payments {
+		body = append(body, columned(payment.Name, FormatAmount(payment.Amount), columns, false))
+		if payment.Tendered != nil {
+			body = append(body, columned("  Tendered", FormatAmount(*payment.Tendered), columns, false))
+		}
+		if payment.Change != nil {
+			body = append(body, columned("  Change", FormatAmount(*payment.Change), columns, false))
+		}
+	}
+	if r.Refunded > 0 {
+		body = append(body, columned("Refunded", FormatAmount(-r.Refunded), columns, false))
+	}
+	body = append(body, rule)
+
+	for _, text := range r.Footer {
+		footer = append(footer, centered(text, columns)...)
+	}
+
+	return
+}
+
+// FormatAmount formats a rupiah amount the Indonesian way, e.g. 12.500 or
+// 12.500,50.
+func FormatAmount(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	cents := int64(math.Round(amount * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	if cents%100 != 0 {
+		return sign + grouped.String() + "," + strconv.FormatInt(cents%100/10, 10) + strconv.FormatInt(cents%10, 10)
+	}
+
+	return sign + grouped.String()
+}
+
+// columned puts label on the left and amount on the right of a line, the
+// label is cut short when both do not fit.
+func columned(label string, amount string, columns int, bold bool) line {
+	room := columns - utf8.RuneCountInString(amount) - 1
+	if room < 0 {
+		room = 0
+	}
+
+	label = truncate(label, room)
+
+	return line{text: label + strings.Repeat(" ", columns-utf8.RuneCountInString(label)-utf8.RuneCountInString(amount)) + amount, bold: bold}
+}
+
+// wrap breaks text over as many lines as it needs.
+func wrap(text string, columns int) (lines []line) {
+	runes := []rune(strings.TrimSpace(text))
+	for len(runes) > columns {
+		cut := columns
+		for i := columns; i > 0; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+
+		lines = append(lines, line{text: pad(string(runes[:cut]), columns)})
+		runes = []rune(strings.TrimSpace(string(runes[cut:])))
+	}
+
+	return append(lines, line{text: pad(string(runes), columns)})
+}
+
+func centered(text string, columns int) (lines []line) {
+	for _, l := range wrap(text, columns) {
+		text := strings.TrimRight(l.text, " ")
+		indent := (columns - utf8.RuneCountInString(text)) / 2
+		lines = append(lines, line{text: pad(strings.Repeat(" ", indent)+text, columns)})
+	}
+
+	return
+}
+
+func pad(text string, columns int) string {
+	text = truncate(text, columns)
+
+	return text + strings.Repeat(" ", columns-utf8.RuneCountInString(text))
+}
+
+func truncate(text string, columns int) string {
+	runes := []rune(text)
+	if len(runes) > columns {
+		return string(runes[:columns])
+	}
+
+	return text
+}
@@ -0,0 +1,21 @@
+package receipt
+
+import "strings"
+
+// RenderText renders the receipt as plain text, e.g. for the POS screen or a
+// printer that only takes text. It has no logo, and the transaction number is
+// printed instead of its code.
+func RenderText(r Receipt) []byte {
+	columns := r.columns()
+	header, body, footer := r.lines(columns)
+
+	var b strings.Builder
+	for _, group := range [][]line{header, body, centered(r.TransactionNumber, columns), footer} {
+		for _, l := range group {
+			b.WriteString(strings.TrimRight(l.text, " "))
+			b.WriteByte('\n')
+		}
+	}
+
+	return []byte(b.String())
+}
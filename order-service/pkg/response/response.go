@@ -1,6 +1,8 @@
 package response
 
 import (
+	"bytes"
+	"encoding/csv"
 	"net/http"
 
 	"github.com/alimikegami/point-of-sales/order-service/pkg/errs"
@@ -43,6 +45,20 @@ func WriteErrorResponse(c echo.Context, err error, errors interface{}) error {
 	return c.JSON(statusCode, resp)
 }
 
+// WriteCSVResponse sends records as a CSV attachment named fileName.
+func WriteCSVResponse(c echo.Context, fileName string, records [][]string) error {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	err := w.WriteAll(records)
+	if err != nil {
+		return WriteErrorResponse(c, errs.ErrInternalServer, nil)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+fileName+`"`)
+
+	return c.Blob(http.StatusOK, "text/csv; charset=utf-8", b.Bytes())
+}
+
 type DataWithPaginationsResponse struct {
 	Data       interface{} `json:"data,omitempty"`
 	Pagination interface{} `json:"pagination,omitempty"`
@@ -34,3 +34,18 @@ func ConvertDateTimeWibToUnixTimestamp(wibTime string) (int64, error) {
 	// Convert to Unix timestamp
 	return t.Unix(), nil
 }
+
+// ParseWIBDate parses a YYYY-MM-DD date as the start of that day in WIB.
+func ParseWIBDate(date string) (time.Time, error) {
+	wibLocation, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error loading WIB time zone: %v", err)
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", date, wibLocation)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing date: %v", err)
+	}
+
+	return t, nil
+}
@@ -64,3 +64,5 @@ require (
 	golang.org/x/text v0.25.0 // indirect
 	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
 )
+
+replace github.com/alimikegami/pos-microservices/proto-defs => ../proto-defs
@@ -1,13 +1,19 @@
 package domain
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Product struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name        string             `bson:"name" json:"name"`
 	Quantity    uint64             `bson:"quantity" json:"quantity"`
 	Description string             `bson:"description" json:"description"`
-	Price       float64            `bson:"price" json:"price"`
+	// Price is UnitPrice in major units, kept for the readers of the
+	// documents written before it.
+	Price     float64     `bson:"price" json:"price"`
+	UnitPrice money.Money `bson:"unit_price" json:"unit_price"`
 }
 
 type ProductImage struct {
@@ -15,3 +21,16 @@ type ProductImage struct {
 	ProductID primitive.ObjectID `bson:"product_id"`
 	ImageURL  string             `bson:"image_url"`
 }
+
+const (
+	StockReservationReserved = "reserved"
+	StockReservationReleased = "released"
+)
+
+// StockReservation is what was done under a reservation key. A release that
+// arrives before its reservation leaves it released, so the reservation is
+// not made after all.
+type StockReservation struct {
+	Key    string `bson:"_id"`
+	Status string `bson:"status"`
+}
@@ -1,11 +1,15 @@
 package dto
 
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
 type ProductRequest struct {
 	ID          string
-	Name        string  `json:"name"`
-	Quantity    uint64  `json:"quantity"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	Name        string `json:"name"`
+	Quantity    uint64 `json:"quantity"`
+	Description string `json:"description"`
+	// Price is either a number of rupiah or an amount of minor units with
+	// its currency. Updates without a price keep the current one.
+	Price *money.Money `json:"price"`
 }
 
 type OrderItem struct {
@@ -14,6 +18,9 @@ type OrderItem struct {
 }
 
 type OrderRequest struct {
-	TransactionNumber string      `json:"transaction_number"`
-	OrderItems        []OrderItem `json:"order_items"`
+	TransactionNumber string `json:"transaction_number"`
+	// ReservationKey, when set, reserves the items at most once under it, and
+	// a restore under the same key gives back only what it reserved.
+	ReservationKey string      `json:"reservation_key"`
+	OrderItems     []OrderItem `json:"order_items"`
 }
@@ -31,7 +31,8 @@ func (h *GrpcHandler) UpdateProductQuantityBatch(ctx context.Context, req *pb.Up
 	}
 
 	err := h.productService.UpdateProductsQuantity(ctx, dto.OrderRequest{
-		OrderItems: orderItem,
+		ReservationKey: req.GetReservationKey(),
+		OrderItems:     orderItem,
 	})
 
 	return &emptypb.Empty{}, err
@@ -15,7 +15,9 @@ type MongoDBProductRepository interface {
 	HandleTrx(ctx context.Context, fn func(ctx mongo.SessionContext) error) error
 	GetProductByID(ctx context.Context, id string) (product domain.Product, err error)
 	DeleteProduct(ctx context.Context, id string) (err error)
-	UpdateProduct(ctx context.Context, data domain.Product) (err error)
+	UpdateProduct(ctx context.Context, data domain.Product, withPrice bool) (product domain.Product, err error)
 	UpdateProductQuantity(ctx context.Context, data domain.Product) (err error)
 	SetProductQuantity(ctx context.Context, data domain.Product) (err error)
+	AddStockReservation(ctx context.Context, key string) (reserved bool, err error)
+	ReleaseStockReservation(ctx context.Context, key string) (released bool, err error)
 }
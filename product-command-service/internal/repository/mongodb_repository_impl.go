@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/alimikegami/point-of-sales/product-command-service/internal/domain"
@@ -115,23 +116,35 @@ func (r *MongoDBProductRepositoryImpl) DeleteProduct(ctx context.Context, id str
 	return
 }
 
-func (r *MongoDBProductRepositoryImpl) UpdateProduct(ctx context.Context, data domain.Product) (err error) {
+func (r *MongoDBProductRepositoryImpl) UpdateProduct(ctx context.Context, data domain.Product, withPrice bool) (product domain.Product, err error) {
 	filter := bson.D{{Key: "_id", Value: data.ID}}
 
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "name", Value: data.Name}, {Key: "description", Value: data.Description}}}}
+	fields := bson.D{
+		{Key: "name", Value: data.Name},
+		{Key: "description", Value: data.Description},
+	}
+	if withPrice {
+		fields = append(fields,
+			bson.E{Key: "price", Value: data.Price},
+			bson.E{Key: "unit_price", Value: data.UnitPrice},
+		)
+	}
 
-	result, err := r.db.Collection("products").UpdateOne(ctx, filter, update)
-	if err != nil {
+	update := bson.D{{Key: "$set", Value: fields}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err = r.db.Collection("products").FindOneAndUpdate(ctx, filter, update, opts).Decode(&product)
+	if errors.Is(err, mongo.ErrNoDocuments) {
 		log.Ctx(ctx).Error().Err(err).Str("component", "UpdateProduct").Msg("Failed to update product")
-		return
+		return product, errs.ErrNotFound
 	}
 
-	if result.MatchedCount == 0 {
+	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Str("component", "UpdateProduct").Msg("Failed to update product")
-		return errs.ErrNotFound
+		return
 	}
 
-	return nil
+	return product, nil
 }
 
 func (r *MongoDBProductRepositoryImpl) UpdateProductQuantity(ctx context.Context, data domain.Product) (err error) {
@@ -171,3 +184,46 @@ func (r *MongoDBProductRepositoryImpl) SetProductQuantity(ctx context.Context, d
 
 	return
 }
+
+// AddStockReservation records a reservation under key. It returns false when
+// key was reserved or released already.
+func (r *MongoDBProductRepositoryImpl) AddStockReservation(ctx context.Context, key string) (reserved bool, err error) {
+	filter := bson.D{{Key: "_id", Value: key}}
+	update := bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: "status", Value: domain.StockReservationReserved}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var previous domain.StockReservation
+	err = r.db.Collection("stock_reservations").FindOneAndUpdate(ctx, filter, update, opts).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return true, nil
+	}
+
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "AddStockReservation").Msg("Failed to add stock reservation")
+		return false, err
+	}
+
+	return false, nil
+}
+
+// ReleaseStockReservation marks the reservation under key as released, and
+// records it released when it was never made. It returns true only when a
+// reservation was released.
+func (r *MongoDBProductRepositoryImpl) ReleaseStockReservation(ctx context.Context, key string) (released bool, err error) {
+	filter := bson.D{{Key: "_id", Value: key}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "status", Value: domain.StockReservationReleased}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var previous domain.StockReservation
+	err = r.db.Collection("stock_reservations").FindOneAndUpdate(ctx, filter, update, opts).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("component", "ReleaseStockReservation").Msg("Failed to release stock reservation")
+		return false, err
+	}
+
+	return previous.Status == domain.StockReservationReserved, nil
+}
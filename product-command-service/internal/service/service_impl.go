@@ -13,6 +13,7 @@ import (
 	"github.com/alimikegami/point-of-sales/product-command-service/internal/dto"
 	"github.com/alimikegami/point-of-sales/product-command-service/internal/repository"
 	"github.com/alimikegami/point-of-sales/product-command-service/pkg/errs"
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
 	"github.com/segmentio/kafka-go"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -30,11 +31,21 @@ func CreateProductService(mongoDBRepo repository.MongoDBProductRepository, confi
 }
 
 func (s *ProductServiceImpl) AddProduct(ctx context.Context, data dto.ProductRequest) (err error) {
+	if data.Price == nil {
+		return errs.ErrClient
+	}
+
+	price, err := productPrice(*data.Price)
+	if err != nil {
+		return
+	}
+
 	productId, err := s.mongoDBRepo.AddProduct(ctx, domain.Product{
 		Name:        data.Name,
 		Description: data.Description,
 		Quantity:    data.Quantity,
-		Price:       data.Price,
+		Price:       price.Float64(),
+		UnitPrice:   price,
 	})
 
 	if err != nil {
@@ -48,7 +59,8 @@ func (s *ProductServiceImpl) AddProduct(ctx context.Context, data dto.ProductReq
 			Name:        data.Name,
 			Description: data.Description,
 			Quantity:    data.Quantity,
-			Price:       data.Price,
+			Price:       price.Float64(),
+			UnitPrice:   price,
 		},
 	}
 	fmt.Printf("%+v\n", kafkaMsg)
@@ -74,6 +86,20 @@ func (s *ProductServiceImpl) AddProduct(ctx context.Context, data dto.ProductReq
 	return
 }
 
+// productPrice defaults the currency of the price to IDR, as it is for the
+// prices given as a plain number.
+func productPrice(price money.Money) (money.Money, error) {
+	if price.Currency == "" {
+		price.Currency = money.IDR
+	}
+
+	if price.Amount < 0 {
+		return price, errs.ErrClient
+	}
+
+	return price, nil
+}
+
 func (s *ProductServiceImpl) writeKafkaMessage(msg []byte) error {
 	_, err := s.kafkaProducer.WriteMessages(
 		kafka.Message{
@@ -185,24 +211,49 @@ func (s *ProductServiceImpl) ConsumeEvent() {
 }
 
 func (s *ProductServiceImpl) RestoreProductStock(ctx context.Context, req dto.OrderRequest) (err error) {
-	// err = s.mongoDBRepo.HandleTrx(ctx, func(ctx mongo.SessionContext, repo repository.MongoDBProductRepository) error {
-	for _, orderItem := range req.OrderItems {
-		productID, err := primitive.ObjectIDFromHex(orderItem.ProductID)
+	restore := func(ctx context.Context) error {
+		for _, orderItem := range req.OrderItems {
+			productID, err := primitive.ObjectIDFromHex(orderItem.ProductID)
+			if err != nil {
+				return err
+			}
+
+			err = s.mongoDBRepo.UpdateProductQuantity(ctx, domain.Product{
+				ID:       productID,
+				Quantity: uint64(orderItem.Quantity),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if req.ReservationKey == "" {
+		err = restore(ctx)
 		if err != nil {
 			return err
 		}
+	} else {
+		// Only what was reserved under the key is given back, once.
+		released := false
+		err = s.mongoDBRepo.HandleTrx(ctx, func(sessionCtx mongo.SessionContext) error {
+			released, err = s.mongoDBRepo.ReleaseStockReservation(sessionCtx, req.ReservationKey)
+			if err != nil || !released {
+				return err
+			}
 
-		err = s.mongoDBRepo.UpdateProductQuantity(ctx, domain.Product{
-			ID:       productID,
-			Quantity: uint64(orderItem.Quantity),
+			return restore(sessionCtx)
 		})
 		if err != nil {
 			return err
 		}
-	}
 
-	// return nil
-	// })
+		if !released {
+			return nil
+		}
+	}
 
 	var products []domain.Product
 
@@ -246,7 +297,18 @@ func (s *ProductServiceImpl) RestoreProductStock(ctx context.Context, req dto.Or
 
 func (s *ProductServiceImpl) UpdateProductsQuantity(ctx context.Context, req dto.OrderRequest) (err error) {
 	// TODO: handle transactions
+	reserved := true
 	err = s.mongoDBRepo.HandleTrx(ctx, func(sessionCtx mongo.SessionContext) error {
+		// Items already reserved under the key, or released before the
+		// reservation arrived, are not taken out again.
+		if req.ReservationKey != "" {
+			var err error
+			reserved, err = s.mongoDBRepo.AddStockReservation(sessionCtx, req.ReservationKey)
+			if err != nil || !reserved {
+				return err
+			}
+		}
+
 		for _, orderItem := range req.OrderItems {
 			product, err := s.mongoDBRepo.GetProductByID(sessionCtx, orderItem.ProductID)
 			if err != nil {
@@ -273,6 +335,10 @@ func (s *ProductServiceImpl) UpdateProductsQuantity(ctx context.Context, req dto
 		return err
 	}
 
+	if !reserved {
+		return nil
+	}
+
 	var products []domain.Product
 
 	for _, orderItem := range req.OrderItems {
@@ -359,11 +425,21 @@ func (s *ProductServiceImpl) UpdateProduct(ctx context.Context, data dto.Product
 		ID:          objectID,
 		Name:        data.Name,
 		Description: data.Description,
-		Quantity:    data.Quantity,
-		Price:       data.Price,
 	}
 
-	err = s.mongoDBRepo.UpdateProduct(ctx, updatedData)
+	if data.Price != nil {
+		price, err := productPrice(*data.Price)
+		if err != nil {
+			return err
+		}
+
+		updatedData.Price = price.Float64()
+		updatedData.UnitPrice = price
+	}
+
+	// The event carries the stored document so the search index keeps the
+	// price and quantity the update did not touch.
+	product, err := s.mongoDBRepo.UpdateProduct(ctx, updatedData, data.Price != nil)
 	if err != nil {
 		return
 	}
@@ -372,10 +448,11 @@ func (s *ProductServiceImpl) UpdateProduct(ctx context.Context, data dto.Product
 		EventType: "update_product",
 		Data: dto.Product{
 			ID:          data.ID,
-			Name:        data.Name,
-			Description: data.Description,
-			Quantity:    data.Quantity,
-			Price:       data.Price,
+			Name:        product.Name,
+			Description: product.Description,
+			Quantity:    product.Quantity,
+			Price:       product.Price,
+			UnitPrice:   product.UnitPrice,
 		},
 	}
 
@@ -1,13 +1,29 @@
 package domain
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"github.com/alimikegami/pos-microservices/proto-defs/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Product struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name        string             `bson:"name" json:"name"`
 	Quantity    uint64             `bson:"quantity" json:"quantity"`
 	Description string             `bson:"description" json:"description"`
-	Price       float64            `bson:"price" json:"price"`
+	// Price is UnitPrice in major units, kept for the readers of the
+	// documents written before it.
+	Price     float64     `bson:"price" json:"price"`
+	UnitPrice money.Money `bson:"unit_price" json:"unit_price"`
+}
+
+// ProductUnitPrice is unitPrice, or the legacy price in IDR for the products
+// written before unit prices.
+func ProductUnitPrice(unitPrice money.Money, price float64) money.Money {
+	if unitPrice.Currency == "" {
+		return money.FromFloat(price, money.IDR)
+	}
+
+	return unitPrice
 }
 
 type ProductImage struct {
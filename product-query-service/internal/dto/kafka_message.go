@@ -1,5 +1,7 @@
 package dto
 
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
 type KafkaMessage struct {
 	EventType string      `json:"event_type"`
 	Data      interface{} `json:"data"`
@@ -13,13 +15,14 @@ type User struct {
 }
 
 type Product struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Quantity    uint64  `json:"quantity"`
-	Description string  `json:"description"`
-	UserID      string  `json:"user_id"`
-	UserName    string  `json:"user_name"`
-	Price       float64 `json:"price"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Quantity    uint64      `json:"quantity"`
+	Description string      `json:"description"`
+	UserID      string      `json:"user_id"`
+	UserName    string      `json:"user_name"`
+	Price       float64     `json:"price"`
+	UnitPrice   money.Money `json:"unit_price"`
 }
 
 type StockUpdate struct {
@@ -1,9 +1,12 @@
 package dto
 
+import "github.com/alimikegami/pos-microservices/proto-defs/money"
+
 type ProductResponse struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Quantity    uint64  `json:"quantity"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Quantity    uint64      `json:"quantity"`
+	Description string      `json:"description"`
+	Price       float64     `json:"price"`
+	UnitPrice   money.Money `json:"unit_price"`
 }
@@ -35,6 +35,7 @@ func (h *GrpcHandler) GetProductPrice(_ context.Context, req *pb.GetProductPrice
 			ProductId: product.ID,
 			Name:      product.Name,
 			Price:     float32(product.Price),
+			UnitPrice: product.UnitPrice.Proto(),
 			Quantity:  int64(product.Quantity),
 		})
 	}
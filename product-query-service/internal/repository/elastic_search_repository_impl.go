@@ -85,7 +85,9 @@ func (r *ElasticSearchProductRepositoryImpl) GetProducts(ctx context.Context, fi
 		return
 	}
 	for _, productData := range parsedResponseBody.Hits.Hits {
-		data = append(data, productData.Source)
+		product := productData.Source
+		product.UnitPrice = domain.ProductUnitPrice(product.UnitPrice, product.Price)
+		data = append(data, product)
 	}
 
 	return data, parsedResponseBody.Hits.Total.Value, nil
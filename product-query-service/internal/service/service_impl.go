@@ -41,6 +41,7 @@ func (s *ProductServiceImpl) GetProducts(ctx context.Context, filter pkgdto.Filt
 }
 
 func (s *ProductServiceImpl) AddProductToElasticsearch(ctx context.Context, data dto.ProductResponse) (err error) {
+	data.UnitPrice = domain.ProductUnitPrice(data.UnitPrice, data.Price)
 	err = s.elasticSearchRepo.AddProduct(ctx, "products", data)
 
 	return
@@ -198,6 +199,7 @@ func (s *ProductServiceImpl) UpdateElasticSearchProduct(ctx context.Context, dat
 		Description: data.Description,
 		Quantity:    data.Quantity,
 		Price:       data.Price,
+		UnitPrice:   domain.ProductUnitPrice(data.UnitPrice, data.Price),
 	})
 
 	return
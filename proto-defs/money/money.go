@@ -0,0 +1,219 @@
+// Package money holds amounts as integers in the minor unit of their
+// currency, so that they add up exactly wherever they travel.
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/alimikegami/pos-microservices/proto-defs/pb"
+)
+
+// IDR is the currency amounts are in unless told otherwise.
+const IDR = "IDR"
+
+var (
+	ErrCurrencyMismatch = errors.New("money: currencies do not match")
+	ErrInvalidAmount    = errors.New("money: invalid amount")
+)
+
+// exponents is the number of minor units digits of the currencies in use,
+// as in ISO 4217. Currencies missing from it have two.
+var exponents = map[string]int{
+	IDR:   2,
+	"JPY": 0,
+}
+
+// Money is Amount minor units, e.g. sen, of Currency.
+type Money struct {
+	Amount   int64  `json:"amount" bson:"amount"`
+	Currency string `json:"currency" bson:"currency"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// FromFloat converts an amount in major units, e.g. rupiah, rounding it to
+// the nearest minor unit. It is meant for amounts that were kept as floats.
+func FromFloat(amount float64, currency string) Money {
+	return Money{Amount: int64(math.Round(amount * float64(scale(currency)))), Currency: currency}
+}
+
+// Parse reads a decimal amount in major units, e.g. "15000.50", exactly.
+func Parse(amount string, currency string) (Money, error) {
+	amount = strings.TrimSpace(amount)
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(amount, "-")
+
+	whole, fraction, _ := strings.Cut(amount, ".")
+	exponent := exponentOf(currency)
+	if whole == "" || len(fraction) > exponent && strings.TrimRight(fraction[exponent:], "0") != "" {
+		return Money{}, ErrInvalidAmount
+	}
+
+	if len(fraction) > exponent {
+		fraction = fraction[:exponent]
+	}
+
+	digits := whole + fraction + strings.Repeat("0", exponent-len(fraction))
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// FromProto converts m, nil being no money at all.
+func FromProto(m *pb.Money) Money {
+	return Money{Amount: m.GetAmount(), Currency: m.GetCurrency()}
+}
+
+func (m Money) Proto() *pb.Money {
+	return &pb.Money{Amount: m.Amount, Currency: m.Currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Mul is the amount of quantity items of price m.
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+func (m Money) Equal(other Money) bool {
+	return m.Amount == other.Amount && m.Currency == other.Currency
+}
+
+// Float64 is the amount in major units, for the code still keeping amounts
+// as floats.
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / float64(scale(m.Currency))
+}
+
+// MajorUnits is the amount in whole major units, and whether it has none of
+// the minor units the major unit cannot express.
+func (m Money) MajorUnits() (amount int64, exact bool) {
+	s := scale(m.Currency)
+	return m.Amount / s, m.Amount%s == 0
+}
+
+// String is the amount in major units with every minor unit digit, e.g.
+// "15000.50", as Postgres NUMERIC and the payment gateways take it.
+func (m Money) String() string {
+	exponent := exponentOf(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	if exponent == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+
+	s := scale(m.Currency)
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/s, exponent, amount%s)
+}
+
+// Value stores the amount in a NUMERIC column, the currency goes in a column
+// of its own.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a NUMERIC column in the currency m already has, IDR when it has
+// none.
+func (m *Money) Scan(src interface{}) error {
+	currency := m.Currency
+	if currency == "" {
+		currency = IDR
+	}
+
+	var amount string
+	switch v := src.(type) {
+	case []byte:
+		amount = string(v)
+	case string:
+		amount = v
+	case int64:
+		amount = strconv.FormatInt(v, 10)
+	case float64:
+		*m = FromFloat(v, currency)
+		return nil
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+
+	parsed, err := Parse(amount, currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// UnmarshalJSON also reads the plain number of major units amounts were
+// written as before, in IDR.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var amount json.Number
+	if json.Unmarshal(data, &amount) == nil {
+		parsed, err := Parse(amount.String(), IDR)
+		if err != nil {
+			f, err := amount.Float64()
+			if err != nil {
+				return ErrInvalidAmount
+			}
+			parsed = FromFloat(f, IDR)
+		}
+
+		*m = parsed
+		return nil
+	}
+
+	type plain Money
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+func exponentOf(currency string) int {
+	exponent, ok := exponents[currency]
+	if !ok {
+		return 2
+	}
+
+	return exponent
+}
+
+func scale(currency string) int64 {
+	s := int64(1)
+	for i := 0; i < exponentOf(currency); i++ {
+		s *= 10
+	}
+
+	return s
+}
@@ -0,0 +1,161 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	type TestCase struct {
+		Name        string
+		Amount      string
+		Currency    string
+		Expected    Money
+		ExpectedErr error
+	}
+
+	testCases := []TestCase{
+		{Name: "Whole amount", Amount: "15000", Currency: IDR, Expected: New(1500000, IDR)},
+		{Name: "One fraction digit", Amount: "15000.5", Currency: IDR, Expected: New(1500050, IDR)},
+		{Name: "Every fraction digit", Amount: "15000.50", Currency: IDR, Expected: New(1500050, IDR)},
+		{Name: "Trailing zeros past the minor unit", Amount: "15000.500", Currency: IDR, Expected: New(1500050, IDR)},
+		{Name: "Negative amount", Amount: "-12.34", Currency: IDR, Expected: New(-1234, IDR)},
+		{Name: "Surrounding spaces", Amount: " 12 ", Currency: IDR, Expected: New(1200, IDR)},
+		{Name: "Currency without minor units", Amount: "100", Currency: "JPY", Expected: New(100, "JPY")},
+		{Name: "Zero fraction without minor units", Amount: "100.0", Currency: "JPY", Expected: New(100, "JPY")},
+		{Name: "Unknown currency has two digits", Amount: "1.5", Currency: "USD", Expected: New(150, "USD")},
+		{Name: "Fraction of a minor unit", Amount: "15000.505", Currency: IDR, ExpectedErr: ErrInvalidAmount},
+		{Name: "Fraction without minor units", Amount: "100.5", Currency: "JPY", ExpectedErr: ErrInvalidAmount},
+		{Name: "Empty", Amount: "", Currency: IDR, ExpectedErr: ErrInvalidAmount},
+		{Name: "Missing whole part", Amount: ".5", Currency: IDR, ExpectedErr: ErrInvalidAmount},
+		{Name: "Not a number", Amount: "abc", Currency: IDR, ExpectedErr: ErrInvalidAmount},
+		{Name: "Two decimal points", Amount: "1.2.3", Currency: IDR, ExpectedErr: ErrInvalidAmount},
+		{Name: "Overflow", Amount: "999999999999999999", Currency: IDR, ExpectedErr: ErrInvalidAmount},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			amount, err := Parse(tc.Amount, tc.Currency)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if amount != tc.Expected {
+				t.Errorf("expected %+v, got %+v", tc.Expected, amount)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	type TestCase struct {
+		Name     string
+		Money    Money
+		Expected string
+	}
+
+	testCases := []TestCase{
+		{Name: "Whole amount", Money: New(1500000, IDR), Expected: "15000.00"},
+		{Name: "Fraction", Money: New(1500050, IDR), Expected: "15000.50"},
+		{Name: "Less than a major unit", Money: New(5, IDR), Expected: "0.05"},
+		{Name: "Zero", Money: New(0, IDR), Expected: "0.00"},
+		{Name: "Negative", Money: New(-1234, IDR), Expected: "-12.34"},
+		{Name: "Negative less than a major unit", Money: New(-5, IDR), Expected: "-0.05"},
+		{Name: "Currency without minor units", Money: New(100, "JPY"), Expected: "100"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if s := tc.Money.String(); s != tc.Expected {
+				t.Errorf("expected %q, got %q", tc.Expected, s)
+			}
+
+			parsed, err := Parse(tc.Money.String(), tc.Money.Currency)
+			if err != nil || parsed != tc.Money {
+				t.Errorf("expected %q to parse back to %+v, got %+v, %v", tc.Money.String(), tc.Money, parsed, err)
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	type TestCase struct {
+		Name      string
+		Currency  string
+		Src       interface{}
+		Expected  Money
+		ExpectErr bool
+	}
+
+	testCases := []TestCase{
+		{Name: "NUMERIC bytes", Src: []byte("15000.50"), Expected: New(1500050, IDR)},
+		{Name: "String", Src: "12", Expected: New(1200, IDR)},
+		{Name: "Integer", Src: int64(7), Expected: New(700, IDR)},
+		{Name: "Float", Src: 12.5, Expected: New(1250, IDR)},
+		{Name: "Currency already set", Currency: "JPY", Src: []byte("100"), Expected: New(100, "JPY")},
+		{Name: "Fraction of a minor unit", Src: []byte("1.005"), ExpectErr: true},
+		{Name: "Not a number", Src: "abc", ExpectErr: true},
+		{Name: "Unsupported type", Src: true, ExpectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			m := Money{Currency: tc.Currency}
+			err := m.Scan(tc.Src)
+			if tc.ExpectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", m)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if m != tc.Expected {
+				t.Errorf("expected %+v, got %+v", tc.Expected, m)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	type TestCase struct {
+		Name      string
+		Data      string
+		Expected  Money
+		ExpectErr bool
+	}
+
+	testCases := []TestCase{
+		{Name: "Object", Data: `{"amount":100,"currency":"JPY"}`, Expected: New(100, "JPY")},
+		{Name: "Legacy whole number", Data: `15000`, Expected: New(1500000, IDR)},
+		{Name: "Legacy fraction", Data: `15000.5`, Expected: New(1500050, IDR)},
+		{Name: "Legacy fraction of a minor unit is rounded", Data: `0.125`, Expected: New(13, IDR)},
+		{Name: "Legacy exponent", Data: `1.5e3`, Expected: New(150000, IDR)},
+		{Name: "Null", Data: `null`, Expected: Money{}},
+		{Name: "Not an amount", Data: `"abc"`, ExpectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var m Money
+			err := m.UnmarshalJSON([]byte(tc.Data))
+			if tc.ExpectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", m)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if m != tc.Expected {
+				t.Errorf("expected %+v, got %+v", tc.Expected, m)
+			}
+		})
+	}
+}
@@ -23,12 +23,16 @@ const (
 )
 
 type Product struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ProductId     string                 `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
-	Quantity      int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
-	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
-	Price         float32                `protobuf:"fixed32,4,opt,name=price,proto3" json:"price,omitempty"`
-	Description   string                 `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductId string                 `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Quantity  int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Name      string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// price cannot hold every rupiah amount in a float, use unit_price.
+	//
+	// Deprecated: Marked as deprecated in product.proto.
+	Price         float32 `protobuf:"fixed32,4,opt,name=price,proto3" json:"price,omitempty"`
+	Description   string  `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	UnitPrice     *Money  `protobuf:"bytes,6,opt,name=unit_price,json=unitPrice,proto3" json:"unit_price,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -84,6 +88,7 @@ func (x *Product) GetName() string {
 	return ""
 }
 
+// Deprecated: Marked as deprecated in product.proto.
 func (x *Product) GetPrice() float32 {
 	if x != nil {
 		return x.Price
@@ -98,6 +103,13 @@ func (x *Product) GetDescription() string {
 	return ""
 }
 
+func (x *Product) GetUnitPrice() *Money {
+	if x != nil {
+		return x.UnitPrice
+	}
+	return nil
+}
+
 type ProductQuantityUpdate struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ProductId     string                 `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
@@ -151,10 +163,14 @@ func (x *ProductQuantityUpdate) GetQuantity() int64 {
 }
 
 type UpdateProductQuantityRequest struct {
-	state         protoimpl.MessageState   `protogen:"open.v1"`
-	Products      []*ProductQuantityUpdate `protobuf:"bytes,1,rep,name=products,proto3" json:"products,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state    protoimpl.MessageState   `protogen:"open.v1"`
+	Products []*ProductQuantityUpdate `protobuf:"bytes,1,rep,name=products,proto3" json:"products,omitempty"`
+	// reservation_key, when set, reserves the products at most once under that
+	// key, and a restore carrying the same key gives back only what it
+	// reserved.
+	ReservationKey string `protobuf:"bytes,2,opt,name=reservation_key,json=reservationKey,proto3" json:"reservation_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *UpdateProductQuantityRequest) Reset() {
@@ -194,6 +210,13 @@ func (x *UpdateProductQuantityRequest) GetProducts() []*ProductQuantityUpdate {
 	return nil
 }
 
+func (x *UpdateProductQuantityRequest) GetReservationKey() string {
+	if x != nil {
+		return x.ReservationKey
+	}
+	return ""
+}
+
 type GetProductPriceRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ProductIds    []string               `protobuf:"bytes,1,rep,name=product_ids,json=productIds,proto3" json:"product_ids,omitempty"`
@@ -282,29 +305,90 @@ func (x *ProductPriceResponse) GetProducts() []*Product {
 	return nil
 }
 
+// Money is an amount in the minor unit of its currency, e.g. 1500000 is
+// IDR 15,000.00, so amounts add up exactly.
+type Money struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Amount int64                  `protobuf:"varint,1,opt,name=amount,proto3" json:"amount,omitempty"`
+	// currency is the ISO 4217 code, e.g. IDR.
+	Currency      string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Money) Reset() {
+	*x = Money{}
+	mi := &file_product_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Money) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Money) ProtoMessage() {}
+
+func (x *Money) ProtoReflect() protoreflect.Message {
+	mi := &file_product_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Money.ProtoReflect.Descriptor instead.
+func (*Money) Descriptor() ([]byte, []int) {
+	return file_product_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Money) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Money) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
 var File_product_proto protoreflect.FileDescriptor
 
 const file_product_proto_rawDesc = "" +
 	"\n" +
-	"\rproduct.proto\x12\aproduct\x1a\x1bgoogle/protobuf/empty.proto\"\x90\x01\n" +
+	"\rproduct.proto\x12\aproduct\x1a\x1bgoogle/protobuf/empty.proto\"\xc3\x01\n" +
 	"\aProduct\x12\x1d\n" +
 	"\n" +
 	"product_id\x18\x01 \x01(\tR\tproductId\x12\x1a\n" +
 	"\bquantity\x18\x02 \x01(\x03R\bquantity\x12\x12\n" +
-	"\x04name\x18\x03 \x01(\tR\x04name\x12\x14\n" +
-	"\x05price\x18\x04 \x01(\x02R\x05price\x12 \n" +
-	"\vdescription\x18\x05 \x01(\tR\vdescription\"R\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x18\n" +
+	"\x05price\x18\x04 \x01(\x02B\x02\x18\x01R\x05price\x12 \n" +
+	"\vdescription\x18\x05 \x01(\tR\vdescription\x12-\n" +
+	"\n" +
+	"unit_price\x18\x06 \x01(\v2\x0e.product.MoneyR\tunitPrice\"R\n" +
 	"\x15ProductQuantityUpdate\x12\x1d\n" +
 	"\n" +
 	"product_id\x18\x01 \x01(\tR\tproductId\x12\x1a\n" +
-	"\bquantity\x18\x02 \x01(\x03R\bquantity\"Z\n" +
+	"\bquantity\x18\x02 \x01(\x03R\bquantity\"\x83\x01\n" +
 	"\x1cUpdateProductQuantityRequest\x12:\n" +
-	"\bproducts\x18\x01 \x03(\v2\x1e.product.ProductQuantityUpdateR\bproducts\"9\n" +
+	"\bproducts\x18\x01 \x03(\v2\x1e.product.ProductQuantityUpdateR\bproducts\x12'\n" +
+	"\x0freservation_key\x18\x02 \x01(\tR\x0ereservationKey\"9\n" +
 	"\x16GetProductPriceRequest\x12\x1f\n" +
 	"\vproduct_ids\x18\x01 \x03(\tR\n" +
 	"productIds\"D\n" +
 	"\x14ProductPriceResponse\x12,\n" +
-	"\bproducts\x18\x01 \x03(\v2\x10.product.ProductR\bproducts2t\n" +
+	"\bproducts\x18\x01 \x03(\v2\x10.product.ProductR\bproducts\";\n" +
+	"\x05Money\x12\x16\n" +
+	"\x06amount\x18\x01 \x01(\x03R\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency2t\n" +
 	"\x15ProductCommandService\x12[\n" +
 	"\x1aUpdateProductQuantityBatch\x12%.product.UpdateProductQuantityRequest\x1a\x16.google.protobuf.Empty2h\n" +
 	"\x13ProductQueryService\x12Q\n" +
@@ -322,27 +406,29 @@ func file_product_proto_rawDescGZIP() []byte {
 	return file_product_proto_rawDescData
 }
 
-var file_product_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_product_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_product_proto_goTypes = []any{
 	(*Product)(nil),                      // 0: product.Product
 	(*ProductQuantityUpdate)(nil),        // 1: product.ProductQuantityUpdate
 	(*UpdateProductQuantityRequest)(nil), // 2: product.UpdateProductQuantityRequest
 	(*GetProductPriceRequest)(nil),       // 3: product.GetProductPriceRequest
 	(*ProductPriceResponse)(nil),         // 4: product.ProductPriceResponse
-	(*emptypb.Empty)(nil),                // 5: google.protobuf.Empty
+	(*Money)(nil),                        // 5: product.Money
+	(*emptypb.Empty)(nil),                // 6: google.protobuf.Empty
 }
 var file_product_proto_depIdxs = []int32{
-	1, // 0: product.UpdateProductQuantityRequest.products:type_name -> product.ProductQuantityUpdate
-	0, // 1: product.ProductPriceResponse.products:type_name -> product.Product
-	2, // 2: product.ProductCommandService.UpdateProductQuantityBatch:input_type -> product.UpdateProductQuantityRequest
-	3, // 3: product.ProductQueryService.GetProductPrice:input_type -> product.GetProductPriceRequest
-	5, // 4: product.ProductCommandService.UpdateProductQuantityBatch:output_type -> google.protobuf.Empty
-	4, // 5: product.ProductQueryService.GetProductPrice:output_type -> product.ProductPriceResponse
-	4, // [4:6] is the sub-list for method output_type
-	2, // [2:4] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	5, // 0: product.Product.unit_price:type_name -> product.Money
+	1, // 1: product.UpdateProductQuantityRequest.products:type_name -> product.ProductQuantityUpdate
+	0, // 2: product.ProductPriceResponse.products:type_name -> product.Product
+	2, // 3: product.ProductCommandService.UpdateProductQuantityBatch:input_type -> product.UpdateProductQuantityRequest
+	3, // 4: product.ProductQueryService.GetProductPrice:input_type -> product.GetProductPriceRequest
+	6, // 5: product.ProductCommandService.UpdateProductQuantityBatch:output_type -> google.protobuf.Empty
+	4, // 6: product.ProductQueryService.GetProductPrice:output_type -> product.ProductPriceResponse
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_product_proto_init() }
@@ -356,7 +442,7 @@ func file_product_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_product_proto_rawDesc), len(file_product_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
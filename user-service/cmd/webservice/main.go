@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/alimikegami/pos-microservices/user-service/config"
 	"github.com/alimikegami/pos-microservices/user-service/internal/app"
@@ -17,6 +18,21 @@ func main() {
 		panic(err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = runMigrateCommand(db, os.Args[2:])
+		if err != nil {
+			log.Fatalf("Failed to migrate the database: %v", err)
+		}
+		return
+	}
+
+	if config.AutoMigrate {
+		err = autoMigrate(db)
+		if err != nil {
+			log.Fatalf("Failed to migrate the database: %v", err)
+		}
+	}
+
 	server := app.App{
 		DB:     db,
 		Config: config,
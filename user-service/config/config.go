@@ -16,6 +16,8 @@ type Config struct {
 	JWTConfig        JWTConfig
 	KafkaConfig      KafkaConfig
 	TracingConfig    TracingConfig
+	// AutoMigrate applies the pending migrations when the service starts.
+	AutoMigrate bool
 }
 
 func CreateNewConfig() *Config {
@@ -59,6 +61,7 @@ func CreateNewConfig() *Config {
 	}
 
 	conf.KafkaConfig.BrokerPartition = brokerPartition
+	conf.AutoMigrate, _ = strconv.ParseBool(os.Getenv("AUTO_MIGRATE"))
 
 	return &conf
 }
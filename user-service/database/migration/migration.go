@@ -0,0 +1,10 @@
+// Package migration embeds the SQL migrations of the service, so the binary
+// can migrate its database without the files at hand.
+package migration
+
+import "embed"
+
+// FS holds the migrations, named {version}_{title}.{up|down}.sql.
+//
+//go:embed *.sql
+var FS embed.FS